@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/DataDog/ddtest/internal/buildinfo"
@@ -18,6 +21,7 @@ import (
 	"github.com/DataDog/ddtest/internal/settings"
 	"github.com/DataDog/ddtest/internal/telemetry"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -34,8 +38,28 @@ var rootCmd = &cobra.Command{
 
 var (
 	planCommand = func(ctx context.Context, telemetryClient telemetry.Client) error {
+		// A workspace package plan never plans a workspace itself.
+		if workspace := settings.GetWorkspace(); workspace != "" && os.Getenv(constants.WorkspacePackageEnvVar) == "" {
+			args, err := workspacePlanArgs(rootCmd.PersistentFlags())
+			if err != nil {
+				return errcode.WithCode(errcode.PlanWorkspaceInvalid, err)
+			}
+			return planner.PlanWorkspace(ctx, workspace, args)
+		}
 		return planner.NewWithTelemetry(telemetryClient).Plan(ctx)
 	}
+	serveCommand = func(ctx context.Context, telemetryClient telemetry.Client) error {
+		return runner.NewWithTelemetry(telemetryClient).Serve(ctx)
+	}
+	explainCommand = func(target string) error {
+		return planner.Explain(os.Stdout, target)
+	}
+	verifyPlanCommand = func() error {
+		return planner.VerifyPlan(os.Stdout)
+	}
+	planDiffCommand = func(basePlanDir string, headPlanDir string, jsonOutput bool) error {
+		return planner.DiffPlans(os.Stdout, basePlanDir, headPlanDir, jsonOutput)
+	}
 	newRunner          = func(telemetryClient telemetry.Client) runner.Runner { return runner.NewWithTelemetry(telemetryClient) }
 	newTelemetryClient = createTelemetryClient
 	exitProcess        = os.Exit
@@ -59,6 +83,43 @@ var runCmd = &cobra.Command{
 	Run:   runTestCommand,
 }
 
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a test queue for CI nodes",
+	Long:  "Loads the test optimization plan and serves its test files as a queue. CI nodes started with `ddtest run --queue-url` lease batches of test files from it until every file has run.",
+	Run:   runServeCommand,
+}
+
+var explainCmd = &cobra.Command{
+	Use:   "explain <test-file-or-suite>",
+	Short: "Explain plan decisions for a test file or suite",
+	Long:  "Reads the stored test optimization plan and prints whether a test file or suite runs, on which runner, its estimated duration and where it came from, and how TIA skippables, disabled tests, and unskippable markers affected it.",
+	Args:  cobra.ExactArgs(1),
+	// explain only reads plan files, so it does not need git.
+	PersistentPreRunE: func(*cobra.Command, []string) error { return nil },
+	Run:               runExplainCommand,
+}
+
+var verifyPlanCmd = &cobra.Command{
+	Use:   "verify-plan",
+	Short: "Verify that the plan directory is complete",
+	Long:  "Checks a plan directory, for example one copied between CI jobs: the manifest version, the plan cache, one runner split per parallel runner, splits that cover test-files.txt exactly once, and that every planned test file exists. Fails with a specific error code for the first problem found.",
+	Args:  cobra.NoArgs,
+	// verify-plan only reads plan files, so it does not need git.
+	PersistentPreRunE: func(*cobra.Command, []string) error { return nil },
+	Run:               runVerifyPlanCommand,
+}
+
+var planDiffCmd = &cobra.Command{
+	Use:   "plan-diff <base-plan-dir> <head-plan-dir>",
+	Short: "Compare two plan directories",
+	Long:  "Compares two plan directories, for example the plans of a pull request and of its base commit: test files added to or removed from test-files.txt, changed runner assignments, and changes in parallel runners, skippable percentage, and estimated wall time.",
+	Args:  cobra.ExactArgs(2),
+	// plan-diff only reads plan files, so it does not need git.
+	PersistentPreRunE: func(*cobra.Command, []string) error { return nil },
+	Run:               runPlanDiffCommand,
+}
+
 type persistentFlagBinding struct {
 	configKey string
 	flagName  string
@@ -71,9 +132,38 @@ var rootPersistentFlagBindings = []persistentFlagBinding{
 	{configKey: "max_parallelism", flagName: "max-parallelism"},
 	{configKey: "parallel_runner_overhead", flagName: "ci-job-overhead"},
 	{configKey: "target_time", flagName: "target-time"},
+	{configKey: "split_optimizer", flagName: "split-optimizer"},
+	{configKey: "duration_estimate", flagName: "duration-estimate"},
+	{configKey: "optimize_for", flagName: "optimize-for"},
+	{configKey: "ci_job_price_per_minute", flagName: "ci-job-price-per-minute"},
+	{configKey: "ci_job_billing_increment", flagName: "ci-job-billing-increment"},
+	{configKey: "ci_job_startup_time", flagName: "ci-job-startup-time"},
+	{configKey: "ci_budget", flagName: "ci-budget"},
+	{configKey: "chunk_slow_test_files", flagName: "chunk-slow-test-files"},
+	{configKey: "constraints_file", flagName: "constraints-file"},
+	{configKey: "workspace", flagName: "workspace"},
+	{configKey: "previous_plan", flagName: "previous-plan"},
+	{configKey: "sticky_split_tolerance", flagName: "sticky-split-tolerance"},
+	{configKey: "plan_layout", flagName: "plan-layout"},
+	{configKey: "split_order", flagName: "split-order"},
+	{configKey: "pipeline_template", flagName: "pipeline-template"},
 	{configKey: "worker_env", flagName: "worker-env"},
 	{configKey: "ci_node", flagName: "ci-node"},
 	{configKey: "ci_node_workers", flagName: "ci-node-workers"},
+	{configKey: "ci_node_capacities", flagName: "ci-node-capacities"},
+	{configKey: "work_queue", flagName: "work-queue"},
+	{configKey: "work_queue_batch_size", flagName: "work-queue-batch-size"},
+	{configKey: "queue_url", flagName: "queue-url"},
+	{configKey: "retry_failed_files", flagName: "retry-failed-files"},
+	{configKey: "worker_output", flagName: "worker-output"},
+	{configKey: "fail_fast", flagName: "fail-fast"},
+	{configKey: "worker_timeout", flagName: "worker-timeout"},
+	{configKey: "worker_timeout_multiplier", flagName: "worker-timeout-multiplier"},
+	{configKey: "worker_timeout_signal", flagName: "worker-timeout-signal"},
+	{configKey: "junit_reports", flagName: "junit-reports"},
+	{configKey: "run_report_path", flagName: "run-report-path"},
+	{configKey: "prefer_local_durations", flagName: "prefer-local-durations"},
+	{configKey: "duration_history", flagName: "duration-history"},
 	{configKey: "command", flagName: "command"},
 	{configKey: "tests_location", flagName: "tests-location"},
 	{configKey: "tests_exclude_pattern", flagName: "tests-exclude-pattern"},
@@ -84,18 +174,52 @@ var rootPersistentFlagBindings = []persistentFlagBinding{
 	{configKey: "runtime_tags", flagName: "runtime-tags"},
 }
 
+var servePersistentFlagBindings = []persistentFlagBinding{
+	{configKey: "queue_listen", flagName: "queue-listen"},
+	{configKey: "queue_lease_timeout", flagName: "queue-lease-timeout"},
+}
+
 func init() {
 	rootCmd.SetVersionTemplate("{{ .Version }}\n")
 
 	rootCmd.PersistentFlags().String("platform", "ruby", "Platform that runs tests")
-	rootCmd.PersistentFlags().String("framework", "rspec", "Test framework to use")
+	rootCmd.PersistentFlags().String("framework", "rspec", "Test framework to use, or a comma-separated list of name[=pattern] entries to plan several frameworks of the --platform platform together")
 	rootCmd.PersistentFlags().Int("min-parallelism", defaultParallelism, "Minimum number of parallel test processes (default: number of physical CPUs)")
 	rootCmd.PersistentFlags().Int("max-parallelism", defaultParallelism, "Maximum number of parallel test processes (default: number of physical CPUs)")
 	rootCmd.PersistentFlags().String("ci-job-overhead", settings.DefaultParallelRunnerOverhead().String(), "Modeled overhead for adding one more CI job / parallel runner (for example, 25s, 1m, 1500ms, or 0s to disable the bias). Increase it to use fewer CI jobs; decrease it to prefer faster wall time")
 	rootCmd.PersistentFlags().String("target-time", settings.DefaultTargetTime().String(), "Target wall time for selected CI job / parallel runner split (for example, 10m, 300s, 1500ms, or 0s to disable the target)")
+	rootCmd.PersistentFlags().String("split-optimizer", string(settings.SplitOptimizerGreedy), `How test files are split between runners: "greedy" assigns each file, longest first, to the least loaded runner; "local-search" then moves and swaps files between runners to reduce wall time`)
+	rootCmd.PersistentFlags().String("duration-estimate", string(settings.DurationEstimateP50), `Which test file durations the planner balances: "p50" uses median durations; "expected" and "p90" model each test file from its P50 and P90 durations and minimize the expected or P90 wall time`)
+	rootCmd.PersistentFlags().String("optimize-for", string(settings.OptimizationGoalTime), `What parallelism selection minimizes: "time" minimizes wall time plus CI job overhead within --ci-budget; "cost" minimizes estimated CI cost within --target-time`)
+	rootCmd.PersistentFlags().Float64("ci-job-price-per-minute", 0, "Price of one minute of a CI job, used to estimate the CI cost of each split (default: 0 disables the cost model)")
+	rootCmd.PersistentFlags().String("ci-job-billing-increment", "0s", "Increment CI jobs are billed in, such as 1m for providers that round each job up to whole minutes (default: 0s bills exact durations)")
+	rootCmd.PersistentFlags().String("ci-job-startup-time", "0s", "Billed time each CI job spends before its tests start, such as checkout and dependency setup")
+	rootCmd.PersistentFlags().Float64("ci-budget", 0, "Maximum estimated CI cost of one pipeline run; splits over budget are only selected when none fits (default: 0 disables the budget)")
+	rootCmd.PersistentFlags().Bool("chunk-slow-test-files", false, "Split test files that would take longer than one runner's share of the work into chunks of tests (RSpec, Minitest, and pytest with full test discovery)")
+	rootCmd.PersistentFlags().String("constraints-file", "", "Path to a JSON file of glob rules that keep test files on the same runner (group) or make them run alone (exclusive)")
+	rootCmd.PersistentFlags().String("workspace", "", "Path to a JSON workspace file listing the packages of a monorepo that ddtest plan plans one by one")
+	rootCmd.PersistentFlags().String("previous-plan", "", "Plan directory of an earlier plan, such as a cached .testoptimization; test files stay on their previous runner unless that makes the split too unbalanced")
+	rootCmd.PersistentFlags().Float64("sticky-split-tolerance", settings.DefaultStickySplitTolerance(), "How much longer, in percent, the wall time may get to keep test files on their --previous-plan runner")
+	rootCmd.PersistentFlags().String("plan-layout", string(settings.PlanLayoutV1), `Plan files to write: "v1" writes the plain text plan files; "v2" also writes the versioned .testoptimization/plan.json manifest`)
+	rootCmd.PersistentFlags().String("split-order", "", `Comma-separated priorities that order the test files within each runner split, highest first: "new", "changed", "flaky", "longest" (default: assignment order)`)
+	rootCmd.PersistentFlags().String("pipeline-template", "", "Path to a job skeleton that ddtest plan renders into a Buildkite, GitLab, or CircleCI pipeline definition for the selected parallelism")
 	rootCmd.PersistentFlags().String("worker-env", "", "Worker environment configuration")
-	rootCmd.PersistentFlags().Int("ci-node", -1, "CI node index to run (0-indexed; default: -1 disables CI-node mode)")
+	rootCmd.PersistentFlags().Int("ci-node", -1, "CI node index to run (0-indexed; default: detected from the parallel job variables of the CI provider, -1 disables CI-node mode)")
 	rootCmd.PersistentFlags().String("ci-node-workers", "1", `Number of parallel workers per CI node (positive integer or "ncpu"; default: 1)`)
+	rootCmd.PersistentFlags().String("ci-node-capacities", "", `Local worker count of CI nodes that differ in size, such as "0-3:8,4-7:4"; planning balances expected wall time across them`)
+	rootCmd.PersistentFlags().Bool("work-queue", false, "Let local workers pull test files, longest estimated first, from a shared queue instead of running static splits")
+	rootCmd.PersistentFlags().Int("work-queue-batch-size", 1, "Number of test files a worker pulls from the work queue at a time")
+	rootCmd.PersistentFlags().String("queue-url", "", "URL of a ddtest serve test queue to lease test files from instead of running a static split")
+	rootCmd.PersistentFlags().Int("retry-failed-files", 0, "Number of times to re-run each test file of a failed batch on its own before the run fails (default: 0 disables retries)")
+	rootCmd.PersistentFlags().String("worker-output", string(settings.WorkerOutputStream), `How to show worker test output: "stream", "prefix", "buffered", or "file"`)
+	rootCmd.PersistentFlags().Bool("fail-fast", false, "Stop the other local workers as soon as one worker fails instead of running every test file")
+	rootCmd.PersistentFlags().String("worker-timeout", "0s", "Maximum time a worker's test process may run before it is stopped (for example, 20m; default: 0s disables it)")
+	rootCmd.PersistentFlags().Float64("worker-timeout-multiplier", 0, "Stop a worker's test process once it runs longer than its estimated duration times this multiplier (default: 0 disables it)")
+	rootCmd.PersistentFlags().String("worker-timeout-signal", "SIGQUIT", `Signal sent to a timed-out test process so it can dump diagnostics before it is terminated ("SIGQUIT", "SIGABRT", "SIGINT", "SIGTERM", "SIGUSR1", "SIGUSR2", or "none")`)
+	rootCmd.PersistentFlags().String("junit-reports", "", "Glob pattern of JUnit XML reports written by the test command; ddtest run records per-file durations from them for later plans")
+	rootCmd.PersistentFlags().String("run-report-path", "", "Path of the JSON run report written by ddtest run (default: .testoptimization/run-report-node-N.json)")
+	rootCmd.PersistentFlags().Bool("prefer-local-durations", false, "Prefer test file durations recorded from JUnit reports over backend durations when planning")
+	rootCmd.PersistentFlags().String("duration-history", "", "Path to a JSONL history of observed test file durations that ddtest run appends to and ddtest plan merges with backend durations")
 	rootCmd.PersistentFlags().String("command", "", "Test command that ddtest should wrap")
 	rootCmd.PersistentFlags().String("tests-location", "", "Glob pattern used to discover test files")
 	rootCmd.PersistentFlags().String("tests-exclude-pattern", "", "Glob pattern used to exclude test files from discovery")
@@ -109,12 +233,64 @@ func init() {
 		os.Exit(1)
 	}
 
+	serveCmd.PersistentFlags().String("queue-listen", settings.DefaultQueueListen(), "Address the test queue listens on")
+	serveCmd.PersistentFlags().String("queue-lease-timeout", settings.DefaultQueueLeaseTimeout().String(), "How long a leased batch stays assigned to a CI node without a heartbeat before it is handed out again")
+	if err := bindPersistentFlags(serveCmd, servePersistentFlagBindings); err != nil {
+		fmt.Fprintf(os.Stderr, "Error binding serve CLI flags: %v\n", err)
+		os.Exit(1)
+	}
+
 	rootCmd.AddCommand(planCmd)
 	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(explainCmd)
+	rootCmd.AddCommand(verifyPlanCmd)
+	planDiffCmd.Flags().Bool("json", false, "Print the plan differences as JSON")
+	rootCmd.AddCommand(planDiffCmd)
 
 	cobra.OnInitialize(settings.Init)
 }
 
+// workspacePathFlagBindings are the settings that hold a path. Package plans
+// run from their package directory, so relative paths are passed to them
+// resolved against the working directory of the workspace plan.
+var workspacePathFlagBindings = []persistentFlagBinding{
+	{configKey: "constraints_file", flagName: "constraints-file"},
+	{configKey: "previous_plan", flagName: "previous-plan"},
+	{configKey: "pipeline_template", flagName: "pipeline-template"},
+	{configKey: "junit_reports", flagName: "junit-reports"},
+	{configKey: "run_report_path", flagName: "run-report-path"},
+	{configKey: "duration_history", flagName: "duration-history"},
+	{configKey: "test_discovery_cache", flagName: "test-discovery-cache"},
+}
+
+// workspacePlanArgs returns the flags set on the command line, except
+// --workspace, to pass to the plan of each workspace package. Path settings,
+// whether set by a flag or an environment variable, are passed as absolute
+// paths.
+func workspacePlanArgs(flags *pflag.FlagSet) ([]string, error) {
+	pathFlags := make(map[string]bool, len(workspacePathFlagBindings))
+	var args []string
+	for _, binding := range workspacePathFlagBindings {
+		path := viper.GetString(binding.configKey)
+		if path == "" {
+			continue
+		}
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s %s: %w", binding.flagName, path, err)
+		}
+		pathFlags[binding.flagName] = true
+		args = append(args, "--"+binding.flagName+"="+absPath)
+	}
+	flags.Visit(func(flag *pflag.Flag) {
+		if flag.Name != "workspace" && !pathFlags[flag.Name] {
+			args = append(args, "--"+flag.Name+"="+flag.Value.String())
+		}
+	})
+	return args, nil
+}
+
 func bindPersistentFlags(cmd *cobra.Command, bindings []persistentFlagBinding) error {
 	for _, binding := range bindings {
 		flag := cmd.PersistentFlags().Lookup(binding.flagName)
@@ -147,6 +323,8 @@ func gitAvailabilityTelemetryContext(cmd *cobra.Command) (telemetry.CLICommandTy
 		return telemetry.CLICommandPlan, errcode.PlanGitUnavailable, true
 	case string(telemetry.CLICommandRun):
 		return telemetry.CLICommandRun, errcode.RunGitUnavailable, true
+	case string(telemetry.CLICommandServe):
+		return telemetry.CLICommandServe, errcode.ServeGitUnavailable, true
 	default:
 		return "", errcode.Unknown, false
 	}
@@ -176,6 +354,56 @@ func runTestCommand(cmd *cobra.Command, args []string) {
 	}
 }
 
+func runServeCommand(cmd *cobra.Command, args []string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := runWithTelemetry(ctx, telemetry.CLICommandServe, func(telemetryClient telemetry.Client) error {
+		return serveCommand(ctx, telemetryClient)
+	})
+	if err != nil {
+		slog.Error("Test queue failed", "error", err)
+		exitProcess(1)
+		return
+	}
+}
+
+func runExplainCommand(cmd *cobra.Command, args []string) {
+	err := runWithTelemetry(context.Background(), telemetry.CLICommandExplain, func(telemetry.Client) error {
+		return explainCommand(args[0])
+	})
+	if err != nil {
+		slog.Error("Explain failed", "error", err)
+		exitProcess(1)
+		return
+	}
+}
+
+func runVerifyPlanCommand(cmd *cobra.Command, args []string) {
+	err := runWithTelemetry(context.Background(), telemetry.CLICommandVerifyPlan, func(telemetry.Client) error {
+		return verifyPlanCommand()
+	})
+	if err != nil {
+		slog.Error("Plan verification failed", "error", err)
+		exitProcess(1)
+		return
+	}
+}
+
+func runPlanDiffCommand(cmd *cobra.Command, args []string) {
+	err := runWithTelemetry(context.Background(), telemetry.CLICommandPlanDiff, func(telemetry.Client) error {
+		jsonOutput, err := cmd.Flags().GetBool("json")
+		if err != nil {
+			return err
+		}
+		return planDiffCommand(args[0], args[1], jsonOutput)
+	})
+	if err != nil {
+		slog.Error("Plan diff failed", "error", err)
+		exitProcess(1)
+		return
+	}
+}
+
 func createTelemetryClient() (telemetry.Client, error) {
 	ciTags := environment.GetCITags()
 	return telemetry.NewClient(telemetry.Config{
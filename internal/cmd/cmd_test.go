@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"os"
+	"path/filepath"
 	"slices"
 	"strings"
 	"testing"
@@ -83,6 +84,90 @@ func TestRootCommandFlags(t *testing.T) {
 		return
 	}
 
+	ciNodeCapacitiesFlag := rootCmd.PersistentFlags().Lookup("ci-node-capacities")
+	if ciNodeCapacitiesFlag == nil {
+		t.Error("ci-node-capacities flag should be defined")
+		return
+	}
+
+	workQueueFlag := rootCmd.PersistentFlags().Lookup("work-queue")
+	if workQueueFlag == nil {
+		t.Error("work-queue flag should be defined")
+		return
+	}
+
+	workQueueBatchSizeFlag := rootCmd.PersistentFlags().Lookup("work-queue-batch-size")
+	if workQueueBatchSizeFlag == nil {
+		t.Error("work-queue-batch-size flag should be defined")
+		return
+	}
+
+	queueURLFlag := rootCmd.PersistentFlags().Lookup("queue-url")
+	if queueURLFlag == nil {
+		t.Error("queue-url flag should be defined")
+		return
+	}
+
+	retryFailedFilesFlag := rootCmd.PersistentFlags().Lookup("retry-failed-files")
+	if retryFailedFilesFlag == nil {
+		t.Error("retry-failed-files flag should be defined")
+		return
+	}
+
+	workerOutputFlag := rootCmd.PersistentFlags().Lookup("worker-output")
+	if workerOutputFlag == nil {
+		t.Error("worker-output flag should be defined")
+		return
+	}
+
+	failFastFlag := rootCmd.PersistentFlags().Lookup("fail-fast")
+	if failFastFlag == nil {
+		t.Error("fail-fast flag should be defined")
+		return
+	}
+
+	workerTimeoutFlag := rootCmd.PersistentFlags().Lookup("worker-timeout")
+	if workerTimeoutFlag == nil {
+		t.Error("worker-timeout flag should be defined")
+		return
+	}
+
+	workerTimeoutMultiplierFlag := rootCmd.PersistentFlags().Lookup("worker-timeout-multiplier")
+	if workerTimeoutMultiplierFlag == nil {
+		t.Error("worker-timeout-multiplier flag should be defined")
+		return
+	}
+
+	workerTimeoutSignalFlag := rootCmd.PersistentFlags().Lookup("worker-timeout-signal")
+	if workerTimeoutSignalFlag == nil {
+		t.Error("worker-timeout-signal flag should be defined")
+		return
+	}
+
+	junitReportsFlag := rootCmd.PersistentFlags().Lookup("junit-reports")
+	if junitReportsFlag == nil {
+		t.Error("junit-reports flag should be defined")
+		return
+	}
+
+	runReportPathFlag := rootCmd.PersistentFlags().Lookup("run-report-path")
+	if runReportPathFlag == nil {
+		t.Error("run-report-path flag should be defined")
+		return
+	}
+
+	preferLocalDurationsFlag := rootCmd.PersistentFlags().Lookup("prefer-local-durations")
+	if preferLocalDurationsFlag == nil {
+		t.Error("prefer-local-durations flag should be defined")
+		return
+	}
+
+	durationHistoryFlag := rootCmd.PersistentFlags().Lookup("duration-history")
+	if durationHistoryFlag == nil {
+		t.Error("duration-history flag should be defined")
+		return
+	}
+
 	ciNodeFlag := rootCmd.PersistentFlags().Lookup("ci-node")
 	if ciNodeFlag == nil {
 		t.Error("ci-node flag should be defined")
@@ -101,6 +186,96 @@ func TestRootCommandFlags(t *testing.T) {
 		return
 	}
 
+	splitOptimizerFlag := rootCmd.PersistentFlags().Lookup("split-optimizer")
+	if splitOptimizerFlag == nil {
+		t.Error("split-optimizer flag should be defined")
+		return
+	}
+
+	durationEstimateFlag := rootCmd.PersistentFlags().Lookup("duration-estimate")
+	if durationEstimateFlag == nil {
+		t.Error("duration-estimate flag should be defined")
+		return
+	}
+
+	optimizeForFlag := rootCmd.PersistentFlags().Lookup("optimize-for")
+	if optimizeForFlag == nil {
+		t.Error("optimize-for flag should be defined")
+		return
+	}
+
+	ciJobPricePerMinuteFlag := rootCmd.PersistentFlags().Lookup("ci-job-price-per-minute")
+	if ciJobPricePerMinuteFlag == nil {
+		t.Error("ci-job-price-per-minute flag should be defined")
+		return
+	}
+
+	ciJobBillingIncrementFlag := rootCmd.PersistentFlags().Lookup("ci-job-billing-increment")
+	if ciJobBillingIncrementFlag == nil {
+		t.Error("ci-job-billing-increment flag should be defined")
+		return
+	}
+
+	ciJobStartupTimeFlag := rootCmd.PersistentFlags().Lookup("ci-job-startup-time")
+	if ciJobStartupTimeFlag == nil {
+		t.Error("ci-job-startup-time flag should be defined")
+		return
+	}
+
+	ciBudgetFlag := rootCmd.PersistentFlags().Lookup("ci-budget")
+	if ciBudgetFlag == nil {
+		t.Error("ci-budget flag should be defined")
+		return
+	}
+
+	chunkSlowTestFilesFlag := rootCmd.PersistentFlags().Lookup("chunk-slow-test-files")
+	if chunkSlowTestFilesFlag == nil {
+		t.Error("chunk-slow-test-files flag should be defined")
+		return
+	}
+
+	constraintsFileFlag := rootCmd.PersistentFlags().Lookup("constraints-file")
+	if constraintsFileFlag == nil {
+		t.Error("constraints-file flag should be defined")
+		return
+	}
+
+	workspaceFlag := rootCmd.PersistentFlags().Lookup("workspace")
+	if workspaceFlag == nil {
+		t.Error("workspace flag should be defined")
+		return
+	}
+
+	previousPlanFlag := rootCmd.PersistentFlags().Lookup("previous-plan")
+	if previousPlanFlag == nil {
+		t.Error("previous-plan flag should be defined")
+		return
+	}
+
+	stickySplitToleranceFlag := rootCmd.PersistentFlags().Lookup("sticky-split-tolerance")
+	if stickySplitToleranceFlag == nil {
+		t.Error("sticky-split-tolerance flag should be defined")
+		return
+	}
+
+	planLayoutFlag := rootCmd.PersistentFlags().Lookup("plan-layout")
+	if planLayoutFlag == nil {
+		t.Error("plan-layout flag should be defined")
+		return
+	}
+
+	splitOrderFlag := rootCmd.PersistentFlags().Lookup("split-order")
+	if splitOrderFlag == nil {
+		t.Error("split-order flag should be defined")
+		return
+	}
+
+	pipelineTemplateFlag := rootCmd.PersistentFlags().Lookup("pipeline-template")
+	if pipelineTemplateFlag == nil {
+		t.Error("pipeline-template flag should be defined")
+		return
+	}
+
 	// Check default values
 	if platformFlag.DefValue != "ruby" {
 		t.Errorf("expected platform default to be 'ruby', got %q", platformFlag.DefValue)
@@ -142,6 +317,62 @@ func TestRootCommandFlags(t *testing.T) {
 		t.Errorf("expected ci-node-workers default to be '1', got %q", ciNodeWorkersFlag.DefValue)
 	}
 
+	if ciNodeCapacitiesFlag.DefValue != "" {
+		t.Errorf("expected ci-node-capacities default to be empty, got %q", ciNodeCapacitiesFlag.DefValue)
+	}
+
+	if workQueueFlag.DefValue != "false" {
+		t.Errorf("expected work-queue default to be 'false', got %q", workQueueFlag.DefValue)
+	}
+
+	if workQueueBatchSizeFlag.DefValue != "1" {
+		t.Errorf("expected work-queue-batch-size default to be '1', got %q", workQueueBatchSizeFlag.DefValue)
+	}
+
+	if queueURLFlag.DefValue != "" {
+		t.Errorf("expected queue-url default to be empty, got %q", queueURLFlag.DefValue)
+	}
+
+	if retryFailedFilesFlag.DefValue != "0" {
+		t.Errorf("expected retry-failed-files default to be '0', got %q", retryFailedFilesFlag.DefValue)
+	}
+
+	if workerOutputFlag.DefValue != "stream" {
+		t.Errorf("expected worker-output default to be 'stream', got %q", workerOutputFlag.DefValue)
+	}
+
+	if failFastFlag.DefValue != "false" {
+		t.Errorf("expected fail-fast default to be 'false', got %q", failFastFlag.DefValue)
+	}
+
+	if workerTimeoutFlag.DefValue != "0s" {
+		t.Errorf("expected worker-timeout default to be '0s', got %q", workerTimeoutFlag.DefValue)
+	}
+
+	if workerTimeoutMultiplierFlag.DefValue != "0" {
+		t.Errorf("expected worker-timeout-multiplier default to be '0', got %q", workerTimeoutMultiplierFlag.DefValue)
+	}
+
+	if workerTimeoutSignalFlag.DefValue != "SIGQUIT" {
+		t.Errorf("expected worker-timeout-signal default to be 'SIGQUIT', got %q", workerTimeoutSignalFlag.DefValue)
+	}
+
+	if junitReportsFlag.DefValue != "" {
+		t.Errorf("expected junit-reports default to be empty, got %q", junitReportsFlag.DefValue)
+	}
+
+	if runReportPathFlag.DefValue != "" {
+		t.Errorf("expected run-report-path default to be empty, got %q", runReportPathFlag.DefValue)
+	}
+
+	if preferLocalDurationsFlag.DefValue != "false" {
+		t.Errorf("expected prefer-local-durations default to be 'false', got %q", preferLocalDurationsFlag.DefValue)
+	}
+
+	if durationHistoryFlag.DefValue != "" {
+		t.Errorf("expected duration-history default to be empty, got %q", durationHistoryFlag.DefValue)
+	}
+
 	if ciNodeFlag.DefValue != "-1" {
 		t.Errorf("expected ci-node default to be '-1', got %q", ciNodeFlag.DefValue)
 	}
@@ -155,12 +386,57 @@ func TestRootCommandFlags(t *testing.T) {
 	if targetTimeFlag.DefValue != expectedTargetTime {
 		t.Errorf("expected target-time default to be %q, got %q", expectedTargetTime, targetTimeFlag.DefValue)
 	}
+	if splitOptimizerFlag.DefValue != "greedy" {
+		t.Errorf("expected split-optimizer default to be 'greedy', got %q", splitOptimizerFlag.DefValue)
+	}
+	if durationEstimateFlag.DefValue != "p50" {
+		t.Errorf("expected duration-estimate default to be 'p50', got %q", durationEstimateFlag.DefValue)
+	}
+	if optimizeForFlag.DefValue != "time" {
+		t.Errorf("expected optimize-for default to be 'time', got %q", optimizeForFlag.DefValue)
+	}
+	if ciJobPricePerMinuteFlag.DefValue != "0" {
+		t.Errorf("expected ci-job-price-per-minute default to be '0', got %q", ciJobPricePerMinuteFlag.DefValue)
+	}
+	if ciJobBillingIncrementFlag.DefValue != "0s" {
+		t.Errorf("expected ci-job-billing-increment default to be '0s', got %q", ciJobBillingIncrementFlag.DefValue)
+	}
+	if ciJobStartupTimeFlag.DefValue != "0s" {
+		t.Errorf("expected ci-job-startup-time default to be '0s', got %q", ciJobStartupTimeFlag.DefValue)
+	}
+	if ciBudgetFlag.DefValue != "0" {
+		t.Errorf("expected ci-budget default to be '0', got %q", ciBudgetFlag.DefValue)
+	}
+	if chunkSlowTestFilesFlag.DefValue != "false" {
+		t.Errorf("expected chunk-slow-test-files default to be 'false', got %q", chunkSlowTestFilesFlag.DefValue)
+	}
+	if constraintsFileFlag.DefValue != "" {
+		t.Errorf("expected constraints-file default to be empty, got %q", constraintsFileFlag.DefValue)
+	}
+	if workspaceFlag.DefValue != "" {
+		t.Errorf("expected workspace default to be empty, got %q", workspaceFlag.DefValue)
+	}
+	if previousPlanFlag.DefValue != "" {
+		t.Errorf("expected previous-plan default to be empty, got %q", previousPlanFlag.DefValue)
+	}
+	if stickySplitToleranceFlag.DefValue != "5" {
+		t.Errorf("expected sticky-split-tolerance default to be '5', got %q", stickySplitToleranceFlag.DefValue)
+	}
+	if planLayoutFlag.DefValue != "v1" {
+		t.Errorf("expected plan-layout default to be 'v1', got %q", planLayoutFlag.DefValue)
+	}
+	if splitOrderFlag.DefValue != "" {
+		t.Errorf("expected split-order default to be empty, got %q", splitOrderFlag.DefValue)
+	}
+	if pipelineTemplateFlag.DefValue != "" {
+		t.Errorf("expected pipeline-template default to be empty, got %q", pipelineTemplateFlag.DefValue)
+	}
 }
 
 func TestCommandHierarchy(t *testing.T) {
 	// Verify that planCmd and runCmd are added to rootCmd
 	commands := rootCmd.Commands()
-	var foundPlan, foundRun bool
+	var foundPlan, foundRun, foundServe, foundExplain, foundVerifyPlan, foundPlanDiff bool
 	for _, cmd := range commands {
 		if cmd.Use == "plan" {
 			foundPlan = true
@@ -168,6 +444,18 @@ func TestCommandHierarchy(t *testing.T) {
 		if cmd.Use == "run" {
 			foundRun = true
 		}
+		if cmd.Use == "serve" {
+			foundServe = true
+		}
+		if cmd.Name() == "explain" {
+			foundExplain = true
+		}
+		if cmd.Name() == "verify-plan" {
+			foundVerifyPlan = true
+		}
+		if cmd.Name() == "plan-diff" {
+			foundPlanDiff = true
+		}
 	}
 
 	if !foundPlan {
@@ -176,6 +464,57 @@ func TestCommandHierarchy(t *testing.T) {
 	if !foundRun {
 		t.Error("run command should be added to root command")
 	}
+	if !foundServe {
+		t.Error("serve command should be added to root command")
+	}
+	if !foundExplain {
+		t.Error("explain command should be added to root command")
+	}
+	if !foundVerifyPlan {
+		t.Error("verify-plan command should be added to root command")
+	}
+	if !foundPlanDiff {
+		t.Error("plan-diff command should be added to root command")
+	}
+}
+
+func TestServeCommandFlags(t *testing.T) {
+	queueListenFlag := serveCmd.PersistentFlags().Lookup("queue-listen")
+	if queueListenFlag == nil {
+		t.Fatal("queue-listen flag should be defined on serve command")
+	}
+	if queueListenFlag.DefValue != settings.DefaultQueueListen() {
+		t.Errorf("expected queue-listen default to be %q, got %q", settings.DefaultQueueListen(), queueListenFlag.DefValue)
+	}
+
+	queueLeaseTimeoutFlag := serveCmd.PersistentFlags().Lookup("queue-lease-timeout")
+	if queueLeaseTimeoutFlag == nil {
+		t.Fatal("queue-lease-timeout flag should be defined on serve command")
+	}
+	if queueLeaseTimeoutFlag.DefValue != settings.DefaultQueueLeaseTimeout().String() {
+		t.Errorf("expected queue-lease-timeout default to be %q, got %q", settings.DefaultQueueLeaseTimeout().String(), queueLeaseTimeoutFlag.DefValue)
+	}
+	if rootCmd.PersistentFlags().Lookup("queue-listen") != nil {
+		t.Error("queue-listen flag should only be defined on serve command")
+	}
+
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	if err := bindPersistentFlags(serveCmd, servePersistentFlagBindings); err != nil {
+		t.Fatalf("bindPersistentFlags() failed: %v", err)
+	}
+	if err := serveCmd.PersistentFlags().Set("queue-listen", "0.0.0.0:9000"); err != nil {
+		t.Fatalf("Error setting queue-listen flag: %v", err)
+	}
+	if err := serveCmd.PersistentFlags().Set("queue-lease-timeout", "45s"); err != nil {
+		t.Fatalf("Error setting queue-lease-timeout flag: %v", err)
+	}
+	if viper.GetString("queue_listen") != "0.0.0.0:9000" {
+		t.Errorf("expected viper queue_listen to be '0.0.0.0:9000', got %q", viper.GetString("queue_listen"))
+	}
+	if viper.GetString("queue_lease_timeout") != "45s" {
+		t.Errorf("expected viper queue_lease_timeout to be '45s', got %q", viper.GetString("queue_lease_timeout"))
+	}
 }
 
 func TestRootPersistentPreRunReportsGitAvailabilityFailures(t *testing.T) {
@@ -197,6 +536,7 @@ func TestRootPersistentPreRunReportsGitAvailabilityFailures(t *testing.T) {
 	}{
 		{name: "plan", command: planCmd, commandType: "plan", errorCode: errcode.PlanGitUnavailable},
 		{name: "run", command: runCmd, commandType: "run", errorCode: errcode.RunGitUnavailable},
+		{name: "serve", command: serveCmd, commandType: "serve", errorCode: errcode.ServeGitUnavailable},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -373,6 +713,36 @@ func TestRunTestCommandExitsOnError(t *testing.T) {
 	telemetryClient.assertSamples(t, "distribution", "ddtest.cli.command_ms", tags, 1)
 }
 
+func TestRunServeCommandExitsOnError(t *testing.T) {
+	originalServeCommand := serveCommand
+	originalNewTelemetryClient := newTelemetryClient
+	originalExitProcess := exitProcess
+	t.Cleanup(func() {
+		serveCommand = originalServeCommand
+		newTelemetryClient = originalNewTelemetryClient
+		exitProcess = originalExitProcess
+	})
+
+	telemetryClient := &fakeTelemetryClient{}
+	newTelemetryClient = func() (telemetry.Client, error) { return telemetryClient, nil }
+	serveCommand = func(ctx context.Context, got telemetry.Client) error {
+		return errcode.New(errcode.ServeListenFailed, "address in use")
+	}
+	var exitCodes []int
+	exitProcess = func(code int) {
+		exitCodes = append(exitCodes, code)
+	}
+
+	runServeCommand(&cobra.Command{}, nil)
+
+	if len(exitCodes) != 1 || exitCodes[0] != 1 {
+		t.Fatalf("expected exit code 1, got %v", exitCodes)
+	}
+	tags := cliMetricTags("serve", "1", errcode.ServeListenFailed, unknownCLICommandAttributes())
+	telemetryClient.assertValue(t, "count", "ddtest.cli.command", tags, 1)
+	telemetryClient.assertSamples(t, "distribution", "ddtest.cli.command_ms", tags, 1)
+}
+
 func TestRunWithTelemetryFallsBackWhenCreationFails(t *testing.T) {
 	originalNewTelemetryClient := newTelemetryClient
 	t.Cleanup(func() { newTelemetryClient = originalNewTelemetryClient })
@@ -511,12 +881,99 @@ func TestFlagBinding(t *testing.T) {
 	if err := rootCmd.PersistentFlags().Set("ci-node-workers", "ncpu"); err != nil {
 		t.Fatalf("Error setting ci-node-workers flag: %v", err)
 	}
+	if err := rootCmd.PersistentFlags().Set("ci-node-capacities", "0-3:8,4-7:4"); err != nil {
+		t.Fatalf("Error setting ci-node-capacities flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("work-queue", "true"); err != nil {
+		t.Fatalf("Error setting work-queue flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("work-queue-batch-size", "4"); err != nil {
+		t.Fatalf("Error setting work-queue-batch-size flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("queue-url", "http://10.0.0.5:7878"); err != nil {
+		t.Fatalf("Error setting queue-url flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("retry-failed-files", "2"); err != nil {
+		t.Fatalf("Error setting retry-failed-files flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("worker-output", "prefix"); err != nil {
+		t.Fatalf("Error setting worker-output flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("junit-reports", "tmp/junit/*.xml"); err != nil {
+		t.Fatalf("Error setting junit-reports flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("run-report-path", "tmp/run-report.json"); err != nil {
+		t.Fatalf("Error setting run-report-path flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("fail-fast", "true"); err != nil {
+		t.Fatalf("Error setting fail-fast flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("worker-timeout", "20m"); err != nil {
+		t.Fatalf("Error setting worker-timeout flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("worker-timeout-multiplier", "3"); err != nil {
+		t.Fatalf("Error setting worker-timeout-multiplier flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("worker-timeout-signal", "SIGUSR1"); err != nil {
+		t.Fatalf("Error setting worker-timeout-signal flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("prefer-local-durations", "true"); err != nil {
+		t.Fatalf("Error setting prefer-local-durations flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("duration-history", ".ddtest-cache/durations.jsonl"); err != nil {
+		t.Fatalf("Error setting duration-history flag: %v", err)
+	}
 	if err := rootCmd.PersistentFlags().Set("ci-node", "3"); err != nil {
 		t.Fatalf("Error setting ci-node flag: %v", err)
 	}
 	if err := rootCmd.PersistentFlags().Set("ci-job-overhead", "30s"); err != nil {
 		t.Fatalf("Error setting ci-job-overhead flag: %v", err)
 	}
+	if err := rootCmd.PersistentFlags().Set("split-optimizer", "local-search"); err != nil {
+		t.Fatalf("Error setting split-optimizer flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("duration-estimate", "p90"); err != nil {
+		t.Fatalf("Error setting duration-estimate flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("optimize-for", "cost"); err != nil {
+		t.Fatalf("Error setting optimize-for flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("ci-job-price-per-minute", "0.008"); err != nil {
+		t.Fatalf("Error setting ci-job-price-per-minute flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("ci-job-billing-increment", "1m"); err != nil {
+		t.Fatalf("Error setting ci-job-billing-increment flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("ci-job-startup-time", "45s"); err != nil {
+		t.Fatalf("Error setting ci-job-startup-time flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("ci-budget", "1.5"); err != nil {
+		t.Fatalf("Error setting ci-budget flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("chunk-slow-test-files", "true"); err != nil {
+		t.Fatalf("Error setting chunk-slow-test-files flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("constraints-file", "ci/ddtest-constraints.json"); err != nil {
+		t.Fatalf("Error setting constraints-file flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("workspace", "ci/ddtest-workspace.json"); err != nil {
+		t.Fatalf("Error setting workspace flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("previous-plan", "previous/.testoptimization"); err != nil {
+		t.Fatalf("Error setting previous-plan flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("sticky-split-tolerance", "10"); err != nil {
+		t.Fatalf("Error setting sticky-split-tolerance flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("plan-layout", "v2"); err != nil {
+		t.Fatalf("Error setting plan-layout flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("split-order", "new,longest"); err != nil {
+		t.Fatalf("Error setting split-order flag: %v", err)
+	}
+	if err := rootCmd.PersistentFlags().Set("pipeline-template", ".gitlab/ddtest-job.yml"); err != nil {
+		t.Fatalf("Error setting pipeline-template flag: %v", err)
+	}
 	if err := rootCmd.PersistentFlags().Set("target-time", "10m"); err != nil {
 		t.Fatalf("Error setting target-time flag: %v", err)
 	}
@@ -552,6 +1009,48 @@ func TestFlagBinding(t *testing.T) {
 	if viper.GetString("ci_node_workers") != "ncpu" {
 		t.Errorf("expected viper ci_node_workers to be 'ncpu', got %q", viper.GetString("ci_node_workers"))
 	}
+	if viper.GetString("ci_node_capacities") != "0-3:8,4-7:4" {
+		t.Errorf("expected viper ci_node_capacities to be '0-3:8,4-7:4', got %q", viper.GetString("ci_node_capacities"))
+	}
+	if !viper.GetBool("work_queue") {
+		t.Error("expected viper work_queue to be true")
+	}
+	if viper.GetInt("work_queue_batch_size") != 4 {
+		t.Errorf("expected viper work_queue_batch_size to be 4, got %d", viper.GetInt("work_queue_batch_size"))
+	}
+	if viper.GetString("queue_url") != "http://10.0.0.5:7878" {
+		t.Errorf("expected viper queue_url to be 'http://10.0.0.5:7878', got %q", viper.GetString("queue_url"))
+	}
+	if viper.GetInt("retry_failed_files") != 2 {
+		t.Errorf("expected viper retry_failed_files to be 2, got %d", viper.GetInt("retry_failed_files"))
+	}
+	if viper.GetString("worker_output") != "prefix" {
+		t.Errorf("expected viper worker_output to be 'prefix', got %q", viper.GetString("worker_output"))
+	}
+	if !viper.GetBool("fail_fast") {
+		t.Error("expected viper fail_fast to be true")
+	}
+	if viper.GetString("worker_timeout") != "20m" {
+		t.Errorf("expected viper worker_timeout to be '20m', got %q", viper.GetString("worker_timeout"))
+	}
+	if viper.GetFloat64("worker_timeout_multiplier") != 3 {
+		t.Errorf("expected viper worker_timeout_multiplier to be 3, got %g", viper.GetFloat64("worker_timeout_multiplier"))
+	}
+	if viper.GetString("worker_timeout_signal") != "SIGUSR1" {
+		t.Errorf("expected viper worker_timeout_signal to be 'SIGUSR1', got %q", viper.GetString("worker_timeout_signal"))
+	}
+	if viper.GetString("junit_reports") != "tmp/junit/*.xml" {
+		t.Errorf("expected viper junit_reports to be 'tmp/junit/*.xml', got %q", viper.GetString("junit_reports"))
+	}
+	if !viper.GetBool("prefer_local_durations") {
+		t.Error("expected viper prefer_local_durations to be true")
+	}
+	if viper.GetString("duration_history") != ".ddtest-cache/durations.jsonl" {
+		t.Errorf("expected viper duration_history to be '.ddtest-cache/durations.jsonl', got %q", viper.GetString("duration_history"))
+	}
+	if viper.GetString("run_report_path") != "tmp/run-report.json" {
+		t.Errorf("expected viper run_report_path to be 'tmp/run-report.json', got %q", viper.GetString("run_report_path"))
+	}
 	if viper.GetInt("ci_node") != 3 {
 		t.Errorf("expected viper ci_node to be 3, got %d", viper.GetInt("ci_node"))
 	}
@@ -561,6 +1060,51 @@ func TestFlagBinding(t *testing.T) {
 	if viper.GetString("target_time") != "10m" {
 		t.Errorf("expected viper target_time to be '10m', got %q", viper.GetString("target_time"))
 	}
+	if viper.GetString("split_optimizer") != "local-search" {
+		t.Errorf("expected viper split_optimizer to be 'local-search', got %q", viper.GetString("split_optimizer"))
+	}
+	if viper.GetString("duration_estimate") != "p90" {
+		t.Errorf("expected viper duration_estimate to be 'p90', got %q", viper.GetString("duration_estimate"))
+	}
+	if viper.GetString("optimize_for") != "cost" {
+		t.Errorf("expected viper optimize_for to be 'cost', got %q", viper.GetString("optimize_for"))
+	}
+	if viper.GetFloat64("ci_job_price_per_minute") != 0.008 {
+		t.Errorf("expected viper ci_job_price_per_minute to be 0.008, got %g", viper.GetFloat64("ci_job_price_per_minute"))
+	}
+	if viper.GetString("ci_job_billing_increment") != "1m" {
+		t.Errorf("expected viper ci_job_billing_increment to be '1m', got %q", viper.GetString("ci_job_billing_increment"))
+	}
+	if viper.GetString("ci_job_startup_time") != "45s" {
+		t.Errorf("expected viper ci_job_startup_time to be '45s', got %q", viper.GetString("ci_job_startup_time"))
+	}
+	if viper.GetFloat64("ci_budget") != 1.5 {
+		t.Errorf("expected viper ci_budget to be 1.5, got %g", viper.GetFloat64("ci_budget"))
+	}
+	if !viper.GetBool("chunk_slow_test_files") {
+		t.Error("expected viper chunk_slow_test_files to be true")
+	}
+	if viper.GetString("constraints_file") != "ci/ddtest-constraints.json" {
+		t.Errorf("expected viper constraints_file to be 'ci/ddtest-constraints.json', got %q", viper.GetString("constraints_file"))
+	}
+	if viper.GetString("workspace") != "ci/ddtest-workspace.json" {
+		t.Errorf("expected viper workspace to be 'ci/ddtest-workspace.json', got %q", viper.GetString("workspace"))
+	}
+	if viper.GetString("previous_plan") != "previous/.testoptimization" {
+		t.Errorf("expected viper previous_plan to be 'previous/.testoptimization', got %q", viper.GetString("previous_plan"))
+	}
+	if viper.GetFloat64("sticky_split_tolerance") != 10 {
+		t.Errorf("expected viper sticky_split_tolerance to be 10, got %g", viper.GetFloat64("sticky_split_tolerance"))
+	}
+	if viper.GetString("plan_layout") != "v2" {
+		t.Errorf("expected viper plan_layout to be 'v2', got %q", viper.GetString("plan_layout"))
+	}
+	if viper.GetString("split_order") != "new,longest" {
+		t.Errorf("expected viper split_order to be 'new,longest', got %q", viper.GetString("split_order"))
+	}
+	if viper.GetString("pipeline_template") != ".gitlab/ddtest-job.yml" {
+		t.Errorf("expected viper pipeline_template to be '.gitlab/ddtest-job.yml', got %q", viper.GetString("pipeline_template"))
+	}
 }
 
 func TestBindPersistentFlags(t *testing.T) {
@@ -585,6 +1129,86 @@ func TestBindPersistentFlags(t *testing.T) {
 	}
 }
 
+func TestWorkspacePlanArgs(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	testCmd := &cobra.Command{}
+	testCmd.PersistentFlags().String("workspace", "", "workspace")
+	testCmd.PersistentFlags().String("platform", "ruby", "platform")
+	testCmd.PersistentFlags().Int("max-parallelism", 1, "max parallelism")
+	testCmd.PersistentFlags().Bool("report", true, "report")
+
+	for name, value := range map[string]string{"workspace": "ddtest-workspace.json", "max-parallelism": "8", "report": "false"} {
+		if err := testCmd.PersistentFlags().Set(name, value); err != nil {
+			t.Fatalf("failed to set %s flag: %v", name, err)
+		}
+	}
+
+	args, err := workspacePlanArgs(testCmd.PersistentFlags())
+	if err != nil || !slices.Equal(args, []string{"--max-parallelism=8", "--report=false"}) {
+		t.Fatalf("workspacePlanArgs() = %v, %v", args, err)
+	}
+}
+
+func TestWorkspacePlanArgs_ResolvesRelativePaths(t *testing.T) {
+	t.Chdir(t.TempDir())
+	workspaceDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	packageDir := filepath.Join(workspaceDir, "services", "api")
+	if err := os.MkdirAll(packageDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workspaceDir, "ddtest-constraints.json"), []byte(`{"groups":[]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_DURATION_HISTORY", "cache/durations.jsonl")
+
+	testCmd := &cobra.Command{}
+	testCmd.PersistentFlags().String("workspace", "", "workspace")
+	testCmd.PersistentFlags().String("constraints-file", "", "constraints file")
+	testCmd.PersistentFlags().String("duration-history", "", "duration history")
+	testCmd.PersistentFlags().Int("max-parallelism", 1, "max parallelism")
+	if err := bindPersistentFlags(testCmd, []persistentFlagBinding{
+		{configKey: "constraints_file", flagName: "constraints-file"},
+		{configKey: "duration_history", flagName: "duration-history"},
+		{configKey: "max_parallelism", flagName: "max-parallelism"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	viper.SetEnvPrefix("DD_TEST_OPTIMIZATION_RUNNER")
+	viper.AutomaticEnv()
+	for name, value := range map[string]string{"workspace": "ddtest-workspace.json", "constraints-file": "ddtest-constraints.json", "max-parallelism": "4"} {
+		if err := testCmd.PersistentFlags().Set(name, value); err != nil {
+			t.Fatalf("failed to set %s flag: %v", name, err)
+		}
+	}
+
+	args, err := workspacePlanArgs(testCmd.PersistentFlags())
+	if err != nil {
+		t.Fatalf("workspacePlanArgs() returned error: %v", err)
+	}
+	constraintsFile := filepath.Join(workspaceDir, "ddtest-constraints.json")
+	want := []string{
+		"--constraints-file=" + constraintsFile,
+		"--duration-history=" + filepath.Join(workspaceDir, "cache", "durations.jsonl"),
+		"--max-parallelism=4",
+	}
+	if !slices.Equal(args, want) {
+		t.Fatalf("workspacePlanArgs() = %v, want %v", args, want)
+	}
+
+	// The package plan runs from the package directory and still finds the
+	// constraints file of the workspace.
+	t.Chdir(packageDir)
+	if _, err := os.Stat(strings.TrimPrefix(args[0], "--constraints-file=")); err != nil {
+		t.Errorf("expected the package plan to find the constraints file, got: %v", err)
+	}
+}
+
 func TestBindPersistentFlagsMissingFlag(t *testing.T) {
 	viper.Reset()
 	t.Cleanup(viper.Reset)
@@ -597,6 +1221,109 @@ func TestBindPersistentFlagsMissingFlag(t *testing.T) {
 	}
 }
 
+func TestRunExplainCommandExitsOnError(t *testing.T) {
+	originalExplainCommand := explainCommand
+	originalNewTelemetryClient := newTelemetryClient
+	originalExitProcess := exitProcess
+	t.Cleanup(func() {
+		explainCommand = originalExplainCommand
+		newTelemetryClient = originalNewTelemetryClient
+		exitProcess = originalExitProcess
+	})
+
+	telemetryClient := &fakeTelemetryClient{}
+	newTelemetryClient = func() (telemetry.Client, error) { return telemetryClient, nil }
+	var explainedTarget string
+	explainCommand = func(target string) error {
+		explainedTarget = target
+		return errcode.New(errcode.ExplainTargetNotFound, "not in plan")
+	}
+	var exitCodes []int
+	exitProcess = func(code int) {
+		exitCodes = append(exitCodes, code)
+	}
+
+	runExplainCommand(&cobra.Command{}, []string{"spec/models/user_spec.rb"})
+
+	if explainedTarget != "spec/models/user_spec.rb" {
+		t.Fatalf("expected explain target to be passed through, got %q", explainedTarget)
+	}
+	if len(exitCodes) != 1 || exitCodes[0] != 1 {
+		t.Fatalf("expected exit code 1, got %v", exitCodes)
+	}
+	tags := cliMetricTags("explain", "1", errcode.ExplainTargetNotFound, unknownCLICommandAttributes())
+	telemetryClient.assertValue(t, "count", "ddtest.cli.command", tags, 1)
+}
+
+func TestRunVerifyPlanCommandExitsOnError(t *testing.T) {
+	originalVerifyPlanCommand := verifyPlanCommand
+	originalNewTelemetryClient := newTelemetryClient
+	originalExitProcess := exitProcess
+	t.Cleanup(func() {
+		verifyPlanCommand = originalVerifyPlanCommand
+		newTelemetryClient = originalNewTelemetryClient
+		exitProcess = originalExitProcess
+	})
+
+	telemetryClient := &fakeTelemetryClient{}
+	newTelemetryClient = func() (telemetry.Client, error) { return telemetryClient, nil }
+	verifyPlanCommand = func() error {
+		return errcode.New(errcode.VerifyPlanRunnerCountMismatch, "runner-1 is missing")
+	}
+	var exitCodes []int
+	exitProcess = func(code int) {
+		exitCodes = append(exitCodes, code)
+	}
+
+	runVerifyPlanCommand(&cobra.Command{}, nil)
+
+	if len(exitCodes) != 1 || exitCodes[0] != 1 {
+		t.Fatalf("expected exit code 1, got %v", exitCodes)
+	}
+	tags := cliMetricTags("verify-plan", "1", errcode.VerifyPlanRunnerCountMismatch, unknownCLICommandAttributes())
+	telemetryClient.assertValue(t, "count", "ddtest.cli.command", tags, 1)
+}
+
+func TestRunPlanDiffCommandExitsOnError(t *testing.T) {
+	originalPlanDiffCommand := planDiffCommand
+	originalNewTelemetryClient := newTelemetryClient
+	originalExitProcess := exitProcess
+	t.Cleanup(func() {
+		planDiffCommand = originalPlanDiffCommand
+		newTelemetryClient = originalNewTelemetryClient
+		exitProcess = originalExitProcess
+	})
+
+	telemetryClient := &fakeTelemetryClient{}
+	newTelemetryClient = func() (telemetry.Client, error) { return telemetryClient, nil }
+	var gotBase, gotHead string
+	var gotJSON bool
+	planDiffCommand = func(basePlanDir string, headPlanDir string, jsonOutput bool) error {
+		gotBase, gotHead, gotJSON = basePlanDir, headPlanDir, jsonOutput
+		return errcode.New(errcode.PlanDiffPlanLoadFailed, "test-files.txt is missing")
+	}
+	var exitCodes []int
+	exitProcess = func(code int) {
+		exitCodes = append(exitCodes, code)
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("json", false, "")
+	if err := cmd.Flags().Set("json", "true"); err != nil {
+		t.Fatal(err)
+	}
+	runPlanDiffCommand(cmd, []string{"base/.testoptimization", "head/.testoptimization"})
+
+	if gotBase != "base/.testoptimization" || gotHead != "head/.testoptimization" || !gotJSON {
+		t.Fatalf("unexpected plan-diff arguments %q, %q, json=%t", gotBase, gotHead, gotJSON)
+	}
+	if len(exitCodes) != 1 || exitCodes[0] != 1 {
+		t.Fatalf("expected exit code 1, got %v", exitCodes)
+	}
+	tags := cliMetricTags("plan-diff", "1", errcode.PlanDiffPlanLoadFailed, unknownCLICommandAttributes())
+	telemetryClient.assertValue(t, "count", "ddtest.cli.command", tags, 1)
+}
+
 func TestCommandUsage(t *testing.T) {
 	// Get all commands including root and subcommands
 	allCommands := []*cobra.Command{rootCmd}
@@ -12,6 +12,12 @@ const ManifestVersion = "1"
 
 var ManifestPath = filepath.Join(PlanDirectory, "manifest.txt")
 
+// PlanJSONPath is the plan layout v2 manifest, a single JSON file describing
+// the whole plan. PlanJSONSchemaVersion is its schema version.
+var PlanJSONPath = filepath.Join(PlanDirectory, "plan.json")
+
+const PlanJSONSchemaVersion = 2
+
 const TestOptimizationManifestFileEnvVar = "TEST_OPTIMIZATION_MANIFEST_FILE"
 const DDTestOptimizationManifestFileEnvVar = "DD_TEST_OPTIMIZATION_MANIFEST_FILE"
 
@@ -20,11 +26,26 @@ var RunnerDirectory = filepath.Join(PlanDirectory, "runner")
 var TestFilesOutputPath = filepath.Join(RunnerDirectory, "test-files.txt")
 var SkippablePercentageOutputPath = filepath.Join(RunnerDirectory, "skippable-percentage.txt")
 var ParallelRunnersOutputPath = filepath.Join(RunnerDirectory, "parallel-runners.txt")
+var CINodeWorkersOutputPath = filepath.Join(RunnerDirectory, "ci-node-workers.txt")
 var TestsSplitDir = filepath.Join(RunnerDirectory, "tests-split")
 var RunnerCacheDir = filepath.Join(RunnerDirectory, "cache")
 
+// WorkspaceMatrixPath lists the CI jobs of every package of a workspace plan.
+var WorkspaceMatrixPath = filepath.Join(PlanDirectory, "workspace", "matrix.json")
+
+// WorkspacePackageEnvVar names the workspace package that a ddtest plan
+// process plans, for the plans of a workspace.
+const WorkspacePackageEnvVar = "DD_TEST_OPTIMIZATION_RUNNER_WORKSPACE_PACKAGE"
+
+// WorkerLogsDir holds per-worker test output when worker output goes to files.
+var WorkerLogsDir = filepath.Join(PlanDirectory, "logs")
+
 const TestOptimizationPlanCacheFile = "test_suite_durations.json"
 
+// TestFileDurationsCacheFile stores per-file durations measured from JUnit
+// reports during ddtest run, next to the plan cache.
+const TestFileDurationsCacheFile = "test_file_durations.json"
+
 const DefaultTestFileWeight = int(time.Second / time.Millisecond)
 
 const RunModeCINode = "CI node"
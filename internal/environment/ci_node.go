@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2026 Datadog, Inc.
+
+package environment
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+// CINodePosition is the position of this CI job among the parallel jobs that
+// a CI provider started.
+type CINodePosition struct {
+	// Provider is the name of the CI provider, as returned by CIProvider.Name.
+	Provider string
+	// Index is zero-indexed, like --ci-node.
+	Index int
+	Total int
+	// IndexEnv and TotalEnv name the environment variables Index and Total
+	// were read from.
+	IndexEnv string
+	TotalEnv string
+}
+
+// ciNodeVariables names the environment variables a CI provider sets on each
+// of its parallel jobs.
+type ciNodeVariables struct {
+	provider string
+	// detectEnv is only set by the CI provider, so that the index and total of
+	// another tool are not mistaken for those of the CI provider.
+	detectEnv string
+	indexEnv  string
+	totalEnv  string
+	// oneBased is set when the CI provider counts jobs from 1.
+	oneBased bool
+}
+
+var ciNodeProviders = []ciNodeVariables{
+	{provider: "circleci", detectEnv: "CIRCLECI", indexEnv: "CIRCLE_NODE_INDEX", totalEnv: "CIRCLE_NODE_TOTAL"},
+	{provider: "buildkite", detectEnv: "BUILDKITE", indexEnv: "BUILDKITE_PARALLEL_JOB", totalEnv: "BUILDKITE_PARALLEL_JOB_COUNT"},
+	{provider: "gitlab", detectEnv: "GITLAB_CI", indexEnv: "CI_NODE_INDEX", totalEnv: "CI_NODE_TOTAL", oneBased: true},
+	{provider: "semaphore", detectEnv: "SEMAPHORE", indexEnv: "SEMAPHORE_JOB_INDEX", totalEnv: "SEMAPHORE_JOB_COUNT", oneBased: true},
+	{provider: "azurepipelines", detectEnv: "TF_BUILD", indexEnv: "SYSTEM_JOBPOSITIONINPHASE", totalEnv: "SYSTEM_TOTALJOBSINPHASE", oneBased: true},
+}
+
+// DetectCINodePosition returns the position of this CI job from the
+// environment variables of its CI provider. It reports false outside of a
+// supported CI provider, when the CI provider does not set the position, or
+// when the position is not valid.
+func DetectCINodePosition() (CINodePosition, bool) {
+	for _, variables := range ciNodeProviders {
+		if os.Getenv(variables.detectEnv) == "" {
+			continue
+		}
+		indexValue, totalValue := os.Getenv(variables.indexEnv), os.Getenv(variables.totalEnv)
+		if indexValue == "" || totalValue == "" {
+			continue
+		}
+
+		position, err := variables.parse(indexValue, totalValue)
+		if err != nil {
+			slog.Warn("Ignoring CI node position from CI environment", "provider", variables.provider, "error", err)
+			return CINodePosition{}, false
+		}
+		return position, true
+	}
+	return CINodePosition{}, false
+}
+
+func (v ciNodeVariables) parse(indexValue string, totalValue string) (CINodePosition, error) {
+	index, err := strconv.Atoi(indexValue)
+	if err != nil {
+		return CINodePosition{}, fmt.Errorf("%s is not an integer: %q", v.indexEnv, indexValue)
+	}
+	total, err := strconv.Atoi(totalValue)
+	if err != nil {
+		return CINodePosition{}, fmt.Errorf("%s is not an integer: %q", v.totalEnv, totalValue)
+	}
+	if v.oneBased {
+		index--
+	}
+	if total < 1 || index < 0 || index >= total {
+		return CINodePosition{}, fmt.Errorf("%s=%s is out of range for %s=%s", v.indexEnv, indexValue, v.totalEnv, totalValue)
+	}
+	return CINodePosition{
+		Provider: v.provider,
+		Index:    index,
+		Total:    total,
+		IndexEnv: v.indexEnv,
+		TotalEnv: v.totalEnv,
+	}, nil
+}
@@ -0,0 +1,87 @@
+package environment
+
+import (
+	"testing"
+)
+
+func clearCINodeEnv(t *testing.T) {
+	t.Helper()
+	for _, variables := range ciNodeProviders {
+		t.Setenv(variables.detectEnv, "")
+		t.Setenv(variables.indexEnv, "")
+		t.Setenv(variables.totalEnv, "")
+	}
+}
+
+func TestDetectCINodePosition(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		want   CINodePosition
+		wantOK bool
+	}{
+		{
+			name:   "CircleCI",
+			env:    map[string]string{"CIRCLECI": "true", "CIRCLE_NODE_INDEX": "2", "CIRCLE_NODE_TOTAL": "4"},
+			want:   CINodePosition{Provider: "circleci", Index: 2, Total: 4, IndexEnv: "CIRCLE_NODE_INDEX", TotalEnv: "CIRCLE_NODE_TOTAL"},
+			wantOK: true,
+		},
+		{
+			name:   "Buildkite",
+			env:    map[string]string{"BUILDKITE": "true", "BUILDKITE_PARALLEL_JOB": "0", "BUILDKITE_PARALLEL_JOB_COUNT": "3"},
+			want:   CINodePosition{Provider: "buildkite", Index: 0, Total: 3, IndexEnv: "BUILDKITE_PARALLEL_JOB", TotalEnv: "BUILDKITE_PARALLEL_JOB_COUNT"},
+			wantOK: true,
+		},
+		{
+			name:   "GitLab counts from 1",
+			env:    map[string]string{"GITLAB_CI": "true", "CI_NODE_INDEX": "3", "CI_NODE_TOTAL": "3"},
+			want:   CINodePosition{Provider: "gitlab", Index: 2, Total: 3, IndexEnv: "CI_NODE_INDEX", TotalEnv: "CI_NODE_TOTAL"},
+			wantOK: true,
+		},
+		{
+			name:   "Semaphore counts from 1",
+			env:    map[string]string{"SEMAPHORE": "true", "SEMAPHORE_JOB_INDEX": "1", "SEMAPHORE_JOB_COUNT": "2"},
+			want:   CINodePosition{Provider: "semaphore", Index: 0, Total: 2, IndexEnv: "SEMAPHORE_JOB_INDEX", TotalEnv: "SEMAPHORE_JOB_COUNT"},
+			wantOK: true,
+		},
+		{
+			name:   "Azure Pipelines counts from 1",
+			env:    map[string]string{"TF_BUILD": "True", "SYSTEM_JOBPOSITIONINPHASE": "2", "SYSTEM_TOTALJOBSINPHASE": "5"},
+			want:   CINodePosition{Provider: "azurepipelines", Index: 1, Total: 5, IndexEnv: "SYSTEM_JOBPOSITIONINPHASE", TotalEnv: "SYSTEM_TOTALJOBSINPHASE"},
+			wantOK: true,
+		},
+		{
+			name: "index outside of its CI provider",
+			env:  map[string]string{"CI_NODE_INDEX": "1", "CI_NODE_TOTAL": "2"},
+		},
+		{
+			name: "CI provider without parallel jobs",
+			env:  map[string]string{"BUILDKITE": "true"},
+		},
+		{
+			name: "index that is not an integer",
+			env:  map[string]string{"CIRCLECI": "true", "CIRCLE_NODE_INDEX": "one", "CIRCLE_NODE_TOTAL": "2"},
+		},
+		{
+			name: "index out of range",
+			env:  map[string]string{"GITLAB_CI": "true", "CI_NODE_INDEX": "0", "CI_NODE_TOTAL": "2"},
+		},
+		{
+			name: "no CI provider",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearCINodeEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			got, ok := DetectCINodePosition()
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("DetectCINodePosition() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -65,14 +65,28 @@ type diagJobData struct {
 
 type CIProvider interface {
 	Name() string
-	Configure(parallelRunners int) error
+	// Configure prepares the CI provider for parallelRunners CI nodes.
+	// ciNodeWorkers holds the local worker count of each CI node, or is nil
+	// when every CI node has the same worker count.
+	Configure(parallelRunners int, ciNodeWorkers []int) error
+}
+
+// WorkspaceCIProvider is implemented by CI providers that can run the CI jobs
+// of every package of a workspace plan from one matrix.
+type WorkspaceCIProvider interface {
+	ConfigureWorkspace(jobs []WorkspaceMatrixEntry) error
 }
 
 type CIProviderDetector interface {
 	DetectCIProvider() (CIProvider, error)
 }
 
-type DatadogCIProviderDetector struct{}
+// DatadogCIProviderDetector detects the CI provider from the CI environment.
+// pipelineTemplate is the job skeleton that the Buildkite, GitLab and CircleCI
+// providers render when configured, or "" for none.
+type DatadogCIProviderDetector struct {
+	pipelineTemplate string
+}
 
 type genericCIProvider struct {
 	name string
@@ -81,44 +95,67 @@ type genericCIProvider struct {
 type GitHub struct{}
 
 type matrixEntry struct {
-	CINodeIndex int `json:"ci_node_index"`
-	CINodeTotal int `json:"ci_node_total"`
+	CINodeIndex   int `json:"ci_node_index"`
+	CINodeTotal   int `json:"ci_node_total"`
+	CINodeWorkers int `json:"ci_node_workers,omitempty"`
 }
 
 type matrixConfig struct {
 	Include []matrixEntry `json:"include"`
 }
 
+// WorkspaceMatrixEntry is a CI job of a workspace plan: one CI node of the
+// plan of one package, run from the package directory.
+type WorkspaceMatrixEntry struct {
+	Package       string `json:"package"`
+	Dir           string `json:"dir"`
+	CINodeIndex   int    `json:"ci_node_index"`
+	CINodeTotal   int    `json:"ci_node_total"`
+	CINodeWorkers int    `json:"ci_node_workers,omitempty"`
+}
+
+// WorkspaceMatrix is the combined CI matrix of a workspace plan.
+type WorkspaceMatrix struct {
+	Include []WorkspaceMatrixEntry `json:"include"`
+}
+
 func (d *DatadogCIProviderDetector) DetectCIProvider() (CIProvider, error) {
-	return DetectCIProvider()
+	return DetectCIProvider(d.pipelineTemplate)
 }
 
-func DetectCIProvider() (CIProvider, error) {
+func DetectCIProvider(pipelineTemplate string) (CIProvider, error) {
 	envTags := GetCITags()
 	providerName := strings.TrimSpace(envTags[constants.CIProviderName])
 	if providerName == "" {
 		return nil, fmt.Errorf("no CI provider detected")
 	}
 
-	return newCIProvider(providerName), nil
+	return newCIProvider(providerName, pipelineTemplate), nil
 }
 
-func newCIProvider(providerName string) CIProvider {
-	if providerName == "github" {
+func newCIProvider(providerName string, pipelineTemplate string) CIProvider {
+	switch providerName {
+	case "github":
 		return NewGitHub()
+	case "buildkite":
+		return NewBuildkite(pipelineTemplate)
+	case "gitlab":
+		return NewGitLab(pipelineTemplate)
+	case "circleci":
+		return NewCircleCI(pipelineTemplate)
 	}
 	return &genericCIProvider{name: providerName}
 }
 
-func NewCIProviderDetector() CIProviderDetector {
-	return &DatadogCIProviderDetector{}
+func NewCIProviderDetector(pipelineTemplate string) CIProviderDetector {
+	return &DatadogCIProviderDetector{pipelineTemplate: pipelineTemplate}
 }
 
 func (p *genericCIProvider) Name() string {
 	return p.name
 }
 
-func (p *genericCIProvider) Configure(_ int) error {
+func (p *genericCIProvider) Configure(_ int, _ []int) error {
 	return nil
 }
 
@@ -130,7 +167,7 @@ func (g *GitHub) Name() string {
 	return "github"
 }
 
-func (g *GitHub) Configure(parallelRunners int) error {
+func (g *GitHub) Configure(parallelRunners int, ciNodeWorkers []int) error {
 	if parallelRunners <= 0 {
 		return fmt.Errorf("parallelRunners must be greater than 0, got %d", parallelRunners)
 	}
@@ -144,8 +181,24 @@ func (g *GitHub) Configure(parallelRunners int) error {
 			CINodeIndex: i,
 			CINodeTotal: parallelRunners,
 		}
+		if i < len(ciNodeWorkers) {
+			matrix.Include[i].CINodeWorkers = ciNodeWorkers[i]
+		}
+	}
+
+	return writeGitHubMatrix(matrix)
+}
+
+// ConfigureWorkspace writes the combined matrix of a workspace plan, so that
+// one matrix job runs every CI node of every package.
+func (g *GitHub) ConfigureWorkspace(jobs []WorkspaceMatrixEntry) error {
+	if len(jobs) == 0 {
+		return fmt.Errorf("a workspace matrix needs at least one CI job")
 	}
+	return writeGitHubMatrix(WorkspaceMatrix{Include: jobs})
+}
 
+func writeGitHubMatrix(matrix any) error {
 	jsonData, err := json.Marshal(matrix)
 	if err != nil {
 		return fmt.Errorf("failed to marshal matrix configuration: %w", err)
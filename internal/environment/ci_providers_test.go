@@ -59,7 +59,7 @@ func setDetectedProvider(t *testing.T, providerName string) {
 func TestDetectCIProvider_GitHub(t *testing.T) {
 	setDetectedProvider(t, "github")
 
-	provider, err := DetectCIProvider()
+	provider, err := DetectCIProvider("")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -72,7 +72,7 @@ func TestDetectCIProvider_GitHub(t *testing.T) {
 func TestDetectCIProvider_NoProvider(t *testing.T) {
 	setDetectedProvider(t, "")
 
-	provider, err := DetectCIProvider()
+	provider, err := DetectCIProvider("")
 	if err == nil {
 		t.Fatalf("Expected no provider error, got provider %q", provider.Name())
 	}
@@ -93,14 +93,17 @@ func TestDatadogCIProviderDetector_DetectCIProvider(t *testing.T) {
 }
 
 func TestNewCIProviderDetector(t *testing.T) {
-	detector := NewCIProviderDetector()
+	detector := NewCIProviderDetector("ci/pipeline.yml.tmpl")
 	if detector == nil {
 		t.Fatal("Expected detector to be non-nil")
 	}
 
-	_, ok := detector.(*DatadogCIProviderDetector)
+	datadogDetector, ok := detector.(*DatadogCIProviderDetector)
 	if !ok {
-		t.Errorf("Expected detector to be of type *DatadogCIProviderDetector")
+		t.Fatalf("Expected detector to be of type *DatadogCIProviderDetector")
+	}
+	if datadogDetector.pipelineTemplate != "ci/pipeline.yml.tmpl" {
+		t.Errorf("Expected detector to keep the pipeline template, got %q", datadogDetector.pipelineTemplate)
 	}
 }
 
@@ -108,7 +111,7 @@ func TestCIProviderConfigure(t *testing.T) {
 	t.Setenv(githubOutputEnvVar, "")
 
 	provider := NewGitHub()
-	err := provider.Configure(4) // Test with 4 parallel runners
+	err := provider.Configure(4, nil) // Test with 4 parallel runners
 	if err != nil {
 		t.Errorf("Expected Configure(4) to return nil, got %v", err)
 	}
@@ -135,9 +138,10 @@ func TestDetectCIProvider_NonGitHubProvidersAreConfigurable(t *testing.T) {
 
 	for _, providerName := range providerNames {
 		t.Run(providerName, func(t *testing.T) {
+			t.Chdir(t.TempDir())
 			setDetectedProvider(t, providerName)
 
-			provider, err := DetectCIProvider()
+			provider, err := DetectCIProvider("")
 			if err != nil {
 				t.Fatalf("Expected provider %q to be detected, got error: %v", providerName, err)
 			}
@@ -146,7 +150,7 @@ func TestDetectCIProvider_NonGitHubProvidersAreConfigurable(t *testing.T) {
 				t.Errorf("Expected provider name %q, got %q", providerName, provider.Name())
 			}
 
-			if err := provider.Configure(4); err != nil {
+			if err := provider.Configure(4, nil); err != nil {
 				t.Errorf("Expected Configure(4) to succeed for %q, got %v", providerName, err)
 			}
 		})
@@ -57,7 +57,7 @@ func TestGitHub_Configure(t *testing.T) {
 			// Clean up before each test
 			_ = os.RemoveAll(constants.PlanDirectory)
 
-			err := g.Configure(tt.parallelRunners)
+			err := g.Configure(tt.parallelRunners, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GitHub.Configure() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -119,7 +119,7 @@ func TestGitHub_ConfigureJSONFormat(t *testing.T) {
 	_ = os.RemoveAll(constants.PlanDirectory)
 	defer func() { _ = os.RemoveAll(constants.PlanDirectory) }()
 
-	err := g.Configure(2)
+	err := g.Configure(2, nil)
 	if err != nil {
 		t.Fatalf("Configure() failed: %v", err)
 	}
@@ -138,6 +138,61 @@ func TestGitHub_ConfigureJSONFormat(t *testing.T) {
 	}
 }
 
+func TestGitHub_ConfigureWithCINodeWorkers(t *testing.T) {
+	g := NewGitHub()
+	t.Setenv(githubOutputEnvVar, "")
+
+	_ = os.RemoveAll(constants.PlanDirectory)
+	defer func() { _ = os.RemoveAll(constants.PlanDirectory) }()
+
+	if err := g.Configure(3, []int{8, 8, 4}); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(GitHubMatrixPath)
+	if err != nil {
+		t.Fatalf("Failed to read matrix file: %v", err)
+	}
+
+	expectedContent := `matrix={"include":[{"ci_node_index":0,"ci_node_total":3,"ci_node_workers":8},{"ci_node_index":1,"ci_node_total":3,"ci_node_workers":8},{"ci_node_index":2,"ci_node_total":3,"ci_node_workers":4}]}`
+	if string(data) != expectedContent {
+		t.Errorf("Expected content:\n%s\nGot content:\n%s", expectedContent, string(data))
+	}
+}
+
+func TestGitHub_ConfigureWorkspace(t *testing.T) {
+	g := NewGitHub()
+	t.Setenv(githubOutputEnvVar, "")
+
+	_ = os.RemoveAll(constants.PlanDirectory)
+	defer func() { _ = os.RemoveAll(constants.PlanDirectory) }()
+
+	if err := g.ConfigureWorkspace(nil); err == nil {
+		t.Error("expected an empty workspace matrix to be rejected")
+	}
+	err := g.ConfigureWorkspace([]WorkspaceMatrixEntry{
+		{Package: "api", Dir: "services/api", CINodeIndex: 0, CINodeTotal: 2},
+		{Package: "api", Dir: "services/api", CINodeIndex: 1, CINodeTotal: 2},
+		{Package: "web", Dir: "apps/web", CINodeIndex: 0, CINodeTotal: 1, CINodeWorkers: 4},
+	})
+	if err != nil {
+		t.Fatalf("ConfigureWorkspace() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(GitHubMatrixPath)
+	if err != nil {
+		t.Fatalf("Failed to read matrix file: %v", err)
+	}
+
+	expectedContent := `matrix={"include":[` +
+		`{"package":"api","dir":"services/api","ci_node_index":0,"ci_node_total":2},` +
+		`{"package":"api","dir":"services/api","ci_node_index":1,"ci_node_total":2},` +
+		`{"package":"web","dir":"apps/web","ci_node_index":0,"ci_node_total":1,"ci_node_workers":4}]}`
+	if string(data) != expectedContent {
+		t.Errorf("Expected content:\n%s\nGot content:\n%s", expectedContent, string(data))
+	}
+}
+
 func TestGitHub_ConfigureWritesGitHubOutput(t *testing.T) {
 	g := NewGitHub()
 
@@ -153,7 +208,7 @@ func TestGitHub_ConfigureWritesGitHubOutput(t *testing.T) {
 	}
 	t.Setenv(githubOutputEnvVar, outputFile.Name())
 
-	err = g.Configure(2)
+	err = g.Configure(2, nil)
 	if err != nil {
 		t.Fatalf("Configure() failed: %v", err)
 	}
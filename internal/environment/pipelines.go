@@ -0,0 +1,174 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2026 Datadog, Inc.
+
+package environment
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/DataDog/ddtest/internal/constants"
+)
+
+var (
+	BuildkitePipelinePath  = filepath.Join(constants.PlanDirectory, "buildkite/pipeline.yml")
+	GitLabPipelinePath     = filepath.Join(constants.PlanDirectory, "gitlab/pipeline.yml")
+	CircleCIConfigPath     = filepath.Join(constants.PlanDirectory, "circleci/config.yml")
+	CircleCIParametersPath = filepath.Join(constants.PlanDirectory, "circleci/parameters.json")
+)
+
+// Pipeline templates use [[ and ]] as delimiters, so that the {{ }} of
+// CircleCI templates such as {{ .Revision }} are copied as they are.
+const (
+	pipelineTemplateLeftDelim  = "[["
+	pipelineTemplateRightDelim = "]]"
+)
+
+// pipelineTemplateData is what a pipeline template is rendered with.
+type pipelineTemplateData struct {
+	// Parallelism is the number of CI nodes DDTest selected.
+	Parallelism int
+	// Jobs holds one entry per CI node, as in the GitHub Actions matrix.
+	Jobs []matrixEntry
+}
+
+// Buildkite renders the pipeline template into a pipeline that
+// `buildkite-agent pipeline upload` adds to the build.
+type Buildkite struct {
+	pipelineTemplate string
+}
+
+// GitLab renders the pipeline template into a child pipeline that a trigger
+// job includes with `include: artifact`.
+type GitLab struct {
+	pipelineTemplate string
+}
+
+// CircleCI writes the pipeline parameters that a setup workflow continues
+// with, and renders the pipeline template into the config to continue with.
+type CircleCI struct {
+	pipelineTemplate string
+}
+
+type circleCIParameters struct {
+	Parallelism int `json:"parallelism"`
+}
+
+func NewBuildkite(pipelineTemplate string) *Buildkite {
+	return &Buildkite{pipelineTemplate: pipelineTemplate}
+}
+
+func (b *Buildkite) Name() string {
+	return "buildkite"
+}
+
+func (b *Buildkite) Configure(parallelRunners int, ciNodeWorkers []int) error {
+	return renderPipelineTemplate(b.pipelineTemplate, BuildkitePipelinePath, parallelRunners, ciNodeWorkers)
+}
+
+func NewGitLab(pipelineTemplate string) *GitLab {
+	return &GitLab{pipelineTemplate: pipelineTemplate}
+}
+
+func (g *GitLab) Name() string {
+	return "gitlab"
+}
+
+func (g *GitLab) Configure(parallelRunners int, ciNodeWorkers []int) error {
+	return renderPipelineTemplate(g.pipelineTemplate, GitLabPipelinePath, parallelRunners, ciNodeWorkers)
+}
+
+func NewCircleCI(pipelineTemplate string) *CircleCI {
+	return &CircleCI{pipelineTemplate: pipelineTemplate}
+}
+
+func (c *CircleCI) Name() string {
+	return "circleci"
+}
+
+func (c *CircleCI) Configure(parallelRunners int, ciNodeWorkers []int) error {
+	if parallelRunners <= 0 {
+		return fmt.Errorf("parallelRunners must be greater than 0, got %d", parallelRunners)
+	}
+
+	jsonData, err := json.Marshal(circleCIParameters{Parallelism: parallelRunners})
+	if err != nil {
+		return fmt.Errorf("failed to marshal CircleCI pipeline parameters: %w", err)
+	}
+	if err := writePipelineFile(CircleCIParametersPath, append(jsonData, '\n')); err != nil {
+		return err
+	}
+	slog.Info("testoptimization: wrote CircleCI pipeline parameters",
+		"parameters", string(jsonData),
+		"parametersPath", CircleCIParametersPath,
+	)
+
+	return renderPipelineTemplate(c.pipelineTemplate, CircleCIConfigPath, parallelRunners, ciNodeWorkers)
+}
+
+// renderPipelineTemplate renders the pipeline template at templatePath for
+// parallelRunners CI nodes into outputPath. Without a pipeline template, it
+// writes nothing.
+func renderPipelineTemplate(templatePath string, outputPath string, parallelRunners int, ciNodeWorkers []int) error {
+	if templatePath == "" {
+		slog.Debug("No pipeline template configured, not writing a pipeline definition", "pipelinePath", outputPath)
+		return nil
+	}
+	if parallelRunners <= 0 {
+		return fmt.Errorf("parallelRunners must be greater than 0, got %d", parallelRunners)
+	}
+
+	content, err := os.ReadFile(templatePath)
+	if err != nil {
+		return fmt.Errorf("failed to read pipeline template %s: %w", templatePath, err)
+	}
+	tmpl, err := template.New(filepath.Base(templatePath)).
+		Delims(pipelineTemplateLeftDelim, pipelineTemplateRightDelim).
+		Parse(string(content))
+	if err != nil {
+		return fmt.Errorf("failed to parse pipeline template %s: %w", templatePath, err)
+	}
+
+	data := pipelineTemplateData{
+		Parallelism: parallelRunners,
+		Jobs:        make([]matrixEntry, parallelRunners),
+	}
+	for i := range parallelRunners {
+		data.Jobs[i] = matrixEntry{CINodeIndex: i, CINodeTotal: parallelRunners}
+		if i < len(ciNodeWorkers) {
+			data.Jobs[i].CINodeWorkers = ciNodeWorkers[i]
+		}
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return fmt.Errorf("failed to render pipeline template %s: %w", templatePath, err)
+	}
+	if err := writePipelineFile(outputPath, rendered.Bytes()); err != nil {
+		return err
+	}
+
+	slog.Info("testoptimization: wrote pipeline definition",
+		"pipelineTemplate", templatePath,
+		"pipelinePath", outputPath,
+		"parallelism", parallelRunners,
+	)
+	return nil
+}
+
+func writePipelineFile(path string, content []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
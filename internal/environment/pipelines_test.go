@@ -0,0 +1,193 @@
+package environment
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePipelineTemplate(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "job.yml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPipelineProviders_Name(t *testing.T) {
+	for want, provider := range map[string]CIProvider{
+		"buildkite": NewBuildkite(""),
+		"gitlab":    NewGitLab(""),
+		"circleci":  NewCircleCI(""),
+	} {
+		if got := provider.Name(); got != want {
+			t.Errorf("Name() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestBuildkite_Configure(t *testing.T) {
+	t.Chdir(t.TempDir())
+	pipelineTemplate := writePipelineTemplate(t, `steps:
+  - label: "tests"
+    command: "ddtest run"
+    parallelism: [[ .Parallelism ]]
+`)
+
+	if err := NewBuildkite(pipelineTemplate).Configure(3, nil); err != nil {
+		t.Fatalf("Buildkite.Configure() returned error: %v", err)
+	}
+	data, err := os.ReadFile(BuildkitePipelinePath)
+	if err != nil {
+		t.Fatalf("Failed to read pipeline: %v", err)
+	}
+	want := `steps:
+  - label: "tests"
+    command: "ddtest run"
+    parallelism: 3
+`
+	if string(data) != want {
+		t.Errorf("unexpected pipeline:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestGitLab_ConfigureRendersJobs(t *testing.T) {
+	t.Chdir(t.TempDir())
+	pipelineTemplate := writePipelineTemplate(t, `[[ range .Jobs ]]tests-[[ .CINodeIndex ]]:
+  variables:
+    CI_NODE_INDEX: "[[ .CINodeIndex ]]"
+    CI_NODE_TOTAL: "[[ .CINodeTotal ]]"
+    WORKERS: "[[ .CINodeWorkers ]]"
+[[ end ]]`)
+
+	if err := NewGitLab(pipelineTemplate).Configure(2, []int{4, 2}); err != nil {
+		t.Fatalf("GitLab.Configure() returned error: %v", err)
+	}
+	data, err := os.ReadFile(GitLabPipelinePath)
+	if err != nil {
+		t.Fatalf("Failed to read pipeline: %v", err)
+	}
+	want := `tests-0:
+  variables:
+    CI_NODE_INDEX: "0"
+    CI_NODE_TOTAL: "2"
+    WORKERS: "4"
+tests-1:
+  variables:
+    CI_NODE_INDEX: "1"
+    CI_NODE_TOTAL: "2"
+    WORKERS: "2"
+`
+	if string(data) != want {
+		t.Errorf("unexpected pipeline:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestGitLab_ConfigureWithoutPipelineTemplate(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if err := NewGitLab("").Configure(2, nil); err != nil {
+		t.Fatalf("GitLab.Configure() returned error: %v", err)
+	}
+	if _, err := os.Stat(GitLabPipelinePath); !os.IsNotExist(err) {
+		t.Errorf("expected no pipeline without a pipeline template, got: %v", err)
+	}
+}
+
+func TestCircleCI_Configure(t *testing.T) {
+	t.Chdir(t.TempDir())
+	pipelineTemplate := writePipelineTemplate(t, `jobs:
+  tests:
+    parallelism: [[ .Parallelism ]]
+    steps:
+      - restore_cache:
+          key: deps-{{ .Revision }}
+`)
+
+	if err := NewCircleCI(pipelineTemplate).Configure(4, nil); err != nil {
+		t.Fatalf("CircleCI.Configure() returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(CircleCIParametersPath)
+	if err != nil {
+		t.Fatalf("Failed to read pipeline parameters: %v", err)
+	}
+	var parameters map[string]int
+	if err := json.Unmarshal(data, &parameters); err != nil {
+		t.Fatalf("Failed to parse pipeline parameters: %v", err)
+	}
+	if len(parameters) != 1 || parameters["parallelism"] != 4 {
+		t.Errorf("unexpected pipeline parameters %s", data)
+	}
+
+	data, err = os.ReadFile(CircleCIConfigPath)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	// CircleCI template keys are not pipeline template actions.
+	want := `jobs:
+  tests:
+    parallelism: 4
+    steps:
+      - restore_cache:
+          key: deps-{{ .Revision }}
+`
+	if string(data) != want {
+		t.Errorf("unexpected config:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestCircleCI_ConfigureWithoutPipelineTemplate(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if err := NewCircleCI("").Configure(2, nil); err != nil {
+		t.Fatalf("CircleCI.Configure() returned error: %v", err)
+	}
+	if _, err := os.Stat(CircleCIParametersPath); err != nil {
+		t.Errorf("expected pipeline parameters without a pipeline template, got: %v", err)
+	}
+	if _, err := os.Stat(CircleCIConfigPath); !os.IsNotExist(err) {
+		t.Errorf("expected no config without a pipeline template, got: %v", err)
+	}
+}
+
+func TestRenderPipelineTemplate_Errors(t *testing.T) {
+	t.Chdir(t.TempDir())
+	validTemplate := writePipelineTemplate(t, "parallelism: [[ .Parallelism ]]\n")
+
+	tests := []struct {
+		name            string
+		templatePath    string
+		parallelRunners int
+	}{
+		{name: "missing template", templatePath: filepath.Join(t.TempDir(), "missing.yml"), parallelRunners: 2},
+		{name: "invalid template", templatePath: writePipelineTemplate(t, "parallelism: [[ .Parallelism\n"), parallelRunners: 2},
+		{name: "unknown field", templatePath: writePipelineTemplate(t, "parallelism: [[ .Runners ]]\n"), parallelRunners: 2},
+		{name: "invalid 0 runners", templatePath: validTemplate, parallelRunners: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := renderPipelineTemplate(tt.templatePath, BuildkitePipelinePath, tt.parallelRunners, nil); err == nil {
+				t.Error("expected renderPipelineTemplate() to return an error")
+			}
+		})
+	}
+	if _, err := os.Stat(BuildkitePipelinePath); !os.IsNotExist(err) {
+		t.Errorf("expected no pipeline after failed renders, got: %v", err)
+	}
+}
+
+func TestNewCIProvider_PipelineProviders(t *testing.T) {
+	const pipelineTemplate = "ci/pipeline.yml.tmpl"
+	if provider, ok := newCIProvider("buildkite", pipelineTemplate).(*Buildkite); !ok || provider.pipelineTemplate != pipelineTemplate {
+		t.Error("expected a Buildkite provider with the pipeline template")
+	}
+	if provider, ok := newCIProvider("gitlab", pipelineTemplate).(*GitLab); !ok || provider.pipelineTemplate != pipelineTemplate {
+		t.Error("expected a GitLab provider with the pipeline template")
+	}
+	if provider, ok := newCIProvider("circleci", pipelineTemplate).(*CircleCI); !ok || provider.pipelineTemplate != pipelineTemplate {
+		t.Error("expected a CircleCI provider with the pipeline template")
+	}
+}
@@ -24,8 +24,10 @@ const (
 	PlanPlatformTagsCreationFailed             Code = "plan_platform_tags_creation_failed"
 	PlanRuntimeTagsInvalid                     Code = "plan_runtime_tags_invalid"
 	PlanFrameworkDetectionFailed               Code = "plan_framework_detection_failed"
+	PlanFrameworkPlatformMismatch              Code = "plan_framework_platform_mismatch"
 	PlanOptimizationClientCreationFailed       Code = "plan_optimization_client_creation_failed"
 	PlanTestFilesResolutionFailed              Code = "plan_test_files_resolution_failed"
+	PlanConstraintsInvalid                     Code = "plan_constraints_invalid"
 	PlanOptimizationClientInitializationFailed Code = "plan_optimization_client_initialization_failed"
 	PlanFullTestDiscoveryFailed                Code = "plan_full_test_discovery_failed"
 	PlanFastTestDiscoveryFailed                Code = "plan_fast_test_discovery_failed"
@@ -36,7 +38,12 @@ const (
 	PlanTestFilesWriteFailed                   Code = "plan_test_files_write_failed"
 	PlanSkippablePercentageWriteFailed         Code = "plan_skippable_percentage_write_failed"
 	PlanParallelRunnersWriteFailed             Code = "plan_parallel_runners_write_failed"
+	PlanCINodeWorkersWriteFailed               Code = "plan_ci_node_workers_write_failed"
 	PlanTestSplitsWriteFailed                  Code = "plan_test_splits_write_failed"
+	PlanJSONWriteFailed                        Code = "plan_json_write_failed"
+	PlanWorkspaceInvalid                       Code = "plan_workspace_invalid"
+	PlanWorkspacePackageFailed                 Code = "plan_workspace_package_failed"
+	PlanWorkspaceMatrixWriteFailed             Code = "plan_workspace_matrix_write_failed"
 	RunGitUnavailable                          Code = "run_git_unavailable"
 	RunPlanningFailed                          Code = "run_planning_failed"
 	RunPlanStatusCheckFailed                   Code = "run_plan_status_check_failed"
@@ -45,6 +52,7 @@ const (
 	RunParallelRunnersParseFailed              Code = "run_parallel_runners_parse_failed"
 	RunPlatformDetectionFailed                 Code = "run_platform_detection_failed"
 	RunFrameworkDetectionFailed                Code = "run_framework_detection_failed"
+	RunFrameworkPlatformMismatch               Code = "run_framework_platform_mismatch"
 	RunSequentialTestFilesReadFailed           Code = "run_sequential_test_files_read_failed"
 	RunSequentialTestsFailed                   Code = "run_sequential_tests_failed"
 	RunParallelSplitsReadFailed                Code = "run_parallel_splits_read_failed"
@@ -53,6 +61,35 @@ const (
 	RunCINodeTestFilesMissing                  Code = "run_ci_node_test_files_missing"
 	RunCINodeTestFilesReadFailed               Code = "run_ci_node_test_files_read_failed"
 	RunCINodeTestsFailed                       Code = "run_ci_node_tests_failed"
+	RunCINodeTotalMismatch                     Code = "run_ci_node_total_mismatch"
+	RunQueueURLInvalid                         Code = "run_queue_url_invalid"
+	RunQueueUnavailable                        Code = "run_queue_unavailable"
+	RunQueueTestsFailed                        Code = "run_queue_tests_failed"
+	RunWorkerLogsDirCreateFailed               Code = "run_worker_logs_dir_create_failed"
+	ServeGitUnavailable                        Code = "serve_git_unavailable"
+	ServePlanningFailed                        Code = "serve_planning_failed"
+	ServePlanStatusCheckFailed                 Code = "serve_plan_status_check_failed"
+	ServePlanLoadFailed                        Code = "serve_plan_load_failed"
+	ServeTestFilesReadFailed                   Code = "serve_test_files_read_failed"
+	ServeListenFailed                          Code = "serve_listen_failed"
+	ServeQueueFailed                           Code = "serve_queue_failed"
+	ServeQueueInterrupted                      Code = "serve_queue_interrupted"
+	ExplainPlanLoadFailed                      Code = "explain_plan_load_failed"
+	ExplainTestSplitsReadFailed                Code = "explain_test_splits_read_failed"
+	ExplainTargetNotFound                      Code = "explain_target_not_found"
+	VerifyPlanManifestMissing                  Code = "verify_plan_manifest_missing"
+	VerifyPlanManifestVersionMismatch          Code = "verify_plan_manifest_version_mismatch"
+	VerifyPlanCacheInvalid                     Code = "verify_plan_cache_invalid"
+	VerifyPlanTestFilesReadFailed              Code = "verify_plan_test_files_read_failed"
+	VerifyPlanDuplicateTestFile                Code = "verify_plan_duplicate_test_file"
+	VerifyPlanParallelRunnersInvalid           Code = "verify_plan_parallel_runners_invalid"
+	VerifyPlanTestSplitsReadFailed             Code = "verify_plan_test_splits_read_failed"
+	VerifyPlanRunnerCountMismatch              Code = "verify_plan_runner_count_mismatch"
+	VerifyPlanTestSplitsMismatch               Code = "verify_plan_test_splits_mismatch"
+	VerifyPlanTestFileMissing                  Code = "verify_plan_test_file_missing"
+	VerifyPlanJSONInvalid                      Code = "verify_plan_json_invalid"
+	PlanDiffPlanLoadFailed                     Code = "plan_diff_plan_load_failed"
+	PlanDiffTestSplitsReadFailed               Code = "plan_diff_test_splits_read_failed"
 )
 
 // Error associates a stable code with an underlying error while preserving
@@ -56,8 +56,10 @@ func TestFatalCodesAreUnique(t *testing.T) {
 		PlanPlatformTagsCreationFailed,
 		PlanRuntimeTagsInvalid,
 		PlanFrameworkDetectionFailed,
+		PlanFrameworkPlatformMismatch,
 		PlanOptimizationClientCreationFailed,
 		PlanTestFilesResolutionFailed,
+		PlanConstraintsInvalid,
 		PlanOptimizationClientInitializationFailed,
 		PlanFullTestDiscoveryFailed,
 		PlanFastTestDiscoveryFailed,
@@ -68,7 +70,12 @@ func TestFatalCodesAreUnique(t *testing.T) {
 		PlanTestFilesWriteFailed,
 		PlanSkippablePercentageWriteFailed,
 		PlanParallelRunnersWriteFailed,
+		PlanCINodeWorkersWriteFailed,
 		PlanTestSplitsWriteFailed,
+		PlanJSONWriteFailed,
+		PlanWorkspaceInvalid,
+		PlanWorkspacePackageFailed,
+		PlanWorkspaceMatrixWriteFailed,
 		RunGitUnavailable,
 		RunPlanningFailed,
 		RunPlanStatusCheckFailed,
@@ -77,6 +84,7 @@ func TestFatalCodesAreUnique(t *testing.T) {
 		RunParallelRunnersParseFailed,
 		RunPlatformDetectionFailed,
 		RunFrameworkDetectionFailed,
+		RunFrameworkPlatformMismatch,
 		RunSequentialTestFilesReadFailed,
 		RunSequentialTestsFailed,
 		RunParallelSplitsReadFailed,
@@ -85,6 +93,35 @@ func TestFatalCodesAreUnique(t *testing.T) {
 		RunCINodeTestFilesMissing,
 		RunCINodeTestFilesReadFailed,
 		RunCINodeTestsFailed,
+		RunCINodeTotalMismatch,
+		RunQueueURLInvalid,
+		RunQueueUnavailable,
+		RunQueueTestsFailed,
+		RunWorkerLogsDirCreateFailed,
+		ServeGitUnavailable,
+		ServePlanningFailed,
+		ServePlanStatusCheckFailed,
+		ServePlanLoadFailed,
+		ServeTestFilesReadFailed,
+		ServeListenFailed,
+		ServeQueueFailed,
+		ServeQueueInterrupted,
+		ExplainPlanLoadFailed,
+		ExplainTestSplitsReadFailed,
+		ExplainTargetNotFound,
+		VerifyPlanManifestMissing,
+		VerifyPlanManifestVersionMismatch,
+		VerifyPlanCacheInvalid,
+		VerifyPlanTestFilesReadFailed,
+		VerifyPlanDuplicateTestFile,
+		VerifyPlanParallelRunnersInvalid,
+		VerifyPlanTestSplitsReadFailed,
+		VerifyPlanRunnerCountMismatch,
+		VerifyPlanTestSplitsMismatch,
+		VerifyPlanTestFileMissing,
+		VerifyPlanJSONInvalid,
+		PlanDiffPlanLoadFailed,
+		PlanDiffTestSplitsReadFailed,
 	}
 
 	seen := make(map[Code]struct{}, len(codes))
@@ -3,10 +3,13 @@ package ext
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 type CommandExecutor interface {
@@ -14,6 +17,103 @@ type CommandExecutor interface {
 	Run(ctx context.Context, name string, args []string, envMap map[string]string) error
 }
 
+type outputContextKey struct{}
+
+type output struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// WithOutput returns a context that makes Run write the command's stdout and
+// stderr to the given writers instead of the parent process streams.
+func WithOutput(ctx context.Context, stdout, stderr io.Writer) context.Context {
+	return context.WithValue(ctx, outputContextKey{}, output{stdout: stdout, stderr: stderr})
+}
+
+func outputFromContext(ctx context.Context) (io.Writer, io.Writer) {
+	if out, ok := ctx.Value(outputContextKey{}).(output); ok {
+		return out.stdout, out.stderr
+	}
+	return os.Stdout, os.Stderr
+}
+
+type dirContextKey struct{}
+
+// WithDir returns a context that makes the executor start the command in dir
+// instead of the current working directory.
+func WithDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, dirContextKey{}, dir)
+}
+
+func dirFromContext(ctx context.Context) string {
+	dir, _ := ctx.Value(dirContextKey{}).(string)
+	return dir
+}
+
+type gracefulCancelContextKey struct{}
+
+// gracefulCancelTimeout is how long a cancelled command's process tree gets to
+// exit after SIGTERM before it is killed.
+const gracefulCancelTimeout = 10 * time.Second
+
+// WithGracefulCancel returns a context that makes Run start the command in its
+// own process group and, when ctx is cancelled, send SIGTERM to the whole group
+// before killing it. Without it, cancellation kills only the command process.
+// When stdin is a terminal, the command stays in the terminal's process group
+// and only the command process is signalled.
+func WithGracefulCancel(ctx context.Context) context.Context {
+	return context.WithValue(ctx, gracefulCancelContextKey{}, true)
+}
+
+func gracefulCancelFromContext(ctx context.Context) bool {
+	graceful, _ := ctx.Value(gracefulCancelContextKey{}).(bool)
+	return graceful
+}
+
+type hangTimeoutContextKey struct{}
+
+type hangTimeout struct {
+	timeout          time.Duration
+	diagnosticSignal os.Signal
+}
+
+// hangDiagnosticPeriod is how long a timed-out command gets to write
+// diagnostics after its diagnostic signal before it is terminated.
+const hangDiagnosticPeriod = 5 * time.Second
+
+// WithHangTimeout returns a context that makes Run stop the command when it
+// runs longer than timeout. The command's process group first gets
+// diagnosticSignal, when it is not nil, so it can dump stacks; it is then
+// terminated like a graceful cancellation. Run returns a *TimeoutError when the
+// timeout fired. Like WithGracefulCancel, it starts the command in its own
+// process group.
+func WithHangTimeout(ctx context.Context, timeout time.Duration, diagnosticSignal os.Signal) context.Context {
+	return context.WithValue(ctx, hangTimeoutContextKey{}, hangTimeout{timeout: timeout, diagnosticSignal: diagnosticSignal})
+}
+
+func hangTimeoutFromContext(ctx context.Context) hangTimeout {
+	hang, _ := ctx.Value(hangTimeoutContextKey{}).(hangTimeout)
+	return hang
+}
+
+// TimeoutError is returned by Run when the command was stopped because it ran
+// longer than its hang timeout.
+type TimeoutError struct {
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("command timed out after %s", e.Timeout)
+	}
+	return fmt.Sprintf("command timed out after %s: %v", e.Timeout, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
 type signalNotifier interface {
 	Notify(chan<- os.Signal, ...os.Signal)
 	Stop(chan<- os.Signal)
@@ -51,9 +151,21 @@ func applyEnvMap(cmd *exec.Cmd, envMap map[string]string) {
 	}
 }
 
+// stdinIsTerminal reports whether the parent's stdin is a terminal: a
+// character device other than the null device.
+func stdinIsTerminal() bool {
+	stdinInfo, err := os.Stdin.Stat()
+	if err != nil || stdinInfo.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	nullInfo, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(stdinInfo, nullInfo)
+}
+
 func (e *DefaultCommandExecutor) CombinedOutput(ctx context.Context, name string, args []string, envMap map[string]string) ([]byte, error) {
 	// no-dd-sa:go-security/command-injection
 	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dirFromContext(ctx)
 	applyEnvMap(cmd, envMap)
 
 	return cmd.CombinedOutput()
@@ -62,6 +174,7 @@ func (e *DefaultCommandExecutor) CombinedOutput(ctx context.Context, name string
 func (e *DefaultCommandExecutor) Output(ctx context.Context, name string, args []string, envMap map[string]string) ([]byte, []byte, error) {
 	// no-dd-sa:go-security/command-injection
 	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dirFromContext(ctx)
 	applyEnvMap(cmd, envMap)
 
 	var stdout bytes.Buffer
@@ -73,15 +186,35 @@ func (e *DefaultCommandExecutor) Output(ctx context.Context, name string, args [
 }
 
 func (e *DefaultCommandExecutor) Run(ctx context.Context, name string, args []string, envMap map[string]string) error {
-	// no-dd-sa:go-security/command-injection
-	cmd := exec.CommandContext(ctx, name, args...)
+	graceful := gracefulCancelFromContext(ctx)
+	hang := hangTimeoutFromContext(ctx)
+	managed := graceful || hang.timeout > 0
+	// A command in its own process group is outside the terminal's
+	// foreground group and would be stopped by SIGTTIN or SIGTTOU when it uses
+	// a terminal stdin, so it is then signalled on its own instead.
+	processGroup := managed && !stdinIsTerminal()
+
+	var cmd *exec.Cmd
+	if managed {
+		// Cancellation and timeouts are handled below so the whole process
+		// group gets a chance to shut down.
+		// no-dd-sa:go-security/command-injection
+		cmd = exec.Command(name, args...)
+		if processGroup {
+			startInProcessGroup(cmd)
+		}
+	} else {
+		// no-dd-sa:go-security/command-injection
+		cmd = exec.CommandContext(ctx, name, args...)
+	}
+	cmd.Dir = dirFromContext(ctx)
 	applyEnvMap(cmd, envMap)
 
-	// Connect command's stdin/stdout/stderr to parent's stdin/stdout/stderr for proper streaming
+	// Connect command's stdin/stdout/stderr to parent's stdin/stdout/stderr for proper streaming,
+	// unless the context routes output elsewhere.
 	// stdin is needed even for non-interactive commands because some gems (like reline) check terminal properties
 	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout, cmd.Stderr = outputFromContext(ctx)
 
 	// Start the command
 	if err := cmd.Start(); err != nil {
@@ -104,16 +237,72 @@ func (e *DefaultCommandExecutor) Run(ctx context.Context, name string, args []st
 		errChan <- cmd.Wait()
 	}()
 
-	// Wait for either signals or command completion
+	var cancelled <-chan struct{}
+	if managed {
+		cancelled = ctx.Done()
+	}
+	signalCommand := func(sig os.Signal) {
+		if processGroup {
+			_ = signalProcessGroup(cmd.Process, sig)
+		} else {
+			_ = signalProcess(cmd.Process, sig)
+		}
+	}
+	var hangTimer <-chan time.Time
+	if hang.timeout > 0 {
+		timer := time.NewTimer(hang.timeout)
+		defer timer.Stop()
+		hangTimer = timer.C
+	}
+	var terminateTimeout, killTimeout <-chan time.Time
+	terminate := func() {
+		if killTimeout == nil {
+			signalCommand(syscall.SIGTERM)
+			killTimeout = time.After(gracefulCancelTimeout)
+		}
+	}
+	timedOut := false
+
+	// Wait for either signals, cancellation, a timeout, or command completion
 	for {
 		select {
 		case sig := <-sigChan:
-			// Forward the signal to the child process
-			if cmd.Process != nil {
+			// Forward the signal to the child process, or to its whole
+			// process group when it runs in one
+			if managed {
+				signalCommand(sig)
+			} else if cmd.Process != nil {
 				_ = cmd.Process.Signal(sig)
 			}
+		case <-cancelled:
+			cancelled = nil
+			if !graceful {
+				// Without graceful cancellation, cancelling kills the command
+				// right away, like exec.CommandContext does.
+				signalCommand(syscall.SIGKILL)
+				continue
+			}
+			terminate()
+		case <-hangTimer:
+			hangTimer = nil
+			timedOut = true
+			if hang.diagnosticSignal == nil {
+				terminate()
+				continue
+			}
+			signalCommand(hang.diagnosticSignal)
+			terminateTimeout = time.After(hangDiagnosticPeriod)
+		case <-terminateTimeout:
+			terminateTimeout = nil
+			terminate()
+		case <-killTimeout:
+			killTimeout = nil
+			signalCommand(syscall.SIGKILL)
 		case err := <-errChan:
 			// Command finished
+			if timedOut {
+				return &TimeoutError{Timeout: hang.timeout, Err: err}
+			}
 			return err
 		}
 	}
@@ -1,8 +1,11 @@
 package ext
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"os"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"testing"
@@ -182,6 +185,38 @@ func TestDefaultCommandExecutor_Run_Success(t *testing.T) {
 	}
 }
 
+func TestDefaultCommandExecutor_Run_WritesToContextOutput(t *testing.T) {
+	executor := &DefaultCommandExecutor{}
+
+	var stdout, stderr bytes.Buffer
+	ctx := WithOutput(context.Background(), &stdout, &stderr)
+	err := executor.Run(ctx, "sh", []string{"-c", "printf stdout; printf stderr >&2"}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if stdout.String() != "stdout" || stderr.String() != "stderr" {
+		t.Fatalf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestDefaultCommandExecutor_Run_WithDir(t *testing.T) {
+	executor := &DefaultCommandExecutor{}
+	dir := t.TempDir()
+
+	var stdout bytes.Buffer
+	ctx := WithOutput(WithDir(context.Background(), dir), &stdout, &stdout)
+	if err := executor.Run(ctx, "sh", []string{"-c", "pwd -P"}, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatalf("failed to resolve %s: %v", dir, err)
+	}
+	if strings.TrimSpace(stdout.String()) != expected {
+		t.Fatalf("expected the command to run in %s, got %q", expected, stdout.String())
+	}
+}
+
 func TestDefaultCommandExecutor_Run_CommandFailure(t *testing.T) {
 	executor := &DefaultCommandExecutor{}
 
@@ -338,3 +373,135 @@ func TestDefaultCommandExecutor_Run_SignalForwardingSIGTERM(t *testing.T) {
 
 	t.Logf("Process correctly terminated with: %v", err)
 }
+
+// withStdinFromNullDevice makes the commands of the test read stdin from the
+// null device, so they run in their own process group even when the tests run
+// in a terminal.
+func withStdinFromNullDevice(t *testing.T) {
+	t.Helper()
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = null
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		_ = null.Close()
+	})
+}
+
+func TestDefaultCommandExecutor_Run_GracefulCancelSignalsProcessGroup(t *testing.T) {
+	withStdinFromNullDevice(t)
+	dir := t.TempDir()
+	startedFile := filepath.Join(dir, "started")
+	terminatedFile := filepath.Join(dir, "terminated")
+	// The nested shell stands in for a worker process started by the test
+	// command; it must see SIGTERM too, not only the command itself.
+	script := `sh -c 'trap "echo > ` + terminatedFile + `; exit 0" TERM; echo > ` + startedFile + `; while :; do sleep 0.1; done' & wait`
+
+	ctx, cancel := context.WithCancel(WithGracefulCancel(context.Background()))
+	defer cancel()
+	executor := &DefaultCommandExecutor{}
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- executor.Run(ctx, "sh", []string{"-c", script}, nil)
+	}()
+
+	waitForFile(t, startedFile)
+	cancel()
+
+	select {
+	case err := <-errChan:
+		if err == nil {
+			t.Fatal("expected error from cancelled process")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected cancelled process to exit before the kill timeout")
+	}
+	waitForFile(t, terminatedFile)
+}
+
+func waitForFile(t *testing.T, path string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDefaultCommandExecutor_Run_HangTimeoutSendsDiagnosticSignal(t *testing.T) {
+	diagnosticsFile := filepath.Join(t.TempDir(), "diagnostics")
+	script := `trap "echo > ` + diagnosticsFile + `; exit 3" QUIT; while :; do sleep 0.1; done`
+
+	executor := &DefaultCommandExecutor{}
+	ctx := WithHangTimeout(context.Background(), 200*time.Millisecond, syscall.SIGQUIT)
+	err := executor.Run(ctx, "sh", []string{"-c", script}, nil)
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected a TimeoutError, got %v", err)
+	}
+	if timeoutErr.Timeout != 200*time.Millisecond {
+		t.Errorf("expected timeout to be 200ms, got %s", timeoutErr.Timeout)
+	}
+	if _, err := os.Stat(diagnosticsFile); err != nil {
+		t.Fatalf("expected the diagnostic signal to reach the command: %v", err)
+	}
+}
+
+func TestDefaultCommandExecutor_Run_HangTimeoutWithoutDiagnosticSignalTerminates(t *testing.T) {
+	executor := &DefaultCommandExecutor{}
+	ctx := WithHangTimeout(context.Background(), 100*time.Millisecond, nil)
+
+	start := time.Now()
+	err := executor.Run(ctx, "sleep", []string{"30"}, nil)
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected a TimeoutError, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the command to be terminated right after the timeout, took %s", elapsed)
+	}
+}
+
+func TestDefaultCommandExecutor_Run_HangTimeoutNotReached(t *testing.T) {
+	executor := &DefaultCommandExecutor{}
+	ctx := WithHangTimeout(context.Background(), time.Minute, syscall.SIGQUIT)
+
+	if err := executor.Run(ctx, "true", nil, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestDefaultCommandExecutor_Run_HangTimeoutStopsOnContextCancellation(t *testing.T) {
+	executor := &DefaultCommandExecutor{}
+	ctx, cancel := context.WithCancel(WithHangTimeout(context.Background(), time.Minute, syscall.SIGQUIT))
+	defer cancel()
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	err := executor.Run(ctx, "sleep", []string{"30"}, nil)
+
+	if err == nil {
+		t.Fatal("expected error from cancelled process")
+	}
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		t.Fatalf("expected cancellation, not a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected the command to stop right after cancellation, took %s", elapsed)
+	}
+}
@@ -0,0 +1,44 @@
+//go:build unix
+
+package ext
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// startInProcessGroup makes cmd the leader of a new process group, so the
+// processes it starts can be signalled together.
+func startInProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signalProcessGroup sends sig to every process in the group led by process.
+func signalProcessGroup(process *os.Process, sig os.Signal) error {
+	unixSignal, ok := sig.(syscall.Signal)
+	if !ok {
+		return process.Signal(sig)
+	}
+	return syscall.Kill(-process.Pid, unixSignal)
+}
+
+// signalProcess sends sig to process only.
+func signalProcess(process *os.Process, sig os.Signal) error {
+	return process.Signal(sig)
+}
+
+var signalsByName = map[string]os.Signal{
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGABRT": syscall.SIGABRT,
+	"SIGINT":  syscall.SIGINT,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
+
+// SignalByName returns the signal with the given name, such as "SIGQUIT".
+func SignalByName(name string) (os.Signal, bool) {
+	sig, ok := signalsByName[name]
+	return sig, ok
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package ext
+
+import (
+	"os"
+	"os/exec"
+)
+
+// startInProcessGroup is a no-op on Windows.
+func startInProcessGroup(*exec.Cmd) {}
+
+// signalProcessGroup kills process on Windows, which cannot deliver other
+// signals to a process or its children.
+func signalProcessGroup(process *os.Process, _ os.Signal) error {
+	return process.Kill()
+}
+
+// signalProcess kills process on Windows, which cannot deliver other signals
+// to a process.
+func signalProcess(process *os.Process, _ os.Signal) error {
+	return process.Kill()
+}
+
+// SignalByName reports that no signal can be delivered on Windows.
+func SignalByName(string) (os.Signal, bool) {
+	return nil, false
+}
@@ -0,0 +1,93 @@
+package framework
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/DataDog/ddtest/internal/settings"
+	"github.com/DataDog/ddtest/internal/utils"
+)
+
+// reportFailedTestFiles reports whether test commands should report which of
+// their test files failed. Only retries need the report, so without them test
+// commands run as they would without ddtest's failure reports. Fail-fast turns
+// retries off.
+func reportFailedTestFiles() bool {
+	return settings.GetRetryFailedFiles() > 0 && !settings.GetFailFast()
+}
+
+// createFailureReport creates an empty temporary file for a test command to
+// write the failures of its run to. The caller removes it.
+func createFailureReport(pattern string) (string, error) {
+	reportFile, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create failure report: %w", err)
+	}
+	reportPath := reportFile.Name()
+	if err := reportFile.Close(); err != nil {
+		_ = os.Remove(reportPath)
+		return "", fmt.Errorf("failed to close failure report: %w", err)
+	}
+	return reportPath, nil
+}
+
+// reportedTestFilesFailed returns a *TestFilesFailedError with the entries of
+// testFiles, either test files or test chunks, whose test file is one of the
+// reportedFiles a failure report named. It returns err unchanged when the
+// report does not account for the failure, because it names no file or a file
+// outside testFiles: retrying only the named files could then hide a failure.
+func reportedTestFilesFailed(err error, testFiles []string, reportedFiles []string) error {
+	if len(reportedFiles) == 0 {
+		return err
+	}
+
+	failed := make(map[string]bool, len(reportedFiles))
+	for _, reportedFile := range reportedFiles {
+		failed[utils.NormalizeReportedPath(reportedFile)] = true
+	}
+
+	var failedTestFiles []string
+	matched := make(map[string]bool, len(failed))
+	for _, entry := range testFiles {
+		testFile := utils.NormalizePath(TestChunkFile(entry))
+		if failed[testFile] {
+			failedTestFiles = append(failedTestFiles, entry)
+			matched[testFile] = true
+		}
+	}
+	if len(matched) < len(failed) {
+		return err
+	}
+	return &TestFilesFailedError{TestFiles: failedTestFiles, Err: err}
+}
+
+// failedTestFilesOf returns the entries of testFiles that failed with err: the
+// ones it names when it is a *TestFilesFailedError, all of them otherwise.
+func failedTestFilesOf(err error, testFiles []string) []string {
+	var filesErr *TestFilesFailedError
+	if errors.As(err, &filesErr) {
+		return filesErr.TestFiles
+	}
+	return testFiles
+}
+
+// joinTestFilesFailed joins the errors of test commands that ran the matching
+// groups of testFiles into a *TestFilesFailedError with the failed entries of
+// every group. A command that cannot tell which of its test files failed
+// counts all of them as failed.
+func joinTestFilesFailed(groups [][]string, errs []error) error {
+	var joined []error
+	var failedTestFiles []string
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		joined = append(joined, err)
+		failedTestFiles = append(failedTestFiles, failedTestFilesOf(err, groups[i])...)
+	}
+	if len(joined) == 0 {
+		return nil
+	}
+	return &TestFilesFailedError{TestFiles: failedTestFiles, Err: errors.Join(joined...)}
+}
@@ -0,0 +1,39 @@
+package framework
+
+import (
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/settings"
+	"github.com/spf13/viper"
+)
+
+// setRetryFailedFiles enables --retry-failed-files, which makes test commands
+// report their failed test files.
+func setRetryFailedFiles(t *testing.T) {
+	t.Helper()
+	resetSettings := func() {
+		viper.Reset()
+		settings.Init()
+	}
+	t.Cleanup(resetSettings)
+	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_RETRY_FAILED_FILES", "1")
+	resetSettings()
+}
+
+func TestReportFailedTestFiles(t *testing.T) {
+	if reportFailedTestFiles() {
+		t.Error("expected no failure reports without --retry-failed-files")
+	}
+
+	setRetryFailedFiles(t)
+	if !reportFailedTestFiles() {
+		t.Error("expected failure reports with --retry-failed-files")
+	}
+
+	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_FAIL_FAST", "true")
+	viper.Reset()
+	settings.Init()
+	if reportFailedTestFiles() {
+		t.Error("expected no failure reports with --fail-fast")
+	}
+}
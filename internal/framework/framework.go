@@ -19,3 +19,19 @@ type Framework interface {
 	SourceFileForSuite(suite string) (string, bool)
 	HasUnskippableMarker(testFile string) bool
 }
+
+// TestFilesFailedError is returned by RunTests implementations that can tell
+// which of the given test files failed. Callers that retry failures use it to
+// re-run only those files instead of the whole batch.
+type TestFilesFailedError struct {
+	TestFiles []string
+	Err       error
+}
+
+func (e *TestFilesFailedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TestFilesFailedError) Unwrap() error {
+	return e.Err
+}
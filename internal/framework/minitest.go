@@ -2,10 +2,14 @@ package framework
 
 import (
 	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/DataDog/ddtest/internal/discovery"
@@ -19,8 +23,14 @@ const (
 	binRailsPath            = "bin/rails"
 	minitestTestFilePattern = "*_test.rb"
 	minitestRootDir         = "test"
+
+	rubyOptEnvVar               = "RUBYOPT"
+	minitestFailureReportEnvVar = "DDTEST_MINITEST_FAILURE_REPORT"
 )
 
+//go:embed scripts/minitest/ddtest_failure_report_plugin.rb
+var minitestFailureReportPlugin string
+
 type Minitest struct {
 	executor        ext.CommandExecutor
 	commandOverride []string
@@ -93,24 +103,130 @@ func (m *Minitest) RunTests(ctx context.Context, testFiles []string, envMap map[
 	command, args, isRails := m.getMinitestCommand()
 	slog.Info("Running tests with command", "command", command, "args", args)
 
+	pluginDir := ""
+	if reportFailedTestFiles() {
+		var err error
+		pluginDir, err = prepareMinitestFailureReportPlugin()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = os.RemoveAll(pluginDir) }()
+	}
+
+	// Test chunks run their test file with a name filter. The filter cannot
+	// tell the classes of a chunked test file that other chunks run from the
+	// classes of whole test files, so chunked test files run in a command of
+	// their own.
+	var wholeFiles, chunks []string
+	for _, entry := range testFiles {
+		if _, _, ok := ParseTestChunk(entry); ok {
+			chunks = append(chunks, entry)
+		} else {
+			wholeFiles = append(wholeFiles, entry)
+		}
+	}
+	if len(chunks) == 0 {
+		return m.runTestCommand(ctx, command, args, isRails, pluginDir, testFiles, envMap)
+	}
+	if len(wholeFiles) == 0 {
+		return m.runTestCommand(ctx, command, args, isRails, pluginDir, chunks, envMap)
+	}
+	wholeFilesErr := m.runTestCommand(ctx, command, args, isRails, pluginDir, wholeFiles, envMap)
+	chunksErr := m.runTestCommand(ctx, command, args, isRails, pluginDir, chunks, envMap)
+	return joinTestFilesFailed([][]string{wholeFiles, chunks}, []error{wholeFilesErr, chunksErr})
+}
+
+// runTestCommand runs entries, which are either all test files or all test
+// chunks. Test chunks run their test files with a name filter that selects
+// the tests of the chunks. The failure report plugin in pluginDir reports the
+// failed test files when pluginDir is set.
+func (m *Minitest) runTestCommand(ctx context.Context, command string, args []string, isRails bool, pluginDir string, entries []string, envMap map[string]string) error {
+	testFiles, chunkFiles, selectors := groupTestChunks(entries)
+	filter := ""
+	if len(chunkFiles) > 0 {
+		testFiles = chunkFiles
+		filter = minitestChunkFilter(chunkFiles, selectors)
+	}
+
+	args = slices.Clone(args)
+	mergedEnv := make(map[string]string)
+	maps.Copy(mergedEnv, m.platformEnv)
+	maps.Copy(mergedEnv, envMap)
+	reportPath := ""
+	if pluginDir != "" {
+		var err error
+		reportPath, err = createFailureReport("ddtest-minitest-report-*.json")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = os.Remove(reportPath) }()
+		rubyOpt, ok := mergedEnv[rubyOptEnvVar]
+		if !ok {
+			rubyOpt = os.Getenv(rubyOptEnvVar)
+		}
+		mergedEnv[rubyOptEnvVar] = strings.TrimSpace(rubyOpt + " -I" + pluginDir)
+		mergedEnv[minitestFailureReportEnvVar] = reportPath
+	}
+
 	// Add test files if provided
 	if len(testFiles) > 0 {
 		if isRails {
 			// Rails test accepts files as command-line arguments
 			args = append(args, testFiles...)
+			if filter != "" {
+				args = append(args, "-n", filter)
+			}
 		} else {
 			// Rake test requires TEST_FILES environment variable
-			if envMap == nil {
-				envMap = make(map[string]string)
+			mergedEnv["TEST_FILES"] = strings.Join(testFiles, " ")
+			if filter != "" {
+				mergedEnv["TESTOPTS"] = strings.TrimSpace(mergedEnv["TESTOPTS"] + " -n '" + filter + "'")
 			}
-			envMap["TEST_FILES"] = strings.Join(testFiles, " ")
 		}
 	}
 
-	mergedEnv := make(map[string]string)
-	maps.Copy(mergedEnv, m.platformEnv)
-	maps.Copy(mergedEnv, envMap)
-	return m.executor.Run(ctx, command, args, mergedEnv)
+	err := m.executor.Run(ctx, command, args, mergedEnv)
+	if err != nil && reportPath != "" {
+		return reportedTestFilesFailed(err, entries, minitestFailedFiles(reportPath))
+	}
+	return err
+}
+
+// prepareMinitestFailureReportPlugin writes the Minitest plugin that reports
+// the source files of failed tests to a temporary directory, laid out so that
+// Minitest finds the plugin when the directory is on the load path. The caller
+// removes the directory.
+func prepareMinitestFailureReportPlugin() (string, error) {
+	pluginDir, err := os.MkdirTemp("", "ddtest-minitest-plugin-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create Minitest plugin directory: %w", err)
+	}
+	pluginPath := filepath.Join(pluginDir, "minitest", "ddtest_failure_report_plugin.rb")
+	if err := os.MkdirAll(filepath.Dir(pluginPath), 0o755); err != nil {
+		_ = os.RemoveAll(pluginDir)
+		return "", fmt.Errorf("failed to create Minitest plugin directory: %w", err)
+	}
+	if err := os.WriteFile(pluginPath, []byte(minitestFailureReportPlugin), 0o644); err != nil {
+		_ = os.RemoveAll(pluginDir)
+		return "", fmt.Errorf("failed to write Minitest plugin: %w", err)
+	}
+	return pluginDir, nil
+}
+
+// minitestFailedFiles returns the source files of the failed tests that the
+// Minitest plugin wrote to the report at path, or nil when the run ended
+// before Minitest reported, for instance because a test file failed to load.
+func minitestFailedFiles(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	var failedFiles []string
+	if err := json.Unmarshal(data, &failedFiles); err != nil {
+		slog.Debug("Failed to parse Minitest failure report", "path", path, "error", err)
+		return nil
+	}
+	return failedFiles
 }
 
 // isRailsApplication determines if the current project is a Rails application
@@ -179,6 +295,23 @@ func (m *Minitest) SupportsFullTestDiscovery() bool {
 	return true
 }
 
+// TestSelector returns the test class and method of test, such as
+// UserTest#test_validation.
+func (m *Minitest) TestSelector(test testoptimization.Test) (string, bool) {
+	class := test.Suite
+	if separatorIndex := strings.LastIndex(class, rubySuiteSourceFileSeparator); separatorIndex >= 0 {
+		class = class[:separatorIndex]
+	}
+	if class == "" || strings.Contains(class, "#") {
+		return "", false
+	}
+	selector := class + "#" + test.Name
+	if !validTestSelector(selector) {
+		return "", false
+	}
+	return selector, true
+}
+
 func (m *Minitest) SourceFileForSuite(suite string) (string, bool) {
 	return trailingRubySuiteSourceFile(suite)
 }
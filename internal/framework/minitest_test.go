@@ -3,10 +3,12 @@ package framework
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"slices"
+	"strings"
 	"testing"
 
 	"github.com/DataDog/ddtest/internal/discovery"
@@ -20,6 +22,11 @@ type mockRailsCommandExecutor struct {
 	onTestExecution func(name string, args []string)
 	railsGemPath    string            // Optional: custom path for rails gem, defaults to temp dir
 	capturedEnvMap  map[string]string // Captured environment map from Run calls
+	// capturedEnvMaps holds the environment map of every Run call.
+	capturedEnvMaps []map[string]string
+	// onTestRun is called by Run with the environment of the test command,
+	// and its error is returned.
+	onTestRun func(envMap map[string]string) error
 }
 
 func (m *mockRailsCommandExecutor) CombinedOutput(ctx context.Context, name string, args []string, envMap map[string]string) ([]byte, error) {
@@ -60,9 +67,13 @@ func (m *mockRailsCommandExecutor) CombinedOutput(ctx context.Context, name stri
 func (m *mockRailsCommandExecutor) Run(ctx context.Context, name string, args []string, envMap map[string]string) error {
 	// Capture the envMap for test assertions
 	m.capturedEnvMap = envMap
+	m.capturedEnvMaps = append(m.capturedEnvMaps, envMap)
 	if m.onTestExecution != nil {
 		m.onTestExecution(name, args)
 	}
+	if m.onTestRun != nil {
+		return m.onTestRun(envMap)
+	}
 	return nil
 }
 
@@ -376,6 +387,174 @@ func TestMinitest_RunTests(t *testing.T) {
 	}
 }
 
+func TestMinitest_RunTests_WithTestChunks(t *testing.T) {
+	testFiles := []string{"test/models/user_test.rb", "test/system/checkout_test.rb[CheckoutTest#test_pay]"}
+
+	var capturedArgs [][]string
+	mockExecutor := &mockRailsCommandExecutor{
+		isRails: false,
+		onTestExecution: func(name string, args []string) {
+			capturedArgs = append(capturedArgs, args)
+		},
+	}
+
+	minitest := newTestMinitestWithExecutor(mockExecutor)
+	minitest.SetPlatformEnv(map[string]string{"TESTOPTS": "-v"})
+	if err := minitest.RunTests(context.Background(), testFiles, nil); err != nil {
+		t.Fatalf("RunTests failed: %v", err)
+	}
+
+	// Whole test files and test chunks run in separate commands, so that the
+	// chunk filter does not apply to whole test files.
+	if len(mockExecutor.capturedEnvMaps) != 2 {
+		t.Fatalf("expected 2 test commands, got %d", len(mockExecutor.capturedEnvMaps))
+	}
+	wholeEnv, chunkEnv := mockExecutor.capturedEnvMaps[0], mockExecutor.capturedEnvMaps[1]
+	if wholeEnv["TEST_FILES"] != "test/models/user_test.rb" || wholeEnv["TESTOPTS"] != "-v" {
+		t.Errorf("unexpected whole test files command env TEST_FILES=%q TESTOPTS=%q", wholeEnv["TEST_FILES"], wholeEnv["TESTOPTS"])
+	}
+	if chunkEnv["TEST_FILES"] != "test/system/checkout_test.rb" {
+		t.Errorf("expected TEST_FILES=%q, got %q", "test/system/checkout_test.rb", chunkEnv["TEST_FILES"])
+	}
+	expectedTestOpts := `-v -n '/^(?:CheckoutTest#(?:test_pay))$/'`
+	if chunkEnv["TESTOPTS"] != expectedTestOpts {
+		t.Errorf("expected TESTOPTS=%q, got %q", expectedTestOpts, chunkEnv["TESTOPTS"])
+	}
+	for _, args := range capturedArgs {
+		if slices.Contains(args, "-n") {
+			t.Errorf("expected no -n argument for rake test, got %v", args)
+		}
+	}
+}
+
+func TestMinitest_RunTests_RailsApplication_WithTestChunks(t *testing.T) {
+	testFiles := []string{"test/system/checkout_test.rb[CheckoutTest#test_pay,CheckoutTest#test_refund]"}
+
+	var capturedArgs []string
+	mockExecutor := &mockRailsCommandExecutor{
+		isRails: true,
+		onTestExecution: func(name string, args []string) {
+			capturedArgs = args
+		},
+	}
+
+	minitest := newTestMinitestWithExecutor(mockExecutor)
+	if err := minitest.RunTests(context.Background(), testFiles, nil); err != nil {
+		t.Fatalf("RunTests failed: %v", err)
+	}
+
+	expectedTail := []string{"test/system/checkout_test.rb", "-n", `/^(?:CheckoutTest#(?:test_pay|test_refund))$/`}
+	if len(capturedArgs) < len(expectedTail) || !slices.Equal(capturedArgs[len(capturedArgs)-len(expectedTail):], expectedTail) {
+		t.Fatalf("expected args to end with %v, got %v", expectedTail, capturedArgs)
+	}
+}
+
+func TestMinitest_RunTests_WithRetryFailedFiles_AddsFailureReportPlugin(t *testing.T) {
+	setRetryFailedFiles(t)
+
+	mockExecutor := &mockRailsCommandExecutor{}
+	minitest := newTestMinitestWithExecutor(mockExecutor)
+	platformEnv := map[string]string{
+		"RUBYOPT": "-rbundler/setup -rdatadog/ci/auto_instrument",
+	}
+	minitest.SetPlatformEnv(platformEnv)
+
+	if err := minitest.RunTests(context.Background(), []string{"test/models/user_test.rb"}, nil); err != nil {
+		t.Fatalf("RunTests failed: %v", err)
+	}
+
+	rubyOpt := mockExecutor.capturedEnvMap["RUBYOPT"]
+	pluginDir, ok := strings.CutPrefix(rubyOpt, platformEnv["RUBYOPT"]+" -I")
+	if !ok || pluginDir == "" {
+		t.Fatalf("expected RUBYOPT to add the plugin directory to %q, got %q", platformEnv["RUBYOPT"], rubyOpt)
+	}
+	if mockExecutor.capturedEnvMap["DDTEST_MINITEST_FAILURE_REPORT"] == "" {
+		t.Error("expected DDTEST_MINITEST_FAILURE_REPORT to name the failure report")
+	}
+}
+
+func TestMinitest_RunTests_WithoutRetryFailedFiles_AddsNoFailureReportPlugin(t *testing.T) {
+	mockExecutor := &mockRailsCommandExecutor{}
+	minitest := newTestMinitestWithExecutor(mockExecutor)
+
+	if err := minitest.RunTests(context.Background(), []string{"test/models/user_test.rb"}, nil); err != nil {
+		t.Fatalf("RunTests failed: %v", err)
+	}
+
+	if _, ok := mockExecutor.capturedEnvMap["RUBYOPT"]; ok {
+		t.Errorf("expected no RUBYOPT, got %q", mockExecutor.capturedEnvMap["RUBYOPT"])
+	}
+	if _, ok := mockExecutor.capturedEnvMap["DDTEST_MINITEST_FAILURE_REPORT"]; ok {
+		t.Error("expected no failure report")
+	}
+}
+
+func TestMinitest_RunTests_ReportsFailedTestFiles(t *testing.T) {
+	setRetryFailedFiles(t)
+	testFiles := []string{"test/models/user_test.rb", "test/models/order_test.rb", "test/system/checkout_test.rb[CheckoutTest#test_pay]"}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+
+	// The plugin reports source locations, which Rails loads by absolute path.
+	reports := [][]string{
+		{filepath.Join(cwd, "test/models/user_test.rb")},
+		{"test/system/checkout_test.rb"},
+	}
+	mockExecutor := &mockRailsCommandExecutor{
+		onTestRun: func(envMap map[string]string) error {
+			pluginDir := strings.TrimPrefix(strings.Fields(envMap["RUBYOPT"])[0], "-I")
+			if _, err := os.Stat(filepath.Join(pluginDir, "minitest", "ddtest_failure_report_plugin.rb")); err != nil {
+				t.Errorf("expected the failure report plugin on the load path: %v", err)
+			}
+			report, err := json.Marshal(reports[0])
+			if err != nil {
+				t.Fatalf("failed to marshal report: %v", err)
+			}
+			reports = reports[1:]
+			if err := os.WriteFile(envMap["DDTEST_MINITEST_FAILURE_REPORT"], report, 0644); err != nil {
+				t.Fatalf("failed to write report: %v", err)
+			}
+			return errors.New("exit status 1")
+		},
+	}
+
+	minitest := newTestMinitestWithExecutor(mockExecutor)
+	err = minitest.RunTests(context.Background(), testFiles, nil)
+
+	var filesErr *TestFilesFailedError
+	if !errors.As(err, &filesErr) {
+		t.Fatalf("expected TestFilesFailedError, got %v", err)
+	}
+	expected := []string{"test/models/user_test.rb", "test/system/checkout_test.rb[CheckoutTest#test_pay]"}
+	if !slices.Equal(filesErr.TestFiles, expected) {
+		t.Errorf("expected failed test files %v, got %v", expected, filesErr.TestFiles)
+	}
+}
+
+func TestMinitest_RunTests_FailureWithoutSourceLocationFailsWholeBatch(t *testing.T) {
+	setRetryFailedFiles(t)
+	testFiles := []string{"test/models/user_test.rb", "test/models/order_test.rb"}
+
+	mockExecutor := &mockRailsCommandExecutor{
+		onTestRun: func(envMap map[string]string) error {
+			if err := os.WriteFile(envMap["DDTEST_MINITEST_FAILURE_REPORT"], []byte(`["test/models/user_test.rb",null]`), 0644); err != nil {
+				t.Fatalf("failed to write report: %v", err)
+			}
+			return errors.New("exit status 1")
+		},
+	}
+
+	minitest := newTestMinitestWithExecutor(mockExecutor)
+	err := minitest.RunTests(context.Background(), testFiles, nil)
+
+	var filesErr *TestFilesFailedError
+	if err == nil || errors.As(err, &filesErr) {
+		t.Fatalf("expected a plain error, got %v", err)
+	}
+}
+
 func TestMinitest_RunTests_WithOverride(t *testing.T) {
 	testFiles := []string{"test/models/user_test.rb"}
 
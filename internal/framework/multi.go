@@ -0,0 +1,212 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/DataDog/ddtest/internal/discovery"
+	"github.com/DataDog/ddtest/internal/testoptimization"
+	"github.com/DataDog/ddtest/internal/utils"
+)
+
+// MultiMember is one framework of a Multi framework. TestPattern is the glob
+// pattern of the test files it owns, or "" for the framework's own pattern.
+type MultiMember struct {
+	Framework   Framework
+	TestPattern string
+}
+
+// Multi plans and runs several frameworks of one platform together. Each test
+// file belongs to the first framework whose test pattern matches it: that
+// framework discovers its tests and runs it, so a runner split can mix test
+// files of every framework.
+type Multi struct {
+	frameworks []Framework
+	patterns   []string
+	matchers   []utils.PathMatcher
+}
+
+func NewMulti(members []MultiMember) (*Multi, error) {
+	m := &Multi{}
+	for _, member := range members {
+		pattern := member.TestPattern
+		if pattern == "" {
+			pattern = member.Framework.TestPattern()
+		}
+		pattern = filepath.ToSlash(pattern)
+		matcher, err := utils.NewPathMatcher(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid test pattern for framework %s: %w", member.Framework.Name(), err)
+		}
+		m.frameworks = append(m.frameworks, member.Framework)
+		m.patterns = append(m.patterns, pattern)
+		m.matchers = append(m.matchers, matcher)
+	}
+	return m, nil
+}
+
+// Frameworks returns the frameworks planned together, in ownership order.
+func (m *Multi) Frameworks() []Framework {
+	return m.frameworks
+}
+
+func (m *Multi) Name() string {
+	names := make([]string, len(m.frameworks))
+	for i, fw := range m.frameworks {
+		names[i] = fw.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+func (m *Multi) TestPattern() string {
+	return "{" + strings.Join(m.patterns, ",") + "}"
+}
+
+// owner returns the index of the framework that owns a test file or test
+// chunk, or -1 when no framework's test pattern matches it.
+func (m *Multi) owner(testFile string) int {
+	testFile = TestChunkFile(testFile)
+	for i, matcher := range m.matchers {
+		if matcher.Match(testFile) {
+			return i
+		}
+	}
+	return -1
+}
+
+// memberTestFiles returns the test files of testFiles that framework i may
+// own: the explicit files its test pattern matches, or its test pattern.
+func (m *Multi) memberTestFiles(i int, testFiles discovery.TestFileSet) discovery.TestFileSet {
+	if !testFiles.UseExplicitFiles() {
+		return discovery.TestFileSet{Pattern: m.patterns[i]}
+	}
+	explicitFiles := []string{}
+	for _, testFile := range testFiles.ExplicitFiles {
+		if m.owner(testFile) == i {
+			explicitFiles = append(explicitFiles, testFile)
+		}
+	}
+	return discovery.TestFileSet{Pattern: m.patterns[i], ExplicitFiles: explicitFiles}
+}
+
+func (m *Multi) DiscoverTestFiles(ctx context.Context, testFiles discovery.TestFileSet) ([]string, error) {
+	var discovered []string
+	for i, fw := range m.frameworks {
+		memberFiles, err := fw.DiscoverTestFiles(ctx, m.memberTestFiles(i, testFiles))
+		if err != nil {
+			return nil, err
+		}
+
+		var unowned int
+		for _, testFile := range memberFiles {
+			if m.owner(testFile) == i {
+				discovered = append(discovered, testFile)
+			} else {
+				unowned++
+			}
+		}
+		if unowned > 0 {
+			slog.Info("Ignoring discovered test files owned by another framework or matching no framework test pattern",
+				"framework", fw.Name(), "testFilesCount", unowned)
+		}
+	}
+	if discovered == nil {
+		discovered = []string{}
+	}
+	return discovered, nil
+}
+
+// DiscoverTests discovers the tests of each framework in turn: frameworks
+// write their discovered tests to the same file.
+func (m *Multi) DiscoverTests(ctx context.Context, testFiles discovery.TestFileSet) ([]testoptimization.Test, error) {
+	var tests []testoptimization.Test
+	for i, fw := range m.frameworks {
+		memberTests, err := fw.DiscoverTests(ctx, m.memberTestFiles(i, testFiles))
+		if err != nil {
+			return nil, err
+		}
+		for _, test := range memberTests {
+			if owner := m.owner(test.SuiteSourceFile); owner == i || owner == -1 {
+				tests = append(tests, test)
+			}
+		}
+	}
+	return tests, nil
+}
+
+// RunTests runs each test file with the framework that owns it, one framework
+// after the other in ownership order.
+func (m *Multi) RunTests(ctx context.Context, testFiles []string, envMap map[string]string) error {
+	groups := make([][]string, len(m.frameworks))
+	for _, testFile := range testFiles {
+		owner := m.owner(testFile)
+		if owner == -1 {
+			return fmt.Errorf("no framework of %s owns test file %s", m.Name(), testFile)
+		}
+		groups[owner] = append(groups[owner], testFile)
+	}
+
+	errs := make([]error, len(m.frameworks))
+	for i, fw := range m.frameworks {
+		if len(groups[i]) == 0 {
+			continue
+		}
+		errs[i] = fw.RunTests(ctx, groups[i], envMap)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return joinTestFilesFailed(groups, errs)
+}
+
+func (m *Multi) SetPlatformEnv(platformEnv map[string]string) {
+	for _, fw := range m.frameworks {
+		fw.SetPlatformEnv(platformEnv)
+	}
+}
+
+func (m *Multi) GetPlatformEnv() map[string]string {
+	return m.frameworks[0].GetPlatformEnv()
+}
+
+// SupportsFullTestDiscovery reports whether every framework supports full test
+// discovery, which the planner needs for all test files or none.
+func (m *Multi) SupportsFullTestDiscovery() bool {
+	for _, fw := range m.frameworks {
+		if !fw.SupportsFullTestDiscovery() {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Multi) SourceFileForSuite(suite string) (string, bool) {
+	for _, fw := range m.frameworks {
+		if sourceFile, ok := fw.SourceFileForSuite(suite); ok {
+			return sourceFile, true
+		}
+	}
+	return "", false
+}
+
+func (m *Multi) HasUnskippableMarker(testFile string) bool {
+	owner := m.owner(testFile)
+	return owner != -1 && m.frameworks[owner].HasUnskippableMarker(testFile)
+}
+
+// TestSelector implements TestChunkSelector with the framework that owns the
+// test's source file, when that framework can run test chunks.
+func (m *Multi) TestSelector(test testoptimization.Test) (string, bool) {
+	owner := m.owner(test.SuiteSourceFile)
+	if owner == -1 {
+		return "", false
+	}
+	selector, ok := m.frameworks[owner].(TestChunkSelector)
+	if !ok {
+		return "", false
+	}
+	return selector.TestSelector(test)
+}
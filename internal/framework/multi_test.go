@@ -0,0 +1,174 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/discovery"
+	"github.com/DataDog/ddtest/internal/testoptimization"
+)
+
+var _ TestChunkSelector = (*Multi)(nil)
+
+// multiMemberFramework is a framework that discovers fixed test files and
+// records the test files it runs.
+type multiMemberFramework struct {
+	name        string
+	pattern     string
+	testFiles   []string
+	tests       []testoptimization.Test
+	runErr      error
+	ran         [][]string
+	platformEnv map[string]string
+}
+
+func (f *multiMemberFramework) Name() string        { return f.name }
+func (f *multiMemberFramework) TestPattern() string { return f.pattern }
+
+func (f *multiMemberFramework) DiscoverTestFiles(ctx context.Context, testFiles discovery.TestFileSet) ([]string, error) {
+	if testFiles.UseExplicitFiles() {
+		return testFiles.ExplicitFiles, nil
+	}
+	return f.testFiles, nil
+}
+
+func (f *multiMemberFramework) DiscoverTests(ctx context.Context, testFiles discovery.TestFileSet) ([]testoptimization.Test, error) {
+	return f.tests, nil
+}
+
+func (f *multiMemberFramework) RunTests(ctx context.Context, testFiles []string, envMap map[string]string) error {
+	f.ran = append(f.ran, testFiles)
+	return f.runErr
+}
+
+func (f *multiMemberFramework) SetPlatformEnv(platformEnv map[string]string) {
+	f.platformEnv = platformEnv
+}
+func (f *multiMemberFramework) GetPlatformEnv() map[string]string { return f.platformEnv }
+func (f *multiMemberFramework) SupportsFullTestDiscovery() bool   { return f.tests != nil }
+func (f *multiMemberFramework) SourceFileForSuite(suite string) (string, bool) {
+	return "", false
+}
+func (f *multiMemberFramework) HasUnskippableMarker(testFile string) bool { return false }
+
+func newTestMulti(t *testing.T, members ...MultiMember) *Multi {
+	t.Helper()
+	multi, err := NewMulti(members)
+	if err != nil {
+		t.Fatalf("NewMulti() returned error: %v", err)
+	}
+	return multi
+}
+
+func TestMulti_OwnsTestFilesByFirstMatchingPattern(t *testing.T) {
+	playwright := &multiMemberFramework{name: "playwright", pattern: "**/*.spec.ts"}
+	jest := &multiMemberFramework{name: "jest", pattern: "**/*.{spec,test}.ts"}
+	multi := newTestMulti(t, MultiMember{Framework: playwright, TestPattern: "e2e/**/*.spec.ts"}, MultiMember{Framework: jest})
+
+	if multi.Name() != "playwright,jest" {
+		t.Errorf("Name() = %q", multi.Name())
+	}
+	if multi.TestPattern() != "{e2e/**/*.spec.ts,**/*.{spec,test}.ts}" {
+		t.Errorf("TestPattern() = %q", multi.TestPattern())
+	}
+	for testFile, owner := range map[string]int{
+		"e2e/login.spec.ts":       0,
+		"src/login.spec.ts":       1,
+		"src/login.test.ts":       1,
+		"src/login.test.ts[1:1]":  1,
+		"src/login.stories.ts":    -1,
+		"e2e/checkout.spec.ts[3]": 0,
+	} {
+		if got := multi.owner(testFile); got != owner {
+			t.Errorf("owner(%q) = %d, want %d", testFile, got, owner)
+		}
+	}
+}
+
+func TestMulti_DiscoverTestFiles(t *testing.T) {
+	rspec := &multiMemberFramework{name: "rspec", pattern: "spec/**/*_spec.rb", testFiles: []string{"spec/a_spec.rb", "test/shared_test.rb"}}
+	minitest := &multiMemberFramework{name: "minitest", pattern: "test/**/*_test.rb", testFiles: []string{"test/b_test.rb"}}
+	multi := newTestMulti(t, MultiMember{Framework: rspec}, MultiMember{Framework: minitest})
+
+	testFiles, err := multi.DiscoverTestFiles(context.Background(), discovery.TestFileSet{Pattern: multi.TestPattern()})
+	if err != nil {
+		t.Fatalf("DiscoverTestFiles() returned error: %v", err)
+	}
+	if !slices.Equal(testFiles, []string{"spec/a_spec.rb", "test/b_test.rb"}) {
+		t.Errorf("expected each framework to keep only the test files it owns, got %v", testFiles)
+	}
+
+	testFiles, err = multi.DiscoverTestFiles(context.Background(), discovery.TestFileSet{ExplicitFiles: []string{"test/c_test.rb", "spec/d_spec.rb"}})
+	if err != nil {
+		t.Fatalf("DiscoverTestFiles() returned error: %v", err)
+	}
+	if !slices.Equal(testFiles, []string{"spec/d_spec.rb", "test/c_test.rb"}) {
+		t.Errorf("expected explicit test files to be split between frameworks, got %v", testFiles)
+	}
+}
+
+func TestMulti_DiscoverTests(t *testing.T) {
+	rspec := &multiMemberFramework{name: "rspec", pattern: "spec/**/*_spec.rb", tests: []testoptimization.Test{
+		{Suite: "A", Name: "a", SuiteSourceFile: "spec/a_spec.rb"},
+	}}
+	minitest := &multiMemberFramework{name: "minitest", pattern: "test/**/*_test.rb", tests: []testoptimization.Test{
+		{Suite: "B", Name: "b", SuiteSourceFile: "test/b_test.rb"},
+	}}
+	multi := newTestMulti(t, MultiMember{Framework: rspec}, MultiMember{Framework: minitest})
+
+	if !multi.SupportsFullTestDiscovery() {
+		t.Fatal("expected full test discovery when every framework supports it")
+	}
+	tests, err := multi.DiscoverTests(context.Background(), discovery.TestFileSet{Pattern: multi.TestPattern()})
+	if err != nil || len(tests) != 2 || tests[0].Suite != "A" || tests[1].Suite != "B" {
+		t.Fatalf("expected the tests of both frameworks, got %v, %v", tests, err)
+	}
+
+	minitest.tests = nil
+	if multi.SupportsFullTestDiscovery() {
+		t.Error("expected no full test discovery when a framework does not support it")
+	}
+}
+
+func TestMulti_RunTestsDispatchesEachTestFileToItsFramework(t *testing.T) {
+	rspec := &multiMemberFramework{name: "rspec", pattern: "spec/**/*_spec.rb"}
+	minitest := &multiMemberFramework{name: "minitest", pattern: "test/**/*_test.rb"}
+	multi := newTestMulti(t, MultiMember{Framework: rspec}, MultiMember{Framework: minitest})
+
+	err := multi.RunTests(context.Background(), []string{"test/b_test.rb", "spec/a_spec.rb", "spec/c_spec.rb[1:1]"}, nil)
+	if err != nil {
+		t.Fatalf("RunTests() returned error: %v", err)
+	}
+	if len(rspec.ran) != 1 || !slices.Equal(rspec.ran[0], []string{"spec/a_spec.rb", "spec/c_spec.rb[1:1]"}) {
+		t.Errorf("unexpected RSpec runs %v", rspec.ran)
+	}
+	if len(minitest.ran) != 1 || !slices.Equal(minitest.ran[0], []string{"test/b_test.rb"}) {
+		t.Errorf("unexpected Minitest runs %v", minitest.ran)
+	}
+
+	if err := multi.RunTests(context.Background(), []string{"features/a.feature"}, nil); err == nil {
+		t.Error("expected a test file owned by no framework to be an error")
+	}
+}
+
+func TestMulti_RunTestsMergesFailedTestFiles(t *testing.T) {
+	rspec := &multiMemberFramework{name: "rspec", pattern: "spec/**/*_spec.rb",
+		runErr: &TestFilesFailedError{TestFiles: []string{"spec/a_spec.rb"}, Err: errors.New("rspec failed")}}
+	minitest := &multiMemberFramework{name: "minitest", pattern: "test/**/*_test.rb",
+		runErr: &TestFilesFailedError{TestFiles: []string{"test/b_test.rb"}, Err: errors.New("minitest failed")}}
+	multi := newTestMulti(t, MultiMember{Framework: rspec}, MultiMember{Framework: minitest})
+
+	err := multi.RunTests(context.Background(), []string{"spec/a_spec.rb", "test/b_test.rb"}, nil)
+	var filesErr *TestFilesFailedError
+	if !errors.As(err, &filesErr) || !slices.Equal(filesErr.TestFiles, []string{"spec/a_spec.rb", "test/b_test.rb"}) {
+		t.Fatalf("expected the failed test files of both frameworks, got %v", err)
+	}
+
+	minitest.runErr = errors.New("minitest crashed")
+	err = multi.RunTests(context.Background(), []string{"spec/a_spec.rb", "test/b_test.rb", "test/c_test.rb"}, nil)
+	if !errors.As(err, &filesErr) || !slices.Equal(filesErr.TestFiles, []string{"spec/a_spec.rb", "test/b_test.rb", "test/c_test.rb"}) {
+		t.Fatalf("expected every test file of a framework that cannot tell which failed, got %v", err)
+	}
+}
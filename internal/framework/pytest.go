@@ -2,8 +2,12 @@ package framework
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"maps"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/DataDog/ddtest/internal/discovery"
@@ -125,14 +129,73 @@ func (p *PyTest) HasUnskippableMarker(testFile string) bool {
 	return false
 }
 
+// TestSelector returns the node id of test within its test file, such as
+// TestUser::test_is_valid. Parametrized tests are selected with every
+// parameter set.
+func (p *PyTest) TestSelector(test testoptimization.Test) (string, bool) {
+	name, _, _ := strings.Cut(test.Name, "[")
+	if !strings.Contains(name, "::") && test.Suite != "" && !strings.HasSuffix(test.Suite, ".py") {
+		name = test.Suite + "::" + name
+	}
+	if !validTestSelector(name) {
+		return "", false
+	}
+	return name, true
+}
+
 func (p *PyTest) RunTests(ctx context.Context, testFiles []string, envMap map[string]string) error {
 	command := "python"
 	args := []string{"-m", "pytest"}
+	cacheDir := ""
+	if reportFailedTestFiles() {
+		// pytest records the node ids of failed tests in the lastfailed entry
+		// of its cache. A cache of its own keeps the entry to this run.
+		var err error
+		cacheDir, err = os.MkdirTemp("", "ddtest-pytest-cache-*")
+		if err != nil {
+			return fmt.Errorf("failed to create pytest cache directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(cacheDir) }()
+		args = append(args, "-o", "cache_dir="+cacheDir)
+	}
 	slog.Info("Running tests with command", "command", command, "args", args)
-	args = append(args, testFiles...)
+	plainFiles, chunkFiles, selectors := groupTestChunks(testFiles)
+	args = append(args, plainFiles...)
+	for _, testFile := range chunkFiles {
+		for _, selector := range selectors[testFile] {
+			args = append(args, testFile+"::"+selector)
+		}
+	}
 
 	mergedEnv := make(map[string]string)
 	maps.Copy(mergedEnv, p.platformEnv)
 	maps.Copy(mergedEnv, envMap)
-	return p.executor.Run(ctx, command, args, mergedEnv)
+	err := p.executor.Run(ctx, command, args, mergedEnv)
+	if err != nil && cacheDir != "" {
+		return reportedTestFilesFailed(err, testFiles, pytestFailedFiles(cacheDir))
+	}
+	return err
+}
+
+// pytestFailedFiles returns the test files of the node ids in the lastfailed
+// entry of the pytest cache at cacheDir. Node ids are relative to the pytest
+// root directory, so they only match the test files of the run when it is the
+// working directory.
+func pytestFailedFiles(cacheDir string) []string {
+	data, err := os.ReadFile(filepath.Join(cacheDir, "v", "cache", "lastfailed"))
+	if err != nil {
+		return nil
+	}
+	var lastFailed map[string]bool
+	if err := json.Unmarshal(data, &lastFailed); err != nil {
+		slog.Debug("Failed to parse pytest lastfailed cache", "cacheDir", cacheDir, "error", err)
+		return nil
+	}
+
+	failedFiles := make([]string, 0, len(lastFailed))
+	for nodeID := range lastFailed {
+		testFile, _, _ := strings.Cut(nodeID, "::")
+		failedFiles = append(failedFiles, testFile)
+	}
+	return failedFiles
 }
@@ -3,9 +3,11 @@ package framework
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"testing"
 
 	"github.com/DataDog/ddtest/internal/discovery"
@@ -248,3 +250,115 @@ func TestPyTest_RunTests(t *testing.T) {
 		t.Errorf("expected run env to override platform env, got %q", mockExecutor.capturedEnvMap["SHARED_VAR"])
 	}
 }
+
+func TestPyTest_RunTests_WithTestChunks(t *testing.T) {
+	testFiles := []string{"tests/test_user.py", "tests/test_slow.py[TestSlow::test_a,test_b]", "tests/test_slow.py[test_c]"}
+
+	var capturedArgs []string
+	mockExecutor := &mockCommandExecutor{
+		onExecution: func(name string, args []string) {
+			capturedArgs = args
+		},
+	}
+
+	pytest := &PyTest{executor: mockExecutor}
+	if err := pytest.RunTests(context.Background(), testFiles, nil); err != nil {
+		t.Fatalf("RunTests failed: %v", err)
+	}
+
+	expectedArgs := []string{"-m", "pytest", "tests/test_user.py", "tests/test_slow.py::TestSlow::test_a", "tests/test_slow.py::test_b", "tests/test_slow.py::test_c"}
+	if !slices.Equal(capturedArgs, expectedArgs) {
+		t.Fatalf("expected args %v, got %v", expectedArgs, capturedArgs)
+	}
+}
+
+func TestPyTest_RunTests_WithRetryFailedFiles_UsesCacheDirOfItsOwn(t *testing.T) {
+	setRetryFailedFiles(t)
+
+	var capturedArgs []string
+	mockExecutor := &mockCommandExecutor{
+		onExecution: func(name string, args []string) {
+			capturedArgs = args
+		},
+	}
+
+	pytest := &PyTest{executor: mockExecutor}
+	if err := pytest.RunTests(context.Background(), []string{"tests/test_user.py"}, nil); err != nil {
+		t.Fatalf("RunTests failed: %v", err)
+	}
+
+	cacheDir := pytestCacheDir(t, capturedArgs)
+	expectedArgs := []string{"-m", "pytest", "-o", "cache_dir=" + cacheDir, "tests/test_user.py"}
+	if !slices.Equal(capturedArgs, expectedArgs) {
+		t.Fatalf("expected args %v, got %v", expectedArgs, capturedArgs)
+	}
+	if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+		t.Errorf("expected the cache directory to be removed after the run, got %v", err)
+	}
+}
+
+func TestPyTest_RunTests_ReportsFailedTestFiles(t *testing.T) {
+	setRetryFailedFiles(t)
+	testFiles := []string{"tests/test_user.py", "tests/test_auth.py", "tests/test_slow.py[test_a]"}
+
+	mockExecutor := &mockCommandExecutor{
+		err: errors.New("exit status 1"),
+		onExecution: func(name string, args []string) {
+			writePytestLastFailed(t, args, `{"tests/test_auth.py::test_login[admin]": true, "tests/test_slow.py::test_a": true}`)
+		},
+	}
+
+	pytest := &PyTest{executor: mockExecutor}
+	err := pytest.RunTests(context.Background(), testFiles, nil)
+
+	var filesErr *TestFilesFailedError
+	if !errors.As(err, &filesErr) {
+		t.Fatalf("expected TestFilesFailedError, got %v", err)
+	}
+	expected := []string{"tests/test_auth.py", "tests/test_slow.py[test_a]"}
+	if !slices.Equal(filesErr.TestFiles, expected) {
+		t.Errorf("expected failed test files %v, got %v", expected, filesErr.TestFiles)
+	}
+}
+
+func TestPyTest_RunTests_FailureOutsideBatchFailsWholeBatch(t *testing.T) {
+	setRetryFailedFiles(t)
+	mockExecutor := &mockCommandExecutor{
+		err: errors.New("exit status 1"),
+		onExecution: func(name string, args []string) {
+			// Node ids are relative to the pytest root directory, which is not
+			// the working directory here.
+			writePytestLastFailed(t, args, `{"app/tests/test_user.py::test_name": true}`)
+		},
+	}
+
+	pytest := &PyTest{executor: mockExecutor}
+	err := pytest.RunTests(context.Background(), []string{"tests/test_user.py", "tests/test_auth.py"}, nil)
+
+	var filesErr *TestFilesFailedError
+	if err == nil || errors.As(err, &filesErr) {
+		t.Fatalf("expected a plain error, got %v", err)
+	}
+}
+
+func pytestCacheDir(t *testing.T, args []string) string {
+	t.Helper()
+	for i, arg := range args {
+		if arg == "-o" && i+1 < len(args) && strings.HasPrefix(args[i+1], "cache_dir=") {
+			return strings.TrimPrefix(args[i+1], "cache_dir=")
+		}
+	}
+	t.Fatalf("expected a cache_dir option in %v", args)
+	return ""
+}
+
+func writePytestLastFailed(t *testing.T, args []string, lastFailed string) {
+	t.Helper()
+	cacheDir := filepath.Join(pytestCacheDir(t, args), "v", "cache")
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		t.Fatalf("failed to create pytest cache: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(cacheDir, "lastfailed"), []byte(lastFailed), 0644); err != nil {
+		t.Fatalf("failed to write lastfailed: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package framework
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
 	"maps"
 	"os"
@@ -88,13 +89,74 @@ func (r *RSpec) DiscoverTestFiles(ctx context.Context, testFiles discovery.TestF
 func (r *RSpec) RunTests(ctx context.Context, testFiles []string, envMap map[string]string) error {
 	command, baseArgs := r.getRSpecCommand()
 	args := append(baseArgs, "--format", "progress")
+	reportPath := ""
+	if reportFailedTestFiles() {
+		var err error
+		reportPath, err = createFailureReport("ddtest-rspec-report-*.json")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = os.Remove(reportPath) }()
+		args = append(args, "--format", "json", "--out", reportPath)
+	}
 	slog.Info("Running tests with command", "command", command, "args", args)
 	args = append(args, testFiles...)
 
 	mergedEnv := make(map[string]string)
 	maps.Copy(mergedEnv, r.GetPlatformEnv())
 	maps.Copy(mergedEnv, envMap)
-	return r.executor.Run(ctx, command, args, mergedEnv)
+	err := r.executor.Run(ctx, command, args, mergedEnv)
+	if err != nil && reportPath != "" {
+		return reportedTestFilesFailed(err, testFiles, rspecFailedFiles(reportPath))
+	}
+	return err
+}
+
+// rspecReport is the part of the RSpec JSON formatter output that tells which
+// examples failed.
+type rspecReport struct {
+	Examples []struct {
+		ID       string `json:"id"`
+		FilePath string `json:"file_path"`
+		Status   string `json:"status"`
+	} `json:"examples"`
+	Summary struct {
+		ErrorsOutsideOfExamplesCount int `json:"errors_outside_of_examples_count"`
+	} `json:"summary"`
+}
+
+// rspecFailedFiles returns the spec files of the failed examples in the JSON
+// report at path. It returns nil when the report is missing or when errors
+// outside of examples, such as a spec file that fails to load, failed the
+// run, since those name no file.
+func rspecFailedFiles(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	var report rspecReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		slog.Debug("Failed to parse RSpec JSON report", "path", path, "error", err)
+		return nil
+	}
+	if report.Summary.ErrorsOutsideOfExamplesCount > 0 {
+		return nil
+	}
+
+	var failedFiles []string
+	for _, example := range report.Examples {
+		if example.Status != "failed" {
+			continue
+		}
+		// The example id names the spec file that ran the example, even when
+		// a shared example group defined it in another file.
+		testFile, _, ok := ParseTestChunk(example.ID)
+		if !ok {
+			testFile = example.FilePath
+		}
+		failedFiles = append(failedFiles, testFile)
+	}
+	return failedFiles
 }
 
 // getRSpecCommand determines whether to use bin/rspec or bundle exec rspec
@@ -120,6 +182,16 @@ func (r *RSpec) SupportsFullTestDiscovery() bool {
 	return true
 }
 
+// TestSelector returns the example id of test, such as 1:2:1. RSpec runs test
+// chunks natively.
+func (r *RSpec) TestSelector(test testoptimization.Test) (string, bool) {
+	scopedID, ok := rspecScopedID(test.Parameters)
+	if !ok || !validTestSelector(scopedID) {
+		return "", false
+	}
+	return scopedID, true
+}
+
 func (r *RSpec) SourceFileForSuite(suite string) (string, bool) {
 	return trailingRubySuiteSourceFile(suite)
 }
@@ -3,6 +3,7 @@ package framework
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -515,6 +516,124 @@ func TestRSpec_RunTests(t *testing.T) {
 	}
 }
 
+func TestRSpec_RunTests_WithoutRetryFailedFiles_WritesNoJSONReport(t *testing.T) {
+	_ = os.RemoveAll("bin")
+
+	var capturedArgs []string
+	mockExecutor := &mockCommandExecutor{
+		onExecution: func(name string, args []string) {
+			capturedArgs = args
+		},
+	}
+
+	rspec := newTestRSpecWithExecutor(mockExecutor)
+	if err := rspec.RunTests(context.Background(), []string{"spec/models/user_spec.rb"}, nil); err != nil {
+		t.Fatalf("RunTests failed: %v", err)
+	}
+
+	expectedArgs := []string{"exec", "rspec", "--format", "progress", "spec/models/user_spec.rb"}
+	if !slices.Equal(capturedArgs, expectedArgs) {
+		t.Fatalf("expected args %v, got %v", expectedArgs, capturedArgs)
+	}
+}
+
+func TestRSpec_RunTests_WithRetryFailedFiles_WritesJSONReport(t *testing.T) {
+	setRetryFailedFiles(t)
+	_ = os.RemoveAll("bin")
+
+	var capturedArgs []string
+	mockExecutor := &mockCommandExecutor{
+		onExecution: func(name string, args []string) {
+			capturedArgs = args
+		},
+	}
+
+	rspec := newTestRSpecWithExecutor(mockExecutor)
+	if err := rspec.RunTests(context.Background(), []string{"spec/models/user_spec.rb"}, nil); err != nil {
+		t.Fatalf("RunTests failed: %v", err)
+	}
+
+	if len(capturedArgs) != 9 {
+		t.Fatalf("expected a JSON formatter writing to a report file, got %v", capturedArgs)
+	}
+	expectedArgs := []string{"exec", "rspec", "--format", "progress", "--format", "json", "--out", capturedArgs[7], "spec/models/user_spec.rb"}
+	if !slices.Equal(capturedArgs, expectedArgs) {
+		t.Fatalf("expected args %v, got %v", expectedArgs, capturedArgs)
+	}
+}
+
+func TestRSpec_RunTests_ReportsFailedTestFiles(t *testing.T) {
+	setRetryFailedFiles(t)
+	_ = os.RemoveAll("bin")
+
+	testFiles := []string{"spec/models/user_spec.rb", "spec/models/order_spec.rb", "spec/system/checkout_spec.rb[1:2]"}
+	report := `{
+		"examples": [
+			{"id": "./spec/models/user_spec.rb[1:1]", "file_path": "./spec/models/user_spec.rb", "status": "passed"},
+			{"id": "./spec/models/order_spec.rb[1:1]", "file_path": "./spec/support/shared_examples.rb", "status": "failed"},
+			{"id": "./spec/system/checkout_spec.rb[1:2]", "file_path": "./spec/system/checkout_spec.rb", "status": "failed"}
+		],
+		"summary": {"failure_count": 2, "errors_outside_of_examples_count": 0}
+	}`
+	mockExecutor := &mockCommandExecutor{
+		err: errors.New("exit status 1"),
+		onExecution: func(name string, args []string) {
+			writeRSpecReport(t, args, report)
+		},
+	}
+
+	rspec := newTestRSpecWithExecutor(mockExecutor)
+	err := rspec.RunTests(context.Background(), testFiles, nil)
+
+	var filesErr *TestFilesFailedError
+	if !errors.As(err, &filesErr) {
+		t.Fatalf("expected TestFilesFailedError, got %v", err)
+	}
+	expected := []string{"spec/models/order_spec.rb", "spec/system/checkout_spec.rb[1:2]"}
+	if !slices.Equal(filesErr.TestFiles, expected) {
+		t.Errorf("expected failed test files %v, got %v", expected, filesErr.TestFiles)
+	}
+}
+
+func TestRSpec_RunTests_ErrorsOutsideOfExamplesFailWholeBatch(t *testing.T) {
+	setRetryFailedFiles(t)
+	_ = os.RemoveAll("bin")
+
+	report := `{
+		"examples": [
+			{"id": "./spec/models/user_spec.rb[1:1]", "file_path": "./spec/models/user_spec.rb", "status": "failed"}
+		],
+		"summary": {"failure_count": 1, "errors_outside_of_examples_count": 1}
+	}`
+	mockExecutor := &mockCommandExecutor{
+		err: errors.New("exit status 1"),
+		onExecution: func(name string, args []string) {
+			writeRSpecReport(t, args, report)
+		},
+	}
+
+	rspec := newTestRSpecWithExecutor(mockExecutor)
+	err := rspec.RunTests(context.Background(), []string{"spec/models/user_spec.rb", "spec/models/order_spec.rb"}, nil)
+
+	var filesErr *TestFilesFailedError
+	if err == nil || errors.As(err, &filesErr) {
+		t.Fatalf("expected a plain error, got %v", err)
+	}
+}
+
+// writeRSpecReport writes report to the file that args tell the JSON
+// formatter to write to.
+func writeRSpecReport(t *testing.T, args []string, report string) {
+	t.Helper()
+	index := slices.Index(args, "--out")
+	if index == -1 || index+1 == len(args) {
+		t.Fatalf("expected an --out argument in %v", args)
+	}
+	if err := os.WriteFile(args[index+1], []byte(report), 0644); err != nil {
+		t.Fatalf("failed to write RSpec report: %v", err)
+	}
+}
+
 func TestRSpec_RunTests_WithOverride(t *testing.T) {
 	testFiles := []string{"spec/models/user_spec.rb"}
 
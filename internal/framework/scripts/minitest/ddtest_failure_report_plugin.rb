@@ -0,0 +1,38 @@
+# frozen_string_literal: true
+
+# Minitest loads this plugin from the load path that ddtest adds to RUBYOPT.
+# It writes the source files of failed tests to the JSON file named by
+# DDTEST_MINITEST_FAILURE_REPORT so that ddtest can retry only those files. A
+# failed test without a source location is written as null, which makes ddtest
+# retry the whole batch.
+require "json"
+
+module Minitest
+  module DDTestFailureReport
+    @failed_files = []
+
+    class << self
+      attr_reader :failed_files
+    end
+
+    # Reporters like minitest-reporters replace the reporters of the composite
+    # reporter, so failures are recorded on the composite reporter itself.
+    def record(result)
+      unless result.passed? || result.skipped?
+        file, = result.source_location if result.respond_to?(:source_location)
+        DDTestFailureReport.failed_files << file
+      end
+      super
+    end
+  end
+
+  def self.plugin_ddtest_failure_report_init(_options)
+    path = ENV["DDTEST_MINITEST_FAILURE_REPORT"]
+    return if path.nil? || path.empty?
+
+    CompositeReporter.prepend(DDTestFailureReport)
+    after_run do
+      File.write(path, JSON.generate(DDTestFailureReport.failed_files.uniq))
+    end
+  end
+end
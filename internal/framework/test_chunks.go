@@ -0,0 +1,124 @@
+package framework
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/DataDog/ddtest/internal/testoptimization"
+)
+
+// TestChunkSelector is implemented by frameworks that can run some of the
+// tests of a test file. Their RunTests accepts test chunks, formatted by
+// FormatTestChunk, in place of test files.
+type TestChunkSelector interface {
+	// TestSelector returns how test is selected within its test file, or false
+	// when it cannot be selected on its own.
+	TestSelector(test testoptimization.Test) (string, bool)
+}
+
+// FormatTestChunk returns the test chunk that runs the tests of testFile with
+// the given selectors, such as spec/models/user_spec.rb[1:1,1:2]. The syntax
+// is the one RSpec uses for example ids.
+func FormatTestChunk(testFile string, selectors []string) string {
+	return testFile + "[" + strings.Join(selectors, ",") + "]"
+}
+
+// ParseTestChunk returns the test file and selectors of a test chunk, or false
+// when entry is a plain test file.
+func ParseTestChunk(entry string) (string, []string, bool) {
+	if !strings.HasSuffix(entry, "]") {
+		return "", nil, false
+	}
+	open := strings.LastIndex(entry, "[")
+	if open <= 0 || open == len(entry)-2 {
+		return "", nil, false
+	}
+	return entry[:open], strings.Split(entry[open+1:len(entry)-1], ","), true
+}
+
+// TestChunkFile returns the test file that a runner split entry, either a
+// test file or a test chunk, belongs to.
+func TestChunkFile(entry string) string {
+	if testFile, _, ok := ParseTestChunk(entry); ok {
+		return testFile
+	}
+	return entry
+}
+
+// validTestSelector reports whether selector can be written into a test
+// chunk and passed to a test command unchanged.
+func validTestSelector(selector string) bool {
+	return selector != "" && !strings.ContainsAny(selector, "[],'\"\\ \t\r\n")
+}
+
+// groupTestChunks returns the plain test files of entries, in order, and the
+// selectors of their test chunks, by test file and in order of first use.
+func groupTestChunks(entries []string) ([]string, []string, map[string][]string) {
+	testFiles := make([]string, 0, len(entries))
+	chunkFiles := make([]string, 0)
+	selectors := make(map[string][]string)
+	for _, entry := range entries {
+		testFile, chunkSelectors, ok := ParseTestChunk(entry)
+		if !ok {
+			testFiles = append(testFiles, entry)
+			continue
+		}
+		if _, seen := selectors[testFile]; !seen {
+			chunkFiles = append(chunkFiles, testFile)
+		}
+		for _, selector := range chunkSelectors {
+			if !slices.Contains(selectors[testFile], selector) {
+				selectors[testFile] = append(selectors[testFile], selector)
+			}
+		}
+	}
+	return testFiles, chunkFiles, selectors
+}
+
+// rspecScopedID returns the RSpec example id that the Datadog library stores
+// in the test parameters.
+func rspecScopedID(parameters string) (string, bool) {
+	var decoded struct {
+		ScopedID string `json:"scoped_id"`
+		Metadata struct {
+			ScopedID string `json:"scoped_id"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal([]byte(parameters), &decoded); err != nil {
+		return "", false
+	}
+	if decoded.Metadata.ScopedID != "" {
+		return decoded.Metadata.ScopedID, true
+	}
+	return decoded.ScopedID, decoded.ScopedID != ""
+}
+
+// minitestChunkFilter returns the -n filter that runs only the selected tests
+// of the chunked test files. Minitest matches the filter against both the test
+// method name and "TestClass#test_method", so every alternative requires the
+// class. The filter skips every test of other classes, so the chunked test
+// files run without whole test files.
+func minitestChunkFilter(chunkFiles []string, selectors map[string][]string) string {
+	methodsByClass := make(map[string][]string)
+	classes := make([]string, 0)
+	for _, testFile := range chunkFiles {
+		for _, selector := range selectors[testFile] {
+			class, method, ok := strings.Cut(selector, "#")
+			if !ok {
+				continue
+			}
+			if _, seen := methodsByClass[class]; !seen {
+				classes = append(classes, class)
+			}
+			methodsByClass[class] = append(methodsByClass[class], regexp.QuoteMeta(method))
+		}
+	}
+
+	selected := make([]string, 0, len(classes))
+	for _, class := range classes {
+		selected = append(selected, regexp.QuoteMeta(class)+"#(?:"+strings.Join(methodsByClass[class], "|")+")")
+	}
+	return "/^(?:" + strings.Join(selected, "|") + ")$/"
+}
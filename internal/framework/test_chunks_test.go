@@ -0,0 +1,150 @@
+package framework
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/testoptimization"
+)
+
+var (
+	_ TestChunkSelector = (*RSpec)(nil)
+	_ TestChunkSelector = (*Minitest)(nil)
+	_ TestChunkSelector = (*PyTest)(nil)
+)
+
+func TestParseTestChunk(t *testing.T) {
+	tests := []struct {
+		entry             string
+		expectedFile      string
+		expectedSelectors []string
+		expectedOK        bool
+	}{
+		{entry: "spec/models/user_spec.rb[1:1,1:2]", expectedFile: "spec/models/user_spec.rb", expectedSelectors: []string{"1:1", "1:2"}, expectedOK: true},
+		{entry: "test/[slug]/page_test.rb[PageTest#test_show]", expectedFile: "test/[slug]/page_test.rb", expectedSelectors: []string{"PageTest#test_show"}, expectedOK: true},
+		{entry: "spec/models/user_spec.rb", expectedOK: false},
+		{entry: "pages/[id].test.js", expectedOK: false},
+		{entry: "spec/models/user_spec.rb[]", expectedOK: false},
+		{entry: "[1:1]", expectedOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			testFile, selectors, ok := ParseTestChunk(tt.entry)
+			if ok != tt.expectedOK || testFile != tt.expectedFile || !slices.Equal(selectors, tt.expectedSelectors) {
+				t.Fatalf("ParseTestChunk(%q) = %q, %v, %t; expected %q, %v, %t", tt.entry, testFile, selectors, ok, tt.expectedFile, tt.expectedSelectors, tt.expectedOK)
+			}
+
+			expectedTestFile := tt.entry
+			if tt.expectedOK {
+				expectedTestFile = tt.expectedFile
+				if formatted := FormatTestChunk(testFile, selectors); formatted != tt.entry {
+					t.Errorf("FormatTestChunk() = %q, expected %q", formatted, tt.entry)
+				}
+			}
+			if testFile := TestChunkFile(tt.entry); testFile != expectedTestFile {
+				t.Errorf("TestChunkFile(%q) = %q, expected %q", tt.entry, testFile, expectedTestFile)
+			}
+		})
+	}
+}
+
+func TestTestSelector(t *testing.T) {
+	tests := []struct {
+		name             string
+		framework        TestChunkSelector
+		test             testoptimization.Test
+		expectedSelector string
+		expectedOK       bool
+	}{
+		{
+			name:             "rspec example id",
+			framework:        &RSpec{},
+			test:             testoptimization.Test{Parameters: `{"arguments":{},"metadata":{"scoped_id":"1:2:1"}}`},
+			expectedSelector: "1:2:1",
+			expectedOK:       true,
+		},
+		{
+			name:       "rspec without example id",
+			framework:  &RSpec{},
+			test:       testoptimization.Test{Parameters: "{}"},
+			expectedOK: false,
+		},
+		{
+			name:             "minitest class and method",
+			framework:        &Minitest{},
+			test:             testoptimization.Test{Suite: "Admin::UserTest at test/models/admin/user_test.rb", Name: "test_validation?"},
+			expectedSelector: "Admin::UserTest#test_validation?",
+			expectedOK:       true,
+		},
+		{
+			name:       "minitest name with a comma",
+			framework:  &Minitest{},
+			test:       testoptimization.Test{Suite: "UserTest", Name: "test_name,_email"},
+			expectedOK: false,
+		},
+		{
+			name:             "pytest class test",
+			framework:        &PyTest{},
+			test:             testoptimization.Test{Suite: "TestUser", Name: "test_is_valid"},
+			expectedSelector: "TestUser::test_is_valid",
+			expectedOK:       true,
+		},
+		{
+			name:             "pytest module test with parameters",
+			framework:        &PyTest{},
+			test:             testoptimization.Test{Suite: "test_user.py", Name: "test_login[admin]"},
+			expectedSelector: "test_login",
+			expectedOK:       true,
+		},
+		{
+			name:             "pytest name with class",
+			framework:        &PyTest{},
+			test:             testoptimization.Test{Suite: "test_user.py", Name: "TestUser::test_is_valid"},
+			expectedSelector: "TestUser::test_is_valid",
+			expectedOK:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, ok := tt.framework.TestSelector(tt.test)
+			if selector != tt.expectedSelector || ok != tt.expectedOK {
+				t.Fatalf("TestSelector() = %q, %t; expected %q, %t", selector, ok, tt.expectedSelector, tt.expectedOK)
+			}
+		})
+	}
+}
+
+func TestMinitestChunkFilter(t *testing.T) {
+	chunkFiles := []string{"test/models/user_test.rb"}
+	selectors := map[string][]string{
+		"test/models/user_test.rb": {"UserTest#test_valid?", "UserTest#test_name", "Admin::UserTest#test_role"},
+	}
+
+	filter := minitestChunkFilter(chunkFiles, selectors)
+	expected := `/^(?:UserTest#(?:test_valid\?|test_name)|Admin::UserTest#(?:test_role))$/`
+	if filter != expected {
+		t.Fatalf("minitestChunkFilter() = %q, expected %q", filter, expected)
+	}
+}
+
+func TestMinitestChunkFilter_TestFileWithTwoClasses(t *testing.T) {
+	// test/models/order_test.rb defines OrderTest and Order::LineTest, split
+	// into one chunk per class. Each chunk runs only the tests of its class.
+	testFile := "test/models/order_test.rb"
+	chunks := [][]string{{"OrderTest#test_total"}, {"Order::LineTest#test_price"}}
+	tests := []string{"OrderTest#test_total", "Order::LineTest#test_price"}
+
+	for i, chunk := range chunks {
+		filter := minitestChunkFilter([]string{testFile}, map[string][]string{testFile: chunk})
+		pattern := regexp.MustCompile(strings.Trim(filter, "/"))
+		for j, test := range tests {
+			if got := pattern.MatchString(test); got != (i == j) {
+				t.Errorf("filter %s of chunk %d matches %s: %v, want %v", filter, i, test, got, i == j)
+			}
+		}
+	}
+}
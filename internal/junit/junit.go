@@ -0,0 +1,135 @@
+// Package junit reads per-file test durations from JUnit XML reports.
+package junit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/utils"
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// testSuite covers both the <testsuites> root and <testsuite> elements, which
+// can nest. Reporters disagree on where they put the source file: RSpec and
+// pytest set "file" on each test case, minitest-reporters sets "filepath" on
+// the suite, and jest-junit sets "file" on the suite.
+type testSuite struct {
+	File      string      `xml:"file,attr"`
+	FilePath  string      `xml:"filepath,attr"`
+	Time      string      `xml:"time,attr"`
+	Suites    []testSuite `xml:"testsuite"`
+	TestCases []testCase  `xml:"testcase"`
+}
+
+type testCase struct {
+	File string `xml:"file,attr"`
+	Time string `xml:"time,attr"`
+}
+
+// ParseTestFileDurations returns the time spent in each test file of a JUnit
+// XML report. A suite that names its source file counts with its own time,
+// which includes setup and teardown hooks; otherwise the times of test cases
+// that name their file are added up. Test cases without a file are ignored.
+func ParseTestFileDurations(r io.Reader) (map[string]time.Duration, error) {
+	var root testSuite
+	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+		return nil, err
+	}
+
+	durations := make(map[string]time.Duration)
+	addSuiteDurations(durations, root, "")
+	return durations, nil
+}
+
+func addSuiteDurations(durations map[string]time.Duration, suite testSuite, parentFile string) {
+	suiteFile := firstNonEmpty(suite.File, suite.FilePath)
+	if suiteFile != "" {
+		if duration, ok := parseSeconds(suite.Time); ok && duration > 0 {
+			durations[utils.NormalizeReportedPath(suiteFile)] += duration
+			return
+		}
+	}
+	suiteFile = firstNonEmpty(suiteFile, parentFile)
+
+	for _, child := range suite.Suites {
+		addSuiteDurations(durations, child, suiteFile)
+	}
+	for _, tc := range suite.TestCases {
+		file := firstNonEmpty(tc.File, suiteFile)
+		if file == "" {
+			continue
+		}
+		if duration, ok := parseSeconds(tc.Time); ok {
+			durations[utils.NormalizeReportedPath(file)] += duration
+		}
+	}
+}
+
+// ReadTestFileDurations parses every JUnit XML report matching pattern that
+// was written at or after since, and adds up the durations of each test file
+// across those reports. Older reports are left over from earlier runs and are
+// skipped. It returns the read report paths along with the durations.
+func ReadTestFileDurations(pattern string, since time.Time) (map[string]time.Duration, []string, error) {
+	matches, err := doublestar.FilepathGlob(pattern)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid JUnit reports pattern %q: %w", pattern, err)
+	}
+
+	// Some file systems store modification times with a precision of one
+	// second, so a report written right after since can look older.
+	since = since.Truncate(time.Second)
+	durations := make(map[string]time.Duration)
+	reports := make([]string, 0, len(matches))
+	for _, report := range matches {
+		info, err := os.Stat(report)
+		if err != nil {
+			return nil, reports, fmt.Errorf("failed to read JUnit report %s: %w", report, err)
+		}
+		if info.ModTime().Before(since) {
+			continue
+		}
+		reports = append(reports, report)
+
+		reportDurations, err := readReport(report)
+		if err != nil {
+			return nil, reports, fmt.Errorf("failed to parse JUnit report %s: %w", report, err)
+		}
+		for file, duration := range reportDurations {
+			durations[file] += duration
+		}
+	}
+	return durations, reports, nil
+}
+
+func readReport(path string) (map[string]time.Duration, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	return ParseTestFileDurations(file)
+}
+
+func parseSeconds(value string) (time.Duration, bool) {
+	// Some reporters format large times with thousands separators.
+	seconds, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(value), ",", ""), 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
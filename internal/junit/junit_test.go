@@ -0,0 +1,125 @@
+package junit
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTestFileDurations_TestCaseFiles(t *testing.T) {
+	report := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="rspec" tests="3" time="4.5">
+  <testcase classname="spec.models.user_spec" name="validates email" file="./spec/models/user_spec.rb" time="1.25"/>
+  <testcase classname="spec.models.user_spec" name="saves" file="./spec/models/user_spec.rb" time="0.75"/>
+  <testcase classname="spec.services.checkout_spec" name="charges" file="./spec/services/checkout_spec.rb" time="2.5"/>
+  <testcase classname="unknown" name="no file" time="9"/>
+</testsuite>`
+
+	durations, err := ParseTestFileDurations(strings.NewReader(report))
+	if err != nil {
+		t.Fatalf("ParseTestFileDurations() error = %v", err)
+	}
+
+	want := map[string]time.Duration{
+		"spec/models/user_spec.rb":       2 * time.Second,
+		"spec/services/checkout_spec.rb": 2500 * time.Millisecond,
+	}
+	if !maps.Equal(durations, want) {
+		t.Fatalf("durations = %v, want %v", durations, want)
+	}
+}
+
+func TestParseTestFileDurations_SuiteFiles(t *testing.T) {
+	report := `<testsuites>
+  <testsuite name="UserTest" filepath="test/models/user_test.rb" time="3.5">
+    <testcase name="test_valid" time="1"/>
+  </testsuite>
+  <testsuite name="checkout.test.js" file="src/checkout.test.js">
+    <testcase name="charges" time="0.5"/>
+    <testcase name="refunds" time="1,000.5"/>
+  </testsuite>
+</testsuites>`
+
+	durations, err := ParseTestFileDurations(strings.NewReader(report))
+	if err != nil {
+		t.Fatalf("ParseTestFileDurations() error = %v", err)
+	}
+
+	want := map[string]time.Duration{
+		"test/models/user_test.rb": 3500 * time.Millisecond,
+		"src/checkout.test.js":     1001 * time.Second,
+	}
+	if !maps.Equal(durations, want) {
+		t.Fatalf("durations = %v, want %v", durations, want)
+	}
+}
+
+func TestParseTestFileDurations_InvalidXML(t *testing.T) {
+	if _, err := ParseTestFileDurations(strings.NewReader("<testsuite>")); err == nil {
+		t.Fatal("expected error for truncated report")
+	}
+}
+
+func TestReadTestFileDurations_AddsUpReports(t *testing.T) {
+	dir := t.TempDir()
+	writeReport(t, filepath.Join(dir, "worker-0", "rspec.xml"), `<testsuite><testcase file="spec/a_spec.rb" time="1"/></testsuite>`)
+	writeReport(t, filepath.Join(dir, "worker-1", "rspec.xml"), `<testsuite><testcase file="spec/a_spec.rb" time="2"/><testcase file="spec/b_spec.rb" time="3"/></testsuite>`)
+
+	durations, reports, err := ReadTestFileDurations(filepath.Join(dir, "**", "*.xml"), time.Time{})
+	if err != nil {
+		t.Fatalf("ReadTestFileDurations() error = %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 reports, got %v", reports)
+	}
+	want := map[string]time.Duration{"spec/a_spec.rb": 3 * time.Second, "spec/b_spec.rb": 3 * time.Second}
+	if !maps.Equal(durations, want) {
+		t.Fatalf("durations = %v, want %v", durations, want)
+	}
+}
+
+func TestReadTestFileDurations_SkipsReportsOfEarlierRuns(t *testing.T) {
+	dir := t.TempDir()
+	runStart := time.Now()
+	stale := filepath.Join(dir, "stale.xml")
+	writeReport(t, stale, `<testsuite><testcase file="spec/a_spec.rb" time="5"/></testsuite>`)
+	if err := os.Chtimes(stale, runStart.Add(-time.Hour), runStart.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	fresh := filepath.Join(dir, "fresh.xml")
+	writeReport(t, fresh, `<testsuite><testcase file="spec/a_spec.rb" time="1"/></testsuite>`)
+
+	durations, reports, err := ReadTestFileDurations(filepath.Join(dir, "*.xml"), runStart)
+	if err != nil {
+		t.Fatalf("ReadTestFileDurations() error = %v", err)
+	}
+	if len(reports) != 1 || reports[0] != fresh {
+		t.Fatalf("reports = %v, want only %s", reports, fresh)
+	}
+	want := map[string]time.Duration{"spec/a_spec.rb": time.Second}
+	if !maps.Equal(durations, want) {
+		t.Fatalf("durations = %v, want %v", durations, want)
+	}
+}
+
+func TestReadTestFileDurations_ReportsParseErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeReport(t, filepath.Join(dir, "broken.xml"), "not xml")
+
+	if _, _, err := ReadTestFileDurations(filepath.Join(dir, "*.xml"), time.Time{}); err == nil || !strings.Contains(err.Error(), "broken.xml") {
+		t.Fatalf("ReadTestFileDurations() error = %v, want parse error naming the report", err)
+	}
+}
+
+func writeReport(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
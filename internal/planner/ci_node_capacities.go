@@ -0,0 +1,105 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/settings"
+)
+
+// splitOptions configures how test files are split between runners.
+type splitOptions struct {
+	optimizer settings.SplitOptimizer
+	// ciNodeCapacities holds the local worker count of the CI nodes that
+	// declare one. When it is empty, every runner has the same capacity.
+	ciNodeCapacities map[int]int
+	// ciNodeWorkers is the local worker count of CI nodes missing from
+	// ciNodeCapacities.
+	ciNodeWorkers int
+	constraints   TestFileConstraints
+	// durationEstimate selects the wall time that splits are scored by. With
+	// an estimate other than the median, expectedWeights and
+	// durationVariances hold the expected duration and the duration variance
+	// of the test files whose P90 duration is known.
+	durationEstimate  settings.DurationEstimate
+	expectedWeights   map[string]int
+	durationVariances map[string]float64
+	// optimizeFor and costModel select the parallelism: by wall time within
+	// the CI budget, or by estimated CI cost within the target time.
+	optimizeFor settings.OptimizationGoal
+	costModel   ciCostModel
+	// previousRunners holds the runner of each test file in the previous
+	// plan, or nil to distribute test files from scratch. Test files stay on
+	// their previous runner while the wall time is at most
+	// stickySplitTolerance percent longer than from scratch.
+	previousRunners      map[string]int
+	stickySplitTolerance float64
+}
+
+func newSplitOptionsFromSettings() splitOptions {
+	return splitOptions{
+		optimizer:            settings.GetSplitOptimizer(),
+		durationEstimate:     settings.GetDurationEstimate(),
+		optimizeFor:          settings.GetOptimizeFor(),
+		costModel:            newCICostModelFromSettings(),
+		ciNodeCapacities:     settings.GetCiNodeCapacitiesMap(),
+		ciNodeWorkers:        settings.GetCiNodeWorkers(),
+		stickySplitTolerance: settings.GetStickySplitTolerance(),
+	}
+}
+
+// ciNodeWorkerCounts returns the local worker count of each of
+// parallelRunners CI nodes, or nil when no CI node declares a capacity.
+func (o splitOptions) ciNodeWorkerCounts(parallelRunners int) []int {
+	if len(o.ciNodeCapacities) == 0 {
+		return nil
+	}
+
+	workers := make([]int, max(parallelRunners, 1))
+	for ciNode := range workers {
+		if capacity, ok := o.ciNodeCapacities[ciNode]; ok {
+			workers[ciNode] = capacity
+		} else {
+			workers[ciNode] = max(o.ciNodeWorkers, 1)
+		}
+	}
+	return workers
+}
+
+func (o splitOptions) newTestSplitBuilder(parallelRunners int) testSplitBuilder {
+	builder := newTestSplitBuilder(parallelRunners).withOptimizer(o.optimizer).withConstraints(o.constraints)
+	if workers := o.ciNodeWorkerCounts(builder.parallelRunners); workers != nil {
+		builder = builder.withCapacities(workers)
+	}
+	if o.assignsByP90() {
+		builder = builder.withP90Durations()
+	}
+	return builder
+}
+
+// writeCINodeWorkersArtifact writes the local worker count of each CI node,
+// one line per CI node, so that ddtest run --ci-node N can use it. Without
+// declared capacities it removes the artifact of an earlier plan instead.
+func writeCINodeWorkersArtifact(ciNodeWorkers []int) error {
+	if ciNodeWorkers == nil {
+		if err := os.Remove(constants.CINodeWorkersOutputPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove stale CI node worker counts %s: %w", constants.CINodeWorkersOutputPath, err)
+		}
+		return nil
+	}
+
+	var content strings.Builder
+	for _, workers := range ciNodeWorkers {
+		content.WriteString(strconv.Itoa(workers))
+		content.WriteString("\n")
+	}
+	if err := writePlanFile(constants.CINodeWorkersOutputPath, []byte(content.String())); err != nil {
+		return fmt.Errorf("failed to write CI node worker counts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package planner
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/settings"
+)
+
+func TestSplitOptionsCINodeWorkerCounts(t *testing.T) {
+	if workers := (splitOptions{ciNodeWorkers: 4}).ciNodeWorkerCounts(3); workers != nil {
+		t.Fatalf("ciNodeWorkerCounts() without capacities = %v, want nil", workers)
+	}
+
+	options := splitOptions{ciNodeCapacities: map[int]int{0: 8, 1: 8, 3: 2}, ciNodeWorkers: 4}
+	if workers := options.ciNodeWorkerCounts(5); !slices.Equal(workers, []int{8, 8, 4, 2, 4}) {
+		t.Fatalf("ciNodeWorkerCounts() = %v, want [8 8 4 2 4]", workers)
+	}
+}
+
+func TestScoreSortedWeightedRunnerSplit_CINodeCapacities(t *testing.T) {
+	files := make([]weightedTestFile, 12)
+	for i := range files {
+		files[i] = weightedTestFile{path: fmt.Sprintf("spec/%02d_spec.rb", i), weight: 10}
+	}
+	options := splitOptions{ciNodeCapacities: map[int]int{0: 3}, ciNodeWorkers: 1}
+
+	result := scoreSortedWeightedRunnerSplit(files, 2, options)
+	expected := splitScore{
+		parallelRunners: 2,
+		wallTime:        30,
+		imbalance:       0,
+		totalRuntime:    120,
+	}
+	if result != expected {
+		t.Fatalf("scoreSortedWeightedRunnerSplit() = %+v, expected %+v", result, expected)
+	}
+
+	builder := options.newTestSplitBuilder(2)
+	distribution := builder.distributeSortedFiles(files)
+	if len(distribution[0]) != 9 || len(distribution[1]) != 3 {
+		t.Fatalf("expected 9 files on the 3-worker CI node and 3 on the other, got %v", distribution)
+	}
+}
+
+func TestScoreSortedWeightedRunnerSplit_CINodeCapacitiesPreferFasterNode(t *testing.T) {
+	files := []weightedTestFile{{path: "slow_spec.rb", weight: 10}}
+	options := splitOptions{ciNodeCapacities: map[int]int{1: 2}, ciNodeWorkers: 1}
+
+	builder := options.newTestSplitBuilder(2)
+	distribution := builder.distributeSortedFiles(files)
+	assertDistribution(t, distribution, [][]string{{}, {"slow_spec.rb"}})
+	if score := builder.score(); score.wallTime != 5 {
+		t.Fatalf("expected wall time 5 on the 2-worker CI node, got %+v", score)
+	}
+}
+
+func TestRefineSortedFiles_CINodeCapacitiesNeverWorseThanGreedy(t *testing.T) {
+	random := rand.New(rand.NewPCG(3, 4))
+	for iteration := range 50 {
+		testFiles := make(map[string]int)
+		for i := range 20 + random.IntN(200) {
+			testFiles[fmt.Sprintf("spec/%03d_spec.rb", i)] = 1 + random.IntN(5000)
+		}
+		files := sortedWeightedTestFiles(testFiles)
+		parallelRunners := 2 + random.IntN(8)
+		capacities := make(map[int]int)
+		for ciNode := range parallelRunners {
+			capacities[ciNode] = 1 + random.IntN(8)
+		}
+		greedyOptions := splitOptions{ciNodeCapacities: capacities}
+		localSearchOptions := splitOptions{ciNodeCapacities: capacities, optimizer: settings.SplitOptimizerLocalSearch}
+
+		greedy := scoreSortedWeightedRunnerSplit(files, parallelRunners, greedyOptions)
+		refined := scoreSortedWeightedRunnerSplit(files, parallelRunners, localSearchOptions)
+		if refined.wallTime > greedy.wallTime || refined.totalRuntime != greedy.totalRuntime {
+			t.Fatalf("iteration %d: refined score %+v is worse than greedy score %+v", iteration, refined, greedy)
+		}
+
+		builder := localSearchOptions.newTestSplitBuilder(parallelRunners)
+		var distributed int
+		for _, runnerFiles := range builder.distributeSortedFiles(files) {
+			distributed += len(runnerFiles)
+		}
+		if distributed != len(files) {
+			t.Fatalf("iteration %d: distributed %d test files, want %d", iteration, distributed, len(files))
+		}
+	}
+}
+
+func TestWriteCINodeWorkersArtifact(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if err := writeCINodeWorkersArtifact([]int{8, 4}); err != nil {
+		t.Fatalf("writeCINodeWorkersArtifact() error = %v", err)
+	}
+	data, err := os.ReadFile(constants.CINodeWorkersOutputPath)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", constants.CINodeWorkersOutputPath, err)
+	}
+	if string(data) != "8\n4\n" {
+		t.Fatalf("ci-node-workers.txt = %q, want %q", data, "8\n4\n")
+	}
+
+	if err := writeCINodeWorkersArtifact(nil); err != nil {
+		t.Fatalf("writeCINodeWorkersArtifact(nil) error = %v", err)
+	}
+	if _, err := os.Stat(constants.CINodeWorkersOutputPath); !os.IsNotExist(err) {
+		t.Fatalf("expected stale %s to be removed, got %v", constants.CINodeWorkersOutputPath, err)
+	}
+	if err := writeCINodeWorkersArtifact(nil); err != nil {
+		t.Fatalf("writeCINodeWorkersArtifact(nil) without an artifact error = %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+package planner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/DataDog/ddtest/internal/utils"
+)
+
+// testFileConstraintsConfig is the content of the constraints file. Group
+// rules keep the test files they match on the same runner; exclusive rules
+// make the test files they match run alone.
+type testFileConstraintsConfig struct {
+	Group     []testFileConstraintRule `json:"group"`
+	Exclusive []testFileConstraintRule `json:"exclusive"`
+}
+
+// testFileConstraintRule matches test files with glob patterns, such as
+// spec/search/**/*_spec.rb.
+type testFileConstraintRule struct {
+	Name  string   `json:"name"`
+	Paths []string `json:"paths"`
+}
+
+// TestFileConstraints holds the co-location and isolation rules of a plan,
+// resolved to its runnable test files.
+type TestFileConstraints struct {
+	// Groups holds, by group rule name, the test files that must run on the
+	// same runner, and in the same batch when runners pull test files from a
+	// queue. Only groups of two or more test files are kept.
+	Groups map[string][]string
+	// Exclusive holds the test files that must run with no other test process
+	// of the run on their runner.
+	Exclusive []string
+
+	groupByTestFile map[string]string
+	exclusive       map[string]bool
+}
+
+// NewTestFileConstraints returns the constraints that keep the test files of
+// each of groups together and make the exclusive test files run alone.
+func NewTestFileConstraints(groups map[string][]string, exclusive []string) TestFileConstraints {
+	constraints := TestFileConstraints{
+		Groups:          groups,
+		Exclusive:       exclusive,
+		groupByTestFile: make(map[string]string),
+		exclusive:       make(map[string]bool, len(exclusive)),
+	}
+	for name, testFiles := range groups {
+		for _, testFile := range testFiles {
+			constraints.groupByTestFile[testFile] = name
+		}
+	}
+	for _, testFile := range exclusive {
+		constraints.exclusive[testFile] = true
+	}
+	return constraints
+}
+
+// Empty reports whether no test file is constrained.
+func (c TestFileConstraints) Empty() bool {
+	return len(c.groupByTestFile) == 0 && len(c.exclusive) == 0
+}
+
+// IsExclusive reports whether testFile must run alone.
+func (c TestFileConstraints) IsExclusive(testFile string) bool {
+	return c.exclusive[testFile]
+}
+
+// Partition splits testFiles into the units that must run together and the
+// exclusive test files, both in order of first appearance. Each unit holds
+// the test files of one group, or a single test file that is not grouped.
+func (c TestFileConstraints) Partition(testFiles []string) ([][]string, []string) {
+	units := make([][]string, 0, len(testFiles))
+	var exclusive []string
+	groupUnits := make(map[string]int)
+	for _, testFile := range testFiles {
+		if c.exclusive[testFile] {
+			exclusive = append(exclusive, testFile)
+			continue
+		}
+		name, grouped := c.groupByTestFile[testFile]
+		if !grouped {
+			units = append(units, []string{testFile})
+			continue
+		}
+		if index, ok := groupUnits[name]; ok {
+			units[index] = append(units[index], testFile)
+			continue
+		}
+		groupUnits[name] = len(units)
+		units = append(units, []string{testFile})
+	}
+	return units, exclusive
+}
+
+// groupedTestFilesCount returns how many test files belong to a group.
+func (c TestFileConstraints) groupedTestFilesCount() int {
+	return len(c.groupByTestFile)
+}
+
+// constrains reports whether testFile is grouped or exclusive.
+func (c TestFileConstraints) constrains(testFile string) bool {
+	_, grouped := c.groupByTestFile[testFile]
+	return grouped || c.exclusive[testFile]
+}
+
+// sortedWeightedUnits returns the split units of testFileWeights sorted by
+// compareWeightedTestFiles, and the test files of each group unit by the path
+// of the unit. A group unit weighs as much as its test files together and
+// takes the path of its heaviest test file; an exclusive test file is a serial
+// unit of its own.
+func (c TestFileConstraints) sortedWeightedUnits(testFileWeights map[string]int) ([]weightedTestFile, map[string][]string) {
+	if c.Empty() {
+		return sortedWeightedTestFiles(testFileWeights), nil
+	}
+
+	units, exclusive := c.Partition(slices.Sorted(maps.Keys(testFileWeights)))
+	files := make([]weightedTestFile, 0, len(units)+len(exclusive))
+	members := make(map[string][]string)
+	for _, unit := range units {
+		if len(unit) == 1 {
+			files = append(files, weightedTestFile{path: unit[0], weight: testFileWeights[unit[0]]})
+			continue
+		}
+		unitFiles := make([]weightedTestFile, 0, len(unit))
+		for _, testFile := range unit {
+			unitFiles = append(unitFiles, weightedTestFile{path: testFile, weight: testFileWeights[testFile]})
+		}
+		slices.SortFunc(unitFiles, compareWeightedTestFiles)
+
+		group := weightedTestFile{path: unitFiles[0].path}
+		for _, file := range unitFiles {
+			group.weight += file.weight
+			members[group.path] = append(members[group.path], file.path)
+		}
+		files = append(files, group)
+	}
+	for _, testFile := range exclusive {
+		files = append(files, weightedTestFile{path: testFile, weight: testFileWeights[testFile], serial: true})
+	}
+
+	slices.SortFunc(files, compareWeightedTestFiles)
+	return files, members
+}
+
+// expandUnits replaces each group unit of distribution by its test files.
+func expandUnits(distribution [][]string, members map[string][]string) [][]string {
+	if len(members) == 0 {
+		return distribution
+	}
+	for i, runnerFiles := range distribution {
+		expanded := make([]string, 0, len(runnerFiles))
+		for _, path := range runnerFiles {
+			if unitFiles, ok := members[path]; ok {
+				expanded = append(expanded, unitFiles...)
+			} else {
+				expanded = append(expanded, path)
+			}
+		}
+		distribution[i] = expanded
+	}
+	return distribution
+}
+
+// loadTestFileConstraintsConfig reads and validates the constraints file at
+// path. It returns an empty configuration when path is empty.
+func loadTestFileConstraintsConfig(path string) (testFileConstraintsConfig, error) {
+	if path == "" {
+		return testFileConstraintsConfig{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return testFileConstraintsConfig{}, fmt.Errorf("failed to read constraints file %s: %w", path, err)
+	}
+
+	var config testFileConstraintsConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return testFileConstraintsConfig{}, fmt.Errorf("failed to parse constraints file %s: %w", path, err)
+	}
+
+	names := make(map[string]bool)
+	for i := range config.Group {
+		rule := &config.Group[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("group %d", i+1)
+		}
+		if names[rule.Name] {
+			return testFileConstraintsConfig{}, fmt.Errorf("constraints file %s: duplicate group name %q", path, rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	for _, kind := range []struct {
+		name  string
+		rules []testFileConstraintRule
+	}{{"group", config.Group}, {"exclusive", config.Exclusive}} {
+		for i, rule := range kind.rules {
+			if len(rule.Paths) == 0 {
+				return testFileConstraintsConfig{}, fmt.Errorf("constraints file %s: %s rule %d has no paths", path, kind.name, i+1)
+			}
+			for _, pattern := range rule.Paths {
+				if _, err := utils.NewPathMatcher(pattern); err != nil {
+					return testFileConstraintsConfig{}, fmt.Errorf("constraints file %s: %s rule %d: %w", path, kind.name, i+1, err)
+				}
+			}
+		}
+	}
+	return config, nil
+}
+
+// resolve matches the rules of config against testFiles. A test file that
+// matches an exclusive rule is only exclusive, and one that matches several
+// group rules belongs to the first of them.
+func (config testFileConstraintsConfig) resolve(testFiles []string) TestFileConstraints {
+	exclusiveMatchers := constraintRuleMatchers(config.Exclusive)
+	groupMatchers := constraintRuleMatchers(config.Group)
+
+	groups := make(map[string][]string)
+	var exclusive []string
+	for _, testFile := range testFiles {
+		if slices.ContainsFunc(exclusiveMatchers, func(matchers []utils.PathMatcher) bool { return matchAny(matchers, testFile) }) {
+			exclusive = append(exclusive, testFile)
+			continue
+		}
+		for i, matchers := range groupMatchers {
+			if matchAny(matchers, testFile) {
+				groups[config.Group[i].Name] = append(groups[config.Group[i].Name], testFile)
+				break
+			}
+		}
+	}
+	for name, groupFiles := range groups {
+		if len(groupFiles) < 2 {
+			delete(groups, name)
+		}
+	}
+	return NewTestFileConstraints(groups, exclusive)
+}
+
+func constraintRuleMatchers(rules []testFileConstraintRule) [][]utils.PathMatcher {
+	matchers := make([][]utils.PathMatcher, len(rules))
+	for i, rule := range rules {
+		for _, pattern := range rule.Paths {
+			// Patterns were validated when the constraints file was loaded.
+			matcher, _ := utils.NewPathMatcher(pattern)
+			matchers[i] = append(matchers[i], matcher)
+		}
+	}
+	return matchers
+}
+
+func matchAny(matchers []utils.PathMatcher, testFile string) bool {
+	return slices.ContainsFunc(matchers, func(matcher utils.PathMatcher) bool { return matcher.Match(testFile) })
+}
+
+// applyTestFileConstraints resolves config against the runnable test files of
+// this plan.
+func (tp *TestPlanner) applyTestFileConstraints(config testFileConstraintsConfig) {
+	tp.testFileConstraints = config.resolve(slices.Sorted(maps.Keys(tp.testFileWeights)))
+	if len(config.Group) == 0 && len(config.Exclusive) == 0 {
+		return
+	}
+	slog.Info("Resolved test file constraints",
+		"groupsCount", len(tp.testFileConstraints.Groups),
+		"groupedTestFilesCount", tp.testFileConstraints.groupedTestFilesCount(),
+		"exclusiveTestFilesCount", len(tp.testFileConstraints.Exclusive))
+}
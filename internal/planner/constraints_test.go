@@ -0,0 +1,275 @@
+package planner
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/testoptimization"
+	"github.com/DataDog/ddtest/internal/testoptimization/api"
+)
+
+func writePlannerConstraintsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ddtest-constraints.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write constraints file: %v", err)
+	}
+	return path
+}
+
+func TestLoadTestFileConstraintsConfig(t *testing.T) {
+	config, err := loadTestFileConstraintsConfig("")
+	if err != nil || len(config.Group) != 0 || len(config.Exclusive) != 0 {
+		t.Fatalf("loadTestFileConstraintsConfig(\"\") = %+v, %v, want an empty config", config, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "constraints.json")
+	if err := os.WriteFile(path, []byte(`{"group":[{"paths":["spec/search/**"]},{"name":"db","paths":["spec/db/**"]}]}`), 0o644); err != nil {
+		t.Fatalf("failed to write constraints file: %v", err)
+	}
+	config, err = loadTestFileConstraintsConfig(path)
+	if err != nil {
+		t.Fatalf("loadTestFileConstraintsConfig() error = %v", err)
+	}
+	if config.Group[0].Name != "group 1" || config.Group[1].Name != "db" {
+		t.Fatalf("expected default and explicit group names, got %+v", config.Group)
+	}
+}
+
+func TestLoadTestFileConstraintsConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "malformed", content: `{"group":`, want: "failed to parse"},
+		{name: "unknown field", content: `{"isolate":[]}`, want: "unknown field"},
+		{name: "duplicate name", content: `{"group":[{"name":"db","paths":["a"]},{"name":"db","paths":["b"]}]}`, want: `duplicate group name "db"`},
+		{name: "no paths", content: `{"exclusive":[{"name":"es"}]}`, want: "exclusive rule 1 has no paths"},
+		{name: "invalid pattern", content: `{"group":[{"paths":["spec/[a"]}]}`, want: "group rule 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "constraints.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("failed to write constraints file: %v", err)
+			}
+			if _, err := loadTestFileConstraintsConfig(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("loadTestFileConstraintsConfig() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := loadTestFileConstraintsConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("expected an error for a missing constraints file")
+	}
+}
+
+func TestTestFileConstraintsConfig_Resolve(t *testing.T) {
+	config := testFileConstraintsConfig{
+		Group: []testFileConstraintRule{
+			{Name: "search", Paths: []string{"spec/search/**"}},
+			{Name: "all", Paths: []string{"spec/**"}},
+			{Name: "lonely", Paths: []string{"spec/lonely_spec.rb"}},
+		},
+		Exclusive: []testFileConstraintRule{{Name: "elasticsearch", Paths: []string{"spec/search/index_spec.rb"}}},
+	}
+
+	constraints := config.resolve([]string{
+		"spec/search/index_spec.rb",
+		"spec/search/query_spec.rb",
+		"spec/search/facet_spec.rb",
+		"spec/models/user_spec.rb",
+		"spec/models/order_spec.rb",
+		"spec/lonely_spec.rb",
+		"test/other_test.rb",
+	})
+
+	expectedGroups := map[string][]string{
+		"search": {"spec/search/query_spec.rb", "spec/search/facet_spec.rb"},
+		"all":    {"spec/models/user_spec.rb", "spec/models/order_spec.rb", "spec/lonely_spec.rb"},
+	}
+	if !maps.EqualFunc(constraints.Groups, expectedGroups, slices.Equal) {
+		t.Errorf("Groups = %v, want %v", constraints.Groups, expectedGroups)
+	}
+	if !slices.Equal(constraints.Exclusive, []string{"spec/search/index_spec.rb"}) {
+		t.Errorf("Exclusive = %v, want [spec/search/index_spec.rb]", constraints.Exclusive)
+	}
+	if constraints.constrains("test/other_test.rb") {
+		t.Error("expected an unmatched test file to be unconstrained")
+	}
+}
+
+func TestTestFileConstraints_Partition(t *testing.T) {
+	constraints := NewTestFileConstraints(map[string][]string{"db": {"spec/db/a_spec.rb", "spec/db/b_spec.rb"}}, []string{"spec/es_spec.rb"})
+
+	units, exclusive := constraints.Partition([]string{"spec/db/b_spec.rb", "spec/one_spec.rb", "spec/es_spec.rb", "spec/db/a_spec.rb"})
+	expectedUnits := [][]string{{"spec/db/b_spec.rb", "spec/db/a_spec.rb"}, {"spec/one_spec.rb"}}
+	if !slices.EqualFunc(units, expectedUnits, slices.Equal) {
+		t.Errorf("Partition() units = %v, want %v", units, expectedUnits)
+	}
+	if !slices.Equal(exclusive, []string{"spec/es_spec.rb"}) {
+		t.Errorf("Partition() exclusive = %v, want [spec/es_spec.rb]", exclusive)
+	}
+}
+
+func TestTestFileConstraints_SortedWeightedUnits(t *testing.T) {
+	constraints := NewTestFileConstraints(map[string][]string{"db": {"spec/db/a_spec.rb", "spec/db/b_spec.rb"}}, []string{"spec/es_spec.rb"})
+
+	files, members := constraints.sortedWeightedUnits(map[string]int{
+		"spec/db/a_spec.rb": 100,
+		"spec/db/b_spec.rb": 300,
+		"spec/es_spec.rb":   200,
+		"spec/one_spec.rb":  250,
+	})
+	expectedFiles := []weightedTestFile{
+		{path: "spec/db/b_spec.rb", weight: 400},
+		{path: "spec/one_spec.rb", weight: 250},
+		{path: "spec/es_spec.rb", weight: 200, serial: true},
+	}
+	if !slices.Equal(files, expectedFiles) {
+		t.Fatalf("sortedWeightedUnits() files = %+v, want %+v", files, expectedFiles)
+	}
+
+	distribution := expandUnits([][]string{{"spec/db/b_spec.rb"}, {"spec/one_spec.rb", "spec/es_spec.rb"}}, members)
+	assertDistribution(t, distribution, [][]string{{"spec/db/b_spec.rb", "spec/db/a_spec.rb"}, {"spec/one_spec.rb", "spec/es_spec.rb"}})
+}
+
+func TestScoreSortedWeightedRunnerSplit_ExclusiveTestFileOccupiesCINodeWorkers(t *testing.T) {
+	constraints := NewTestFileConstraints(nil, []string{"spec/es_spec.rb"})
+	files, _ := constraints.sortedWeightedUnits(map[string]int{
+		"spec/es_spec.rb":  10,
+		"spec/one_spec.rb": 10,
+		"spec/two_spec.rb": 10,
+	})
+	options := splitOptions{ciNodeCapacities: map[int]int{0: 2, 1: 2}, ciNodeWorkers: 2, constraints: constraints}
+
+	// The exclusive test file keeps both workers of its CI node busy, so the
+	// other test files go to the other CI node.
+	builder := options.newTestSplitBuilder(2)
+	distribution := builder.distributeSortedFiles(files)
+	assertDistribution(t, distribution, [][]string{{"spec/es_spec.rb"}, {"spec/one_spec.rb", "spec/two_spec.rb"}})
+	if score := builder.score(); score.wallTime != 10 {
+		t.Fatalf("expected wall time 10, got %+v", score)
+	}
+}
+
+func TestPrintConstraintsPlanningReport(t *testing.T) {
+	var output strings.Builder
+	printConstraintsPlanningReport(&output, constraintsReport{
+		Available:          true,
+		Path:               "ci/ddtest-constraints.json",
+		Groups:             1,
+		GroupedTestFiles:   3,
+		ExclusiveTestFiles: 1,
+		Constrained:        splitScore{parallelRunners: 2, wallTime: 6000, imbalance: 3000},
+		Unconstrained:      splitScore{parallelRunners: 2, wallTime: 5000, imbalance: 1000},
+	})
+
+	for _, want := range []string{
+		"Test file constraints",
+		"Constraints file: ci/ddtest-constraints.json",
+		"Groups: 1 (3 test files)",
+		"Exclusive test files: 1",
+		"Without constraints: wall 5s, imbalance 1s",
+		"Balance cost: 1s slower wall time",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, output.String())
+		}
+	}
+
+	output.Reset()
+	printConstraintsPlanningReport(&output, constraintsReport{})
+	if output.Len() != 0 {
+		t.Errorf("expected no report without a constraints file, got:\n%s", output.String())
+	}
+}
+
+func TestTestPlanner_Plan_TestFileConstraints(t *testing.T) {
+	t.Chdir(t.TempDir())
+	setPlannerForceFullTestDiscovery(t, true)
+	constraintsFile := writePlannerConstraintsFile(t, `{
+		"group": [{"name": "search", "paths": ["spec/search/**"]}],
+		"exclusive": [{"name": "elasticsearch", "paths": ["spec/index_spec.rb"]}]
+	}`)
+	setPlannerEnv(t, map[string]string{
+		"DD_TEST_OPTIMIZATION_RUNNER_CHUNK_SLOW_TEST_FILES": "true",
+		"DD_TEST_OPTIMIZATION_RUNNER_MIN_PARALLELISM":       "2",
+		"DD_TEST_OPTIMIZATION_RUNNER_MAX_PARALLELISM":       "2",
+		"DD_TEST_OPTIMIZATION_RUNNER_CONSTRAINTS_FILE":      constraintsFile,
+	})
+
+	mockFramework := &MockFramework{
+		FrameworkName:    "rspec",
+		TestPatternValue: "spec/**/*_spec.rb",
+		TestFiles:        []string{"spec/search/a_spec.rb", "spec/search/b_spec.rb", "spec/index_spec.rb", "spec/fast_spec.rb"},
+		Tests: []testoptimization.Test{
+			{Module: "rspec", Suite: "SearchA", Name: "a", SuiteSourceFile: "spec/search/a_spec.rb"},
+			{Module: "rspec", Suite: "SearchA", Name: "b", SuiteSourceFile: "spec/search/a_spec.rb"},
+			{Module: "rspec", Suite: "SearchB", Name: "c", SuiteSourceFile: "spec/search/b_spec.rb"},
+			{Module: "rspec", Suite: "Index", Name: "d", SuiteSourceFile: "spec/index_spec.rb"},
+			{Module: "rspec", Suite: "Index", Name: "e", SuiteSourceFile: "spec/index_spec.rb"},
+			{Module: "rspec", Suite: "Fast", Name: "f", SuiteSourceFile: "spec/fast_spec.rb"},
+		},
+	}
+	mockOptimizationClient := &MockTestOptimizationClient{
+		Settings: testOptimizationSettings(true, true, false),
+		Durations: map[string]map[string]api.TestSuiteDurationInfo{
+			"rspec": {
+				"SearchA": {SourceFile: "spec/search/a_spec.rb", Duration: api.DurationPercentiles{P50: "3000000000"}},
+				"SearchB": {SourceFile: "spec/search/b_spec.rb", Duration: api.DurationPercentiles{P50: "3000000000"}},
+				"Index":   {SourceFile: "spec/index_spec.rb", Duration: api.DurationPercentiles{P50: "9000000000"}},
+				"Fast":    {SourceFile: "spec/fast_spec.rb", Duration: api.DurationPercentiles{P50: "1000000000"}},
+			},
+		},
+	}
+	mockPlatform := &MockPlatform{
+		PlatformName: "ruby",
+		Tags:         map[string]string{"platform": "ruby"},
+		Framework:    chunkingMockFramework{mockFramework},
+	}
+
+	runner := NewWithDependencies(&MockPlatformDetector{Platform: mockPlatform}, mockOptimizationClient, newDefaultMockCIProviderDetector())
+	if err := runner.Plan(context.Background()); err != nil {
+		t.Fatalf("Plan() should not return error, got: %v", err)
+	}
+
+	// The slow exclusive test file is not chunked and the grouped test files
+	// share a runner.
+	assertFileContent(t, filepath.Join(constants.TestsSplitDir, "runner-0"), "spec/index_spec.rb\n")
+	assertFileContent(t, filepath.Join(constants.TestsSplitDir, "runner-1"), "spec/search/a_spec.rb\nspec/search/b_spec.rb\nspec/fast_spec.rb\n")
+
+	restored := newTestPlannerWithDefaults().TestFileConstraints()
+	if !slices.Equal(restored.Groups["search"], []string{"spec/search/a_spec.rb", "spec/search/b_spec.rb"}) {
+		t.Errorf("expected restored search group, got %v", restored.Groups)
+	}
+	if !restored.IsExclusive("spec/index_spec.rb") || restored.IsExclusive("spec/fast_spec.rb") {
+		t.Errorf("expected spec/index_spec.rb to be the only restored exclusive test file, got %v", restored.Exclusive)
+	}
+}
+
+func TestTestPlanner_Plan_InvalidConstraintsFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	setPlannerForceFullTestDiscovery(t, true)
+	setPlannerEnv(t, map[string]string{
+		"DD_TEST_OPTIMIZATION_RUNNER_CONSTRAINTS_FILE": writePlannerConstraintsFile(t, `{"group": [{"name": "search"}]}`),
+	})
+
+	mockPlatform := &MockPlatform{
+		PlatformName: "ruby",
+		Tags:         map[string]string{"platform": "ruby"},
+		Framework:    &MockFramework{FrameworkName: "rspec"},
+	}
+	runner := NewWithDependencies(&MockPlatformDetector{Platform: mockPlatform}, &MockTestOptimizationClient{}, newDefaultMockCIProviderDetector())
+	err := runner.Plan(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "group rule 1 has no paths") {
+		t.Fatalf("Plan() error = %v, want an invalid constraints file error", err)
+	}
+}
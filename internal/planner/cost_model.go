@@ -0,0 +1,69 @@
+package planner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/settings"
+)
+
+// ciCostModel estimates what a split costs to run in CI. Every runner is a CI
+// job, billed for its startup time plus its expected wall time, rounded up to
+// the billing increment of the CI provider.
+type ciCostModel struct {
+	pricePerMinute   float64
+	billingIncrement time.Duration
+	jobStartupTime   time.Duration
+	// budget is the maximum estimated cost of a split, or 0 for no budget.
+	budget float64
+}
+
+func newCICostModelFromSettings() ciCostModel {
+	return ciCostModel{
+		pricePerMinute:   settings.GetCiJobPricePerMinute(),
+		billingIncrement: settings.GetCiJobBillingIncrement(),
+		jobStartupTime:   settings.GetCiJobStartupTime(),
+		budget:           settings.GetCiBudget(),
+	}
+}
+
+// enabled reports whether a CI job price is set, without which splits have no
+// estimated cost.
+func (m ciCostModel) enabled() bool {
+	return m.pricePerMinute > 0
+}
+
+func (m ciCostModel) hasBudget() bool {
+	return m.enabled() && m.budget > 0
+}
+
+// billedTime returns the billed time, in milliseconds, of the CI jobs whose
+// tests take the given wall times.
+func (m ciCostModel) billedTime(runnerWallTimes []int) int {
+	if !m.enabled() {
+		return 0
+	}
+
+	var billed time.Duration
+	for _, wallTime := range runnerWallTimes {
+		job := m.jobStartupTime + time.Duration(wallTime)*time.Millisecond
+		if m.billingIncrement > 0 {
+			job = (job + m.billingIncrement - 1) / m.billingIncrement * m.billingIncrement
+		}
+		billed += job
+	}
+	return int(billed / time.Millisecond)
+}
+
+// cost returns the estimated CI cost of a split.
+func (m ciCostModel) cost(score splitScore) float64 {
+	return score.billedTimeDuration().Minutes() * m.pricePerMinute
+}
+
+func (m ciCostModel) withinBudget(score splitScore) bool {
+	return !m.hasBudget() || m.cost(score) <= m.budget
+}
+
+func formatCost(cost float64) string {
+	return fmt.Sprintf("%.3f", cost)
+}
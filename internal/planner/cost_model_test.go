@@ -0,0 +1,104 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/settings"
+)
+
+func TestCICostModel_BilledTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		model ciCostModel
+		want  int
+	}{
+		{name: "disabled", model: ciCostModel{}, want: 0},
+		{name: "exact", model: ciCostModel{pricePerMinute: 1}, want: 100_000},
+		{name: "startup time", model: ciCostModel{pricePerMinute: 1, jobStartupTime: 10 * time.Second}, want: 120_000},
+		{name: "billing increment", model: ciCostModel{pricePerMinute: 1, billingIncrement: time.Minute}, want: 120_000},
+		{name: "startup time and billing increment", model: ciCostModel{pricePerMinute: 1, billingIncrement: time.Minute, jobStartupTime: 30 * time.Second}, want: 240_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.model.billedTime([]int{60_000, 40_000}); got != tt.want {
+				t.Fatalf("billedTime() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCICostModel_Cost(t *testing.T) {
+	model := ciCostModel{pricePerMinute: 0.008, budget: 0.02}
+	score := splitScore{billedTime: 150_000}
+	if cost := model.cost(score); formatCost(cost) != "0.020" {
+		t.Fatalf("expected 2m30s at 0.008 per minute to cost 0.020, got %g", cost)
+	}
+	if !model.withinBudget(score) || model.withinBudget(splitScore{billedTime: 180_000}) {
+		t.Fatal("expected only splits that cost at most the budget to fit it")
+	}
+}
+
+func TestCalculateParallelRunnerSplitSelection_CostModel(t *testing.T) {
+	// One minute test files. With a 30s job startup time billed in whole
+	// minutes, 1 runner bills 5m, 2 runners 6m, 3 runners 7m, and 4 runners 8m.
+	weights := map[string]int{"spec/a_spec.rb": 60_000, "spec/b_spec.rb": 60_000, "spec/c_spec.rb": 60_000, "spec/d_spec.rb": 60_000}
+	costModel := ciCostModel{pricePerMinute: 1, billingIncrement: time.Minute, jobStartupTime: 30 * time.Second}
+	withBudget := func(budget float64) ciCostModel {
+		model := costModel
+		model.budget = budget
+		return model
+	}
+
+	tests := []struct {
+		name            string
+		optimizeFor     settings.OptimizationGoal
+		costModel       ciCostModel
+		targetTime      time.Duration
+		parallelRunners int
+	}{
+		{name: "time without budget", optimizeFor: settings.OptimizationGoalTime, costModel: costModel, parallelRunners: 4},
+		{name: "time within budget", optimizeFor: settings.OptimizationGoalTime, costModel: withBudget(7), parallelRunners: 2},
+		{name: "time over every budget", optimizeFor: settings.OptimizationGoalTime, costModel: withBudget(1), parallelRunners: 1},
+		{name: "cost without target", optimizeFor: settings.OptimizationGoalCost, costModel: costModel, parallelRunners: 1},
+		{name: "cost within target", optimizeFor: settings.OptimizationGoalCost, costModel: costModel, targetTime: 2 * time.Minute, parallelRunners: 2},
+		{name: "cost with unreachable target", optimizeFor: settings.OptimizationGoalCost, costModel: costModel, targetTime: 30 * time.Second, parallelRunners: 4},
+		{name: "target met over budget", optimizeFor: settings.OptimizationGoalTime, costModel: withBudget(5), targetTime: 2 * time.Minute, parallelRunners: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection := calculateParallelRunnerSplitSelection(weights, 1, 4, 0, tt.targetTime,
+				splitOptions{optimizeFor: tt.optimizeFor, costModel: tt.costModel})
+			if selection.selected.parallelRunners != tt.parallelRunners {
+				t.Fatalf("expected %d runners, got %+v", tt.parallelRunners, selection.selected)
+			}
+		})
+	}
+}
+
+func TestPrintRunnerSplitPlanningReport_CostModel(t *testing.T) {
+	weights := map[string]int{"spec/a_spec.rb": 60_000, "spec/b_spec.rb": 60_000, "spec/c_spec.rb": 60_000, "spec/d_spec.rb": 60_000}
+	costModel := ciCostModel{pricePerMinute: 1, billingIncrement: time.Minute, jobStartupTime: 30 * time.Second, budget: 7}
+	selection := calculateParallelRunnerSplitSelection(weights, 1, 4, 0, 0,
+		splitOptions{optimizeFor: settings.OptimizationGoalTime, costModel: costModel})
+
+	var output strings.Builder
+	printRunnerSplitPlanningReport(&output, PlanReportData{SplitSelection: selection})
+
+	for _, want := range []string{
+		"Reason: lowest selection score among splits that fit CI budget",
+		"Estimated CI cost: 6.000 (6m0s billed across 2 CI jobs)",
+		"CI budget: 7.000, satisfied",
+		"Without CI budget: 4 runners (wall 1m0s, overhead 0s, score 1m0s, cost 8.000)",
+		"Selected vs without CI budget: 1m0s slower wall time, same CI overhead, 2.000 less estimated CI cost",
+		"2 runners: wall 2m0s, overhead 0s, score 2m0s, cost 6.000, selected",
+		"4 runners: wall 1m0s, overhead 0s, score 1m0s, cost 8.000, over budget; would choose without CI budget",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, output.String())
+		}
+	}
+}
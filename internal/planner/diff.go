@@ -0,0 +1,278 @@
+package planner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/errcode"
+)
+
+// planSnapshot is the part of a plan directory that plan-diff compares.
+type planSnapshot struct {
+	testFiles       []string
+	parallelRunners int
+	// skippablePercentage is nil when the plan has no skippable percentage.
+	skippablePercentage *float64
+	// runners maps each test file to the runner split it is assigned to.
+	runners map[string]int
+	// estimatedWallTime is nil when the plan has no plan cache to estimate
+	// test file durations from.
+	estimatedWallTime *time.Duration
+}
+
+// planDiffValue is a plan property in the base and head plans.
+type planDiffValue[T any] struct {
+	Base T `json:"base"`
+	Head T `json:"head"`
+}
+
+type runnerAssignmentChange struct {
+	TestFile   string `json:"testFile"`
+	BaseRunner int    `json:"baseRunner"`
+	HeadRunner int    `json:"headRunner"`
+}
+
+// planDiff is the difference between two plan directories. JSON output uses
+// milliseconds for the estimated wall time.
+type planDiff struct {
+	BasePlanDir             string                   `json:"basePlanDir"`
+	HeadPlanDir             string                   `json:"headPlanDir"`
+	ParallelRunners         planDiffValue[int]       `json:"parallelRunners"`
+	SkippablePercentage     planDiffValue[*float64]  `json:"skippablePercentage"`
+	EstimatedWallTimeMs     planDiffValue[*int64]    `json:"estimatedWallTimeMs"`
+	TestFiles               planDiffValue[int]       `json:"testFiles"`
+	TestFilesAdded          []string                 `json:"testFilesAdded"`
+	TestFilesRemoved        []string                 `json:"testFilesRemoved"`
+	RunnerAssignmentChanges []runnerAssignmentChange `json:"runnerAssignmentChanges"`
+	estimatedWallTime       planDiffValue[*time.Duration]
+}
+
+// DiffPlans compares the plan directories basePlanDir and headPlanDir, for
+// example the plans of a pull request and of its base commit, and writes the
+// differences to w as text, or as JSON when jsonOutput is set.
+func DiffPlans(w io.Writer, basePlanDir string, headPlanDir string, jsonOutput bool) error {
+	base, err := readPlanSnapshot(basePlanDir)
+	if err != nil {
+		return err
+	}
+	head, err := readPlanSnapshot(headPlanDir)
+	if err != nil {
+		return err
+	}
+
+	diff := newPlanDiff(basePlanDir, base, headPlanDir, head)
+	if jsonOutput {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diff)
+	}
+	diff.print(w)
+	return nil
+}
+
+func readPlanSnapshot(planDir string) (planSnapshot, error) {
+	var snapshot planSnapshot
+
+	testFiles, err := readPlanFileLines(planDirPath(planDir, constants.TestFilesOutputPath))
+	if err != nil {
+		return snapshot, errcode.WithCode(errcode.PlanDiffPlanLoadFailed, fmt.Errorf("failed to read test files of plan %s: %w", planDir, err))
+	}
+	snapshot.testFiles = testFiles
+
+	data, err := os.ReadFile(planDirPath(planDir, constants.ParallelRunnersOutputPath))
+	if err != nil {
+		return snapshot, errcode.WithCode(errcode.PlanDiffPlanLoadFailed, fmt.Errorf("failed to read parallel runners count of plan %s: %w", planDir, err))
+	}
+	snapshot.parallelRunners, err = strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return snapshot, errcode.WithCode(errcode.PlanDiffPlanLoadFailed, fmt.Errorf("failed to parse parallel runners count of plan %s: %w", planDir, err))
+	}
+
+	data, err = os.ReadFile(planDirPath(planDir, constants.SkippablePercentageOutputPath))
+	if err == nil {
+		if percentage, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64); err == nil {
+			snapshot.skippablePercentage = &percentage
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return snapshot, errcode.WithCode(errcode.PlanDiffPlanLoadFailed, fmt.Errorf("failed to read skippable percentage of plan %s: %w", planDir, err))
+	}
+
+	splits, err := readRunnerSplitFiles(planDirPath(planDir, constants.TestsSplitDir))
+	if err != nil {
+		return snapshot, errcode.WithCode(errcode.PlanDiffTestSplitsReadFailed, err)
+	}
+	snapshot.runners = make(map[string]int)
+	for index, testFiles := range splits {
+		for _, testFile := range testFiles {
+			snapshot.runners[testFile] = index
+		}
+	}
+
+	var cache testOptimizationPlanCache
+	data, err = os.ReadFile(planDirPath(planDir, filepath.Join(constants.RunnerCacheDir, constants.TestOptimizationPlanCacheFile)))
+	if err == nil && json.Unmarshal(data, &cache) == nil && cache.TestFileWeights != nil {
+		weights := maps.Clone(cache.TestFileWeights)
+		maps.Copy(weights, cache.TestChunkWeights)
+		wallTime := estimatedWallTime(splits, weights)
+		snapshot.estimatedWallTime = &wallTime
+	}
+	return snapshot, nil
+}
+
+// planDirPath returns where path, a plan file path under the current plan
+// directory, is found in planDir.
+func planDirPath(planDir string, path string) string {
+	relativePath, err := filepath.Rel(constants.PlanDirectory, path)
+	if err != nil {
+		return path
+	}
+	return filepath.Join(planDir, relativePath)
+}
+
+// estimatedWallTime returns the estimated duration of the slowest runner
+// split.
+func estimatedWallTime(splits map[int][]string, testFileWeights map[string]int) time.Duration {
+	var slowest int
+	for _, testFiles := range splits {
+		var total int
+		for _, testFile := range testFiles {
+			weight, ok := testFileWeights[testFile]
+			if !ok {
+				weight = constants.DefaultTestFileWeight
+			}
+			total += weight
+		}
+		slowest = max(slowest, total)
+	}
+	return time.Duration(slowest) * time.Millisecond
+}
+
+func newPlanDiff(basePlanDir string, base planSnapshot, headPlanDir string, head planSnapshot) planDiff {
+	diff := planDiff{
+		BasePlanDir:             basePlanDir,
+		HeadPlanDir:             headPlanDir,
+		ParallelRunners:         planDiffValue[int]{Base: base.parallelRunners, Head: head.parallelRunners},
+		SkippablePercentage:     planDiffValue[*float64]{Base: base.skippablePercentage, Head: head.skippablePercentage},
+		EstimatedWallTimeMs:     planDiffValue[*int64]{Base: durationMilliseconds(base.estimatedWallTime), Head: durationMilliseconds(head.estimatedWallTime)},
+		TestFiles:               planDiffValue[int]{Base: len(base.testFiles), Head: len(head.testFiles)},
+		TestFilesAdded:          make([]string, 0),
+		TestFilesRemoved:        make([]string, 0),
+		RunnerAssignmentChanges: make([]runnerAssignmentChange, 0),
+		estimatedWallTime:       planDiffValue[*time.Duration]{Base: base.estimatedWallTime, Head: head.estimatedWallTime},
+	}
+
+	baseTestFiles := make(map[string]bool, len(base.testFiles))
+	for _, testFile := range base.testFiles {
+		baseTestFiles[testFile] = true
+	}
+	headTestFiles := make(map[string]bool, len(head.testFiles))
+	for _, testFile := range head.testFiles {
+		headTestFiles[testFile] = true
+		if !baseTestFiles[testFile] {
+			diff.TestFilesAdded = append(diff.TestFilesAdded, testFile)
+		}
+	}
+	for _, testFile := range base.testFiles {
+		if !headTestFiles[testFile] {
+			diff.TestFilesRemoved = append(diff.TestFilesRemoved, testFile)
+		}
+	}
+	for testFile, baseRunner := range base.runners {
+		if headRunner, ok := head.runners[testFile]; ok && headRunner != baseRunner {
+			diff.RunnerAssignmentChanges = append(diff.RunnerAssignmentChanges, runnerAssignmentChange{TestFile: testFile, BaseRunner: baseRunner, HeadRunner: headRunner})
+		}
+	}
+	slices.Sort(diff.TestFilesAdded)
+	slices.Sort(diff.TestFilesRemoved)
+	slices.SortFunc(diff.RunnerAssignmentChanges, func(a, b runnerAssignmentChange) int {
+		return strings.Compare(a.TestFile, b.TestFile)
+	})
+	return diff
+}
+
+func durationMilliseconds(duration *time.Duration) *int64 {
+	if duration == nil {
+		return nil
+	}
+	milliseconds := duration.Milliseconds()
+	return &milliseconds
+}
+
+func (d planDiff) print(w io.Writer) {
+	reportFprintln(w, "+++ DDTest: plan diff")
+	reportFprintf(w, "  Base: %s\n", d.BasePlanDir)
+	reportFprintf(w, "  Head: %s\n", d.HeadPlanDir)
+	reportFprintln(w)
+	reportFprintf(w, "  Parallel runners: %s\n", formatPlanDiffChange(d.ParallelRunners, strconv.Itoa, func(base, head int) string {
+		return fmt.Sprintf("%+d", head-base)
+	}))
+	reportFprintf(w, "  Skippable percentage: %s\n", formatPlanDiffChange(d.SkippablePercentage, formatOptionalPercentage, func(base, head *float64) string {
+		if base == nil || head == nil {
+			return ""
+		}
+		return fmt.Sprintf("%+.2f points", *head-*base)
+	}))
+	reportFprintf(w, "  Estimated wall time: %s\n", formatPlanDiffChange(d.estimatedWallTime, formatOptionalWallTime, func(base, head *time.Duration) string {
+		if base == nil || head == nil {
+			return ""
+		}
+		if *head < *base {
+			return "-" + formatDuration(*base-*head)
+		}
+		return "+" + formatDuration(*head-*base)
+	}))
+	if len(d.TestFilesAdded) == 0 && len(d.TestFilesRemoved) == 0 {
+		reportFprintf(w, "  Test files: %s (unchanged)\n", formatCount(d.TestFiles.Head))
+	} else {
+		reportFprintf(w, "  Test files: %s -> %s (%s added, %s removed)\n",
+			formatCount(d.TestFiles.Base), formatCount(d.TestFiles.Head), formatCount(len(d.TestFilesAdded)), formatCount(len(d.TestFilesRemoved)))
+	}
+	for _, testFile := range d.TestFilesAdded {
+		reportFprintf(w, "    + %s\n", testFile)
+	}
+	for _, testFile := range d.TestFilesRemoved {
+		reportFprintf(w, "    - %s\n", testFile)
+	}
+	reportFprintf(w, "  Runner assignments changed: %s\n", formatCount(len(d.RunnerAssignmentChanges)))
+	for _, change := range d.RunnerAssignmentChanges {
+		reportFprintf(w, "    %s: runner-%d -> runner-%d\n", change.TestFile, change.BaseRunner, change.HeadRunner)
+	}
+}
+
+// formatPlanDiffChange formats a plan property as "base -> head (delta)", or
+// as "value (unchanged)" when both plans agree.
+func formatPlanDiffChange[T any](value planDiffValue[T], format func(T) string, delta func(base, head T) string) string {
+	base, head := format(value.Base), format(value.Head)
+	if base == head {
+		return base + " (unchanged)"
+	}
+	if change := delta(value.Base, value.Head); change != "" {
+		return fmt.Sprintf("%s -> %s (%s)", base, head, change)
+	}
+	return base + " -> " + head
+}
+
+func formatOptionalPercentage(percentage *float64) string {
+	if percentage == nil {
+		return "not available"
+	}
+	return fmt.Sprintf("%.2f%%", *percentage)
+}
+
+func formatOptionalWallTime(wallTime *time.Duration) string {
+	if wallTime == nil {
+		return "not available"
+	}
+	return formatDuration(*wallTime)
+}
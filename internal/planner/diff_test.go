@@ -0,0 +1,130 @@
+package planner
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/errcode"
+)
+
+func writeDiffTestPlan(t *testing.T, planDir string, parallelRunners string, skippablePercentage string, splits []string, weights string) {
+	t.Helper()
+
+	testFiles := make([]string, 0)
+	for index, split := range splits {
+		writePlanTestFile(t, planDirPath(planDir, filepath.Join(constants.TestsSplitDir, "runner-"+strconv.Itoa(index))), split)
+		testFiles = append(testFiles, strings.Fields(split)...)
+	}
+	writePlanTestFile(t, planDirPath(planDir, constants.TestFilesOutputPath), strings.Join(testFiles, "\n")+"\n")
+	writePlanTestFile(t, planDirPath(planDir, constants.ParallelRunnersOutputPath), parallelRunners)
+	writePlanTestFile(t, planDirPath(planDir, constants.SkippablePercentageOutputPath), skippablePercentage)
+	if weights != "" {
+		writePlanTestFile(t, planDirPath(planDir, filepath.Join(constants.RunnerCacheDir, constants.TestOptimizationPlanCacheFile)), `{"testFileWeights":`+weights+`}`)
+	}
+}
+
+func TestDiffPlans_Text(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeDiffTestPlan(t, "base", "2", "40.00",
+		[]string{"spec/a_spec.rb\nspec/b_spec.rb\n", "spec/c_spec.rb\n"},
+		`{"spec/a_spec.rb":60000,"spec/b_spec.rb":30000,"spec/c_spec.rb":60000}`)
+	writeDiffTestPlan(t, "head", "3", "55.50",
+		[]string{"spec/a_spec.rb\n", "spec/b_spec.rb\n", "spec/d_spec.rb\n"},
+		`{"spec/a_spec.rb":60000,"spec/b_spec.rb":30000,"spec/d_spec.rb":20000}`)
+
+	var output strings.Builder
+	if err := DiffPlans(&output, "base", "head", false); err != nil {
+		t.Fatalf("DiffPlans() error = %v", err)
+	}
+
+	expected := `+++ DDTest: plan diff
+  Base: base
+  Head: head
+
+  Parallel runners: 2 -> 3 (+1)
+  Skippable percentage: 40.00% -> 55.50% (+15.50 points)
+  Estimated wall time: 1m30s -> 1m0s (-30s)
+  Test files: 3 -> 3 (1 added, 1 removed)
+    + spec/d_spec.rb
+    - spec/c_spec.rb
+  Runner assignments changed: 1
+    spec/b_spec.rb: runner-0 -> runner-1
+`
+	if output.String() != expected {
+		t.Fatalf("unexpected plan diff:\n%s\nwant:\n%s", output.String(), expected)
+	}
+}
+
+func TestDiffPlans_UnchangedWithoutPlanCache(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeDiffTestPlan(t, "base", "1", "10.00", []string{"spec/a_spec.rb\n"}, "")
+	writeDiffTestPlan(t, "head", "1", "10.00", []string{"spec/a_spec.rb\n"}, "")
+
+	var output strings.Builder
+	if err := DiffPlans(&output, "base", "head", false); err != nil {
+		t.Fatalf("DiffPlans() error = %v", err)
+	}
+	for _, line := range []string{
+		"  Parallel runners: 1 (unchanged)\n",
+		"  Skippable percentage: 10.00% (unchanged)\n",
+		"  Estimated wall time: not available (unchanged)\n",
+		"  Test files: 1 (unchanged)\n",
+		"  Runner assignments changed: 0\n",
+	} {
+		if !strings.Contains(output.String(), line) {
+			t.Errorf("expected %q in plan diff:\n%s", line, output.String())
+		}
+	}
+}
+
+func TestDiffPlans_JSON(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeDiffTestPlan(t, "base", "1", "40.00", []string{"spec/a_spec.rb\nspec/b_spec.rb\n"}, `{"spec/a_spec.rb":1000,"spec/b_spec.rb":2000}`)
+	writeDiffTestPlan(t, "head", "1", "50.00", []string{"spec/a_spec.rb\n"}, "")
+
+	var output strings.Builder
+	if err := DiffPlans(&output, "base", "head", true); err != nil {
+		t.Fatalf("DiffPlans() error = %v", err)
+	}
+
+	var diff struct {
+		ParallelRunners     planDiffValue[int]       `json:"parallelRunners"`
+		SkippablePercentage planDiffValue[float64]   `json:"skippablePercentage"`
+		EstimatedWallTimeMs planDiffValue[*int64]    `json:"estimatedWallTimeMs"`
+		TestFilesAdded      []string                 `json:"testFilesAdded"`
+		TestFilesRemoved    []string                 `json:"testFilesRemoved"`
+		RunnerChanges       []runnerAssignmentChange `json:"runnerAssignmentChanges"`
+	}
+	if err := json.Unmarshal([]byte(output.String()), &diff); err != nil {
+		t.Fatalf("plan diff is not valid JSON: %v\n%s", err, output.String())
+	}
+	if diff.SkippablePercentage.Base != 40 || diff.SkippablePercentage.Head != 50 {
+		t.Fatalf("unexpected skippable percentage %+v", diff.SkippablePercentage)
+	}
+	if diff.EstimatedWallTimeMs.Base == nil || *diff.EstimatedWallTimeMs.Base != 3000 || diff.EstimatedWallTimeMs.Head != nil {
+		t.Fatalf("unexpected estimated wall time in %s", output.String())
+	}
+	if len(diff.TestFilesAdded) != 0 || len(diff.TestFilesRemoved) != 1 || diff.TestFilesRemoved[0] != "spec/b_spec.rb" {
+		t.Fatalf("unexpected test file changes in %s", output.String())
+	}
+	if diff.RunnerChanges == nil {
+		t.Fatalf("expected an empty runner assignment list in %s", output.String())
+	}
+}
+
+func TestDiffPlans_MissingPlan(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeDiffTestPlan(t, "base", "1", "10.00", []string{"spec/a_spec.rb\n"}, "")
+
+	err := DiffPlans(&strings.Builder{}, "base", "head", false)
+	if errcode.CodeOf(err) != errcode.PlanDiffPlanLoadFailed {
+		t.Fatalf("DiffPlans() error = %v, want %s", err, errcode.PlanDiffPlanLoadFailed)
+	}
+	if !strings.Contains(err.Error(), "plan head") {
+		t.Fatalf("expected the missing plan directory in %v", err)
+	}
+}
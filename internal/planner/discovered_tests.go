@@ -18,6 +18,7 @@ func (tp *TestPlanner) resetDiscoveryResults() {
 	tp.testFiles = make(map[string]struct{})
 	tp.suiteAggregates = make(map[testSuiteKey]testSuiteAggregate)
 	tp.suitesBySourceFile = make(map[string][]testSuiteKey)
+	tp.testsBySourceFile = make(map[string][]testoptimization.Test)
 	tp.reportStats = newPlanningReportStats()
 }
 
@@ -65,6 +66,7 @@ func (tp *TestPlanner) recordFullDiscoveryResults(
 		}
 		if normalizedSourceFile != "" {
 			tp.testFiles[normalizedSourceFile] = struct{}{}
+			tp.testsBySourceFile[normalizedSourceFile] = append(tp.testsBySourceFile[normalizedSourceFile], test)
 		}
 
 		match := skippableMatcher.Match(test)
@@ -73,7 +75,7 @@ func (tp *TestPlanner) recordFullDiscoveryResults(
 			recordRunnableTest(tp.suiteAggregates, test, normalizedSourceFile)
 		} else {
 			slog.Debug("Test will be skipped", "test", test.DatadogTestId(), "sourceFile", test.SuiteSourceFile)
-			recordSkippedTest(tp.suiteAggregates, test, normalizedSourceFile)
+			recordSkippedTest(tp.suiteAggregates, test, normalizedSourceFile, match.kind == skippableMatchDisabledTest)
 			tp.recordAppliedSkippable(match)
 			skippableTestsCount++
 		}
@@ -258,7 +260,9 @@ func (tp *TestPlanner) keepUnskippableMarkerSuitesRunnable(testFramework framewo
 			continue
 		}
 
+		aggregate.UnskippableTestsKept = aggregate.NumTestsSkipped
 		aggregate.NumTestsSkipped = 0
+		aggregate.NumTestsDisabled = 0
 		aggregate.EstimatedDuration = aggregate.TotalDuration
 		tp.suiteAggregates[key] = aggregate
 		forceRunnableSuiteAggregatesCount++
@@ -299,10 +303,13 @@ func recordRunnableTest(suiteAggregates map[testSuiteKey]testSuiteAggregate, tes
 	suiteAggregates[testSuiteKey{Module: test.Module, Suite: test.Suite}] = aggregate
 }
 
-func recordSkippedTest(suiteAggregates map[testSuiteKey]testSuiteAggregate, test testoptimization.Test, sourceFile string) {
+func recordSkippedTest(suiteAggregates map[testSuiteKey]testSuiteAggregate, test testoptimization.Test, sourceFile string, disabled bool) {
 	aggregate := suiteAggregateForTest(suiteAggregates, test, sourceFile)
 	aggregate.NumTests++
 	aggregate.NumTestsSkipped++
+	if disabled {
+		aggregate.NumTestsDisabled++
+	}
 	suiteAggregates[testSuiteKey{Module: test.Module, Suite: test.Suite}] = aggregate
 }
 
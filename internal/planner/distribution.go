@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
@@ -12,30 +13,78 @@ import (
 	"time"
 
 	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/settings"
 )
 
 // DistributeTestFiles distributes test files using weights loaded into this planner.
 func (tp *TestPlanner) DistributeTestFiles(testFiles []string, parallelRunners int) [][]string {
-	if !tp.planLoaded {
-		if err := tp.restoreTestOptimizationPlanCache(); err != nil {
-			if errors.Is(err, os.ErrNotExist) {
-				slog.Debug("Test optimization run artifacts not found; distributing test files with default weights")
-			} else {
-				slog.Warn("Failed to load test optimization run artifacts; distributing test files with default weights", "error", err)
-			}
+	return tp.DistributeWeightedTestFiles(tp.TestFileWeights(testFiles), parallelRunners)
+}
+
+// TestFileWeights returns the estimated weight of each test file or test chunk
+// using weights loaded into this planner. Files without an estimate use the
+// default weight.
+func (tp *TestPlanner) TestFileWeights(testFiles []string) map[string]int {
+	tp.loadPlanForDistribution()
+
+	testFileWeights := testFileWeightsForFiles(tp.testFileWeights, testFiles)
+	for _, testFile := range testFiles {
+		if chunkWeight, ok := tp.testChunkWeights[testFile]; ok && chunkWeight > 0 {
+			testFileWeights[testFile] = chunkWeight
 		}
 	}
+	return testFileWeights
+}
 
-	testFileWeights := testFileWeightsForFiles(tp.testFileWeights, testFiles)
-	return tp.DistributeWeightedTestFiles(testFileWeights, parallelRunners)
+// TestFileConstraints returns the co-location and isolation rules of the
+// plan loaded into this planner.
+func (tp *TestPlanner) TestFileConstraints() TestFileConstraints {
+	tp.loadPlanForDistribution()
+	return tp.testFileConstraints
+}
+
+func (tp *TestPlanner) loadPlanForDistribution() {
+	if tp.planLoaded {
+		return
+	}
+	if err := tp.restoreTestOptimizationPlanCache(); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			slog.Debug("Test optimization run artifacts not found; distributing test files with default weights")
+		} else {
+			slog.Warn("Failed to load test optimization run artifacts; distributing test files with default weights", "error", err)
+		}
+	}
 }
 
-// DistributeWeightedTestFiles distributes test files across parallel runners using weighted list scheduling.
+// DistributeWeightedTestFiles distributes test files across parallel runners
+// using weighted list scheduling, refined by the configured split optimizer,
+// keeping the test files of each group on the same runner.
 func (tp *TestPlanner) DistributeWeightedTestFiles(testFiles map[string]int, parallelRunners int) [][]string {
-	builder := newTestSplitBuilder(parallelRunners)
+	builder := newTestSplitBuilder(parallelRunners).
+		withOptimizer(settings.GetSplitOptimizer()).
+		withConstraints(tp.testFileConstraints)
 	return builder.distributeFiles(testFiles)
 }
 
+// distributeCINodeTestFiles distributes test files across CI nodes like
+// DistributeWeightedTestFiles, balancing expected wall time when CI nodes
+// declare different capacities and keeping test files on the runner of the
+// previous plan when one is configured.
+func (tp *TestPlanner) distributeCINodeTestFiles(testFiles map[string]int, parallelRunners int) [][]string {
+	distribution, _ := tp.splitOptions().distributeTestFiles(testFiles, parallelRunners)
+	return distribution
+}
+
+// splitOptions returns the split options of the settings, constrained by the
+// test file constraints of this plan.
+func (tp *TestPlanner) splitOptions() splitOptions {
+	options := newSplitOptionsFromSettings()
+	options.constraints = tp.testFileConstraints
+	options.expectedWeights, options.durationVariances = tp.splitDurationModel()
+	options.previousRunners = tp.previousTestSplit
+	return options
+}
+
 func testFileWeightsForFiles(cacheWeights map[string]int, testFiles []string) map[string]int {
 	testFileWeights := make(map[string]int, len(testFiles))
 	for _, testFile := range testFiles {
@@ -54,17 +103,30 @@ func testFileWeightsForFiles(cacheWeights map[string]int, testFiles []string) ma
 func (tp *TestPlanner) CreateTestSplits(testFiles map[string]int, parallelRunners int, testFilesOutputPath string) error {
 	if parallelRunners > 1 {
 		// Distribute test files across parallel runners using weighted list scheduling.
-		distribution := tp.DistributeWeightedTestFiles(testFiles, parallelRunners)
+		distribution, stickySplit := tp.splitOptions().distributeTestFiles(testFiles, parallelRunners)
+		tp.recordStickySplitReport(stickySplit)
+		tp.orderTestSplits(distribution, testFiles)
 		if err := writeDistributedTestSplits(distribution, constants.TestsSplitDir); err != nil {
 			return err
 		}
 	} else {
+		if tp.previousTestSplit != nil {
+			stickySplit := stickySplitReport{Available: true, Tolerance: settings.GetStickySplitTolerance()}
+			stickySplit.countMoves([][]string{slices.Collect(maps.Keys(testFiles))}, tp.previousTestSplit)
+			tp.recordStickySplitReport(stickySplit)
+		}
+
 		// For single runner, copy test-files.txt to runner-0
 		testFilesData, err := os.ReadFile(testFilesOutputPath)
 		if err != nil {
 			return fmt.Errorf("failed to read test files from %s: %w", testFilesOutputPath, err)
 		}
 
+		if len(settings.GetSplitOrder()) > 0 {
+			distribution := [][]string{planFileLines(testFilesData)}
+			tp.orderTestSplits(distribution, testFiles)
+			return writeDistributedTestSplits(distribution, constants.TestsSplitDir)
+		}
 		if err := writeRunnerSplit(constants.TestsSplitDir, 0, testFilesData); err != nil {
 			return err
 		}
@@ -76,6 +138,12 @@ func (tp *TestPlanner) CreateTestSplits(testFiles map[string]int, parallelRunner
 type weightedTestFile struct {
 	path   string
 	weight int
+	// serial is set for exclusive test files, which keep every local worker
+	// of their runner busy while they run.
+	serial bool
+	// variance is the variance of the duration of the file, in square
+	// milliseconds, when it is modeled from its P50 and P90 durations.
+	variance float64
 }
 
 func sortedWeightedTestFiles(testFiles map[string]int) []weightedTestFile {
@@ -84,36 +152,60 @@ func sortedWeightedTestFiles(testFiles map[string]int) []weightedTestFile {
 		files = append(files, weightedTestFile{path: path, weight: weight})
 	}
 
-	slices.SortFunc(files, func(a, b weightedTestFile) int {
-		if a.weight > b.weight {
-			return -1
-		}
-		if a.weight < b.weight {
-			return 1
-		}
-		if a.path < b.path {
-			return -1
-		}
-		if a.path > b.path {
-			return 1
-		}
-		return 0
-	})
+	slices.SortFunc(files, compareWeightedTestFiles)
 
 	return files
 }
 
+// compareWeightedTestFiles orders test files by descending weight, then by
+// path.
+func compareWeightedTestFiles(a, b weightedTestFile) int {
+	if a.weight > b.weight {
+		return -1
+	}
+	if a.weight < b.weight {
+		return 1
+	}
+	if a.path < b.path {
+		return -1
+	}
+	if a.path > b.path {
+		return 1
+	}
+	return 0
+}
+
 type splitScore struct {
 	parallelRunners int
 	wallTime        int
 	imbalance       int
 	totalRuntime    int
+	// expectedWallTime and p90WallTime are set when the split is scored from
+	// the P50 and P90 durations of its test files. wallTime is then the one of
+	// them that the duration estimate minimizes.
+	expectedWallTime int
+	p90WallTime      int
+	// billedTime is the billed time of the CI jobs of the split when the CI
+	// cost model is enabled.
+	billedTime int
 }
 
 func (s splitScore) wallTimeDuration() time.Duration {
 	return time.Duration(s.wallTime) * time.Millisecond
 }
 
+func (s splitScore) expectedWallTimeDuration() time.Duration {
+	return time.Duration(s.expectedWallTime) * time.Millisecond
+}
+
+func (s splitScore) p90WallTimeDuration() time.Duration {
+	return time.Duration(s.p90WallTime) * time.Millisecond
+}
+
+func (s splitScore) billedTimeDuration() time.Duration {
+	return time.Duration(s.billedTime) * time.Millisecond
+}
+
 func (s splitScore) imbalanceDuration() time.Duration {
 	return time.Duration(s.imbalance) * time.Millisecond
 }
@@ -122,17 +214,52 @@ func (s splitScore) totalRuntimeDuration() time.Duration {
 	return time.Duration(s.totalRuntime) * time.Millisecond
 }
 
-func scoreSortedWeightedRunnerSplit(files []weightedTestFile, parallelRunners int) splitScore {
-	builder := newTestSplitBuilder(parallelRunners)
-	for _, file := range files {
-		builder.addFile(file.weight)
+func scoreSortedWeightedRunnerSplit(files []weightedTestFile, parallelRunners int, options splitOptions) splitScore {
+	builder := options.newTestSplitBuilder(parallelRunners)
+	// The files of each runner are only needed to score the split from the
+	// distributions of their durations.
+	var runners [][]weightedTestFile
+	switch {
+	case builder.optimizer == settings.SplitOptimizerLocalSearch:
+		runners = builder.refineSortedFiles(files)
+	case options.modelsDurationDistributions():
+		runners = make([][]weightedTestFile, builder.parallelRunners)
+		for _, file := range files {
+			runnerIndex := builder.addFile(file)
+			runners[runnerIndex] = append(runners[runnerIndex], file)
+		}
+	default:
+		for _, file := range files {
+			builder.addFile(file)
+		}
 	}
-	return builder.score()
+	score := builder.score()
+	if options.modelsDurationDistributions() {
+		score = builder.distributionScore(runners, options.durationEstimate)
+	}
+	score.billedTime = options.costModel.billedTime(builder.runnerWallTimes())
+	return score
 }
 
 type testSplitBuilder struct {
 	parallelRunners int
-	loads           minLoadHeap
+	// capacities holds the capacity of each runner, or nil when every runner
+	// has capacity 1.
+	capacities []int
+	// classes holds one load heap for the runners of each distinct capacity.
+	classes     []capacityClass
+	optimizer   settings.SplitOptimizer
+	constraints TestFileConstraints
+	// variances holds the duration variance of each runner when files are
+	// assigned by the P90 duration of their runner instead of its load, or
+	// nil otherwise.
+	variances []float64
+}
+
+// capacityClass is the load heap of the runners that share a capacity.
+type capacityClass struct {
+	capacity int
+	loads    minLoadHeap
 }
 
 func newTestSplitBuilder(parallelRunners int) testSplitBuilder {
@@ -142,19 +269,236 @@ func newTestSplitBuilder(parallelRunners int) testSplitBuilder {
 
 	return testSplitBuilder{
 		parallelRunners: parallelRunners,
-		loads:           makeMinLoadHeap(parallelRunners),
+		classes:         []capacityClass{{capacity: 1, loads: makeMinLoadHeap(parallelRunners)}},
+		optimizer:       settings.SplitOptimizerGreedy,
+	}
+}
+
+func (b testSplitBuilder) withOptimizer(optimizer settings.SplitOptimizer) testSplitBuilder {
+	b.optimizer = optimizer
+	return b
+}
+
+// withConstraints makes the builder keep the test files of each group on the
+// same runner and split exclusive test files as serial units.
+func (b testSplitBuilder) withConstraints(constraints TestFileConstraints) testSplitBuilder {
+	b.constraints = constraints
+	return b
+}
+
+// withCapacities makes the builder balance the expected wall time of each
+// runner, its load divided by its capacity, instead of its load. capacities
+// holds the capacity of each runner, such as the local worker count of a CI
+// node; runners past its end have capacity 1.
+func (b testSplitBuilder) withCapacities(capacities []int) testSplitBuilder {
+	b.capacities = make([]int, b.parallelRunners)
+	runnersByCapacity := make(map[int][]int)
+	for i := range b.capacities {
+		b.capacities[i] = 1
+		if i < len(capacities) && capacities[i] > 0 {
+			b.capacities[i] = capacities[i]
+		}
+		runnersByCapacity[b.capacities[i]] = append(runnersByCapacity[b.capacities[i]], i)
 	}
+
+	b.classes = make([]capacityClass, 0, len(runnersByCapacity))
+	for _, capacity := range slices.Sorted(maps.Keys(runnersByCapacity)) {
+		loads := make(minLoadHeap, 0, len(runnersByCapacity[capacity]))
+		for _, index := range runnersByCapacity[capacity] {
+			loads = append(loads, runnerLoad{index: index})
+		}
+		heap.Init(&loads)
+		b.classes = append(b.classes, capacityClass{capacity: capacity, loads: loads})
+	}
+	return b
+}
+
+// withP90Durations makes the builder assign each file to the runner whose P90
+// duration would finish it first, so that files whose durations vary are
+// spread between runners instead of only their expected durations.
+func (b testSplitBuilder) withP90Durations() testSplitBuilder {
+	b.variances = make([]float64, b.parallelRunners)
+	return b
 }
 
-func (b *testSplitBuilder) addFile(weight int) int {
-	lightestRunner := heap.Pop(&b.loads).(runnerLoad)
-	lightestRunner.load += weight
-	heap.Push(&b.loads, lightestRunner)
+func (b testSplitBuilder) capacity(runnerIndex int) int {
+	if b.capacities == nil {
+		return 1
+	}
+	return b.capacities[runnerIndex]
+}
+
+// addFile assigns a file to the runner that would finish it first: the least
+// loaded runner when every runner has the same capacity.
+func (b *testSplitBuilder) addFile(file weightedTestFile) int {
+	if b.variances != nil {
+		return b.addFileByP90(file)
+	}
+
+	class := &b.classes[0]
+	for i := 1; i < len(b.classes); i++ {
+		if b.classes[i].finishesBefore(*class, file) {
+			class = &b.classes[i]
+		}
+	}
+
+	lightestRunner := heap.Pop(&class.loads).(runnerLoad)
+	lightestRunner.load += class.load(file)
+	heap.Push(&class.loads, lightestRunner)
 	return lightestRunner.index
 }
 
+// addFileByP90 assigns a file to the runner that would finish it first at
+// the P90 duration of the runner. The load heaps hold the runners by P90
+// load, which adding the file raises by at least its load, so the search skips
+// every runner below one in the heap that cannot finish the file before the
+// best runner found so far.
+func (b *testSplitBuilder) addFileByP90(file weightedTestFile) int {
+	best := p90Candidate{class: -1}
+	for i := range b.classes {
+		b.findP90Candidate(i, 0, file, &best)
+	}
+
+	class := &b.classes[best.class]
+	runner := &class.loads[best.position]
+	runner.load += class.load(file)
+	b.variances[runner.index] += class.variance(file)
+	runner.p90Load = p90Load(runner.load, b.variances[runner.index])
+	runnerIndex := runner.index
+	heap.Fix(&class.loads, best.position)
+	return runnerIndex
+}
+
+// p90Candidate is the runner that addFileByP90 found to finish a file first
+// so far, by its position in the load heap of its capacity class.
+type p90Candidate struct {
+	class    int
+	position int
+	finish   float64
+}
+
+// findP90Candidate searches the runners of a capacity class at position and
+// below it in the load heap of the class for one that finishes file before
+// best.
+func (b *testSplitBuilder) findP90Candidate(classIndex int, position int, file weightedTestFile, best *p90Candidate) {
+	class := b.classes[classIndex]
+	if position >= len(class.loads) {
+		return
+	}
+	runner := class.loads[position]
+	if best.class >= 0 && (runner.p90Load+float64(class.load(file)))/float64(class.capacity) >= best.finish {
+		return
+	}
+	finish := p90Finish(runner.load+class.load(file), b.variances[runner.index]+class.variance(file), class.capacity)
+	if best.class < 0 || finish < best.finish {
+		*best = p90Candidate{class: classIndex, position: position, finish: finish}
+	}
+	b.findP90Candidate(classIndex, 2*position+1, file, best)
+	b.findP90Candidate(classIndex, 2*position+2, file, best)
+}
+
+// addFileTo assigns a file to the given runner.
+func (b *testSplitBuilder) addFileTo(file weightedTestFile, runnerIndex int) {
+	for i := range b.classes {
+		class := &b.classes[i]
+		for j := range class.loads {
+			if class.loads[j].index == runnerIndex {
+				class.loads[j].load += class.load(file)
+				if b.variances != nil {
+					b.variances[runnerIndex] += class.variance(file)
+					class.loads[j].p90Load = p90Load(class.loads[j].load, b.variances[runnerIndex])
+				}
+				heap.Fix(&class.loads, j)
+				return
+			}
+		}
+	}
+}
+
+// load returns the load that file adds to a runner of c. A serial file keeps
+// every local worker of the runner busy while it runs.
+func (c capacityClass) load(file weightedTestFile) int {
+	if file.serial {
+		return file.weight * c.capacity
+	}
+	return file.weight
+}
+
+// variance returns the duration variance that file adds to a runner of c.
+func (c capacityClass) variance(file weightedTestFile) float64 {
+	if file.serial {
+		return file.variance * float64(c.capacity*c.capacity)
+	}
+	return file.variance
+}
+
+// finishesBefore reports whether the least loaded runner of c would finish
+// file before the least loaded runner of other.
+func (c capacityClass) finishesBefore(other capacityClass, file weightedTestFile) bool {
+	runner, otherRunner := c.loads[0], other.loads[0]
+	finish := (runner.load + c.load(file)) * other.capacity
+	otherFinish := (otherRunner.load + other.load(file)) * c.capacity
+	if finish != otherFinish {
+		return finish < otherFinish
+	}
+	return runner.index < otherRunner.index
+}
+
+// runnerLoads returns the load of each runner.
+func (b testSplitBuilder) runnerLoads() []int {
+	loads := make([]int, b.parallelRunners)
+	for _, class := range b.classes {
+		for _, load := range class.loads {
+			loads[load.index] = load.load
+		}
+	}
+	return loads
+}
+
+// setRunnerFiles sets the load and variance of each runner to those of its
+// files.
+func (b *testSplitBuilder) setRunnerFiles(runners [][]weightedTestFile) {
+	loads := make([]int, b.parallelRunners)
+	for i, files := range runners {
+		class := b.runnerClass(i)
+		for _, file := range files {
+			loads[i] += class.load(file)
+		}
+		if b.variances != nil {
+			b.variances[i] = 0
+			for _, file := range files {
+				b.variances[i] += class.variance(file)
+			}
+		}
+	}
+	b.setRunnerLoads(loads)
+}
+
+func (b testSplitBuilder) runnerClass(runnerIndex int) capacityClass {
+	for _, class := range b.classes {
+		if class.capacity == b.capacity(runnerIndex) {
+			return class
+		}
+	}
+	return b.classes[0]
+}
+
+func (b *testSplitBuilder) setRunnerLoads(loads []int) {
+	for i := range b.classes {
+		class := &b.classes[i]
+		for j := range class.loads {
+			class.loads[j].load = loads[class.loads[j].index]
+			if b.variances != nil {
+				class.loads[j].p90Load = p90Load(class.loads[j].load, b.variances[class.loads[j].index])
+			}
+		}
+		heap.Init(&class.loads)
+	}
+}
+
 func (b *testSplitBuilder) distributeFiles(testFiles map[string]int) [][]string {
-	return b.distributeSortedFiles(sortedWeightedTestFiles(testFiles))
+	files, members := b.constraints.sortedWeightedUnits(testFiles)
+	return expandUnits(b.distributeSortedFiles(files), members)
 }
 
 func (b *testSplitBuilder) distributeSortedFiles(files []weightedTestFile) [][]string {
@@ -163,20 +507,51 @@ func (b *testSplitBuilder) distributeSortedFiles(files []weightedTestFile) [][]s
 		result[i] = []string{}
 	}
 
+	if b.optimizer == settings.SplitOptimizerLocalSearch {
+		for i, runnerFiles := range b.refineSortedFiles(files) {
+			for _, file := range runnerFiles {
+				result[i] = append(result[i], file.path)
+			}
+		}
+		return result
+	}
+
 	for _, file := range files {
-		runnerIndex := b.addFile(file.weight)
+		runnerIndex := b.addFile(file)
 		result[runnerIndex] = append(result[runnerIndex], file.path)
 	}
 
 	return result
 }
 
+// runnerWallTimes estimates the wall time of each runner as its load divided
+// by its capacity, rounded up.
+func (b testSplitBuilder) runnerWallTimes() []int {
+	loads := b.runnerLoads()
+	wallTimes := make([]int, len(loads))
+	for i, load := range loads {
+		capacity := b.capacity(i)
+		wallTimes[i] = (load + capacity - 1) / capacity
+	}
+	return wallTimes
+}
+
+// score scores the split by the wall times of its runners.
 func (b testSplitBuilder) score() splitScore {
-	minLoad, maxLoad, totalLoad := loadStats(b.loads)
+	totalLoad := 0
+	for _, load := range b.runnerLoads() {
+		totalLoad += load
+	}
+	wallTimes := make([]runnerLoad, b.parallelRunners)
+	for i, wallTime := range b.runnerWallTimes() {
+		wallTimes[i] = runnerLoad{index: i, load: wallTime}
+	}
+
+	minWallTime, maxWallTime, _ := loadStats(wallTimes)
 	return splitScore{
 		parallelRunners: b.parallelRunners,
-		wallTime:        maxLoad,
-		imbalance:       maxLoad - minLoad,
+		wallTime:        maxWallTime,
+		imbalance:       maxWallTime - minWallTime,
 		totalRuntime:    totalLoad,
 	}
 }
@@ -184,6 +559,9 @@ func (b testSplitBuilder) score() splitScore {
 type runnerLoad struct {
 	index int
 	load  int
+	// p90Load is the P90 duration of the load when files are assigned by
+	// P90 durations, and 0 otherwise.
+	p90Load float64
 }
 
 type minLoadHeap []runnerLoad
@@ -193,6 +571,9 @@ func (h minLoadHeap) Len() int {
 }
 
 func (h minLoadHeap) Less(i, j int) bool {
+	if h[i].p90Load != h[j].p90Load {
+		return h[i].p90Load < h[j].p90Load
+	}
 	if h[i].load == h[j].load {
 		return h[i].index < h[j].index
 	}
@@ -2,6 +2,7 @@ package planner
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"slices"
@@ -423,7 +424,7 @@ func TestScoreSortedWeightedRunnerSplit(t *testing.T) {
 		{path: "tiny.rb", weight: 2},
 	}
 
-	result := scoreSortedWeightedRunnerSplit(files, 2)
+	result := scoreSortedWeightedRunnerSplit(files, 2, splitOptions{})
 	expected := splitScore{
 		parallelRunners: 2,
 		wallTime:        12,
@@ -441,7 +442,7 @@ func TestScoreSortedWeightedRunnerSplit_UnavoidableEmptyRunners(t *testing.T) {
 		{path: "slow.rb", weight: 10},
 	}
 
-	result := scoreSortedWeightedRunnerSplit(files, 3)
+	result := scoreSortedWeightedRunnerSplit(files, 3, splitOptions{})
 	expected := splitScore{
 		parallelRunners: 3,
 		wallTime:        10,
@@ -647,3 +648,27 @@ func assertDistribution(t *testing.T, result, expected [][]string) {
 		}
 	}
 }
+
+func TestTestSplitBuilder_AddFileByP90FinishesEachFileFirst(t *testing.T) {
+	builder := newTestSplitBuilder(7).withCapacities([]int{1, 2, 2, 1, 3}).withP90Durations()
+	for i := range 200 {
+		weight := (i*7919)%1000 + 1
+		file := weightedTestFile{path: fmt.Sprintf("spec/%03d_spec.rb", i), weight: weight, serial: i%17 == 0}
+		if i%3 == 0 {
+			file.variance = float64(weight * weight)
+		}
+
+		loads := builder.runnerLoads()
+		bestFinish := math.Inf(1)
+		for runner := range loads {
+			class := builder.runnerClass(runner)
+			bestFinish = min(bestFinish, p90Finish(loads[runner]+class.load(file), builder.variances[runner]+class.variance(file), class.capacity))
+		}
+
+		runner := builder.addFile(file)
+		class := builder.runnerClass(runner)
+		if finish := p90Finish(loads[runner]+class.load(file), builder.variances[runner], class.capacity); finish != bestFinish {
+			t.Fatalf("file %d went to runner %d finishing at %g, want the earliest finish %g", i, runner, finish, bestFinish)
+		}
+	}
+}
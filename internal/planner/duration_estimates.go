@@ -0,0 +1,183 @@
+package planner
+
+import (
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/DataDog/ddtest/internal/framework"
+	"github.com/DataDog/ddtest/internal/settings"
+)
+
+// z90 is the 90th percentile of the standard normal distribution.
+const z90 = 1.2815515655446004
+
+// durationDistribution models the duration of a test file, in milliseconds,
+// as a log-normal distribution with the given median and 90th percentile.
+type durationDistribution struct {
+	p50 float64
+	p90 float64
+}
+
+func (d durationDistribution) sigma() float64 {
+	if d.p50 <= 0 || d.p90 <= d.p50 {
+		return 0
+	}
+	return math.Log(d.p90/d.p50) / z90
+}
+
+func (d durationDistribution) mean() float64 {
+	sigma := d.sigma()
+	return d.p50 * math.Exp(sigma*sigma/2)
+}
+
+func (d durationDistribution) variance() float64 {
+	sigma := d.sigma()
+	mean := d.mean()
+	return mean * mean * math.Expm1(sigma*sigma)
+}
+
+// modelsDurationDistributions reports whether splits are scored from the P50
+// and P90 durations of their test files instead of their medians.
+func (o splitOptions) modelsDurationDistributions() bool {
+	return o.durationEstimate == settings.DurationEstimateExpected || o.durationEstimate == settings.DurationEstimateP90
+}
+
+// applyDurationEstimate records the expected duration and the duration
+// variance of each test file whose P90 duration is known, when the planner
+// minimizes the expected or P90 wall time. Splits are scored by them in place
+// of the median weights, which the plan keeps everywhere else. Other test
+// files are modeled as always taking their median duration.
+func (tp *TestPlanner) applyDurationEstimate(estimate settings.DurationEstimate) {
+	tp.testFileExpectedWeights = nil
+	tp.testFileDurationVariances = nil
+	if estimate != settings.DurationEstimateExpected && estimate != settings.DurationEstimateP90 {
+		return
+	}
+
+	tp.testFileExpectedWeights = make(map[string]int, len(tp.testFileP90Weights))
+	tp.testFileDurationVariances = make(map[string]float64, len(tp.testFileP90Weights))
+	for testFile, p90Weight := range tp.testFileP90Weights {
+		weight, ok := tp.testFileWeights[testFile]
+		if !ok {
+			continue
+		}
+		distribution := durationDistribution{p50: float64(weight), p90: float64(p90Weight)}
+		tp.testFileExpectedWeights[testFile] = max(int(math.Round(distribution.mean())), 1)
+		tp.testFileDurationVariances[testFile] = distribution.variance()
+	}
+	slog.Info("Modeled test file durations from their P50 and P90 durations",
+		"durationEstimate", estimate, "testFilesCount", len(tp.testFileDurationVariances))
+}
+
+// modeledDurationEstimate returns the duration estimate of the plan when test
+// files are modeled from their P50 and P90 durations, or "" otherwise.
+func (tp *TestPlanner) modeledDurationEstimate() settings.DurationEstimate {
+	if tp.testFileDurationVariances == nil {
+		return ""
+	}
+	return settings.GetDurationEstimate()
+}
+
+// splitDurationModel returns the expected duration and the duration variance
+// of each runner split entry with a modeled duration. A test chunk takes its
+// share of the duration of its test file, so its expected duration is scaled
+// by that share and its variance by the square of it.
+func (tp *TestPlanner) splitDurationModel() (map[string]int, map[string]float64) {
+	if len(tp.testFileDurationVariances) == 0 || len(tp.testChunkWeights) == 0 {
+		return tp.testFileExpectedWeights, tp.testFileDurationVariances
+	}
+
+	expectedWeights := maps.Clone(tp.testFileExpectedWeights)
+	variances := maps.Clone(tp.testFileDurationVariances)
+	for chunk, chunkWeight := range tp.testChunkWeights {
+		testFile := framework.TestChunkFile(chunk)
+		variance, ok := tp.testFileDurationVariances[testFile]
+		if !ok || tp.testFileWeights[testFile] <= 0 {
+			continue
+		}
+		share := float64(chunkWeight) / float64(tp.testFileWeights[testFile])
+		expectedWeights[chunk] = max(int(math.Round(float64(tp.testFileExpectedWeights[testFile])*share)), 1)
+		variances[chunk] = variance * share * share
+	}
+	return expectedWeights, variances
+}
+
+// assignsByP90 reports whether files are assigned to runners by the P90
+// duration of the runner instead of its expected duration.
+func (o splitOptions) assignsByP90() bool {
+	return o.durationEstimate == settings.DurationEstimateP90 && len(o.durationVariances) > 0
+}
+
+// p90Finish returns when a runner of the given capacity finishes at the P90
+// duration of its files, whose durations sum to load on average with the
+// given variance.
+func p90Finish(load int, variance float64, capacity int) float64 {
+	return p90Load(load, variance) / float64(capacity)
+}
+
+// p90Load returns the P90 duration of files whose durations sum to load on
+// average with the given variance.
+func p90Load(load int, variance float64) float64 {
+	return float64(load) + z90*math.Sqrt(variance)
+}
+
+// sortedWeightedUnits returns the split units of testFileWeights and the
+// members of each group unit, as TestFileConstraints.sortedWeightedUnits
+// does, with the expected duration and the duration variance of each unit.
+func (o splitOptions) sortedWeightedUnits(testFileWeights map[string]int) ([]weightedTestFile, map[string][]string) {
+	files, members := o.constraints.sortedWeightedUnits(testFileWeights)
+	if len(o.durationVariances) == 0 {
+		return files, members
+	}
+	for i := range files {
+		unitFiles, ok := members[files[i].path]
+		if !ok {
+			unitFiles = []string{files[i].path}
+		}
+		for _, testFile := range unitFiles {
+			if expectedWeight, ok := o.expectedWeights[testFile]; ok {
+				files[i].weight += expectedWeight - testFileWeights[testFile]
+			}
+			files[i].variance += o.durationVariances[testFile]
+		}
+	}
+	if len(o.expectedWeights) > 0 {
+		slices.SortFunc(files, compareWeightedTestFiles)
+	}
+	return files, members
+}
+
+// distributionScore scores the split of files between runners from the
+// distributions of their durations. The duration of a runner is the sum of
+// those of its files, modeled as a normal distribution: its mean is the sum
+// of their means, its variance the sum of their variances. The wall time and
+// imbalance are those of the given estimate.
+func (b testSplitBuilder) distributionScore(runners [][]weightedTestFile, estimate settings.DurationEstimate) splitScore {
+	score := b.score()
+	minP90WallTime, maxP90WallTime := b.p90WallTimes(runners)
+	score.expectedWallTime = score.wallTime
+	score.p90WallTime = maxP90WallTime
+	if estimate == settings.DurationEstimateP90 {
+		score.wallTime = maxP90WallTime
+		score.imbalance = maxP90WallTime - minP90WallTime
+	}
+	return score
+}
+
+// p90WallTimes returns the shortest and longest P90 wall times of the runners.
+func (b testSplitBuilder) p90WallTimes(runners [][]weightedTestFile) (int, int) {
+	p90WallTimes := make([]runnerLoad, b.parallelRunners)
+	for i := range p90WallTimes {
+		class := b.runnerClass(i)
+		load, variance := 0, 0.0
+		for _, file := range runners[i] {
+			load += class.load(file)
+			variance += class.variance(file)
+		}
+		p90WallTimes[i] = runnerLoad{index: i, load: int(math.Ceil(p90Finish(load, variance, class.capacity)))}
+	}
+	minP90WallTime, maxP90WallTime, _ := loadStats(p90WallTimes)
+	return minP90WallTime, maxP90WallTime
+}
@@ -0,0 +1,249 @@
+package planner
+
+import (
+	"context"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/settings"
+	"github.com/DataDog/ddtest/internal/testoptimization"
+	"github.com/DataDog/ddtest/internal/testoptimization/api"
+)
+
+func TestDurationDistribution(t *testing.T) {
+	constant := durationDistribution{p50: 1000, p90: 1000}
+	if constant.mean() != 1000 || constant.variance() != 0 {
+		t.Errorf("expected a file without spread to have mean 1000 and variance 0, got %g and %g", constant.mean(), constant.variance())
+	}
+
+	spread := durationDistribution{p50: 1000, p90: 2000}
+	sigma := math.Log(2) / z90
+	if mean := spread.mean(); math.Abs(mean-1000*math.Exp(sigma*sigma/2)) > 1e-9 || mean <= 1000 {
+		t.Errorf("unexpected mean %g", mean)
+	}
+	expectedVariance := spread.mean() * spread.mean() * (math.Exp(sigma*sigma) - 1)
+	if variance := spread.variance(); math.Abs(variance-expectedVariance) > 1e-6 {
+		t.Errorf("variance = %g, want %g", variance, expectedVariance)
+	}
+}
+
+func TestApplyDurationEstimate(t *testing.T) {
+	tp := newTestPlannerWithDefaults()
+	tp.testFileWeights = map[string]int{"spec/volatile_spec.rb": 1000, "spec/steady_spec.rb": 1000}
+	tp.testFileP90Weights = map[string]int{"spec/volatile_spec.rb": 2000}
+
+	tp.applyDurationEstimate(settings.DurationEstimateP50)
+	if tp.testFileExpectedWeights != nil || tp.testFileDurationVariances != nil {
+		t.Fatalf("expected the p50 estimate to model no file, got %v and %v", tp.testFileExpectedWeights, tp.testFileDurationVariances)
+	}
+
+	tp.applyDurationEstimate(settings.DurationEstimateP90)
+	distribution := durationDistribution{p50: 1000, p90: 2000}
+	if len(tp.testFileExpectedWeights) != 1 || tp.testFileExpectedWeights["spec/volatile_spec.rb"] != int(math.Round(distribution.mean())) {
+		t.Errorf("expected the volatile file to be modeled with its expected duration, got %v", tp.testFileExpectedWeights)
+	}
+	if !maps.Equal(tp.testFileWeights, map[string]int{"spec/volatile_spec.rb": 1000, "spec/steady_spec.rb": 1000}) {
+		t.Errorf("expected the test files to keep their median weights, got %v", tp.testFileWeights)
+	}
+	if len(tp.testFileDurationVariances) != 1 || tp.testFileDurationVariances["spec/volatile_spec.rb"] != distribution.variance() {
+		t.Errorf("unexpected variances %v", tp.testFileDurationVariances)
+	}
+}
+
+func TestSplitDurationModel_ScalesTestChunks(t *testing.T) {
+	tp := newTestPlannerWithDefaults()
+	tp.testFileWeights = map[string]int{"spec/slow_spec.rb": 1000}
+	tp.testFileExpectedWeights = map[string]int{"spec/slow_spec.rb": 2000}
+	tp.testFileDurationVariances = map[string]float64{"spec/slow_spec.rb": 400 * 400}
+	tp.testChunkWeights = map[string]int{"spec/slow_spec.rb[1:1]": 250, "spec/slow_spec.rb[1:2]": 750}
+
+	expectedWeights, variances := tp.splitDurationModel()
+	if expectedWeights["spec/slow_spec.rb[1:1]"] != 500 || expectedWeights["spec/slow_spec.rb[1:2]"] != 1500 {
+		t.Errorf("expected the chunks to take their share of the expected duration, got %v", expectedWeights)
+	}
+	if variances["spec/slow_spec.rb[1:1]"] != 100*100 || variances["spec/slow_spec.rb[1:2]"] != 300*300 {
+		t.Errorf("expected the chunk variances to scale by the square of their share, got %v", variances)
+	}
+	if tp.testFileExpectedWeights["spec/slow_spec.rb[1:1]"] != 0 {
+		t.Errorf("expected the test file model to be left unchanged, got %v", tp.testFileExpectedWeights)
+	}
+}
+
+func TestCalculateParallelRunnerSplitSelection_DurationEstimate(t *testing.T) {
+	weights := map[string]int{"spec/a_spec.rb": 1000, "spec/b_spec.rb": 1000}
+	variances := map[string]float64{"spec/a_spec.rb": 1000 * 1000, "spec/b_spec.rb": 1000 * 1000}
+	overhead := 1200 * time.Millisecond
+
+	expected := calculateParallelRunnerSplitSelection(weights, 1, 2, overhead, 0,
+		splitOptions{durationEstimate: settings.DurationEstimateExpected, durationVariances: variances})
+	if expected.selected.parallelRunners != 1 || expected.selected.wallTime != 2000 {
+		t.Fatalf("expected the expected wall time to select 1 runner at 2s, got %+v", expected.selected)
+	}
+	if p90 := expected.selected.p90WallTime; p90 != int(math.Ceil(2000+z90*math.Sqrt(2)*1000)) {
+		t.Errorf("expected the P90 wall time of the sum of both files, got %d", p90)
+	}
+
+	p90 := calculateParallelRunnerSplitSelection(weights, 1, 2, overhead, 0,
+		splitOptions{durationEstimate: settings.DurationEstimateP90, durationVariances: variances})
+	if p90.selected.parallelRunners != 2 {
+		t.Fatalf("expected the P90 wall time to select 2 runners, got %+v", p90.selected)
+	}
+	if p90.selected.expectedWallTime != 1000 || p90.selected.wallTime != p90.selected.p90WallTime || p90.selected.imbalance != 0 {
+		t.Errorf("unexpected P90 score %+v", p90.selected)
+	}
+}
+
+func TestDistributeTestFiles_P90DurationEstimateSpreadsVariableFiles(t *testing.T) {
+	// By their expected durations alone, both files whose durations vary
+	// land on runner 0.
+	weights := map[string]int{"spec/a_spec.rb": 100, "spec/b_spec.rb": 100, "spec/c_spec.rb": 100, "spec/d_spec.rb": 100}
+	variances := map[string]float64{"spec/a_spec.rb": 200 * 200, "spec/c_spec.rb": 200 * 200}
+
+	for _, optimizer := range []settings.SplitOptimizer{settings.SplitOptimizerGreedy, settings.SplitOptimizerLocalSearch} {
+		t.Run(string(optimizer), func(t *testing.T) {
+			expected := splitOptions{optimizer: optimizer, durationEstimate: settings.DurationEstimateExpected, durationVariances: variances}
+			expectedScore := scoreSortedWeightedRunnerSplit(weightedUnits(expected, weights), 2, expected)
+			p90 := splitOptions{optimizer: optimizer, durationEstimate: settings.DurationEstimateP90, durationVariances: variances}
+			p90Score := scoreSortedWeightedRunnerSplit(weightedUnits(p90, weights), 2, p90)
+
+			if want := int(math.Ceil(200 + z90*math.Sqrt(2)*200)); expectedScore.p90WallTime != want {
+				t.Fatalf("expected the expected duration split to run both variable files together, got P90 %d, want %d", expectedScore.p90WallTime, want)
+			}
+			if want := int(math.Ceil(200 + z90*200)); p90Score.p90WallTime != want || p90Score.expectedWallTime != 200 {
+				t.Errorf("expected the P90 split to run the variable files apart, got %+v, want P90 %d", p90Score, want)
+			}
+
+			distribution, _ := p90.distributeTestFiles(weights, 2)
+			for _, runner := range distribution {
+				if len(runner) != 2 || (slices.Contains(runner, "spec/a_spec.rb") == slices.Contains(runner, "spec/c_spec.rb")) {
+					t.Errorf("expected spec/a_spec.rb and spec/c_spec.rb on different runners, got %v", distribution)
+				}
+			}
+		})
+	}
+}
+
+func TestSortedWeightedUnits_ScoresByExpectedWeights(t *testing.T) {
+	weights := map[string]int{"spec/steady_spec.rb": 1500, "spec/volatile_spec.rb": 1000, "spec/chunked_spec.rb[1:1]": 500}
+	options := splitOptions{
+		durationEstimate:  settings.DurationEstimateP90,
+		expectedWeights:   map[string]int{"spec/volatile_spec.rb": 2000, "spec/chunked_spec.rb[1:1]": 600},
+		durationVariances: map[string]float64{"spec/volatile_spec.rb": 1000 * 1000, "spec/chunked_spec.rb[1:1]": 100 * 100},
+	}
+
+	expected := []weightedTestFile{
+		{path: "spec/volatile_spec.rb", weight: 2000, variance: 1000 * 1000},
+		{path: "spec/steady_spec.rb", weight: 1500},
+		{path: "spec/chunked_spec.rb[1:1]", weight: 600, variance: 100 * 100},
+	}
+	if files := weightedUnits(options, weights); !slices.Equal(files, expected) {
+		t.Errorf("expected units weighted by their expected durations %v, got %v", expected, files)
+	}
+	if weights["spec/volatile_spec.rb"] != 1000 {
+		t.Errorf("expected the weights to be left unchanged, got %v", weights)
+	}
+}
+
+func weightedUnits(options splitOptions, weights map[string]int) []weightedTestFile {
+	files, _ := options.sortedWeightedUnits(weights)
+	return files
+}
+
+func TestDistributionScore_SerialFileOccupiesCINodeWorkers(t *testing.T) {
+	options := splitOptions{ciNodeCapacities: map[int]int{0: 2}, ciNodeWorkers: 2, durationEstimate: settings.DurationEstimateP90}
+	builder := options.newTestSplitBuilder(1)
+	builder.addFile(weightedTestFile{path: "spec/es_spec.rb", weight: 1000, serial: true, variance: 100 * 100})
+
+	score := builder.distributionScore([][]weightedTestFile{{{path: "spec/es_spec.rb", weight: 1000, serial: true, variance: 100 * 100}}}, settings.DurationEstimateP90)
+	if score.expectedWallTime != 1000 || score.p90WallTime != int(math.Ceil(1000+z90*100)) {
+		t.Fatalf("expected a serial file to take its whole duration on the CI node, got %+v", score)
+	}
+}
+
+func TestPrintRunnerSplitPlanningReport_DurationEstimate(t *testing.T) {
+	var output strings.Builder
+	split := splitScore{parallelRunners: 2, wallTime: 3000, imbalance: 0, totalRuntime: 4000, expectedWallTime: 2000, p90WallTime: 3000}
+	printRunnerSplitPlanningReport(&output, PlanReportData{
+		SplitSelection: splitSelection{
+			selected:          split,
+			bestWithoutTarget: split,
+			candidates:        []splitScore{split},
+			durationEstimate:  settings.DurationEstimateP90,
+			available:         true,
+		},
+	})
+	printDurationEstimatesPlanningReport(&output, durationApplicationReport{
+		Available:        true,
+		DurationEstimate: settings.DurationEstimateP90,
+		ModeledTestFiles: 2,
+	})
+
+	for _, want := range []string{
+		"Expected wall time: 2s",
+		"P90 wall time: 3s",
+		"Selection score: 3s (P90 wall time + modeled CI overhead)",
+		"Modeled from P50 and P90: 2 files (minimizing P90 wall time)",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, output.String())
+		}
+	}
+}
+
+func TestTestPlanner_Plan_P90DurationEstimate(t *testing.T) {
+	for _, tt := range []struct {
+		estimate        settings.DurationEstimate
+		parallelRunners string
+	}{
+		{estimate: settings.DurationEstimateP50, parallelRunners: "1"},
+		{estimate: settings.DurationEstimateExpected, parallelRunners: "1"},
+		{estimate: settings.DurationEstimateP90, parallelRunners: "2"},
+	} {
+		t.Run(string(tt.estimate), func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			setPlannerForceFullTestDiscovery(t, true)
+			setPlannerEnv(t, map[string]string{
+				"DD_TEST_OPTIMIZATION_RUNNER_MIN_PARALLELISM":   "1",
+				"DD_TEST_OPTIMIZATION_RUNNER_MAX_PARALLELISM":   "2",
+				"DD_TEST_OPTIMIZATION_RUNNER_DURATION_ESTIMATE": string(tt.estimate),
+				"DD_TEST_OPTIMIZATION_RUNNER_CI_JOB_OVERHEAD":   "1200ms",
+			})
+
+			mockFramework := &MockFramework{
+				FrameworkName:    "rspec",
+				TestPatternValue: "spec/**/*_spec.rb",
+				TestFiles:        []string{"spec/browser_a_spec.rb", "spec/browser_b_spec.rb"},
+				Tests: []testoptimization.Test{
+					{Module: "rspec", Suite: "BrowserA", Name: "a", SuiteSourceFile: "spec/browser_a_spec.rb"},
+					{Module: "rspec", Suite: "BrowserB", Name: "b", SuiteSourceFile: "spec/browser_b_spec.rb"},
+				},
+			}
+			volatile := api.DurationPercentiles{P50: "1000000000", P90: "2000000000"}
+			mockOptimizationClient := &MockTestOptimizationClient{
+				Settings: testOptimizationSettings(true, true, false),
+				Durations: map[string]map[string]api.TestSuiteDurationInfo{
+					"rspec": {
+						"BrowserA": {SourceFile: "spec/browser_a_spec.rb", Duration: volatile},
+						"BrowserB": {SourceFile: "spec/browser_b_spec.rb", Duration: volatile},
+					},
+				},
+			}
+			mockPlatform := &MockPlatform{
+				PlatformName: "ruby",
+				Tags:         map[string]string{"platform": "ruby"},
+				Framework:    mockFramework,
+			}
+
+			runner := NewWithDependencies(&MockPlatformDetector{Platform: mockPlatform}, mockOptimizationClient, newDefaultMockCIProviderDetector())
+			if err := runner.Plan(context.Background()); err != nil {
+				t.Fatalf("Plan() should not return error, got: %v", err)
+			}
+			assertFileContent(t, constants.ParallelRunnersOutputPath, tt.parallelRunners)
+		})
+	}
+}
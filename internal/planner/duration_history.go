@@ -0,0 +1,197 @@
+package planner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/settings"
+)
+
+const (
+	// durationHistoryHalfLife is the age at which an observed duration counts
+	// half as much as one observed now.
+	durationHistoryHalfLife = 7 * 24 * time.Hour
+	// durationHistoryMaxObservations is the number of most recent observations
+	// of a test file used to estimate its duration.
+	durationHistoryMaxObservations = 20
+	// durationHistoryMaxLineBytes bounds one line of the duration history file.
+	durationHistoryMaxLineBytes = 1024 * 1024
+)
+
+// DurationHistoryEntry is one line of the duration history file: the duration
+// of a test file observed by one ddtest run.
+type DurationHistoryEntry struct {
+	TestFile   string    `json:"testFile"`
+	DurationMs int64     `json:"durationMs"`
+	RecordedAt time.Time `json:"recordedAt"`
+	// Source tells how the duration was observed, such as "junit" for JUnit
+	// reports.
+	Source string `json:"source"`
+}
+
+type durationObservation struct {
+	durationMs int64
+	recordedAt time.Time
+}
+
+// AppendDurationHistory appends entries to the JSONL duration history file at
+// path, creating the file when it does not exist. Lines written by earlier
+// runs are never rewritten, so the file can be restored from a CI cache, grown
+// by a run, and saved again.
+func AppendDurationHistory(path string, entries []DurationHistoryEntry) error {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		if entry.TestFile == "" || entry.DurationMs <= 0 {
+			continue
+		}
+		entry.RecordedAt = entry.RecordedAt.UTC()
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal duration history entry for %s: %w", entry.TestFile, err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create duration history directory %s: %w", dir, err)
+		}
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open duration history %s: %w", path, err)
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to append to duration history %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close duration history %s: %w", path, err)
+	}
+	return nil
+}
+
+// readDurationHistory returns the most recent observations of each test file
+// in the duration history file at path, oldest first. Lines that cannot be
+// parsed, such as a line cut short by an interrupted run, are skipped.
+func readDurationHistory(path string) (map[string][]durationObservation, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open duration history %s: %w", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	history := make(map[string][]durationObservation)
+	skippedLines := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), durationHistoryMaxLineBytes)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry DurationHistoryEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil || entry.TestFile == "" || entry.DurationMs <= 0 {
+			skippedLines++
+			continue
+		}
+		history[entry.TestFile] = append(history[entry.TestFile], durationObservation{
+			durationMs: entry.DurationMs,
+			recordedAt: entry.RecordedAt,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read duration history %s: %w", path, err)
+	}
+	if skippedLines > 0 {
+		slog.Warn("Skipped invalid duration history lines", "path", path, "linesCount", skippedLines)
+	}
+
+	for testFile, observations := range history {
+		slices.SortStableFunc(observations, func(a, b durationObservation) int {
+			return a.recordedAt.Compare(b.recordedAt)
+		})
+		if len(observations) > durationHistoryMaxObservations {
+			observations = observations[len(observations)-durationHistoryMaxObservations:]
+		}
+		history[testFile] = observations
+	}
+	return history, nil
+}
+
+// loadDurationHistory loads the duration history file, when one is
+// configured. A missing file is not an error: the first run creates it.
+func (tp *TestPlanner) loadDurationHistory() {
+	tp.durationHistory = nil
+	path := settings.GetDurationHistory()
+	if path == "" {
+		return
+	}
+
+	history, err := readDurationHistory(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.Info("Duration history not found; it is created by ddtest run", "path", path)
+		return
+	}
+	if err != nil {
+		slog.Warn("Ignoring unreadable duration history", "path", path, "error", err)
+		return
+	}
+	slog.Info("Loaded duration history", "path", path, "testFilesCount", len(history))
+	tp.durationHistory = history
+	tp.durationHistoryLoadedAt = time.Now()
+}
+
+// applyDurationHistory merges the observed durations of a file into its
+// estimate. Each observation counts for half as much every
+// durationHistoryHalfLife; a backend duration counts as one observation made
+// now, since the backend already aggregates recent runs. Default estimates
+// are replaced. Test-level skips scale the observations down the same way
+// they scale backend durations.
+func (tp *TestPlanner) applyDurationHistory(testFile string, estimate testFileWeightEstimate) testFileWeightEstimate {
+	observations := tp.durationHistory[testFile]
+	if len(observations) == 0 {
+		return estimate
+	}
+
+	fraction := tp.runnableTestFraction(testFile)
+	var weightedSum, totalWeight float64
+	for _, observation := range observations {
+		age := max(tp.durationHistoryLoadedAt.Sub(observation.recordedAt), 0)
+		recency := math.Pow(0.5, float64(age)/float64(durationHistoryHalfLife))
+		weightedSum += recency * float64(observation.durationMs) * fraction
+		totalWeight += recency
+	}
+	if estimate.source == testFileDurationSourceKnown {
+		weightedSum += float64(estimate.weight)
+		totalWeight++
+	}
+	if totalWeight == 0 {
+		return estimate
+	}
+
+	weight := max(int(math.Round(weightedSum/totalWeight)), 1)
+	merged := testFileWeightEstimate{
+		weight: weight,
+		source: testFileDurationSourceHistory,
+	}
+	if estimate.source == testFileDurationSourceKnown && estimate.p90Weight > 0 {
+		merged.p90Weight = int(math.Round(float64(estimate.p90Weight) * float64(weight) / float64(estimate.weight)))
+	}
+	return merged
+}
@@ -0,0 +1,124 @@
+package planner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendDurationHistory_AppendsToEarlierRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "durations.jsonl")
+	firstRun := time.Date(2026, 1, 14, 10, 0, 0, 0, time.UTC)
+	secondRun := firstRun.Add(24 * time.Hour)
+
+	if err := AppendDurationHistory(path, []DurationHistoryEntry{
+		{TestFile: "spec/a_spec.rb", DurationMs: 2000, RecordedAt: firstRun, Source: "junit"},
+		{TestFile: "spec/empty_spec.rb", DurationMs: 0, RecordedAt: firstRun, Source: "junit"},
+	}); err != nil {
+		t.Fatalf("AppendDurationHistory() error = %v", err)
+	}
+	// An interrupted run can leave a partial line behind.
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = file.WriteString(`{"testFile": "spec/b_spec.rb", "durat` + "\n")
+	_ = file.Close()
+	if err := AppendDurationHistory(path, []DurationHistoryEntry{
+		{TestFile: "spec/a_spec.rb", DurationMs: 3000, RecordedAt: secondRun, Source: "worker"},
+	}); err != nil {
+		t.Fatalf("AppendDurationHistory() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), `{"testFile":"spec/a_spec.rb","durationMs":2000,"recordedAt":"2026-01-14T10:00:00Z","source":"junit"}`+"\n") {
+		t.Errorf("expected the first run's entry to stay first, got:\n%s", data)
+	}
+
+	history, err := readDurationHistory(path)
+	if err != nil {
+		t.Fatalf("readDurationHistory() error = %v", err)
+	}
+	observations := history["spec/a_spec.rb"]
+	if len(history) != 1 || len(observations) != 2 || observations[0].durationMs != 2000 || !observations[1].recordedAt.Equal(secondRun) {
+		t.Errorf("unexpected duration history %+v", history)
+	}
+}
+
+func TestReadDurationHistory_KeepsMostRecentObservations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "durations.jsonl")
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var entries []DurationHistoryEntry
+	// Newest first, to check observations are sorted by time.
+	for i := durationHistoryMaxObservations + 4; i > 0; i-- {
+		entries = append(entries, DurationHistoryEntry{TestFile: "spec/a_spec.rb", DurationMs: int64(i), RecordedAt: start.Add(time.Duration(i) * time.Hour)})
+	}
+	if err := AppendDurationHistory(path, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := readDurationHistory(path)
+	if err != nil {
+		t.Fatalf("readDurationHistory() error = %v", err)
+	}
+	observations := history["spec/a_spec.rb"]
+	if len(observations) != durationHistoryMaxObservations || observations[0].durationMs != 5 || observations[len(observations)-1].durationMs != durationHistoryMaxObservations+4 {
+		t.Errorf("expected the %d most recent observations, oldest first, got %+v", durationHistoryMaxObservations, observations)
+	}
+}
+
+func TestTestPlanner_DurationHistoryMergesWithBackendDurations(t *testing.T) {
+	runner := newLocalDurationsTestPlanner()
+	runner.localTestFileWeights = map[string]int{"spec/partial_spec.rb": 9000}
+	runner.durationHistoryLoadedAt = time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	runner.durationHistory = map[string][]durationObservation{
+		// Observed a week ago, so it counts half as much as the backend
+		// duration.
+		"spec/known_spec.rb": {{durationMs: 5000, recordedAt: runner.durationHistoryLoadedAt.Add(-durationHistoryHalfLife)}},
+		"spec/partial_spec.rb": {
+			{durationMs: 4000, recordedAt: runner.durationHistoryLoadedAt.Add(-2 * durationHistoryHalfLife)},
+			{durationMs: 8000, recordedAt: runner.durationHistoryLoadedAt},
+		},
+	}
+
+	weights := runner.calculateFileWeights()
+
+	if weights["spec/known_spec.rb"] != 3000 || runner.testFileDurationSources["spec/known_spec.rb"] != testFileDurationSourceHistory {
+		t.Errorf("expected history merged with backend duration, got weight=%d source=%q", weights["spec/known_spec.rb"], runner.testFileDurationSources["spec/known_spec.rb"])
+	}
+	// (4000*0.25 + 8000) / 1.25 = 7200, scaled to the 3 of 4 runnable tests.
+	if weights["spec/partial_spec.rb"] != 5400 || runner.testFileDurationSources["spec/partial_spec.rb"] != testFileDurationSourceHistory {
+		t.Errorf("expected history to replace the default estimate and local duration, got weight=%d source=%q", weights["spec/partial_spec.rb"], runner.testFileDurationSources["spec/partial_spec.rb"])
+	}
+	if weights["spec/unknown_spec.rb"] != 1000 || runner.testFileDurationSources["spec/unknown_spec.rb"] != testFileDurationSourceDefault {
+		t.Errorf("expected default estimate without history, got weight=%d source=%q", weights["spec/unknown_spec.rb"], runner.testFileDurationSources["spec/unknown_spec.rb"])
+	}
+}
+
+func TestTestPlanner_DurationHistoryScalesP90Duration(t *testing.T) {
+	runner := &TestPlanner{
+		durationHistoryLoadedAt: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC),
+	}
+	runner.durationHistory = map[string][]durationObservation{
+		"spec/a_spec.rb": {{durationMs: 3000, recordedAt: runner.durationHistoryLoadedAt}},
+	}
+
+	estimate := runner.applyDurationHistory("spec/a_spec.rb", testFileWeightEstimate{weight: 1000, p90Weight: 1500, source: testFileDurationSourceKnown})
+	if estimate.weight != 2000 || estimate.p90Weight != 3000 || estimate.source != testFileDurationSourceHistory {
+		t.Errorf("unexpected estimate %+v", estimate)
+	}
+}
+
+func TestPrintDurationEstimatesPlanningReport_HistoryDurations(t *testing.T) {
+	var output strings.Builder
+	printDurationEstimatesPlanningReport(&output, durationApplicationReport{Available: true, HistoryDurationsApplied: 2})
+
+	if !strings.Contains(output.String(), "    History durations used: 2 files\n") {
+		t.Errorf("expected history durations in report, got:\n%s", output.String())
+	}
+}
@@ -0,0 +1,265 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/errcode"
+	"github.com/DataDog/ddtest/internal/framework"
+	"github.com/DataDog/ddtest/internal/testoptimization"
+	"github.com/DataDog/ddtest/internal/testoptimization/api"
+	"github.com/DataDog/ddtest/internal/utils"
+)
+
+// planExplanation is the stored plan data that explains planning decisions.
+type planExplanation struct {
+	cache         testOptimizationPlanCache
+	runnerSplits  [][]string
+	skippables    *api.Skippables
+	disabledTests map[string]bool
+}
+
+// Explain prints why the stored plan runs or skips a test file or suite, based
+// on the plan cache, the runner splits and the cached Datadog responses.
+func Explain(w io.Writer, target string) error {
+	var explanation planExplanation
+	if err := readAndNormalizeTestOptimizationPlanCache(&explanation.cache); err != nil {
+		return errcode.WithCode(errcode.ExplainPlanLoadFailed, fmt.Errorf("test optimization plan is not available, run ddtest plan first: %w", err))
+	}
+
+	runnerSplits, err := readRunnerSplits()
+	if err != nil {
+		return errcode.WithCode(errcode.ExplainTestSplitsReadFailed, err)
+	}
+	explanation.runnerSplits = runnerSplits
+
+	cacheManager := testoptimization.NewCacheManager()
+	if skippables, err := cacheManager.ReadSkippableTestsCache(); err == nil {
+		explanation.skippables = &skippables
+	}
+	if disabledTests, err := cacheManager.ReadDisabledTestsCache(); err == nil {
+		explanation.disabledTests = disabledTests
+	}
+
+	testFiles := explanation.matchTestFiles(target)
+	if len(testFiles) == 0 {
+		return errcode.New(errcode.ExplainTargetNotFound, fmt.Sprintf("%s is not a test file or suite in the test optimization plan; it was not discovered or was excluded", target))
+	}
+
+	reportFprintf(w, "+++ DDTest: explain %s\n", target)
+	for _, match := range testFiles {
+		reportFprintln(w)
+		explanation.print(w, match)
+	}
+	return nil
+}
+
+// explainedTestFile is a test file that matched the explain target, with the
+// suites to show.
+type explainedTestFile struct {
+	path   string
+	suites []testSuiteKey
+}
+
+func (e planExplanation) matchTestFiles(target string) []explainedTestFile {
+	testFile := utils.NormalizePath(utils.StripCwdSubdirPrefix(target))
+	if _, ok := e.cache.TestFileWeights[testFile]; ok {
+		return []explainedTestFile{{path: testFile, suites: e.sortedSuites(e.cache.SuitesBySourceFile[testFile])}}
+	}
+	if suites, ok := e.cache.SuitesBySourceFile[testFile]; ok {
+		return []explainedTestFile{{path: testFile, suites: e.sortedSuites(suites)}}
+	}
+
+	suitesByFile := make(map[string][]testSuiteKey)
+	for key, aggregate := range e.cache.SuiteAggregates {
+		if key.Suite == target {
+			suitesByFile[aggregate.SourceFile] = append(suitesByFile[aggregate.SourceFile], key)
+		}
+	}
+	matches := make([]explainedTestFile, 0, len(suitesByFile))
+	for path, suites := range suitesByFile {
+		matches = append(matches, explainedTestFile{path: path, suites: e.sortedSuites(suites)})
+	}
+	slices.SortFunc(matches, func(a, b explainedTestFile) int {
+		return strings.Compare(a.path, b.path)
+	})
+	return matches
+}
+
+func (e planExplanation) sortedSuites(suites []testSuiteKey) []testSuiteKey {
+	sorted := slices.Clone(suites)
+	slices.SortFunc(sorted, func(a, b testSuiteKey) int {
+		if a.Module != b.Module {
+			return strings.Compare(a.Module, b.Module)
+		}
+		return strings.Compare(a.Suite, b.Suite)
+	})
+	return sorted
+}
+
+func (e planExplanation) print(w io.Writer, match explainedTestFile) {
+	reportFprintf(w, "Test file: %s\n", valueOrNotAvailable(match.path))
+	reportFprintf(w, "  Discovery method: %s\n", valueOrNotAvailable(string(e.cache.DiscoveryMode)))
+
+	weight, runs := e.cache.TestFileWeights[match.path]
+	if runs {
+		reportFprintln(w, "  Runs: yes")
+		reportFprintf(w, "  Runner: %s\n", e.formatRunner(match.path))
+		reportFprintf(w, "  Estimated duration: %s (%s)\n", formatDuration(time.Duration(weight)*time.Millisecond), formatDurationSource(e.cache.TestFileDurationSources[match.path]))
+	} else {
+		reportFprintln(w, "  Runs: no (every test is skipped)")
+	}
+
+	if len(match.suites) == 0 {
+		reportFprintln(w, "  Suites: none discovered")
+		return
+	}
+	reportFprintln(w, "  Suites")
+	for _, key := range match.suites {
+		e.printSuite(w, key)
+	}
+}
+
+func (e planExplanation) printSuite(w io.Writer, key testSuiteKey) {
+	aggregate := e.cache.SuiteAggregates[key]
+	reportFprintf(w, "    %s\n", formatSuiteKey(key))
+	reportFprintf(w, "      Tests: %s\n", formatCount(aggregate.NumTests))
+	reportFprintf(w, "      Duration estimate: %s (%s)\n", formatDuration(durationFromNanoseconds(aggregate.TotalDuration)), formatDurationSource(aggregate.DurationSource))
+	reportFprintf(w, "      Skipped by TIA: %s\n", formatCount(aggregate.NumTestsSkipped-aggregate.NumTestsDisabled))
+	reportFprintf(w, "      Skipped as disabled: %s\n", formatCount(aggregate.NumTestsDisabled))
+	if aggregate.UnskippableTestsKept > 0 {
+		reportFprintf(w, "      Unskippable marker: kept %s runnable\n", formatCountWithUnit(aggregate.UnskippableTestsKept, "test", "tests"))
+	} else {
+		reportFprintln(w, "      Unskippable marker: no effect")
+	}
+	reportFprintf(w, "      TIA skippables from Datadog: %s\n", e.formatTIASkippables(key))
+
+	disabledTests := e.suiteDisabledTests(key)
+	if e.disabledTests == nil {
+		reportFprintln(w, "      Disabled tests from Datadog: not available")
+		return
+	}
+	reportFprintf(w, "      Disabled tests from Datadog: %s\n", formatCount(len(disabledTests)))
+	for _, test := range disabledTests {
+		reportFprintf(w, "        %s\n", test)
+	}
+}
+
+func (e planExplanation) formatRunner(testFile string) string {
+	runners := make([]string, 0)
+	chunks := 0
+	for runner, entries := range e.runnerSplits {
+		assigned := false
+		for _, entry := range entries {
+			if entry == testFile {
+				assigned = true
+			} else if chunkFile, _, ok := framework.ParseTestChunk(entry); ok && chunkFile == testFile {
+				assigned = true
+				chunks++
+			}
+		}
+		if assigned {
+			runners = append(runners, strconv.Itoa(runner))
+		}
+	}
+	if len(runners) == 0 {
+		return "not assigned"
+	}
+	if chunks > 0 {
+		return fmt.Sprintf("%s of %d (%s)", strings.Join(runners, ", "), len(e.runnerSplits), formatCountWithUnit(chunks, "test chunk", "test chunks"))
+	}
+	return fmt.Sprintf("%s of %d", runners[0], len(e.runnerSplits))
+}
+
+func (e planExplanation) formatTIASkippables(key testSuiteKey) string {
+	if e.skippables == nil {
+		return "not available"
+	}
+	if e.skippables.Suites[api.SkippableSuite{Module: key.Module, Suite: key.Suite}] {
+		return "whole suite"
+	}
+	prefix := key.Module + "." + key.Suite + "."
+	count := 0
+	for test := range e.skippables.Tests {
+		if strings.HasPrefix(test, prefix) {
+			count++
+		}
+	}
+	return formatCountWithUnit(count, "test", "tests")
+}
+
+func (e planExplanation) suiteDisabledTests(key testSuiteKey) []string {
+	prefix := key.Module + "." + key.Suite + "."
+	tests := make([]string, 0)
+	for test, disabled := range e.disabledTests {
+		if disabled && strings.HasPrefix(test, prefix) {
+			tests = append(tests, strings.TrimPrefix(test, prefix))
+		}
+	}
+	slices.Sort(tests)
+	return tests
+}
+
+func formatSuiteKey(key testSuiteKey) string {
+	if key.Module == "" {
+		return key.Suite
+	}
+	return key.Module + " / " + key.Suite
+}
+
+func formatDurationSource(source testFileDurationSource) string {
+	return valueOrNotAvailable(string(source))
+}
+
+// readRunnerSplits returns the test files of each runner split, indexed by
+// runner.
+func readRunnerSplits() ([][]string, error) {
+	splitFiles, err := readRunnerSplitFiles(constants.TestsSplitDir)
+	if err != nil {
+		return nil, err
+	}
+
+	splits := make([][]string, 0, len(splitFiles))
+	for index, testFiles := range splitFiles {
+		for len(splits) <= index {
+			splits = append(splits, nil)
+		}
+		splits[index] = testFiles
+	}
+	return splits, nil
+}
+
+// readRunnerSplitFiles returns the test files of each runner-N file in
+// splitDir, keyed by N.
+func readRunnerSplitFiles(splitDir string) (map[int][]string, error) {
+	entries, err := os.ReadDir(splitDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tests split directory %s: %w", splitDir, err)
+	}
+
+	splits := make(map[int][]string, len(entries))
+	for _, entry := range entries {
+		index, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), "runner-"))
+		if err != nil || entry.IsDir() || index < 0 {
+			continue
+		}
+		testFiles, err := readPlanFileLines(filepath.Join(splitDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read runner split %s: %w", entry.Name(), err)
+		}
+		splits[index] = testFiles
+	}
+	return splits, nil
+}
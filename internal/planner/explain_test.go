@@ -0,0 +1,201 @@
+package planner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/errcode"
+	"github.com/DataDog/ddtest/internal/testoptimization"
+)
+
+func writeExplainTestPlan(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+
+	userKey := testSuiteKey{Module: "rspec", Suite: "User"}
+	adminKey := testSuiteKey{Module: "rspec", Suite: "Admin"}
+	cache := testOptimizationPlanCache{
+		SuiteAggregates: map[testSuiteKey]testSuiteAggregate{
+			userKey: {
+				Module:            "rspec",
+				Suite:             "User",
+				SourceFile:        "spec/models/user_spec.rb",
+				TotalDuration:     4e9,
+				EstimatedDuration: 2e9,
+				DurationSource:    testFileDurationSourceKnown,
+				NumTests:          4,
+				NumTestsSkipped:   2,
+				NumTestsDisabled:  1,
+			},
+			adminKey: {
+				Module:          "rspec",
+				Suite:           "Admin",
+				SourceFile:      "spec/models/admin_spec.rb",
+				TotalDuration:   3e9,
+				DurationSource:  testFileDurationSourceDefault,
+				NumTests:        3,
+				NumTestsSkipped: 3,
+			},
+		},
+		TestFileWeights: map[string]int{
+			"spec/models/user_spec.rb": 2000,
+			"spec/other_spec.rb":       1000,
+		},
+		TestFileDurationSources: map[string]testFileDurationSource{
+			"spec/models/user_spec.rb": testFileDurationSourceKnown,
+			"spec/other_spec.rb":       testFileDurationSourceDefault,
+		},
+		DiscoveryMode: discoveryModeFull,
+	}
+	if err := testoptimization.NewCacheManager().StoreTestOptimizationPlanCache(cache); err != nil {
+		t.Fatal(err)
+	}
+
+	writePlanTestFile(t, filepath.Join(constants.TestsSplitDir, "runner-0"), "spec/other_spec.rb\n")
+	writePlanTestFile(t, filepath.Join(constants.TestsSplitDir, "runner-1"), "spec/models/user_spec.rb\n")
+	writePlanTestFile(t, filepath.Join(constants.HTTPCacheDir, "skippable_tests.json"), `{
+  "data": [
+    {"type": "test", "attributes": {"suite": "User", "name": "saves", "configurations": {"test.bundle": "rspec"}}},
+    {"type": "test", "attributes": {"suite": "Admin", "name": "logs in", "configurations": {"test.bundle": "rspec"}}}
+  ]
+}`)
+	writePlanTestFile(t, filepath.Join(constants.HTTPCacheDir, "test_management.json"), `{
+  "data": {
+    "attributes": {
+      "modules": {
+        "rspec": {
+          "suites": {
+            "User": {
+              "tests": {
+                "validates email": {"properties": {"disabled": true}},
+                "is being fixed": {"properties": {"disabled": true, "attempt_to_fix": true}}
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`)
+}
+
+func writePlanTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExplain_TestFile(t *testing.T) {
+	writeExplainTestPlan(t)
+
+	var output strings.Builder
+	if err := Explain(&output, "spec/models/user_spec.rb"); err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+
+	expected := `+++ DDTest: explain spec/models/user_spec.rb
+
+Test file: spec/models/user_spec.rb
+  Discovery method: full
+  Runs: yes
+  Runner: 1 of 2
+  Estimated duration: 2s (known)
+  Suites
+    rspec / User
+      Tests: 4
+      Duration estimate: 4s (known)
+      Skipped by TIA: 1
+      Skipped as disabled: 1
+      Unskippable marker: no effect
+      TIA skippables from Datadog: 1 test
+      Disabled tests from Datadog: 1
+        validates email
+`
+	if output.String() != expected {
+		t.Fatalf("unexpected explain output:\n%s\nwant:\n%s", output.String(), expected)
+	}
+}
+
+func TestExplain_TestFileInTestChunks(t *testing.T) {
+	writeExplainTestPlan(t)
+	writePlanTestFile(t, filepath.Join(constants.TestsSplitDir, "runner-0"), "spec/other_spec.rb\nspec/models/user_spec.rb[1:1,1:2]\n")
+	writePlanTestFile(t, filepath.Join(constants.TestsSplitDir, "runner-1"), "spec/models/user_spec.rb[1:3,1:4]\n")
+
+	var output strings.Builder
+	if err := Explain(&output, "spec/models/user_spec.rb"); err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+	if !strings.Contains(output.String(), "  Runner: 0, 1 of 2 (2 test chunks)\n") {
+		t.Fatalf("unexpected explain output:\n%s", output.String())
+	}
+}
+
+func TestExplain_FullySkippedSuite(t *testing.T) {
+	writeExplainTestPlan(t)
+
+	var output strings.Builder
+	if err := Explain(&output, "Admin"); err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"Test file: spec/models/admin_spec.rb\n",
+		"  Runs: no (every test is skipped)\n",
+		"      Skipped by TIA: 3\n",
+		"      Duration estimate: 3s (default)\n",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("expected explain output to contain %q, got:\n%s", want, output.String())
+		}
+	}
+	if strings.Contains(output.String(), "Runner:") {
+		t.Errorf("expected no runner for a skipped file, got:\n%s", output.String())
+	}
+}
+
+func TestExplain_FileWithoutSuitesOrBackendData(t *testing.T) {
+	writeExplainTestPlan(t)
+	if err := os.RemoveAll(constants.HTTPCacheDir); err != nil {
+		t.Fatal(err)
+	}
+
+	var output strings.Builder
+	if err := Explain(&output, "./spec/other_spec.rb"); err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"  Runner: 0 of 2\n",
+		"  Estimated duration: 1s (default)\n",
+		"  Suites: none discovered\n",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("expected explain output to contain %q, got:\n%s", want, output.String())
+		}
+	}
+}
+
+func TestExplain_UnknownTarget(t *testing.T) {
+	writeExplainTestPlan(t)
+
+	err := Explain(&strings.Builder{}, "spec/missing_spec.rb")
+	if errcode.CodeOf(err) != errcode.ExplainTargetNotFound {
+		t.Fatalf("Explain() error = %v, want %s", err, errcode.ExplainTargetNotFound)
+	}
+}
+
+func TestExplain_WithoutPlan(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	err := Explain(&strings.Builder{}, "spec/models/user_spec.rb")
+	if errcode.CodeOf(err) != errcode.ExplainPlanLoadFailed {
+		t.Fatalf("Explain() error = %v, want %s", err, errcode.ExplainPlanLoadFailed)
+	}
+}
@@ -0,0 +1,110 @@
+package planner
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/testoptimization"
+)
+
+// localTestFileDurationsCache holds test file durations measured from JUnit
+// reports of earlier ddtest run invocations.
+type localTestFileDurationsCache struct {
+	TestFiles map[string]localTestFileDuration `json:"testFiles"`
+}
+
+type localTestFileDuration struct {
+	DurationMs int64     `json:"durationMs"`
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+// StoreLocalTestFileDurations merges measured test file durations into the
+// runner cache. New measurements replace older ones for the same file; other
+// files keep their previous measurement.
+func StoreLocalTestFileDurations(durations map[string]time.Duration, recordedAt time.Time) error {
+	cache, err := readLocalTestFileDurationsCache()
+	if err != nil {
+		slog.Warn("Ignoring unreadable local test file durations", "error", err)
+		cache = localTestFileDurationsCache{}
+	}
+	if cache.TestFiles == nil {
+		cache.TestFiles = make(map[string]localTestFileDuration, len(durations))
+	}
+
+	for testFile, duration := range durations {
+		if testFile == "" || duration <= 0 {
+			continue
+		}
+		cache.TestFiles[testFile] = localTestFileDuration{
+			DurationMs: max(duration.Milliseconds(), 1),
+			RecordedAt: recordedAt.UTC(),
+		}
+	}
+
+	return testoptimization.NewCacheManager().StoreTestFileDurationsCache(cache)
+}
+
+// loadLocalTestFileWeights returns measured test file durations in
+// milliseconds, or nil when no durations were recorded.
+func loadLocalTestFileWeights() map[string]int {
+	cache, err := readLocalTestFileDurationsCache()
+	if err != nil {
+		slog.Warn("Ignoring unreadable local test file durations", "error", err)
+		return nil
+	}
+
+	weights := make(map[string]int, len(cache.TestFiles))
+	for testFile, duration := range cache.TestFiles {
+		if duration.DurationMs > 0 {
+			weights[testFile] = int(duration.DurationMs)
+		}
+	}
+	if len(weights) > 0 {
+		slog.Info("Loaded local test file durations", "testFilesCount", len(weights))
+	}
+	return weights
+}
+
+func readLocalTestFileDurationsCache() (localTestFileDurationsCache, error) {
+	var cache localTestFileDurationsCache
+	err := testoptimization.NewCacheManager().ReadTestFileDurationsCache(&cache)
+	if errors.Is(err, fs.ErrNotExist) {
+		return localTestFileDurationsCache{}, nil
+	}
+	return cache, err
+}
+
+// applyLocalDuration replaces a file's estimate with its local measurement
+// when neither the backend nor the duration history had a duration for it, or
+// always when local durations are preferred. Test-level skips scale the
+// measurement down the same way they scale backend durations.
+func (tp *TestPlanner) applyLocalDuration(testFile string, estimate testFileWeightEstimate) testFileWeightEstimate {
+	localWeight, ok := tp.localTestFileWeights[testFile]
+	if !ok {
+		return estimate
+	}
+	if estimate.source != testFileDurationSourceDefault && !tp.preferLocalDurations {
+		return estimate
+	}
+
+	weight := int(float64(localWeight) * tp.runnableTestFraction(testFile))
+	return testFileWeightEstimate{
+		weight: max(weight, 1),
+		source: testFileDurationSourceLocal,
+	}
+}
+
+func (tp *TestPlanner) runnableTestFraction(testFile string) float64 {
+	var total, runnable int
+	for _, key := range tp.suitesBySourceFile[testFile] {
+		aggregate := tp.suiteAggregates[key]
+		total += aggregate.NumTests
+		runnable += aggregate.NumTests - aggregate.NumTestsSkipped
+	}
+	if total <= 0 {
+		return 1
+	}
+	return float64(runnable) / float64(total)
+}
@@ -0,0 +1,106 @@
+package planner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/testoptimization"
+)
+
+func newLocalDurationsTestPlanner() *TestPlanner {
+	runner := NewWithDependencies(&MockPlatformDetector{}, &MockTestOptimizationClient{}, newDefaultMockCIProviderDetector())
+	runner.testFiles = map[string]struct{}{
+		"spec/known_spec.rb":   {},
+		"spec/partial_spec.rb": {},
+		"spec/unknown_spec.rb": {},
+	}
+	runner.suiteAggregates = map[testSuiteKey]testSuiteAggregate{
+		{Module: "rspec", Suite: "Known"}: {
+			Module:            "rspec",
+			Suite:             "Known",
+			SourceFile:        "spec/known_spec.rb",
+			NumTests:          2,
+			EstimatedDuration: float64(2 * time.Second),
+			DurationSource:    testFileDurationSourceKnown,
+		},
+		{Module: "rspec", Suite: "Partial"}: {
+			Module:            "rspec",
+			Suite:             "Partial",
+			SourceFile:        "spec/partial_spec.rb",
+			NumTests:          4,
+			NumTestsSkipped:   1,
+			EstimatedDuration: float64(3 * time.Second),
+			DurationSource:    testFileDurationSourceDefault,
+		},
+	}
+	runner.suitesBySourceFile = indexSuitesBySourceFile(runner.suiteAggregates)
+	runner.localTestFileWeights = map[string]int{
+		"spec/known_spec.rb":   9000,
+		"spec/partial_spec.rb": 8000,
+		"spec/unknown_spec.rb": 7000,
+	}
+	return runner
+}
+
+func TestTestPlanner_LocalDurationsReplaceDefaultEstimates(t *testing.T) {
+	runner := newLocalDurationsTestPlanner()
+
+	weights := runner.calculateFileWeights()
+
+	if weights["spec/known_spec.rb"] != 2000 || runner.testFileDurationSources["spec/known_spec.rb"] != testFileDurationSourceKnown {
+		t.Errorf("expected backend duration to be kept, got weight=%d source=%q", weights["spec/known_spec.rb"], runner.testFileDurationSources["spec/known_spec.rb"])
+	}
+	if weights["spec/partial_spec.rb"] != 6000 || runner.testFileDurationSources["spec/partial_spec.rb"] != testFileDurationSourceLocal {
+		t.Errorf("expected local duration scaled to runnable tests, got weight=%d source=%q", weights["spec/partial_spec.rb"], runner.testFileDurationSources["spec/partial_spec.rb"])
+	}
+	if weights["spec/unknown_spec.rb"] != 7000 || runner.testFileDurationSources["spec/unknown_spec.rb"] != testFileDurationSourceLocal {
+		t.Errorf("expected local duration for file without suites, got weight=%d source=%q", weights["spec/unknown_spec.rb"], runner.testFileDurationSources["spec/unknown_spec.rb"])
+	}
+}
+
+func TestTestPlanner_PreferLocalDurationsOverridesBackend(t *testing.T) {
+	runner := newLocalDurationsTestPlanner()
+	runner.preferLocalDurations = true
+
+	weights := runner.calculateFileWeights()
+
+	if weights["spec/known_spec.rb"] != 9000 || runner.testFileDurationSources["spec/known_spec.rb"] != testFileDurationSourceLocal {
+		t.Errorf("expected local duration to replace backend duration, got weight=%d source=%q", weights["spec/known_spec.rb"], runner.testFileDurationSources["spec/known_spec.rb"])
+	}
+}
+
+func TestStoreLocalTestFileDurations_MergesWithRecordedDurations(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if err := StoreLocalTestFileDurations(map[string]time.Duration{
+		"spec/a_spec.rb": 2 * time.Second,
+		"spec/b_spec.rb": 3 * time.Second,
+	}, time.Now()); err != nil {
+		t.Fatalf("StoreLocalTestFileDurations() error = %v", err)
+	}
+	if err := StoreLocalTestFileDurations(map[string]time.Duration{
+		"spec/b_spec.rb": 5 * time.Second,
+		"spec/c_spec.rb": 0,
+	}, time.Now()); err != nil {
+		t.Fatalf("StoreLocalTestFileDurations() error = %v", err)
+	}
+
+	weights := loadLocalTestFileWeights()
+	if len(weights) != 2 || weights["spec/a_spec.rb"] != 2000 || weights["spec/b_spec.rb"] != 5000 {
+		t.Fatalf("loadLocalTestFileWeights() = %v", weights)
+	}
+}
+
+func TestLoadLocalTestFileWeights_IgnoresUnreadableCache(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if weights := loadLocalTestFileWeights(); len(weights) != 0 {
+		t.Fatalf("expected no weights without a cache, got %v", weights)
+	}
+	if err := testoptimization.NewCacheManager().StoreTestFileDurationsCache("not a cache"); err != nil {
+		t.Fatal(err)
+	}
+	if weights := loadLocalTestFileWeights(); weights != nil {
+		t.Fatalf("expected unreadable cache to be ignored, got %v", weights)
+	}
+}
@@ -3,27 +3,31 @@ package planner
 import (
 	"log/slog"
 	"time"
+
+	"github.com/DataDog/ddtest/internal/settings"
 )
 
 // calculateParallelRunnerSplit determines the selected runner split by
 // estimating candidates between the configured min and max parallelism.
-func calculateParallelRunnerSplit(testFileWeights map[string]int, minParallelism, maxParallelism int, parallelRunnerOverhead, targetTime time.Duration) splitScore {
-	return calculateParallelRunnerSplitSelection(testFileWeights, minParallelism, maxParallelism, parallelRunnerOverhead, targetTime).selected
+func calculateParallelRunnerSplit(testFileWeights map[string]int, minParallelism, maxParallelism int, parallelRunnerOverhead, targetTime time.Duration, options splitOptions) splitScore {
+	return calculateParallelRunnerSplitSelection(testFileWeights, minParallelism, maxParallelism, parallelRunnerOverhead, targetTime, options).selected
 }
 
-func calculateParallelRunnerSplitSelection(testFileWeights map[string]int, minParallelism, maxParallelism int, parallelRunnerOverhead, targetTime time.Duration) splitSelection {
-	files := sortedWeightedTestFiles(testFileWeights)
+func calculateParallelRunnerSplitSelection(testFileWeights map[string]int, minParallelism, maxParallelism int, parallelRunnerOverhead, targetTime time.Duration, options splitOptions) splitSelection {
+	files, _ := options.sortedWeightedUnits(testFileWeights)
 	selector := splitSelector{
 		parallelRunnerOverhead: parallelRunnerOverhead,
 		targetTime:             targetTime,
+		durationEstimate:       options.durationEstimate,
+		optimizeFor:            options.optimizeFor,
+		costModel:              options.costModel,
 	}
 
 	// maxParallelism could be 0 or negative!
 	if maxParallelism <= 1 {
-		score := scoreSortedWeightedRunnerSplit(files, 1)
+		score := scoreSortedWeightedRunnerSplit(files, 1, options)
 		selector.logCandidate(score)
-		selector.maybeWarnTargetTimeUnreachable(score, 1, 1)
-		return selector.selection(score, score, []splitScore{score})
+		return selector.selectCandidate([]splitScore{score}, 1, 1)
 	}
 
 	if minParallelism < 1 {
@@ -38,45 +42,67 @@ func calculateParallelRunnerSplitSelection(testFileWeights map[string]int, minPa
 	}
 
 	if len(files) == 0 {
-		score := scoreSortedWeightedRunnerSplit(files, minParallelism)
+		score := scoreSortedWeightedRunnerSplit(files, minParallelism, options)
 		selector.logCandidate(score)
-		selector.maybeWarnTargetTimeUnreachable(score, minParallelism, maxParallelism)
-		return selector.selection(score, score, []splitScore{score})
+		return selector.selectCandidate([]splitScore{score}, minParallelism, maxParallelism)
 	}
 
 	candidateMax := maxUsefulParallelism(minParallelism, maxParallelism, len(files))
 
-	bestWithoutTarget := scoreSortedWeightedRunnerSplit(files, minParallelism)
-	lowestWallTime := bestWithoutTarget
-	bestWithinTarget := bestWithoutTarget
-	targetBestFound := selector.meetsTargetTime(bestWithoutTarget)
-	candidates := []splitScore{bestWithoutTarget}
-	selector.logCandidate(bestWithoutTarget)
-	for parallelRunners := minParallelism + 1; parallelRunners <= candidateMax; parallelRunners++ {
-		score := scoreSortedWeightedRunnerSplit(files, parallelRunners)
+	candidates := make([]splitScore, 0, candidateMax-minParallelism+1)
+	for parallelRunners := minParallelism; parallelRunners <= candidateMax; parallelRunners++ {
+		score := scoreSortedWeightedRunnerSplit(files, parallelRunners, options)
 		candidates = append(candidates, score)
 		selector.logCandidate(score)
-		if selector.better(score, bestWithoutTarget) {
-			bestWithoutTarget = score
-		}
-		if selector.betterWallTime(score, lowestWallTime) {
-			lowestWallTime = score
-		}
-		if selector.meetsTargetTime(score) && (!targetBestFound || selector.better(score, bestWithinTarget)) {
-			bestWithinTarget = score
-			targetBestFound = true
-		}
+	}
+	return selector.selectCandidate(candidates, minParallelism, maxParallelism)
+}
+
+// selectCandidate selects the best candidate among those that meet the target
+// time and fit the CI budget. When none does, the target time comes first: it
+// selects the cheapest candidate that meets the target time, or else the one
+// with the lowest wall time to get as close as possible to the target. With
+// only a budget, it selects the cheapest candidate.
+func (s splitSelector) selectCandidate(candidates []splitScore, minParallelism, maxParallelism int) splitSelection {
+	bestWithoutTarget, _ := bestCandidate(candidates, anyCandidate, s.better)
+	if !s.constrained() {
+		return s.selection(bestWithoutTarget, bestWithoutTarget, candidates)
+	}
+	if best, ok := bestCandidate(candidates, s.meetsConstraints, s.better); ok {
+		return s.selection(best, bestWithoutTarget, candidates)
 	}
 
-	if targetBestFound {
-		return selector.selection(bestWithinTarget, bestWithoutTarget, candidates)
+	if s.targetTime > 0 {
+		if cheapest, ok := bestCandidate(candidates, s.meetsTargetTime, s.cheaper); ok {
+			s.warnBudgetExceeded(cheapest, minParallelism, maxParallelism)
+			return s.selection(cheapest, bestWithoutTarget, candidates)
+		}
+		lowestWallTime, _ := bestCandidate(candidates, anyCandidate, s.betterWallTime)
+		s.maybeWarnTargetTimeUnreachable(lowestWallTime, minParallelism, maxParallelism)
+		return s.selection(lowestWallTime, bestWithoutTarget, candidates)
 	}
-	if selector.targetTime <= 0 {
-		return selector.selection(bestWithoutTarget, bestWithoutTarget, candidates)
+
+	cheapest, _ := bestCandidate(candidates, anyCandidate, s.cheaper)
+	s.warnBudgetExceeded(cheapest, minParallelism, maxParallelism)
+	return s.selection(cheapest, bestWithoutTarget, candidates)
+}
+
+// bestCandidate returns the first eligible candidate that no later eligible
+// candidate is better than, and whether any candidate is eligible.
+func bestCandidate(candidates []splitScore, eligible func(splitScore) bool, better func(candidate, currentBest splitScore) bool) (splitScore, bool) {
+	var best splitScore
+	found := false
+	for _, candidate := range candidates {
+		if eligible(candidate) && (!found || better(candidate, best)) {
+			best = candidate
+			found = true
+		}
 	}
+	return best, found
+}
 
-	selector.maybeWarnTargetTimeUnreachable(lowestWallTime, minParallelism, maxParallelism)
-	return selector.selection(lowestWallTime, bestWithoutTarget, candidates)
+func anyCandidate(splitScore) bool {
+	return true
 }
 
 func maxUsefulParallelism(minParallelism, maxParallelism, filesCount int) int {
@@ -92,6 +118,9 @@ func maxUsefulParallelism(minParallelism, maxParallelism, filesCount int) int {
 type splitSelector struct {
 	parallelRunnerOverhead time.Duration
 	targetTime             time.Duration
+	durationEstimate       settings.DurationEstimate
+	optimizeFor            settings.OptimizationGoal
+	costModel              ciCostModel
 }
 
 type splitSelection struct {
@@ -100,10 +129,18 @@ type splitSelection struct {
 	candidates             []splitScore
 	parallelRunnerOverhead time.Duration
 	targetTime             time.Duration
+	durationEstimate       settings.DurationEstimate
+	optimizeFor            settings.OptimizationGoal
+	costModel              ciCostModel
 	available              bool
 }
 
+// better reports whether candidate has a lower estimated CI cost, when
+// optimizing for cost, or else a lower selection score than currentBest.
 func (s splitSelector) better(candidate, currentBest splitScore) bool {
+	if s.optimizeFor == settings.OptimizationGoalCost && candidate.billedTime != currentBest.billedTime {
+		return candidate.billedTime < currentBest.billedTime
+	}
 	candidateScore := s.selectionScore(candidate)
 	currentBestScore := s.selectionScore(currentBest)
 	if candidateScore != currentBestScore {
@@ -118,6 +155,15 @@ func (s splitSelector) better(candidate, currentBest splitScore) bool {
 	return candidate.imbalance < currentBest.imbalance
 }
 
+// cheaper reports whether candidate has a lower estimated CI cost than
+// currentBest, breaking ties like better.
+func (s splitSelector) cheaper(candidate, currentBest splitScore) bool {
+	if candidate.billedTime != currentBest.billedTime {
+		return candidate.billedTime < currentBest.billedTime
+	}
+	return s.better(candidate, currentBest)
+}
+
 func (s splitSelector) betterWallTime(candidate, currentBest splitScore) bool {
 	if candidate.wallTime != currentBest.wallTime {
 		return candidate.wallTime < currentBest.wallTime
@@ -149,6 +195,9 @@ func (s splitSelector) selection(selected, bestWithoutTarget splitScore, candida
 		candidates:             candidates,
 		parallelRunnerOverhead: s.parallelRunnerOverhead,
 		targetTime:             s.targetTime,
+		durationEstimate:       s.durationEstimate,
+		optimizeFor:            s.optimizeFor,
+		costModel:              s.costModel,
 		available:              true,
 	}
 }
@@ -157,6 +206,26 @@ func (s splitSelector) meetsTargetTime(score splitScore) bool {
 	return s.targetTime > 0 && score.wallTimeDuration() <= s.targetTime
 }
 
+// constrained reports whether a target time or a CI budget limits which
+// candidates can be selected.
+func (s splitSelector) constrained() bool {
+	return s.targetTime > 0 || s.costModel.hasBudget()
+}
+
+func (s splitSelector) meetsConstraints(score splitScore) bool {
+	return (s.targetTime <= 0 || s.meetsTargetTime(score)) && s.costModel.withinBudget(score)
+}
+
+func (s splitSelector) warnBudgetExceeded(selected splitScore, minParallelism, maxParallelism int) {
+	slog.Warn("No parallel runner split fits the CI budget; selecting split with lowest estimated CI cost",
+		"ciBudget", s.costModel.budget,
+		"targetTime", s.targetTime,
+		"minParallelism", minParallelism,
+		"maxParallelism", maxParallelism,
+		"selectedParallelRunners", selected.parallelRunners,
+		"selectedEstimatedCost", s.costModel.cost(selected))
+}
+
 func (s splitSelector) maybeWarnTargetTimeUnreachable(best splitScore, minParallelism, maxParallelism int) {
 	if s.targetTime <= 0 || s.meetsTargetTime(best) {
 		return
@@ -179,5 +248,6 @@ func (s splitSelector) logCandidate(score splitScore) {
 		"parallelRunnerOverhead", s.parallelRunnerOverhead,
 		"targetTime", s.targetTime,
 		"meetsTargetTime", s.meetsTargetTime(score),
+		"estimatedCost", s.costModel.cost(score),
 		"selectionScore", time.Duration(s.selectionScore(score))*time.Millisecond)
 }
@@ -9,12 +9,14 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/DataDog/ddtest/internal/settings"
 )
 
 const testParallelRunnerOverhead = 25 * time.Second
 
 func testCalculateParallelRunners(testFileWeights map[string]int, minParallelism, maxParallelism int) int {
-	return calculateParallelRunnerSplit(testFileWeights, minParallelism, maxParallelism, testParallelRunnerOverhead, 0).parallelRunners
+	return calculateParallelRunnerSplit(testFileWeights, minParallelism, maxParallelism, testParallelRunnerOverhead, 0, splitOptions{}).parallelRunners
 }
 
 func TestCalculateParallelRunners_MaxParallelismIsOne(t *testing.T) {
@@ -144,7 +146,7 @@ func TestCalculateParallelRunnerSplit_ReturnsSelectedScore(t *testing.T) {
 		"test5.rb": 6,
 	}
 
-	result := calculateParallelRunnerSplit(testFileWeights, 3, 4, testParallelRunnerOverhead, 0)
+	result := calculateParallelRunnerSplit(testFileWeights, 3, 4, testParallelRunnerOverhead, 0, splitOptions{})
 	expected := splitScore{
 		parallelRunners: 3,
 		wallTime:        12,
@@ -201,7 +203,7 @@ func TestCalculateParallelRunnerSplit_RealGitHubActionsArtifacts(t *testing.T) {
 				)
 			}
 
-			result := calculateParallelRunnerSplit(fixture.TestFileWeights, 1, 8, testParallelRunnerOverhead, 0)
+			result := calculateParallelRunnerSplit(fixture.TestFileWeights, 1, 8, testParallelRunnerOverhead, 0, splitOptions{})
 			if result.parallelRunners != tt.expectedParallelRunners {
 				t.Fatalf(
 					"calculateParallelRunnerSplit() = %d runners, expected %d; artifact selected %d before this fix",
@@ -236,7 +238,7 @@ func TestCalculateParallelRunnerSplit_ParallelRunnerOverheadTunesFanout(t *testi
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := calculateParallelRunnerSplit(fixture.TestFileWeights, 1, 8, tt.parallelRunnerOverhead, 0)
+			result := calculateParallelRunnerSplit(fixture.TestFileWeights, 1, 8, tt.parallelRunnerOverhead, 0, splitOptions{})
 			if result.parallelRunners != tt.expectedParallelRunners {
 				t.Errorf("calculateParallelRunnerSplit() = %d runners, expected %d", result.parallelRunners, tt.expectedParallelRunners)
 			}
@@ -256,7 +258,7 @@ func TestCalculateParallelRunnerSplit_TargetTimeFiltersCandidateSplits(t *testin
 	parallelRunnerOverhead := 5 * time.Minute
 	targetTime := 2 * time.Minute
 
-	result := calculateParallelRunnerSplit(testFileWeights, minParallelism, maxParallelism, parallelRunnerOverhead, targetTime)
+	result := calculateParallelRunnerSplit(testFileWeights, minParallelism, maxParallelism, parallelRunnerOverhead, targetTime, splitOptions{})
 	if result.parallelRunners != 2 {
 		t.Errorf("calculateParallelRunnerSplit() = %d runners, expected 2 to satisfy target time with the lowest selection score", result.parallelRunners)
 	}
@@ -278,7 +280,7 @@ func TestCalculateParallelRunnerSplit_TargetTimeImpossibleWarnsAndSelectsLowestW
 	parallelRunnerOverhead := 5 * time.Minute
 	targetTime := 59 * time.Second
 
-	result := calculateParallelRunnerSplitSelection(testFileWeights, minParallelism, maxParallelism, parallelRunnerOverhead, targetTime)
+	result := calculateParallelRunnerSplitSelection(testFileWeights, minParallelism, maxParallelism, parallelRunnerOverhead, targetTime, splitOptions{})
 	if result.selected.parallelRunners != 4 {
 		t.Errorf("calculateParallelRunnerSplit() = %d runners, expected 4 from fallback lowest wall time split", result.selected.parallelRunners)
 	}
@@ -329,7 +331,7 @@ func TestCalculateParallelRunnerSplit_LogsCandidateSplits(t *testing.T) {
 		"test3.rb": 10,
 	}
 
-	_ = calculateParallelRunnerSplit(testFileWeights, 1, 3, testParallelRunnerOverhead, 0)
+	_ = calculateParallelRunnerSplit(testFileWeights, 1, 3, testParallelRunnerOverhead, 0, splitOptions{})
 
 	logOutput := logs.String()
 	if strings.Count(logOutput, "Considered parallel runner split") != 3 ||
@@ -351,6 +353,36 @@ func BenchmarkCalculateParallelRunners20000TestFiles(b *testing.B) {
 
 	b.ResetTimer()
 	for range b.N {
-		_ = calculateParallelRunnerSplit(testFileWeights, 1, 256, testParallelRunnerOverhead, 0)
+		_ = calculateParallelRunnerSplit(testFileWeights, 1, 256, testParallelRunnerOverhead, 0, splitOptions{})
+	}
+}
+
+func BenchmarkCalculateParallelRunners20000TestFilesLocalSearch(b *testing.B) {
+	testFileWeights := make(map[string]int, 20000)
+	for i := range 20000 {
+		testFileWeights[fmt.Sprintf("test/%05d_test.rb", i)] = (i*7919)%100000 + 1
+	}
+
+	b.ResetTimer()
+	for range b.N {
+		_ = calculateParallelRunnerSplit(testFileWeights, 1, 256, testParallelRunnerOverhead, 0, splitOptions{optimizer: settings.SplitOptimizerLocalSearch})
+	}
+}
+
+func BenchmarkCalculateParallelRunners20000TestFilesP90(b *testing.B) {
+	testFileWeights := make(map[string]int, 20000)
+	variances := make(map[string]float64, 4000)
+	for i := range 20000 {
+		testFile := fmt.Sprintf("test/%05d_test.rb", i)
+		testFileWeights[testFile] = (i % 1000) + 1
+		if i%5 == 0 {
+			variances[testFile] = float64(testFileWeights[testFile] * testFileWeights[testFile])
+		}
+	}
+	options := splitOptions{durationEstimate: settings.DurationEstimateP90, durationVariances: variances}
+
+	b.ResetTimer()
+	for range b.N {
+		_ = calculateParallelRunnerSplit(testFileWeights, 1, 256, testParallelRunnerOverhead, 0, options)
 	}
 }
@@ -37,3 +37,23 @@ func writePlanFile(path string, data []byte) error {
 	}
 	return nil
 }
+
+// readPlanFileLines returns the non-empty lines of a plan file.
+func readPlanFileLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return planFileLines(data), nil
+}
+
+// planFileLines returns the non-empty lines of the content of a plan file.
+func planFileLines(data []byte) []string {
+	lines := make([]string, 0)
+	for line := range strings.Lines(string(data)) {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,247 @@
+package planner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/buildinfo"
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/framework"
+	"github.com/DataDog/ddtest/internal/runmetadata"
+	"github.com/DataDog/ddtest/internal/settings"
+)
+
+// PlanJSON is the content of plan.json, the plan layout v2 manifest. It
+// describes the whole plan in one versioned file for other tools; its fields
+// are documented in docs/layout.md and only change with the schema version.
+type PlanJSON struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	DDTestVersion string    `json:"ddtestVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+	// SourceCommit is the commit that was planned.
+	SourceCommit        string               `json:"sourceCommit"`
+	RunInfo             runmetadata.RunInfo  `json:"runInfo"`
+	PlanMetadata        PlanMetadata         `json:"planMetadata"`
+	Settings            PlanJSONSettings     `json:"settings"`
+	SkippablePercentage float64              `json:"skippablePercentage"`
+	ParallelRunners     int                  `json:"parallelRunners"`
+	Split               PlanJSONSplitScore   `json:"split"`
+	Candidates          []PlanJSONSplitScore `json:"candidates"`
+	Runners             []PlanJSONRunner     `json:"runners"`
+	TestFiles           []PlanJSONTestFile   `json:"testFiles"`
+}
+
+// PlanJSONSettings are the settings the plan was made with.
+type PlanJSONSettings struct {
+	MinParallelism           int    `json:"minParallelism"`
+	MaxParallelism           int    `json:"maxParallelism"`
+	TargetTimeMs             int64  `json:"targetTimeMs"`
+	ParallelRunnerOverheadMs int64  `json:"parallelRunnerOverheadMs"`
+	SplitOptimizer           string `json:"splitOptimizer"`
+	DurationEstimate         string `json:"durationEstimate"`
+	OptimizeFor              string `json:"optimizeFor"`
+	TestsLocation            string `json:"testsLocation"`
+	TestsExcludePattern      string `json:"testsExcludePattern"`
+	ChunkSlowTestFiles       bool   `json:"chunkSlowTestFiles"`
+	CINodeWorkers            int    `json:"ciNodeWorkers"`
+	CINodeCapacities         string `json:"ciNodeCapacities"`
+	ConstraintsFile          string `json:"constraintsFile"`
+}
+
+// PlanJSONSplitScore is the estimated outcome of splitting the test files
+// across ParallelRunners runners.
+type PlanJSONSplitScore struct {
+	ParallelRunners int   `json:"parallelRunners"`
+	WallTimeMs      int64 `json:"wallTimeMs"`
+	// ExpectedWallTimeMs and P90WallTimeMs are set when test files are
+	// modeled from their P50 and P90 durations.
+	ExpectedWallTimeMs int64 `json:"expectedWallTimeMs,omitempty"`
+	P90WallTimeMs      int64 `json:"p90WallTimeMs,omitempty"`
+	ImbalanceMs        int64 `json:"imbalanceMs"`
+	TotalRuntimeMs     int64 `json:"totalRuntimeMs"`
+	// BilledTimeMs is set when the CI cost model is enabled.
+	BilledTimeMs int64 `json:"billedTimeMs,omitempty"`
+}
+
+// PlanJSONRunner is the split of one CI node or worker: the entries of its
+// runner-N file.
+type PlanJSONRunner struct {
+	Index int `json:"index"`
+	// Workers is the local worker count of the CI node, set when CI node
+	// capacities are.
+	Workers   int      `json:"workers,omitempty"`
+	WeightMs  int      `json:"weightMs"`
+	TestFiles []string `json:"testFiles"`
+}
+
+// PlanJSONTestFile is a runnable test file of the plan.
+type PlanJSONTestFile struct {
+	Path     string `json:"path"`
+	WeightMs int    `json:"weightMs"`
+	// P90WeightMs is set when the P90 duration of the file is above its
+	// median.
+	P90WeightMs int `json:"p90WeightMs,omitempty"`
+	// DurationSource is "backend", "history", "local", or "default".
+	DurationSource string `json:"durationSource"`
+	// Runners lists the runners the file is assigned to; a test file split
+	// into test chunks can be assigned to several.
+	Runners []int `json:"runners"`
+}
+
+// writePlanJSONArtifact writes plan.json with the plan layout v2, and removes
+// a stale plan.json of an earlier plan otherwise, so that it never describes
+// another plan than the plain text files.
+func (tp *TestPlanner) writePlanJSONArtifact(selection splitSelection, ciNodeWorkers []int) error {
+	if settings.GetPlanLayout() != settings.PlanLayoutV2 {
+		if err := os.Remove(constants.PlanJSONPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove stale plan manifest %s: %w", constants.PlanJSONPath, err)
+		}
+		return nil
+	}
+
+	splits, err := readRunnerSplits()
+	if err != nil {
+		return err
+	}
+	// Splits past the runner count are left over from an earlier plan with
+	// more runners.
+	parallelRunners := max(selection.selected.parallelRunners, 1)
+	for len(splits) < parallelRunners {
+		splits = append(splits, nil)
+	}
+	splits = splits[:parallelRunners]
+	plan := tp.newPlanJSON(selection, splits, ciNodeWorkers)
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan manifest: %w", err)
+	}
+	if err := writePlanFile(constants.PlanJSONPath, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write plan manifest: %w", err)
+	}
+	return nil
+}
+
+func (tp *TestPlanner) newPlanJSON(selection splitSelection, splits [][]string, ciNodeWorkers []int) PlanJSON {
+	plan := PlanJSON{
+		SchemaVersion:       constants.PlanJSONSchemaVersion,
+		DDTestVersion:       buildinfo.CurrentVersion(),
+		CreatedAt:           time.Now().UTC(),
+		SourceCommit:        tp.runInfo.Commit,
+		RunInfo:             tp.runInfo,
+		PlanMetadata:        tp.planMetadata,
+		Settings:            newPlanJSONSettings(),
+		SkippablePercentage: tp.skippablePercentage,
+		ParallelRunners:     len(splits),
+		Split:               newPlanJSONSplitScore(selection.selected),
+		Candidates:          make([]PlanJSONSplitScore, 0, len(selection.candidates)),
+		Runners:             make([]PlanJSONRunner, 0, len(splits)),
+		TestFiles:           make([]PlanJSONTestFile, 0, len(tp.testFileWeights)),
+	}
+	for _, candidate := range selection.candidates {
+		plan.Candidates = append(plan.Candidates, newPlanJSONSplitScore(candidate))
+	}
+
+	splitWeights := tp.splitWeights()
+	testFileRunners := make(map[string][]int, len(tp.testFileWeights))
+	for index, entries := range splits {
+		runner := PlanJSONRunner{Index: index, TestFiles: entries}
+		if runner.TestFiles == nil {
+			runner.TestFiles = []string{}
+		}
+		if index < len(ciNodeWorkers) {
+			runner.Workers = ciNodeWorkers[index]
+		}
+		for _, entry := range entries {
+			runner.WeightMs += splitWeights[entry]
+			testFile := framework.TestChunkFile(entry)
+			if !slices.Contains(testFileRunners[testFile], index) {
+				testFileRunners[testFile] = append(testFileRunners[testFile], index)
+			}
+		}
+		plan.Runners = append(plan.Runners, runner)
+	}
+
+	for testFile, weight := range tp.testFileWeights {
+		runners := testFileRunners[testFile]
+		if runners == nil {
+			runners = []int{}
+		}
+		plan.TestFiles = append(plan.TestFiles, PlanJSONTestFile{
+			Path:           testFile,
+			WeightMs:       weight,
+			P90WeightMs:    tp.testFileP90Weights[testFile],
+			DurationSource: planJSONDurationSource(tp.testFileDurationSources[testFile]),
+			Runners:        runners,
+		})
+	}
+	slices.SortFunc(plan.TestFiles, func(a, b PlanJSONTestFile) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return plan
+}
+
+func newPlanJSONSettings() PlanJSONSettings {
+	return PlanJSONSettings{
+		MinParallelism:           settings.GetMinParallelism(),
+		MaxParallelism:           settings.GetMaxParallelism(),
+		TargetTimeMs:             settings.GetTargetTime().Milliseconds(),
+		ParallelRunnerOverheadMs: settings.GetParallelRunnerOverhead().Milliseconds(),
+		SplitOptimizer:           string(settings.GetSplitOptimizer()),
+		DurationEstimate:         string(settings.GetDurationEstimate()),
+		OptimizeFor:              string(settings.GetOptimizeFor()),
+		TestsLocation:            settings.GetTestsLocation(),
+		TestsExcludePattern:      settings.GetTestsExcludePattern(),
+		ChunkSlowTestFiles:       settings.GetChunkSlowTestFiles(),
+		CINodeWorkers:            settings.GetCiNodeWorkers(),
+		CINodeCapacities:         settings.GetCiNodeCapacities(),
+		ConstraintsFile:          settings.GetConstraintsFile(),
+	}
+}
+
+func newPlanJSONSplitScore(score splitScore) PlanJSONSplitScore {
+	return PlanJSONSplitScore{
+		ParallelRunners:    score.parallelRunners,
+		WallTimeMs:         int64(score.wallTime),
+		ExpectedWallTimeMs: int64(score.expectedWallTime),
+		P90WallTimeMs:      int64(score.p90WallTime),
+		ImbalanceMs:        int64(score.imbalance),
+		TotalRuntimeMs:     int64(score.totalRuntime),
+		BilledTimeMs:       int64(score.billedTime),
+	}
+}
+
+// planJSONDurationSource names duration sources the way telemetry does:
+// durations known to the backend are "backend".
+func planJSONDurationSource(source testFileDurationSource) string {
+	switch source {
+	case testFileDurationSourceKnown:
+		return "backend"
+	case "":
+		return string(testFileDurationSourceDefault)
+	default:
+		return string(source)
+	}
+}
+
+// ReadPlanJSON reads the plan layout v2 manifest at path. It fails when the
+// manifest has another schema version than this ddtest writes.
+func ReadPlanJSON(path string) (PlanJSON, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PlanJSON{}, fmt.Errorf("failed to read plan manifest %s: %w", path, err)
+	}
+	var plan PlanJSON
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return PlanJSON{}, fmt.Errorf("failed to parse plan manifest %s: %w", path, err)
+	}
+	if plan.SchemaVersion != constants.PlanJSONSchemaVersion {
+		return PlanJSON{}, fmt.Errorf("plan manifest %s has schema version %d, this ddtest expects %d; plan again with this ddtest version", path, plan.SchemaVersion, constants.PlanJSONSchemaVersion)
+	}
+	return plan, nil
+}
@@ -0,0 +1,138 @@
+package planner
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/testoptimization"
+)
+
+func newPlanJSONTestPlanner() *TestPlanner {
+	mockPlatform := &MockPlatform{
+		PlatformName: "ruby",
+		Tags:         map[string]string{"platform": "ruby"},
+		Framework: &MockFramework{
+			FrameworkName: "rspec",
+			Tests: []testoptimization.Test{
+				{Suite: "TestSuite1", Name: "test1", SuiteSourceFile: "test/file1_test.rb"},
+				{Suite: "TestSuite2", Name: "test2", SuiteSourceFile: "test/file2_test.rb"},
+				{Suite: "TestSuite3", Name: "test3", SuiteSourceFile: "test/file3_test.rb"},
+			},
+		},
+	}
+	runner := NewWithDependencies(
+		&MockPlatformDetector{Platform: mockPlatform},
+		&MockTestOptimizationClient{Settings: testOptimizationSettings(true, true, false)},
+		newDefaultMockCIProviderDetector(),
+	)
+	runner.reportWriter = &strings.Builder{}
+	return runner
+}
+
+func TestTestPlanner_Plan_WritesPlanJSON(t *testing.T) {
+	t.Chdir(t.TempDir())
+	// runner-2 is left over from an earlier plan with three runners.
+	if err := writeRunnerSplit(constants.TestsSplitDir, 2, []byte("test/stale_test.rb\n")); err != nil {
+		t.Fatal(err)
+	}
+	setPlannerEnv(t, map[string]string{
+		"DD_TEST_OPTIMIZATION_RUNNER_PLAN_LAYOUT":     "v2",
+		"DD_TEST_OPTIMIZATION_RUNNER_MIN_PARALLELISM": "2",
+		"DD_TEST_OPTIMIZATION_RUNNER_MAX_PARALLELISM": "2",
+	})
+
+	if err := newPlanJSONTestPlanner().Plan(context.Background()); err != nil {
+		t.Fatalf("Plan() should not return error, got: %v", err)
+	}
+
+	plan, err := ReadPlanJSON(constants.PlanJSONPath)
+	if err != nil {
+		t.Fatalf("ReadPlanJSON() returned error: %v", err)
+	}
+	if plan.SchemaVersion != constants.PlanJSONSchemaVersion || plan.DDTestVersion == "" || plan.CreatedAt.IsZero() {
+		t.Errorf("unexpected plan manifest header %+v", plan)
+	}
+	if plan.ParallelRunners != 2 || plan.Split.ParallelRunners != 2 || len(plan.Candidates) == 0 {
+		t.Errorf("unexpected plan manifest split %+v, candidates %+v", plan.Split, plan.Candidates)
+	}
+	if plan.Settings.MinParallelism != 2 || plan.Settings.MaxParallelism != 2 || plan.Settings.SplitOptimizer == "" {
+		t.Errorf("unexpected plan manifest settings %+v", plan.Settings)
+	}
+
+	if len(plan.Runners) != 2 {
+		t.Fatalf("expected 2 runners in the plan manifest, got %+v", plan.Runners)
+	}
+	splits, err := readRunnerSplits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, runner := range plan.Runners {
+		if runner.Index != index || !slices.Equal(runner.TestFiles, splits[index]) {
+			t.Errorf("runner %d of the plan manifest = %+v, want the test files of runner-%d %v", index, runner, index, splits[index])
+		}
+	}
+
+	if len(plan.TestFiles) != 3 {
+		t.Fatalf("expected 3 test files in the plan manifest, got %+v", plan.TestFiles)
+	}
+	for i, testFile := range plan.TestFiles {
+		if want := []string{"test/file1_test.rb", "test/file2_test.rb", "test/file3_test.rb"}[i]; testFile.Path != want {
+			t.Errorf("test file %d of the plan manifest = %s, want %s", i, testFile.Path, want)
+		}
+		if testFile.DurationSource != "default" || testFile.WeightMs <= 0 || len(testFile.Runners) != 1 {
+			t.Errorf("unexpected plan manifest test file %+v", testFile)
+		}
+		runner := plan.Runners[testFile.Runners[0]]
+		if !slices.Contains(runner.TestFiles, testFile.Path) {
+			t.Errorf("test file %s is not in the test files of runner %d: %v", testFile.Path, runner.Index, runner.TestFiles)
+		}
+	}
+}
+
+func TestTestPlanner_Plan_V1RemovesStalePlanJSON(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := writePlanFile(constants.PlanJSONPath, []byte("{}\n")); err != nil {
+		t.Fatal(err)
+	}
+	setPlannerEnv(t, map[string]string{
+		"DD_TEST_OPTIMIZATION_RUNNER_PLAN_LAYOUT":     "v1",
+		"DD_TEST_OPTIMIZATION_RUNNER_MIN_PARALLELISM": "2",
+		"DD_TEST_OPTIMIZATION_RUNNER_MAX_PARALLELISM": "2",
+	})
+
+	if err := newPlanJSONTestPlanner().Plan(context.Background()); err != nil {
+		t.Fatalf("Plan() should not return error, got: %v", err)
+	}
+	if _, err := os.Stat(constants.PlanJSONPath); !os.IsNotExist(err) {
+		t.Errorf("expected a v1 plan to remove the stale plan manifest, got: %v", err)
+	}
+}
+
+func TestReadPlanJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	for name, content := range map[string]string{
+		"invalid JSON":           `{"schemaVersion": 2`,
+		"other schema version":   `{"schemaVersion": 3}`,
+		"missing schema version": `{}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ReadPlanJSON(path); err == nil {
+				t.Error("expected the plan manifest to be rejected")
+			}
+		})
+	}
+
+	if _, err := ReadPlanJSON(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a missing plan manifest to be reported as not found, got: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package planner
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -31,6 +32,8 @@ type Planner interface {
 	Plan(ctx context.Context) error
 	LoadPlan() (PlanMetadata, error)
 	DistributeTestFiles(testFiles []string, parallelRunners int) [][]string
+	TestFileWeights(testFiles []string) map[string]int
+	TestFileConstraints() TestFileConstraints
 }
 
 type testOptimizationClient interface {
@@ -78,18 +81,43 @@ type TestPlanner struct {
 	testSuiteDurations      map[string]map[string]api.TestSuiteDurationInfo
 	testFileWeights         map[string]int
 	testFileDurationSources map[string]testFileDurationSource
-	reportStats             planningReportStats
-	skippablePercentage     float64
-	planLoaded              bool
-	runInfo                 runmetadata.RunInfo
-	planMetadata            PlanMetadata
-	platformDetector        platform.PlatformDetector
-	optimizationClient      testOptimizationClient
-	newOptimizationClient   func(testSkippingLevel settings.TestSkippingLevel) testOptimizationClient
-	ciProviderDetector      environment.CIProviderDetector
-	telemetryClient         telemetry.Client
-	reportWriter            io.Writer
-	tiaSkippingEnabled      bool
+	// testsBySourceFile holds the tests found by full discovery in each test
+	// file, in discovery order.
+	testsBySourceFile map[string][]testoptimization.Test
+	// testChunkWeights holds the estimated weight of each test chunk of the
+	// slow test files, or nil when no test file was split.
+	testChunkWeights    map[string]int
+	testFileConstraints TestFileConstraints
+	// previousTestSplit holds the runner of each test file in the previous
+	// plan, when one is configured and found.
+	previousTestSplit map[string]int
+	// testFileP90Weights holds the P90 duration of the test files whose P90
+	// duration is above their median. When the duration estimate models
+	// those files from both, testFileExpectedWeights and
+	// testFileDurationVariances hold their expected duration and duration
+	// variance, which only the split scoring reads.
+	testFileP90Weights        map[string]int
+	testFileExpectedWeights   map[string]int
+	testFileDurationVariances map[string]float64
+	localTestFileWeights      map[string]int
+	preferLocalDurations      bool
+	reportStats               planningReportStats
+	skippablePercentage       float64
+	planLoaded                bool
+	runInfo                   runmetadata.RunInfo
+	planMetadata              PlanMetadata
+	platformDetector          platform.PlatformDetector
+	optimizationClient        testOptimizationClient
+	newOptimizationClient     func(testSkippingLevel settings.TestSkippingLevel) testOptimizationClient
+	ciProviderDetector        environment.CIProviderDetector
+	telemetryClient           telemetry.Client
+	reportWriter              io.Writer
+	tiaSkippingEnabled        bool
+	// durationHistory holds the observed durations of test files loaded from
+	// the duration history file, and durationHistoryLoadedAt the time their
+	// age is measured from.
+	durationHistory         map[string][]durationObservation
+	durationHistoryLoadedAt time.Time
 }
 
 const (
@@ -121,22 +149,36 @@ type testFileDurationSource string
 const (
 	testFileDurationSourceKnown   testFileDurationSource = "known"
 	testFileDurationSourceDefault testFileDurationSource = "default"
+	testFileDurationSourceLocal   testFileDurationSource = "local"
+	testFileDurationSourceHistory testFileDurationSource = "history"
 )
 
 type testSuiteAggregate struct {
-	Module            string                 `json:"module"`
-	Suite             string                 `json:"suite"`
-	SourceFile        string                 `json:"sourceFile"`
-	TotalDuration     float64                `json:"totalDuration"`
-	EstimatedDuration float64                `json:"estimatedDuration"`
-	DurationSource    testFileDurationSource `json:"durationSource,omitempty"`
-	NumTests          int                    `json:"numTests"`
-	NumTestsSkipped   int                    `json:"numTestsSkipped"`
+	Module            string  `json:"module"`
+	Suite             string  `json:"suite"`
+	SourceFile        string  `json:"sourceFile"`
+	TotalDuration     float64 `json:"totalDuration"`
+	EstimatedDuration float64 `json:"estimatedDuration"`
+	// EstimatedDurationP90 is EstimatedDuration for the P90 duration of the
+	// suite, when the backend knows it.
+	EstimatedDurationP90 float64                `json:"estimatedDurationP90,omitempty"`
+	DurationSource       testFileDurationSource `json:"durationSource,omitempty"`
+	NumTests             int                    `json:"numTests"`
+	NumTestsSkipped      int                    `json:"numTestsSkipped"`
+	// NumTestsDisabled counts the skipped tests that Test Management disabled;
+	// the other skipped tests were skipped by TIA.
+	NumTestsDisabled int `json:"numTestsDisabled,omitempty"`
+	// UnskippableTestsKept counts the tests that would have been skipped but
+	// run because the source file has an unskippable marker.
+	UnskippableTestsKept int `json:"unskippableTestsKept,omitempty"`
 }
 
 type testFileWeightEstimate struct {
 	weight int
 	source testFileDurationSource
+	// p90Weight is the P90 duration of the file in milliseconds, or 0 when
+	// it is not known.
+	p90Weight int
 }
 
 func selectTags(tags map[string]string, keys ...string) map[string]string {
@@ -159,7 +201,7 @@ func New() *TestPlanner {
 	planner.newOptimizationClient = func(testSkippingLevel settings.TestSkippingLevel) testOptimizationClient {
 		return testoptimization.NewTestOptimizationClientWithTestSkippingLevel(testSkippingLevel)
 	}
-	planner.ciProviderDetector = environment.NewCIProviderDetector()
+	planner.ciProviderDetector = environment.NewCIProviderDetector(settings.GetPipelineTemplate())
 	return planner
 }
 
@@ -194,6 +236,7 @@ func newTestPlannerWithDefaults() *TestPlanner {
 		testSuiteDurations:      make(map[string]map[string]api.TestSuiteDurationInfo),
 		testFileWeights:         make(map[string]int),
 		testFileDurationSources: make(map[string]testFileDurationSource),
+		testsBySourceFile:       make(map[string][]testoptimization.Test),
 		reportStats:             newPlanningReportStats(),
 		skippablePercentage:     0.0,
 		telemetryClient:         telemetry.NoopClient(),
@@ -204,6 +247,9 @@ func newTestPlannerWithDefaults() *TestPlanner {
 func (tp *TestPlanner) Plan(ctx context.Context) error {
 	slog.Info("Planning test execution...")
 
+	// The previous plan may be the plan directory this plan overwrites.
+	tp.loadPreviousTestSplit()
+
 	if err := tp.PreparePlanningData(ctx); err != nil {
 		return err
 	}
@@ -225,12 +271,15 @@ func (tp *TestPlanner) Plan(ctx context.Context) error {
 		return errcode.WithCode(errcode.PlanSkippablePercentageWriteFailed, fmt.Errorf("failed to write skippable percentage: %w", err))
 	}
 
+	splitWeights := tp.splitWeights()
+	splitOptions := tp.splitOptions()
 	parallelRunnerSelection := calculateParallelRunnerSplitSelection(
-		tp.testFileWeights,
+		splitWeights,
 		settings.GetMinParallelism(),
 		settings.GetMaxParallelism(),
 		settings.GetParallelRunnerOverhead(),
 		settings.GetTargetTime(),
+		splitOptions,
 	)
 	parallelRunnerSplit := parallelRunnerSelection.selected
 	parallelRunners := parallelRunnerSplit.parallelRunners
@@ -238,21 +287,30 @@ func (tp *TestPlanner) Plan(ctx context.Context) error {
 	if err := writePlanFile(constants.ParallelRunnersOutputPath, []byte(runnersContent)); err != nil {
 		return errcode.WithCode(errcode.PlanParallelRunnersWriteFailed, fmt.Errorf("failed to write parallel runners: %w", err))
 	}
+	ciNodeWorkers := splitOptions.ciNodeWorkerCounts(parallelRunners)
+	if err := writeCINodeWorkersArtifact(ciNodeWorkers); err != nil {
+		return errcode.WithCode(errcode.PlanCINodeWorkersWriteFailed, err)
+	}
 
-	if ciProvider, err := tp.ciProviderDetector.DetectCIProvider(); err == nil {
+	if workspacePackage := os.Getenv(constants.WorkspacePackageEnvVar); workspacePackage != "" {
+		slog.Info("Planning a workspace package; the workspace plan configures the CI provider", "package", workspacePackage)
+	} else if ciProvider, err := tp.ciProviderDetector.DetectCIProvider(); err == nil {
 		slog.Debug("CI provider detected, configuring with parallel runners",
 			"provider", ciProvider.Name(), "parallelRunners", parallelRunners)
 
-		if err := ciProvider.Configure(parallelRunners); err != nil {
+		if err := ciProvider.Configure(parallelRunners, ciNodeWorkers); err != nil {
 			slog.Warn("Failed to configure CI provider", "provider", ciProvider.Name(), "error", err)
 		}
 	} else {
 		slog.Info("No CI provider detected, running tests without CI integration", "error", err)
 	}
 
-	if err := tp.CreateTestSplits(tp.testFileWeights, parallelRunners, constants.TestFilesOutputPath); err != nil {
+	if err := tp.CreateTestSplits(splitWeights, parallelRunners, constants.TestFilesOutputPath); err != nil {
 		return errcode.WithCode(errcode.PlanTestSplitsWriteFailed, fmt.Errorf("failed to create test splits: %w", err))
 	}
+	if err := tp.writePlanJSONArtifact(parallelRunnerSelection, ciNodeWorkers); err != nil {
+		return errcode.WithCode(errcode.PlanJSONWriteFailed, err)
+	}
 
 	if settings.GetReportEnabled() {
 		printPlanReport(tp.reportWriter, tp, parallelRunnerSelection)
@@ -289,8 +347,16 @@ func (tp *TestPlanner) PreparePlanningData(ctx context.Context) error {
 		slog.Info("Preparing test optimization data", "runtimeTags", tags, "platform", detectedPlatform.Name())
 	}
 
+	constraintsConfig, err := loadTestFileConstraintsConfig(settings.GetConstraintsFile())
+	if err != nil {
+		return errcode.WithCode(errcode.PlanConstraintsInvalid, err)
+	}
+
 	// Detect framework once to avoid duplicate work
 	testFramework, err := detectedPlatform.DetectFramework()
+	if errors.Is(err, platform.ErrFrameworksOfSeveralPlatforms) {
+		return errcode.WithCode(errcode.PlanFrameworkPlatformMismatch, fmt.Errorf("failed to detect framework: %w", err))
+	}
 	if err != nil {
 		return errcode.WithCode(errcode.PlanFrameworkDetectionFailed, fmt.Errorf("failed to detect framework: %w", err))
 	}
@@ -498,7 +564,20 @@ func (tp *TestPlanner) PreparePlanningData(ctx context.Context) error {
 	tp.recordITRSkippedTelemetry(isSuiteLevelSkipping)
 	tp.suitesBySourceFile = indexSuitesBySourceFile(tp.suiteAggregates)
 	tp.skippablePercentage = calculateSavedTimePercentage(tp.suiteAggregates)
+	tp.loadDurationHistory()
+	tp.localTestFileWeights = loadLocalTestFileWeights()
+	tp.preferLocalDurations = settings.GetPreferLocalDurations()
 	tp.testFileWeights = tp.calculateFileWeights()
+	tp.applyDurationEstimate(settings.GetDurationEstimate())
+	tp.applyTestFileConstraints(constraintsConfig)
+	tp.testChunkWeights = nil
+	if settings.GetChunkSlowTestFiles() {
+		if selectedDiscoveryMode == discoveryModeFull {
+			tp.testChunkWeights = tp.chunkSlowTestFiles(testFramework)
+		} else {
+			slog.Info("Slow test files are only chunked with full test discovery", "framework", testFramework.Name())
+		}
+	}
 
 	tp.recordDiscoveryReport(selectedDiscoveryMode, cacheResult, selectedDiscoveryDuration)
 	tp.tiaSkippingEnabled = tiaSkippingEnabled
@@ -510,11 +589,18 @@ func (tp *TestPlanner) PreparePlanningData(ctx context.Context) error {
 
 func (tp *TestPlanner) recordPlanningTelemetry(selection splitSelection) {
 	backendDurationTestFiles := 0
+	localDurationTestFiles := 0
+	historyDurationTestFiles := 0
 	defaultDurationTestFiles := 0
 	for _, source := range tp.testFileDurationSources {
-		if source == testFileDurationSourceKnown {
+		switch source {
+		case testFileDurationSourceKnown:
 			backendDurationTestFiles++
-		} else {
+		case testFileDurationSourceLocal:
+			localDurationTestFiles++
+		case testFileDurationSourceHistory:
+			historyDurationTestFiles++
+		default:
 			defaultDurationTestFiles++
 		}
 	}
@@ -538,6 +624,8 @@ func (tp *TestPlanner) recordPlanningTelemetry(selection splitSelection) {
 		RunnableTestFiles:         len(tp.testFileWeights),
 		FullySkippedTestFiles:     fullySkippedTestFiles,
 		BackendDurationTestFiles:  backendDurationTestFiles,
+		LocalDurationTestFiles:    localDurationTestFiles,
+		HistoryDurationTestFiles:  historyDurationTestFiles,
 		DefaultDurationTestFiles:  defaultDurationTestFiles,
 		EstimatedTimeSavedPercent: tp.skippablePercentage,
 		ParallelRunners:           selection.selected.parallelRunners,
@@ -640,10 +728,14 @@ func (tp *TestPlanner) estimateDiscoveredSuiteDurations() {
 		aggregate.DurationSource = testFileDurationSourceDefault
 		if suiteInfo, ok := getTestSuiteDuration(tp.testSuiteDurations, key); ok {
 			if p50, ok := parseDurationP50(suiteInfo); ok {
-				aggregate.TotalDuration = p50
-				aggregate.EstimatedDuration = p50
+				runnableFraction := 1.0
 				if aggregate.NumTests > 0 {
-					aggregate.EstimatedDuration = p50 * float64(aggregate.NumTests-aggregate.NumTestsSkipped) / float64(aggregate.NumTests)
+					runnableFraction = float64(aggregate.NumTests-aggregate.NumTestsSkipped) / float64(aggregate.NumTests)
+				}
+				aggregate.TotalDuration = p50
+				aggregate.EstimatedDuration = p50 * runnableFraction
+				if p90, ok := parseDurationP90(suiteInfo, p50); ok {
+					aggregate.EstimatedDurationP90 = p90 * runnableFraction
 				}
 				if aggregate.EstimatedDuration > 0 {
 					aggregate.DurationSource = testFileDurationSourceKnown
@@ -675,6 +767,7 @@ func (tp *TestPlanner) addDurationDataForFastDiscoveryFallback() {
 			if !ok {
 				continue
 			}
+			p90, _ := parseDurationP90(suiteInfo, duration)
 
 			// Fast discovery only sees files, not test cases. For suite-level TIA we
 			// assume a single backend suite maps to a single source file, so one
@@ -684,14 +777,15 @@ func (tp *TestPlanner) addDurationDataForFastDiscoveryFallback() {
 			}
 
 			tp.suiteAggregates[key] = testSuiteAggregate{
-				Module:            module,
-				Suite:             suite,
-				SourceFile:        sourceFile,
-				TotalDuration:     duration,
-				EstimatedDuration: duration,
-				DurationSource:    testFileDurationSourceKnown,
-				NumTests:          1,
-				NumTestsSkipped:   0,
+				Module:               module,
+				Suite:                suite,
+				SourceFile:           sourceFile,
+				TotalDuration:        duration,
+				EstimatedDuration:    duration,
+				EstimatedDurationP90: p90,
+				DurationSource:       testFileDurationSourceKnown,
+				NumTests:             1,
+				NumTestsSkipped:      0,
 			}
 			seenSourceFiles[sourceFile] = struct{}{}
 		}
@@ -720,6 +814,16 @@ func parseDurationP50(suiteInfo api.TestSuiteDurationInfo) (float64, bool) {
 	return float64(p50), true
 }
 
+// parseDurationP90 returns the P90 duration of a suite whose P50 duration is
+// p50. A P90 below the P50 is not a usable percentile and is ignored.
+func parseDurationP90(suiteInfo api.TestSuiteDurationInfo, p50 float64) (float64, bool) {
+	p90, err := strconv.ParseInt(suiteInfo.Duration.P90, 10, 64)
+	if err != nil || float64(p90) < p50 {
+		return 0, false
+	}
+	return float64(p90), true
+}
+
 func calculateSavedTimePercentage(suiteAggregates map[testSuiteKey]testSuiteAggregate) float64 {
 	var totalDuration float64
 	var estimatedDuration float64
@@ -790,11 +894,15 @@ func (tp *TestPlanner) calculateFileWeights() map[string]int {
 func (tp *TestPlanner) estimateTestFileWeights(testFiles map[string]struct{}) map[string]int {
 	testFileWeights := make(map[string]int, len(testFiles))
 	tp.testFileDurationSources = make(map[string]testFileDurationSource, len(testFiles))
+	tp.testFileP90Weights = make(map[string]int)
 	for testFile := range testFiles {
 		estimate, ok := tp.estimateTestFileWeight(testFile)
 		if ok {
 			testFileWeights[testFile] = estimate.weight
 			tp.testFileDurationSources[testFile] = estimate.source
+			if estimate.p90Weight > estimate.weight {
+				tp.testFileP90Weights[testFile] = estimate.p90Weight
+			}
 		}
 	}
 	return testFileWeights
@@ -806,6 +914,17 @@ func (tp *TestPlanner) testFileWeight(testFile string) (int, bool) {
 }
 
 func (tp *TestPlanner) estimateTestFileWeight(testFile string) (testFileWeightEstimate, bool) {
+	estimate, ok := tp.estimateSuiteTestFileWeight(testFile)
+	if !ok {
+		return testFileWeightEstimate{}, false
+	}
+	estimate = tp.applyDurationHistory(testFile, estimate)
+	return tp.applyLocalDuration(testFile, estimate), true
+}
+
+// estimateSuiteTestFileWeight estimates a file's weight from the durations of
+// its suites.
+func (tp *TestPlanner) estimateSuiteTestFileWeight(testFile string) (testFileWeightEstimate, bool) {
 	suiteKeys := tp.suitesBySourceFile[testFile]
 	if len(suiteKeys) == 0 {
 		return testFileWeightEstimate{
@@ -814,8 +933,8 @@ func (tp *TestPlanner) estimateTestFileWeight(testFile string) (testFileWeightEs
 		}, true
 	}
 
-	var duration float64
-	var hasRunnableSuite bool
+	var duration, p90Duration float64
+	var hasRunnableSuite, hasP90Duration bool
 	var source testFileDurationSource
 	for _, key := range suiteKeys {
 		aggregate := tp.suiteAggregates[key]
@@ -825,6 +944,12 @@ func (tp *TestPlanner) estimateTestFileWeight(testFile string) (testFileWeightEs
 		hasRunnableSuite = true
 		source = aggregate.DurationSource
 		duration += aggregate.EstimatedDuration
+		if aggregate.EstimatedDurationP90 > 0 {
+			hasP90Duration = true
+			p90Duration += aggregate.EstimatedDurationP90
+		} else {
+			p90Duration += aggregate.EstimatedDuration
+		}
 	}
 	if !hasRunnableSuite {
 		return testFileWeightEstimate{}, false
@@ -846,8 +971,12 @@ func (tp *TestPlanner) estimateTestFileWeight(testFile string) (testFileWeightEs
 			source: source,
 		}, true
 	}
-	return testFileWeightEstimate{
+	estimate := testFileWeightEstimate{
 		weight: weight,
 		source: source,
-	}, true
+	}
+	if hasP90Duration {
+		estimate.p90Weight = int(p90Duration / float64(time.Millisecond))
+	}
+	return estimate, true
 }
@@ -29,6 +29,7 @@ import (
 	"github.com/DataDog/ddtest/internal/testoptimization"
 	"github.com/DataDog/ddtest/internal/testoptimization/api"
 	ciUtils "github.com/DataDog/ddtest/internal/utils"
+	"github.com/spf13/viper"
 )
 
 // Mock implementations for testing
@@ -186,6 +187,22 @@ func setPlannerTestsLocation(t *testing.T, pattern string) {
 	})
 }
 
+// setPlannerEnv sets the given environment variables for the test and
+// reinitializes the settings from them. It resets viper as well, because
+// settings.Init overrides some of the settings it parsed with viper.Set.
+func setPlannerEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	resetSettings := func() {
+		viper.Reset()
+		settings.Init()
+	}
+	t.Cleanup(resetSettings)
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	resetSettings()
+}
+
 func setPlannerRuntimeTags(t *testing.T, tags string) {
 	t.Helper()
 	t.Cleanup(settings.Init)
@@ -546,15 +563,17 @@ type MockCIProvider struct {
 	ConfigureCalled bool
 	ConfigureErr    error
 	ParallelRunners int
+	CINodeWorkers   []int
 }
 
 func (m *MockCIProvider) Name() string {
 	return m.ProviderName
 }
 
-func (m *MockCIProvider) Configure(parallelRunners int) error {
+func (m *MockCIProvider) Configure(parallelRunners int, ciNodeWorkers []int) error {
 	m.ConfigureCalled = true
 	m.ParallelRunners = parallelRunners
+	m.CINodeWorkers = ciNodeWorkers
 	return m.ConfigureErr
 }
 
@@ -1589,6 +1608,26 @@ func TestTestPlanner_RecordFullDiscoveryResults_SuiteSkippablesSkipDiscoveredTes
 	}
 }
 
+func TestTestPlanner_RecordFullDiscoveryResults_CountsDisabledTestsSeparately(t *testing.T) {
+	runner := newTestPlannerWithDefaults()
+	tests := []testoptimization.Test{
+		{Module: "rspec", Suite: "Order", Name: "first", SuiteSourceFile: "spec/models/order_spec.rb"},
+		{Module: "rspec", Suite: "Order", Name: "second", SuiteSourceFile: "spec/models/order_spec.rb"},
+		{Module: "rspec", Suite: "Order", Name: "third", SuiteSourceFile: "spec/models/order_spec.rb"},
+	}
+	skippables := api.Skippables{Tests: api.SkippableTests{"rspec.Order.first.": true}}
+	disabledTests := map[string]bool{"rspec.Order.second": true}
+
+	if err := runner.recordFullDiscoveryResults(tests, discovery.TestFileSet{Pattern: "**/*"}, newSkippableMatcher(skippables, disabledTests)); err != nil {
+		t.Fatalf("recordFullDiscoveryResults() should not fail, got: %v", err)
+	}
+
+	aggregate := runner.suiteAggregates[testSuiteKey{Module: "rspec", Suite: "Order"}]
+	if aggregate.NumTests != 3 || aggregate.NumTestsSkipped != 2 || aggregate.NumTestsDisabled != 1 {
+		t.Fatalf("expected one TIA-skipped and one disabled test, got %+v", aggregate)
+	}
+}
+
 func TestTestPlanner_RecordSuiteLevelSkippables_SafetyGuardsKeepFilesRunnable(t *testing.T) {
 	tests := []struct {
 		name                                      string
@@ -1645,6 +1684,15 @@ func TestTestPlanner_RecordSuiteLevelSkippables_SafetyGuardsKeepFilesRunnable(t
 			if _, ok := weights[tt.expectedFile]; !ok {
 				t.Fatalf("expected %s to remain runnable, got weights %+v and aggregates %+v", tt.expectedFile, weights, runner.suiteAggregates)
 			}
+			keptSuites := 0
+			for _, aggregate := range runner.suiteAggregates {
+				if aggregate.UnskippableTestsKept > 0 {
+					keptSuites++
+				}
+			}
+			if keptSuites != tt.expectedForceRunnableSuiteAggregatesCount {
+				t.Fatalf("expected %d suites to record kept unskippable tests, got aggregates %+v", tt.expectedForceRunnableSuiteAggregatesCount, runner.suiteAggregates)
+			}
 			if !strings.Contains(logs.String(), "Checked unskippable marker suites") {
 				t.Fatalf("expected unskippable marker suite guard log, got logs: %s", logs.String())
 			}
@@ -1856,6 +1904,44 @@ func TestTestPlanner_Setup_WithCIProvider(t *testing.T) {
 	}
 }
 
+func TestTestPlanner_Setup_WithCINodeCapacities(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_MIN_PARALLELISM", "2")
+	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_MAX_PARALLELISM", "2")
+	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_CI_NODE_CAPACITIES", "0:4")
+	settings.Init()
+	t.Cleanup(settings.Init)
+
+	mockFramework := &MockFramework{
+		FrameworkName: "rspec",
+		Tests: []testoptimization.Test{
+			{Suite: "TestSuite1", Name: "test1", Parameters: "", SuiteSourceFile: "test/file1_test.rb"},
+			{Suite: "TestSuite2", Name: "test2", Parameters: "", SuiteSourceFile: "test/file2_test.rb"},
+		},
+	}
+	mockPlatform := &MockPlatform{
+		PlatformName: "ruby",
+		Tags:         map[string]string{"platform": "ruby"},
+		Framework:    mockFramework,
+	}
+	mockCIProvider := &MockCIProvider{ProviderName: "github"}
+
+	runner := NewWithDependencies(
+		&MockPlatformDetector{Platform: mockPlatform},
+		&MockTestOptimizationClient{Settings: testOptimizationSettings(true, true, false)},
+		&MockCIProviderDetector{CIProvider: mockCIProvider},
+	)
+	if err := runner.Plan(context.Background()); err != nil {
+		t.Fatalf("Plan() should not return error, got: %v", err)
+	}
+
+	assertFileContent(t, constants.ParallelRunnersOutputPath, "2")
+	assertFileContent(t, constants.CINodeWorkersOutputPath, "4\n1\n")
+	if !slices.Equal(mockCIProvider.CINodeWorkers, []int{4, 1}) {
+		t.Errorf("Expected CI provider Configure called with CI node workers [4 1], got %v", mockCIProvider.CINodeWorkers)
+	}
+}
+
 func TestTestPlanner_Setup_CIProviderDetectionFailure(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -4064,7 +4150,7 @@ func TestRecordRunnableAndSkippedTest_CountsTestsPerSuite(t *testing.T) {
 		Suite:           "Suite1",
 		Name:            "test2",
 		SuiteSourceFile: "spec/file1_test.rb",
-	}, "spec/file1_test.rb")
+	}, "spec/file1_test.rb", false)
 	recordRunnableTest(suiteAggregates, testoptimization.Test{
 		Module:          "rspec",
 		Suite:           "Suite2",
@@ -4210,6 +4296,17 @@ func TestTestPlanner_PreparePlanningData_FrameworkDetectionError(t *testing.T) {
 	assertPlannerErrorCode(t, err, errcode.PlanFrameworkDetectionFailed)
 }
 
+func TestTestPlanner_PreparePlanningData_FrameworksOfSeveralPlatforms(t *testing.T) {
+	mockPlatform := &MockPlatform{
+		Tags:         map[string]string{"platform": "ruby"},
+		FrameworkErr: fmt.Errorf("%w: framework 'jest' belongs to platform 'javascript', not 'ruby'", platform.ErrFrameworksOfSeveralPlatforms),
+	}
+	runner := NewWithDependencies(&MockPlatformDetector{Platform: mockPlatform}, &MockTestOptimizationClient{}, newDefaultMockCIProviderDetector())
+
+	err := runner.PreparePlanningData(context.Background())
+	assertPlannerErrorCode(t, err, errcode.PlanFrameworkPlatformMismatch)
+}
+
 func TestTestPlanner_PreparePlanningData_TestDiscoveryError(t *testing.T) {
 	ctx := context.Background()
 
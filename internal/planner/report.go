@@ -95,6 +95,14 @@ func formatDDTestSettingName(field reflect.StructField) string {
 			words[i] = "CI"
 			continue
 		}
+		if word == "url" {
+			words[i] = "URL"
+			continue
+		}
+		if word == "junit" {
+			words[i] = "JUnit"
+			continue
+		}
 		if i == 0 {
 			words[i] = strings.ToUpper(word[:1]) + word[1:]
 		}
@@ -139,9 +147,19 @@ func defaultDDTestSettings() settings.Config {
 		MaxParallelism:         settings.DefaultParallelism(),
 		ParallelRunnerOverhead: settings.DefaultParallelRunnerOverhead(),
 		TargetTime:             settings.DefaultTargetTime(),
+		SplitOptimizer:         settings.SplitOptimizerGreedy,
+		DurationEstimate:       settings.DurationEstimateP50,
+		OptimizeFor:            settings.OptimizationGoalTime,
 		CiNode:                 -1,
 		CiNodeWorkers:          1,
 		TestSkippingLevel:      settings.TestSkippingLevelTest,
+		WorkQueueBatchSize:     1,
+		QueueListen:            settings.DefaultQueueListen(),
+		QueueLeaseTimeout:      settings.DefaultQueueLeaseTimeout(),
+		WorkerOutput:           settings.WorkerOutputStream,
+		WorkerTimeoutSignal:    "SIGQUIT",
+		StickySplitTolerance:   settings.DefaultStickySplitTolerance(),
+		PlanLayout:             settings.PlanLayoutV1,
 		ReportEnabled:          true,
 	}
 }
@@ -179,6 +197,9 @@ func printPlanningReport(w io.Writer, report PlanReportData) {
 	printSkippingPlanningReport(w, report.DatadogSettings, report.Skippables, report.ManagedFlakyTests, report.Planning.Skipping)
 	printRunSetPlanningReport(w, report.Planning)
 	printRunnerSplitPlanningReport(w, report)
+	printConstraintsPlanningReport(w, report.Constraints)
+	printStickySplitPlanningReport(w, report.StickySplit)
+	printSplitOrderPlanningReport(w, report.SplitOrder)
 }
 
 func printLongSeparateRunnerSuitesReport(w io.Writer, suites []testSuiteTimingReport) {
@@ -347,7 +368,25 @@ func printDurationEstimatesPlanningReport(w io.Writer, durations durationApplica
 	reportFprintln(w, "  Duration estimates")
 	reportFprintf(w, "    Backend durations used: %s\n", formatCountWithUnit(durations.BackendDurationsApplied, "suite", "suites"))
 	reportFprintf(w, "    Default durations used: %s\n", formatCountWithUnit(durations.SuitesWithoutDurations, "suite", "suites"))
+	if durations.LocalDurationsApplied > 0 {
+		reportFprintf(w, "    Local durations used: %s\n", formatCountWithUnit(durations.LocalDurationsApplied, "file", "files"))
+	}
+	if durations.HistoryDurationsApplied > 0 {
+		reportFprintf(w, "    History durations used: %s\n", formatCountWithUnit(durations.HistoryDurationsApplied, "file", "files"))
+	}
 	reportFprintf(w, "    Backend-only suites added: %s\n", formatCount(durations.BackendSuitesAdded))
+	if durations.DurationEstimate != "" {
+		reportFprintf(w, "    Modeled from P50 and P90: %s (minimizing %s wall time)\n",
+			formatCountWithUnit(durations.ModeledTestFiles, "file", "files"),
+			formatDurationEstimate(durations.DurationEstimate))
+	}
+}
+
+func formatDurationEstimate(estimate settings.DurationEstimate) string {
+	if estimate == settings.DurationEstimateP90 {
+		return "P90"
+	}
+	return string(estimate)
 }
 
 func printSkippingPlanningReport(
@@ -402,16 +441,26 @@ func printRunnerSplitPlanningReport(w io.Writer, report PlanReportData) {
 		reportFprintf(w, "    Reason: %s\n", formatSplitSelectionReason(selection))
 		printTargetTimeReport(w, selection)
 	}
-	reportFprintf(w, "    Expected wall time: %s\n", formatDuration(split.wallTimeDuration()))
+	if split.p90WallTime > 0 {
+		reportFprintf(w, "    Expected wall time: %s\n", formatDuration(split.expectedWallTimeDuration()))
+		reportFprintf(w, "    P90 wall time: %s\n", formatDuration(split.p90WallTimeDuration()))
+	} else {
+		reportFprintf(w, "    Expected wall time: %s\n", formatDuration(split.wallTimeDuration()))
+	}
 	if selection.available {
 		reportFprintf(w, "    Modeled CI overhead: %s (%s x configured CI job overhead %s)\n",
 			formatDuration(selection.overheadDuration(split)),
 			formatRunnerCount(split.parallelRunners),
 			formatDuration(selection.parallelRunnerOverhead))
-		reportFprintf(w, "    Selection score: %s (wall time + modeled CI overhead)\n", formatDuration(selection.scoreDuration(split)))
+		wallTime := "wall time"
+		if selection.durationEstimate == settings.DurationEstimateP90 && split.p90WallTime > 0 {
+			wallTime = "P90 wall time"
+		}
+		reportFprintf(w, "    Selection score: %s (%s + modeled CI overhead)\n", formatDuration(selection.scoreDuration(split)), wallTime)
+		printCostReport(w, selection)
 	}
 	reportFprintf(w, "    Imbalance: %s\n", formatDuration(split.imbalanceDuration()))
-	if selection.available && selection.targetTime > 0 {
+	if selection.available && selection.constrained() {
 		printWithoutTargetTimeReport(w, selection)
 	}
 	if selection.available && len(selection.candidates) > 0 {
@@ -419,6 +468,80 @@ func printRunnerSplitPlanningReport(w io.Writer, report PlanReportData) {
 	}
 }
 
+func printConstraintsPlanningReport(w io.Writer, constraints constraintsReport) {
+	if !constraints.Available {
+		return
+	}
+
+	reportFprintln(w, "  Test file constraints")
+	reportFprintf(w, "    Constraints file: %s\n", constraints.Path)
+	reportFprintf(w, "    Groups: %s (%s)\n",
+		formatCount(constraints.Groups),
+		formatCountWithUnit(constraints.GroupedTestFiles, "test file", "test files"))
+	reportFprintf(w, "    Exclusive test files: %s\n", formatCount(constraints.ExclusiveTestFiles))
+	if constraints.Constrained.parallelRunners <= 0 {
+		return
+	}
+	reportFprintf(w, "    Without constraints: wall %s, imbalance %s\n",
+		formatDuration(constraints.Unconstrained.wallTimeDuration()),
+		formatDuration(constraints.Unconstrained.imbalanceDuration()))
+	if constraints.Constrained.wallTime > constraints.Unconstrained.wallTime {
+		reportFprintf(w, "    Balance cost: %s\n", formatWallTimeDifference(constraints.Constrained, constraints.Unconstrained))
+	} else {
+		reportFprintln(w, "    Balance cost: none")
+	}
+}
+
+func printStickySplitPlanningReport(w io.Writer, stickySplit stickySplitReport) {
+	if !stickySplit.Available {
+		return
+	}
+
+	reportFprintln(w, "  Sticky split")
+	reportFprintf(w, "    Previous plan: %s\n", stickySplit.PreviousPlan)
+	reportFprintf(w, "    Test files kept on their runner: %s\n", formatCount(stickySplit.KeptTestFiles))
+	reportFprintf(w, "    Test files moved: %s\n", formatCount(stickySplit.MovedTestFiles))
+	reportFprintf(w, "    New test files: %s\n", formatCount(stickySplit.NewTestFiles))
+	if stickySplit.FromScratchWallTime <= 0 {
+		return
+	}
+	if stickySplit.FromScratch {
+		reportFprintf(w, "    Split: from scratch, keeping test files on their runner exceeded the %s%% tolerance\n", strconv.FormatFloat(stickySplit.Tolerance, 'f', -1, 64))
+		return
+	}
+	reportFprintf(w, "    Wall time: %s (from scratch %s, tolerance %s%%)\n",
+		formatDuration(stickySplit.WallTime),
+		formatDuration(stickySplit.FromScratchWallTime),
+		strconv.FormatFloat(stickySplit.Tolerance, 'f', -1, 64))
+}
+
+func printSplitOrderPlanningReport(w io.Writer, splitOrder splitOrderReport) {
+	if len(splitOrder.Order) == 0 {
+		return
+	}
+
+	priorities := make([]string, 0, len(splitOrder.Order))
+	for _, priority := range splitOrder.Order {
+		priorities = append(priorities, string(priority))
+	}
+	reportFprintln(w, "  Split order")
+	reportFprintf(w, "    Priorities: %s\n", strings.Join(priorities, ", "))
+	for _, priority := range splitOrder.Order {
+		switch priority {
+		case settings.SplitOrderNew:
+			reportFprintf(w, "    New test files: %s\n", formatCount(splitOrder.NewTestFiles))
+		case settings.SplitOrderChanged:
+			if splitOrder.ChangedTestFilesError != "" {
+				reportFprintf(w, "    Changed test files: unavailable (%s)\n", splitOrder.ChangedTestFilesError)
+				continue
+			}
+			reportFprintf(w, "    Changed test files: %s\n", formatCount(splitOrder.ChangedTestFiles))
+		case settings.SplitOrderFlaky:
+			reportFprintf(w, "    Flaky test files: %s\n", formatCount(splitOrder.FlakyTestFiles))
+		}
+	}
+}
+
 func effectiveSplitSelection(report PlanReportData) splitSelection {
 	if report.SplitSelection.available {
 		return report.SplitSelection
@@ -430,16 +553,87 @@ func effectiveSplitSelection(report PlanReportData) splitSelection {
 }
 
 func formatSplitSelectionReason(selection splitSelection) string {
-	if selection.targetTime <= 0 {
-		return "lowest selection score"
+	objective := "lowest selection score"
+	if selection.optimizeFor == settings.OptimizationGoalCost {
+		objective = "lowest estimated CI cost"
 	}
-	if selection.meetsTargetTime(selection.selected) {
-		if sameSplitScore(selection.selected, selection.bestWithoutTarget) {
-			return "lowest selection score; target time satisfied"
+	if !selection.constrained() {
+		return objective
+	}
+
+	selected := selection.selected
+	if selection.meetsConstraints(selected) {
+		if sameSplitScore(selected, selection.bestWithoutTarget) {
+			return objective + "; " + selection.constraintsSatisfiedDescription()
 		}
-		return "lowest selection score among splits that meet target time"
+		return objective + " among splits that " + selection.constraintsDescription()
+	}
+	if selection.targetTime > 0 && !selection.meetsTargetTime(selected) {
+		return "no split met target time; selected lowest wall time to get closest to target"
 	}
-	return "no split met target time; selected lowest wall time to get closest to target"
+	if selection.targetTime > 0 {
+		return "no split that meets target time fits CI budget; selected lowest estimated CI cost among them"
+	}
+	return "no split fits CI budget; selected lowest estimated CI cost"
+}
+
+// constraintsDescription describes the target time and CI budget that limit
+// the selection, such as "meet target time and fit CI budget".
+func (s splitSelection) constraintsDescription() string {
+	parts := make([]string, 0, 2)
+	if s.targetTime > 0 {
+		parts = append(parts, "meet target time")
+	}
+	if s.costModel.hasBudget() {
+		parts = append(parts, "fit CI budget")
+	}
+	return strings.Join(parts, " and ")
+}
+
+func (s splitSelection) constraintsSatisfiedDescription() string {
+	parts := make([]string, 0, 2)
+	if s.targetTime > 0 {
+		parts = append(parts, "target time satisfied")
+	}
+	if s.costModel.hasBudget() {
+		parts = append(parts, "within CI budget")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// withoutConstraintsDescription names the constraints a split would be
+// selected without, such as "target time and CI budget".
+func (s splitSelection) withoutConstraintsDescription() string {
+	parts := make([]string, 0, 2)
+	if s.targetTime > 0 {
+		parts = append(parts, "target time")
+	}
+	if s.costModel.hasBudget() {
+		parts = append(parts, "CI budget")
+	}
+	return strings.Join(parts, " and ")
+}
+
+func printCostReport(w io.Writer, selection splitSelection) {
+	if !selection.costModel.enabled() {
+		return
+	}
+
+	split := selection.selected
+	reportFprintf(w, "    Estimated CI cost: %s (%s billed across %s)\n",
+		formatCost(selection.costModel.cost(split)),
+		formatDuration(split.billedTimeDuration()),
+		formatCountWithUnit(split.parallelRunners, "CI job", "CI jobs"))
+	if !selection.costModel.hasBudget() {
+		return
+	}
+	if selection.costModel.withinBudget(split) {
+		reportFprintf(w, "    CI budget: %s, satisfied\n", formatCost(selection.costModel.budget))
+		return
+	}
+	reportFprintf(w, "    CI budget: %s, exceeded by %s\n",
+		formatCost(selection.costModel.budget),
+		formatCost(selection.costModel.cost(split)-selection.costModel.budget))
 }
 
 func printTargetTimeReport(w io.Writer, selection splitSelection) {
@@ -457,19 +651,22 @@ func printTargetTimeReport(w io.Writer, selection splitSelection) {
 
 func printWithoutTargetTimeReport(w io.Writer, selection splitSelection) {
 	best := selection.bestWithoutTarget
+	without := selection.withoutConstraintsDescription()
 	if sameSplitScore(selection.selected, best) {
 		reportFprintln(w)
-		reportFprintln(w, "    Without target time: same as selected")
+		reportFprintf(w, "    Without %s: same as selected\n", without)
 		return
 	}
 
 	reportFprintln(w)
-	reportFprintf(w, "    Without target time: %s (wall %s, overhead %s, score %s)\n",
+	reportFprintf(w, "    Without %s: %s (wall %s, overhead %s, score %s%s)\n",
+		without,
 		formatRunnerCount(best.parallelRunners),
 		formatDuration(best.wallTimeDuration()),
 		formatDuration(selection.overheadDuration(best)),
-		formatDuration(selection.scoreDuration(best)))
-	reportFprintf(w, "      Selected vs without target: %s\n", formatSelectedVsWithoutTarget(selection))
+		formatDuration(selection.scoreDuration(best)),
+		selection.formatCandidateCost(best))
+	reportFprintf(w, "      Selected vs without %s: %s\n", strings.TrimSuffix(without, " time"), formatSelectedVsWithoutTarget(selection))
 }
 
 func printSplitCandidatesReport(w io.Writer, selection splitSelection) {
@@ -490,11 +687,12 @@ func printSplitCandidatesReport(w io.Writer, selection splitSelection) {
 		if reason != "" {
 			reason = ", " + reason
 		}
-		reportFprintf(w, "      %s: wall %s, overhead %s, score %s%s\n",
+		reportFprintf(w, "      %s: wall %s, overhead %s, score %s%s%s\n",
 			formatRunnerCount(candidate.parallelRunners),
 			formatDuration(candidate.wallTimeDuration()),
 			formatDuration(selection.overheadDuration(candidate)),
 			formatDuration(selection.scoreDuration(candidate)),
+			selection.formatCandidateCost(candidate),
 			reason)
 	}
 }
@@ -508,8 +706,11 @@ func formatSplitCandidateReason(selection splitSelection, candidate splitScore)
 			parts = append(parts, "missed target by "+formatDuration(candidate.wallTimeDuration()-selection.targetTime))
 		}
 	}
-	if selection.targetTime > 0 && sameSplitScore(candidate, selection.bestWithoutTarget) && !sameSplitScore(candidate, selection.selected) {
-		parts = append(parts, "would choose without target time")
+	if selection.costModel.hasBudget() && !selection.costModel.withinBudget(candidate) {
+		parts = append(parts, "over budget")
+	}
+	if selection.constrained() && sameSplitScore(candidate, selection.bestWithoutTarget) && !sameSplitScore(candidate, selection.selected) {
+		parts = append(parts, "would choose without "+selection.withoutConstraintsDescription())
 	}
 	if sameSplitScore(candidate, selection.selected) {
 		parts = append(parts, "selected")
@@ -518,8 +719,32 @@ func formatSplitCandidateReason(selection splitSelection, candidate splitScore)
 }
 
 func formatSelectedVsWithoutTarget(selection splitSelection) string {
-	return formatWallTimeDifference(selection.selected, selection.bestWithoutTarget) + ", " +
+	difference := formatWallTimeDifference(selection.selected, selection.bestWithoutTarget) + ", " +
 		formatOverheadDifference(selection.overheadDuration(selection.selected), selection.overheadDuration(selection.bestWithoutTarget))
+	if selection.costModel.enabled() {
+		difference += ", " + formatCostDifference(selection.costModel.cost(selection.selected), selection.costModel.cost(selection.bestWithoutTarget))
+	}
+	return difference
+}
+
+func formatCostDifference(selected, withoutTarget float64) string {
+	switch {
+	case formatCost(selected) == formatCost(withoutTarget):
+		return "same estimated CI cost"
+	case selected > withoutTarget:
+		return formatCost(selected-withoutTarget) + " more estimated CI cost"
+	default:
+		return formatCost(withoutTarget-selected) + " less estimated CI cost"
+	}
+}
+
+// formatCandidateCost returns the estimated CI cost of a candidate split for
+// the split candidate lines of the report, or "" without a CI cost model.
+func (s splitSelection) formatCandidateCost(score splitScore) string {
+	if !s.costModel.enabled() {
+		return ""
+	}
+	return ", cost " + formatCost(s.costModel.cost(score))
 }
 
 func formatWallTimeDifference(selected, withoutTarget splitScore) string {
@@ -567,7 +792,25 @@ func (s splitSelection) scoreDuration(score splitScore) time.Duration {
 }
 
 func (s splitSelection) meetsTargetTime(score splitScore) bool {
-	return s.targetTime > 0 && score.wallTimeDuration() <= s.targetTime
+	return s.selector().meetsTargetTime(score)
+}
+
+func (s splitSelection) constrained() bool {
+	return s.selector().constrained()
+}
+
+func (s splitSelection) meetsConstraints(score splitScore) bool {
+	return s.selector().meetsConstraints(score)
+}
+
+func (s splitSelection) selector() splitSelector {
+	return splitSelector{
+		parallelRunnerOverhead: s.parallelRunnerOverhead,
+		targetTime:             s.targetTime,
+		durationEstimate:       s.durationEstimate,
+		optimizeFor:            s.optimizeFor,
+		costModel:              s.costModel,
+	}
 }
 
 func (s splitSelection) bestWallTime() time.Duration {
@@ -585,7 +828,7 @@ func (s splitSelection) bestWallTime() time.Duration {
 
 func (s splitSelection) sortedCandidatesByScore() []splitScore {
 	candidates := slices.Clone(s.candidates)
-	selector := splitSelector{parallelRunnerOverhead: s.parallelRunnerOverhead}
+	selector := s.selector()
 	slices.SortFunc(candidates, func(a, b splitScore) int {
 		switch {
 		case selector.better(a, b):
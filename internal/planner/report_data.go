@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/framework"
 	"github.com/DataDog/ddtest/internal/runmetadata"
 	"github.com/DataDog/ddtest/internal/settings"
 	"github.com/DataDog/ddtest/internal/testoptimization/api"
@@ -206,9 +207,15 @@ type durationApplicationReport struct {
 	Available               bool
 	BackendDurationsApplied int
 	BackendSuitesAdded      int
+	LocalDurationsApplied   int
+	HistoryDurationsApplied int
 	SuitesWithoutDurations  int
 	FilesWithoutDurations   int
 	ExpectedFullDuration    time.Duration
+	// DurationEstimate is set when test files are modeled from their P50 and
+	// P90 durations, and ModeledTestFiles counts the files with a known P90.
+	DurationEstimate settings.DurationEstimate
+	ModeledTestFiles int
 }
 
 type skippingApplicationReport struct {
@@ -228,6 +235,18 @@ type planningReport struct {
 	EstimatedTimeSaved float64
 }
 
+// constraintsReport compares the selected split with the split of the same
+// runner count that ignores the test file constraints.
+type constraintsReport struct {
+	Available          bool
+	Path               string
+	Groups             int
+	GroupedTestFiles   int
+	ExclusiveTestFiles int
+	Constrained        splitScore
+	Unconstrained      splitScore
+}
+
 type PlanReportData struct {
 	RunInfo                  runmetadata.RunInfo
 	PlanMetadata             PlanMetadata
@@ -240,6 +259,9 @@ type PlanReportData struct {
 	Planning                 planningReport
 	LongSeparateRunnerSuites []testSuiteTimingReport
 	SlowestTestSuitesOverall []testSuiteTimingReport
+	Constraints              constraintsReport
+	StickySplit              stickySplitReport
+	SplitOrder               splitOrderReport
 	Split                    splitScore
 	SplitSelection           splitSelection
 }
@@ -252,6 +274,8 @@ type planningReportStats struct {
 	uniqueTIASkippableSuitesApplied map[testSuiteKey]struct{}
 	disabledTestsApplied            int
 	unskippableMarkerSuitesForced   int
+	stickySplit                     stickySplitReport
+	splitOrder                      splitOrderReport
 }
 
 func newPlanningReportStats() planningReportStats {
@@ -268,6 +292,9 @@ func (tp *TestPlanner) newPlanReportData(split splitScore) PlanReportData {
 		Planning:                 tp.newPlanningReport(),
 		LongSeparateRunnerSuites: tp.longSeparateRunnerSuitesReport(split.parallelRunners, split),
 		SlowestTestSuitesOverall: tp.slowestTestSuitesOverallReport(slowestTestSuitesReportLimit),
+		Constraints:              tp.constraintsReport(split),
+		StickySplit:              tp.reportStats.stickySplit,
+		SplitOrder:               tp.reportStats.splitOrder,
 		Split:                    split,
 	}
 	return addBackendDataReports(report, tp.optimizationClient)
@@ -284,6 +311,11 @@ func (tp *TestPlanner) recordDiscoveryReport(mode discoveryMode, cache discovery
 	tp.reportStats.discoveryDuration = duration
 }
 
+func (tp *TestPlanner) recordStickySplitReport(report stickySplitReport) {
+	report.PreviousPlan = settings.GetPreviousPlan()
+	tp.reportStats.stickySplit = report
+}
+
 func (tp *TestPlanner) newPlanningReport() planningReport {
 	fullySkippedFiles := len(tp.testFiles) - len(tp.testFileWeights)
 	if fullySkippedFiles < 0 {
@@ -311,9 +343,13 @@ func (tp *TestPlanner) newPlanningReport() planningReport {
 			Available:               true,
 			BackendDurationsApplied: tp.backendDurationApplicationsCount(),
 			BackendSuitesAdded:      tp.backendSuitesAddedCount(mode),
+			LocalDurationsApplied:   tp.localDurationApplicationsCount(),
+			HistoryDurationsApplied: tp.historyDurationApplicationsCount(),
 			SuitesWithoutDurations:  tp.suitesWithoutBackendDurationsCount(),
 			FilesWithoutDurations:   tp.filesWithoutBackendDurationsCount(),
 			ExpectedFullDuration:    tp.expectedFullDuration(),
+			DurationEstimate:        tp.modeledDurationEstimate(),
+			ModeledTestFiles:        len(tp.testFileDurationVariances),
 		},
 		Skipping: skippingApplicationReport{
 			Available:                     true,
@@ -328,6 +364,29 @@ func (tp *TestPlanner) newPlanningReport() planningReport {
 	}
 }
 
+func (tp *TestPlanner) constraintsReport(split splitScore) constraintsReport {
+	path := settings.GetConstraintsFile()
+	if path == "" {
+		return constraintsReport{}
+	}
+
+	report := constraintsReport{
+		Available:          true,
+		Path:               path,
+		Groups:             len(tp.testFileConstraints.Groups),
+		GroupedTestFiles:   tp.testFileConstraints.groupedTestFilesCount(),
+		ExclusiveTestFiles: len(tp.testFileConstraints.Exclusive),
+		Constrained:        split,
+	}
+	if split.parallelRunners > 0 {
+		options := tp.splitOptions()
+		options.constraints = TestFileConstraints{}
+		files, _ := options.sortedWeightedUnits(tp.splitWeights())
+		report.Unconstrained = scoreSortedWeightedRunnerSplit(files, split.parallelRunners, options)
+	}
+	return report
+}
+
 func (tp *TestPlanner) backendDurationApplicationsCount() int {
 	count := 0
 	for _, aggregate := range tp.suiteAggregates {
@@ -345,6 +404,26 @@ func (tp *TestPlanner) backendSuitesAddedCount(mode discoveryMode) int {
 	return len(tp.suiteAggregates)
 }
 
+func (tp *TestPlanner) localDurationApplicationsCount() int {
+	count := 0
+	for _, source := range tp.testFileDurationSources {
+		if source == testFileDurationSourceLocal {
+			count++
+		}
+	}
+	return count
+}
+
+func (tp *TestPlanner) historyDurationApplicationsCount() int {
+	count := 0
+	for _, source := range tp.testFileDurationSources {
+		if source == testFileDurationSourceHistory {
+			count++
+		}
+	}
+	return count
+}
+
 func (tp *TestPlanner) suitesWithoutBackendDurationsCount() int {
 	count := 0
 	for _, aggregate := range tp.suiteAggregates {
@@ -386,17 +465,18 @@ func (tp *TestPlanner) longSeparateRunnerSuitesReport(parallelRunners int, split
 		return nil
 	}
 
-	distribution := tp.DistributeWeightedTestFiles(tp.testFileWeights, parallelRunners)
+	splitWeights := tp.splitWeights()
+	distribution := tp.distributeCINodeTestFiles(splitWeights, parallelRunners)
 	suites := make([]testSuiteTimingReport, 0)
 	for runnerIndex, runnerFiles := range distribution {
 		if len(runnerFiles) != 1 {
 			continue
 		}
 
-		sourceFile := runnerFiles[0]
-		if time.Duration(tp.testFileWeights[sourceFile])*time.Millisecond <= longThreshold {
+		if time.Duration(splitWeights[runnerFiles[0]])*time.Millisecond <= longThreshold {
 			continue
 		}
+		sourceFile := framework.TestChunkFile(runnerFiles[0])
 
 		for _, key := range tp.suitesBySourceFile[sourceFile] {
 			aggregate := tp.suiteAggregates[key]
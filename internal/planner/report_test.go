@@ -45,9 +45,20 @@ func TestPrintPlanReport_AllData(t *testing.T) {
 			MaxParallelism:         maxParallelism,
 			ParallelRunnerOverhead: 30 * time.Second,
 			TargetTime:             5 * time.Minute,
+			SplitOptimizer:         settings.SplitOptimizerGreedy,
+			DurationEstimate:       settings.DurationEstimateP50,
+			OptimizeFor:            settings.OptimizationGoalTime,
+			StickySplitTolerance:   settings.DefaultStickySplitTolerance(),
+			PlanLayout:             settings.PlanLayoutV1,
 			WorkerEnv:              "RAILS_ENV=test;DATABASE_PASSWORD=secret",
 			CiNode:                 0,
 			CiNodeWorkers:          2,
+			WorkQueue:              true,
+			WorkQueueBatchSize:     3,
+			QueueListen:            settings.DefaultQueueListen(),
+			QueueLeaseTimeout:      settings.DefaultQueueLeaseTimeout(),
+			WorkerOutput:           settings.WorkerOutputStream,
+			WorkerTimeoutSignal:    "SIGQUIT",
 			Command:                "pytest -q",
 			TestsLocation:          "spec/**/*_spec.rb",
 			TestsExcludePattern:    "spec/system/**/*_spec.rb",
@@ -228,6 +239,8 @@ DDTest settings
   Worker env: DATABASE_PASSWORD, RAILS_ENV
   CI node: 0
   CI node workers: 2
+  Work queue: true
+  Work queue batch size: 3
   Command: pytest -q
   Tests location: spec/**/*_spec.rb
   Tests exclude pattern: spec/system/**/*_spec.rb
@@ -356,6 +369,7 @@ func TestPrintPlanReport_FastDiscovery(t *testing.T) {
 				Available:               true,
 				BackendDurationsApplied: 12,
 				BackendSuitesAdded:      2,
+				LocalDurationsApplied:   3,
 				SuitesWithoutDurations:  1,
 			},
 			Skipping: skippingApplicationReport{
@@ -430,6 +444,7 @@ Planning
   Duration estimates
     Backend durations used: 12 suites
     Default durations used: 1 suite
+    Local durations used: 3 files
     Backend-only suites added: 2
   Skipping
     TIA skippables applied: 8 suites
@@ -567,8 +582,38 @@ func TestPrintDDTestSettingsReport_AllSupportedSettings(t *testing.T) {
 	config.MaxParallelism += 2
 	config.ParallelRunnerOverhead += time.Second
 	config.TargetTime = 12 * time.Minute
+	config.SplitOptimizer = settings.SplitOptimizerLocalSearch
+	config.DurationEstimate = settings.DurationEstimateP90
+	config.OptimizeFor = settings.OptimizationGoalCost
+	config.CiJobPricePerMinute = 0.008
+	config.CiJobBillingIncrement = time.Minute
+	config.CiJobStartupTime = 45 * time.Second
+	config.CiBudget = 1.5
+	config.ChunkSlowTestFiles = true
+	config.ConstraintsFile = "ci/ddtest-constraints.json"
+	config.Workspace = "ci/ddtest-workspace.json"
+	config.PreviousPlan = "previous/.testoptimization"
+	config.StickySplitTolerance = 10
+	config.PlanLayout = settings.PlanLayoutV2
+	config.SplitOrder = "new,longest"
+	config.PipelineTemplate = ".buildkite/ddtest-step.yml"
 	config.CiNode = 0
 	config.CiNodeWorkers = 2
+	config.CiNodeCapacities = "0-3:8,4-7:4"
+	config.WorkQueue = true
+	config.WorkQueueBatchSize = 4
+	config.QueueURL = "http://10.0.0.5:7878"
+	config.QueueListen = "0.0.0.0:7878"
+	config.QueueLeaseTimeout = time.Minute
+	config.RetryFailedFiles = 2
+	config.WorkerOutput = settings.WorkerOutputFile
+	config.FailFast = true
+	config.WorkerTimeout = 20 * time.Minute
+	config.WorkerTimeoutMultiplier = 3
+	config.WorkerTimeoutSignal = "SIGUSR1"
+	config.JUnitReports = "tmp/junit/*.xml"
+	config.PreferLocalDurations = true
+	config.DurationHistory = ".ddtest-cache/durations.jsonl"
 	config.WorkerEnv = "TOKEN=secret"
 	config.TestsLocation = "tests/**/*_test.py"
 	config.TestsExcludePattern = "tests/system/**/*_test.py"
@@ -578,6 +623,7 @@ func TestPrintDDTestSettingsReport_AllSupportedSettings(t *testing.T) {
 	config.StrictDiscovery = true
 	config.RuntimeTags = `{"runtime.version":"3.3.4"}`
 	config.ReportEnabled = false
+	config.RunReportPath = "tmp/run-report.json"
 
 	var output strings.Builder
 	printDDTestSettingsReport(&output, &config)
@@ -603,9 +649,39 @@ func TestPrintDDTestSettingsReport_AllSupportedSettings(t *testing.T) {
 		"Max parallelism",
 		"CI job overhead",
 		"Target time",
+		"Split optimizer",
+		"Duration estimate",
+		"Optimize for",
+		"CI job price per minute",
+		"CI job billing increment",
+		"CI job startup time",
+		"CI budget",
+		"Chunk slow test files",
+		"Constraints file",
+		"Workspace",
+		"Previous plan",
+		"Sticky split tolerance",
+		"Plan layout",
+		"Split order",
+		"Pipeline template",
 		"Worker env",
 		"CI node",
 		"CI node workers",
+		"CI node capacities",
+		"Work queue",
+		"Work queue batch size",
+		"Queue URL",
+		"Queue listen",
+		"Queue lease timeout",
+		"Retry failed files",
+		"Worker output",
+		"Fail fast",
+		"Worker timeout",
+		"Worker timeout multiplier",
+		"Worker timeout signal",
+		"JUnit reports",
+		"Prefer local durations",
+		"Duration history",
 		"Command",
 		"Tests location",
 		"Tests exclude pattern",
@@ -615,6 +691,7 @@ func TestPrintDDTestSettingsReport_AllSupportedSettings(t *testing.T) {
 		"Strict discovery",
 		"Runtime tags",
 		"Report enabled",
+		"Run report path",
 	}
 	if !reflect.DeepEqual(names, expectedNames) {
 		t.Fatalf("unexpected changed setting names:\ngot:  %v\nwant: %v", names, expectedNames)
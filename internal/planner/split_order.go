@@ -0,0 +1,227 @@
+package planner
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"slices"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/environment"
+	"github.com/DataDog/ddtest/internal/framework"
+	"github.com/DataDog/ddtest/internal/settings"
+	"github.com/DataDog/ddtest/internal/testoptimization"
+	"github.com/DataDog/ddtest/internal/testoptimization/api"
+	"github.com/DataDog/ddtest/internal/utils"
+)
+
+var splitOrderGitOutput = func(args ...string) ([]byte, error) {
+	return exec.Command("git", args...).Output()
+}
+
+// splitOrderReport tells how the entries of each runner split were ordered.
+type splitOrderReport struct {
+	Order            []settings.SplitOrder
+	NewTestFiles     int
+	ChangedTestFiles int
+	FlakyTestFiles   int
+	// ChangedTestFilesError is set when the changed test files could not be
+	// listed, so that no test file was ordered as changed.
+	ChangedTestFilesError string
+}
+
+// splitOrderPriorities holds the test files that each priority of the split
+// order puts first.
+type splitOrderPriorities struct {
+	order            []settings.SplitOrder
+	newTestFiles     map[string]bool
+	changedTestFiles map[string]bool
+	flakyTestFiles   map[string]bool
+	weights          map[string]int
+}
+
+// orderTestSplits orders the entries of each runner split by the split order,
+// highest priority first. Entries that tie keep their assignment order. Only
+// the order within each runner changes, so the split stays as balanced.
+func (tp *TestPlanner) orderTestSplits(distribution [][]string, weights map[string]int) {
+	order := settings.GetSplitOrder()
+	if len(order) == 0 {
+		return
+	}
+
+	priorities, report := tp.newSplitOrderPriorities(order, weights)
+	for _, entries := range distribution {
+		slices.SortStableFunc(entries, priorities.compare)
+	}
+	slog.Debug("Ordered runner splits", "splitOrder", order,
+		"newTestFiles", report.NewTestFiles, "changedTestFiles", report.ChangedTestFiles, "flakyTestFiles", report.FlakyTestFiles)
+	tp.reportStats.splitOrder = report
+}
+
+func (tp *TestPlanner) newSplitOrderPriorities(order []settings.SplitOrder, weights map[string]int) (splitOrderPriorities, splitOrderReport) {
+	priorities := splitOrderPriorities{order: order, weights: weights}
+	report := splitOrderReport{Order: order}
+	for _, priority := range order {
+		switch priority {
+		case settings.SplitOrderNew:
+			priorities.newTestFiles = tp.newTestFiles(tp.optimizationClient.GetKnownTests())
+			report.NewTestFiles = len(priorities.newTestFiles)
+		case settings.SplitOrderChanged:
+			changedTestFiles, err := tp.changedTestFiles(splitOrderBaseCommit(environment.GetCITags()))
+			if err != nil {
+				slog.Warn("Failed to list changed test files; no test file is ordered as changed", "error", err)
+				report.ChangedTestFilesError = err.Error()
+			}
+			priorities.changedTestFiles = changedTestFiles
+			report.ChangedTestFiles = len(changedTestFiles)
+		case settings.SplitOrderFlaky:
+			priorities.flakyTestFiles = tp.flakyTestFiles(tp.optimizationClient.GetTestManagementTestsData())
+			report.FlakyTestFiles = len(priorities.flakyTestFiles)
+		}
+	}
+	return priorities, report
+}
+
+// compare orders entry a before entry b when the first priority that tells
+// them apart puts a first. Test chunks are ordered as their test file.
+func (p splitOrderPriorities) compare(a, b string) int {
+	testFileA, testFileB := framework.TestChunkFile(a), framework.TestChunkFile(b)
+	for _, priority := range p.order {
+		var result int
+		switch priority {
+		case settings.SplitOrderNew:
+			result = compareFirst(p.newTestFiles[testFileA], p.newTestFiles[testFileB])
+		case settings.SplitOrderChanged:
+			result = compareFirst(p.changedTestFiles[testFileA], p.changedTestFiles[testFileB])
+		case settings.SplitOrderFlaky:
+			result = compareFirst(p.flakyTestFiles[testFileA], p.flakyTestFiles[testFileB])
+		case settings.SplitOrderLongest:
+			result = cmp.Compare(p.weights[b], p.weights[a])
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	return 0
+}
+
+// compareFirst orders true before false.
+func compareFirst(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// newTestFiles returns the runnable test files with a test that Datadog does
+// not know. Test files that full discovery did not list tests for are new
+// when Datadog knows none of their test suites.
+func (tp *TestPlanner) newTestFiles(knownTests *api.KnownTestsResponseData) map[string]bool {
+	newTestFiles := make(map[string]bool)
+	if knownTests == nil || len(knownTests.Tests) == 0 {
+		slog.Info("Known tests unavailable; no test file is ordered as new")
+		return newTestFiles
+	}
+
+	for testFile := range tp.testFileWeights {
+		if tests, ok := tp.testsBySourceFile[testFile]; ok {
+			if slices.ContainsFunc(tests, func(test testoptimization.Test) bool {
+				return !slices.Contains(knownTests.Tests[test.Module][test.Suite], test.Name)
+			}) {
+				newTestFiles[testFile] = true
+			}
+			continue
+		}
+		suiteKeys := tp.suitesBySourceFile[testFile]
+		if len(suiteKeys) > 0 && !slices.ContainsFunc(suiteKeys, func(key testSuiteKey) bool {
+			_, ok := knownTests.Tests[key.Module][key.Suite]
+			return ok
+		}) {
+			newTestFiles[testFile] = true
+		}
+	}
+	return newTestFiles
+}
+
+// flakyTestFiles returns the runnable test files with a test that Test
+// Management quarantines or is attempting to fix. Test files that full
+// discovery did not list tests for are flaky when one of their test suites
+// has such a test.
+func (tp *TestPlanner) flakyTestFiles(testManagementTests *api.TestManagementTestsResponseDataModules) map[string]bool {
+	flakyTestFiles := make(map[string]bool)
+	if testManagementTests == nil {
+		return flakyTestFiles
+	}
+
+	isFlaky := func(properties api.TestManagementTestsResponseDataTestPropertiesAttributes) bool {
+		return properties.Quarantined || properties.AttemptToFix
+	}
+	flakySuites := make(map[testSuiteKey]bool)
+	for module, suites := range testManagementTests.Modules {
+		for suite, tests := range suites.Suites {
+			for _, test := range tests.Tests {
+				if isFlaky(test.Properties) {
+					flakySuites[testSuiteKey{Module: module, Suite: suite}] = true
+				}
+			}
+		}
+	}
+
+	for testFile := range tp.testFileWeights {
+		if tests, ok := tp.testsBySourceFile[testFile]; ok {
+			if slices.ContainsFunc(tests, func(test testoptimization.Test) bool {
+				properties := testManagementTests.Modules[test.Module].Suites[test.Suite].Tests[test.Name].Properties
+				return isFlaky(properties)
+			}) {
+				flakyTestFiles[testFile] = true
+			}
+			continue
+		}
+		if slices.ContainsFunc(tp.suitesBySourceFile[testFile], func(key testSuiteKey) bool { return flakySuites[key] }) {
+			flakyTestFiles[testFile] = true
+		}
+	}
+	return flakyTestFiles
+}
+
+// splitOrderBaseCommit returns the commit that the pull request is compared
+// with, or "" outside of a pull request.
+func splitOrderBaseCommit(ciTags map[string]string) string {
+	if baseCommit := ciTags[constants.GitPrBaseCommit]; baseCommit != "" {
+		return baseCommit
+	}
+	return ciTags[constants.GitPrBaseHeadCommit]
+}
+
+// changedTestFiles returns the runnable test files changed since the merge
+// base of HEAD and baseCommit, when there is one, or changed in the working
+// tree.
+func (tp *TestPlanner) changedTestFiles(baseCommit string) (map[string]bool, error) {
+	var changedFiles []string
+	if baseCommit != "" {
+		diffOutput, err := splitOrderGitOutput("diff", "--name-status", "-M", "-z", baseCommit+"...HEAD")
+		if err != nil {
+			return nil, fmt.Errorf("failed to diff HEAD against base commit %s: %w", baseCommit, err)
+		}
+		changedFiles = discoveryCacheParseGitDiffNameStatus(diffOutput)
+	}
+	statusOutput, err := splitOrderGitOutput("status", "--porcelain=v1", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list working tree changes: %w", err)
+	}
+	changedFiles = append(changedFiles, discoveryCacheParseGitStatusPorcelain(statusOutput)...)
+
+	changedTestFiles := make(map[string]bool)
+	for _, changedFile := range changedFiles {
+		testFile := utils.NormalizePath(utils.StripCwdSubdirPrefix(changedFile))
+		if _, ok := tp.testFileWeights[testFile]; ok {
+			changedTestFiles[testFile] = true
+		}
+	}
+	return changedTestFiles, nil
+}
@@ -0,0 +1,224 @@
+package planner
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/settings"
+	"github.com/DataDog/ddtest/internal/testoptimization"
+	"github.com/DataDog/ddtest/internal/testoptimization/api"
+)
+
+func TestSplitOrderPriorities_Compare(t *testing.T) {
+	priorities := splitOrderPriorities{
+		order:          []settings.SplitOrder{settings.SplitOrderNew, settings.SplitOrderFlaky, settings.SplitOrderLongest},
+		newTestFiles:   map[string]bool{"spec/new_spec.rb": true},
+		flakyTestFiles: map[string]bool{"spec/flaky_spec.rb": true, "spec/new_spec.rb": true},
+		weights: map[string]int{
+			"spec/a_spec.rb":          1000,
+			"spec/b_spec.rb":          3000,
+			"spec/c_spec.rb":          1000,
+			"spec/flaky_spec.rb":      500,
+			"spec/new_spec.rb[1:1]":   100,
+			"spec/new_spec.rb[1:2,1]": 200,
+		},
+	}
+	entries := []string{"spec/a_spec.rb", "spec/new_spec.rb[1:1]", "spec/b_spec.rb", "spec/flaky_spec.rb", "spec/c_spec.rb", "spec/new_spec.rb[1:2,1]"}
+
+	slices.SortStableFunc(entries, priorities.compare)
+	// Test chunks are ordered as their test file; entries that tie keep their
+	// order.
+	want := []string{"spec/new_spec.rb[1:2,1]", "spec/new_spec.rb[1:1]", "spec/flaky_spec.rb", "spec/b_spec.rb", "spec/a_spec.rb", "spec/c_spec.rb"}
+	if !slices.Equal(entries, want) {
+		t.Errorf("ordered entries = %v, want %v", entries, want)
+	}
+}
+
+func TestTestPlanner_NewTestFiles(t *testing.T) {
+	tp := &TestPlanner{
+		testFileWeights: map[string]int{"spec/known_spec.rb": 1, "spec/added_test_spec.rb": 1, "spec/fast_spec.rb": 1, "spec/fast_new_spec.rb": 1},
+		testsBySourceFile: map[string][]testoptimization.Test{
+			"spec/known_spec.rb":      {{Module: "rspec", Suite: "Known", Name: "works"}},
+			"spec/added_test_spec.rb": {{Module: "rspec", Suite: "Added", Name: "works"}, {Module: "rspec", Suite: "Added", Name: "is new"}},
+		},
+		suitesBySourceFile: map[string][]testSuiteKey{
+			"spec/fast_spec.rb":     {{Module: "rspec", Suite: "Fast"}},
+			"spec/fast_new_spec.rb": {{Module: "rspec", Suite: "FastNew"}},
+		},
+	}
+	knownTests := &api.KnownTestsResponseData{Tests: api.KnownTestsResponseDataModules{
+		"rspec": {"Known": {"works"}, "Added": {"works"}, "Fast": {"works"}},
+	}}
+
+	got := tp.newTestFiles(knownTests)
+	want := map[string]bool{"spec/added_test_spec.rb": true, "spec/fast_new_spec.rb": true}
+	if len(got) != len(want) || !got["spec/added_test_spec.rb"] || !got["spec/fast_new_spec.rb"] {
+		t.Errorf("newTestFiles() = %v, want %v", got, want)
+	}
+
+	if got := tp.newTestFiles(nil); len(got) != 0 {
+		t.Errorf("expected no new test files without known tests, got %v", got)
+	}
+}
+
+func TestTestPlanner_FlakyTestFiles(t *testing.T) {
+	tp := &TestPlanner{
+		testFileWeights: map[string]int{"spec/stable_spec.rb": 1, "spec/quarantined_spec.rb": 1, "spec/fast_spec.rb": 1},
+		testsBySourceFile: map[string][]testoptimization.Test{
+			"spec/stable_spec.rb":      {{Module: "rspec", Suite: "Stable", Name: "works"}},
+			"spec/quarantined_spec.rb": {{Module: "rspec", Suite: "Quarantined", Name: "flakes"}},
+		},
+		suitesBySourceFile: map[string][]testSuiteKey{"spec/fast_spec.rb": {{Module: "rspec", Suite: "Fast"}}},
+	}
+	properties := func(attributes api.TestManagementTestsResponseDataTestPropertiesAttributes) api.TestManagementTestsResponseDataTests {
+		return api.TestManagementTestsResponseDataTests{Tests: map[string]api.TestManagementTestsResponseDataTestProperties{
+			"flakes": {Properties: attributes},
+			"works":  {Properties: api.TestManagementTestsResponseDataTestPropertiesAttributes{Disabled: true}},
+		}}
+	}
+	testManagementTests := &api.TestManagementTestsResponseDataModules{Modules: map[string]api.TestManagementTestsResponseDataSuites{
+		"rspec": {Suites: map[string]api.TestManagementTestsResponseDataTests{
+			"Stable":      properties(api.TestManagementTestsResponseDataTestPropertiesAttributes{}),
+			"Quarantined": properties(api.TestManagementTestsResponseDataTestPropertiesAttributes{Quarantined: true}),
+			"Fast":        properties(api.TestManagementTestsResponseDataTestPropertiesAttributes{AttemptToFix: true}),
+		}},
+	}}
+
+	got := tp.flakyTestFiles(testManagementTests)
+	if len(got) != 2 || !got["spec/quarantined_spec.rb"] || !got["spec/fast_spec.rb"] {
+		t.Errorf("flakyTestFiles() = %v, want spec/quarantined_spec.rb and spec/fast_spec.rb", got)
+	}
+}
+
+func TestTestPlanner_ChangedTestFiles(t *testing.T) {
+	var calls [][]string
+	originalGit := splitOrderGitOutput
+	splitOrderGitOutput = func(args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		if args[0] == "diff" {
+			return []byte("M\x00spec/a_spec.rb\x00R100\x00spec/old_spec.rb\x00spec/b_spec.rb\x00M\x00app/models/user.rb\x00"), nil
+		}
+		return []byte(" M spec/c_spec.rb\x00"), nil
+	}
+	t.Cleanup(func() { splitOrderGitOutput = originalGit })
+
+	tp := &TestPlanner{testFileWeights: map[string]int{"spec/a_spec.rb": 1, "spec/b_spec.rb": 1, "spec/c_spec.rb": 1, "spec/d_spec.rb": 1}}
+	got, err := tp.changedTestFiles("abc123")
+	if err != nil {
+		t.Fatalf("changedTestFiles() returned error: %v", err)
+	}
+	if len(got) != 3 || !got["spec/a_spec.rb"] || !got["spec/b_spec.rb"] || !got["spec/c_spec.rb"] {
+		t.Errorf("changedTestFiles() = %v, want spec/a_spec.rb, spec/b_spec.rb and spec/c_spec.rb", got)
+	}
+	if len(calls) != 2 || !slices.Contains(calls[0], "abc123...HEAD") {
+		t.Errorf("expected a diff against the merge base and a status, got %v", calls)
+	}
+
+	// Outside of a pull request, only working tree changes count.
+	calls = nil
+	got, err = tp.changedTestFiles("")
+	if err != nil || len(got) != 1 || !got["spec/c_spec.rb"] || len(calls) != 1 {
+		t.Errorf("changedTestFiles() without base commit = %v, %v after %v", got, err, calls)
+	}
+
+	splitOrderGitOutput = func(args ...string) ([]byte, error) { return nil, errors.New("bad revision") }
+	if _, err := tp.changedTestFiles("abc123"); err == nil {
+		t.Error("expected a failed diff to be an error")
+	}
+}
+
+func TestSplitOrderBaseCommit(t *testing.T) {
+	if got := splitOrderBaseCommit(map[string]string{constants.GitPrBaseCommit: "base", constants.GitPrBaseHeadCommit: "head"}); got != "base" {
+		t.Errorf("splitOrderBaseCommit() = %q, want the pull request base commit", got)
+	}
+	if got := splitOrderBaseCommit(map[string]string{constants.GitPrBaseHeadCommit: "head"}); got != "head" {
+		t.Errorf("splitOrderBaseCommit() = %q, want the base branch head commit", got)
+	}
+	if got := splitOrderBaseCommit(map[string]string{}); got != "" {
+		t.Errorf("splitOrderBaseCommit() = %q, want no base commit outside of a pull request", got)
+	}
+}
+
+func TestPrintSplitOrderPlanningReport(t *testing.T) {
+	var output strings.Builder
+	printSplitOrderPlanningReport(&output, splitOrderReport{
+		Order:                 []settings.SplitOrder{settings.SplitOrderNew, settings.SplitOrderChanged, settings.SplitOrderLongest},
+		NewTestFiles:          3,
+		ChangedTestFilesError: "git not found",
+	})
+	want := "  Split order\n" +
+		"    Priorities: new, changed, longest\n" +
+		"    New test files: 3\n" +
+		"    Changed test files: unavailable (git not found)\n"
+	if output.String() != want {
+		t.Errorf("unexpected split order report:\n%s\nwant:\n%s", output.String(), want)
+	}
+
+	output.Reset()
+	printSplitOrderPlanningReport(&output, splitOrderReport{})
+	if output.Len() != 0 {
+		t.Errorf("expected no report without a split order, got:\n%s", output.String())
+	}
+}
+
+func TestTestPlanner_Plan_OrdersRunnerSplits(t *testing.T) {
+	t.Chdir(t.TempDir())
+	setPlannerEnv(t, map[string]string{
+		"DD_TEST_OPTIMIZATION_RUNNER_SPLIT_ORDER":     "new",
+		"DD_TEST_OPTIMIZATION_RUNNER_MIN_PARALLELISM": "2",
+		"DD_TEST_OPTIMIZATION_RUNNER_MAX_PARALLELISM": "2",
+	})
+
+	mockPlatform := &MockPlatform{
+		PlatformName: "ruby",
+		Tags:         map[string]string{"platform": "ruby"},
+		Framework: &MockFramework{
+			FrameworkName: "rspec",
+			Tests: []testoptimization.Test{
+				{Suite: "TestSuite1", Name: "test1", SuiteSourceFile: "test/file1_test.rb"},
+				{Suite: "TestSuite2", Name: "test2", SuiteSourceFile: "test/file2_test.rb"},
+				{Suite: "TestSuite3", Name: "test3", SuiteSourceFile: "test/file3_test.rb"},
+				{Suite: "TestSuite4", Name: "test4", SuiteSourceFile: "test/file4_test.rb"},
+			},
+		},
+	}
+	runner := NewWithDependencies(
+		&MockPlatformDetector{Platform: mockPlatform},
+		&MockTestOptimizationClient{
+			Settings: testOptimizationSettings(true, true, false),
+			KnownTests: &api.KnownTestsResponseData{Tests: api.KnownTestsResponseDataModules{
+				"": {"TestSuite1": {"test1"}, "TestSuite2": {"test2"}, "TestSuite3": {"test3"}},
+			}},
+		},
+		newDefaultMockCIProviderDetector(),
+	)
+	var report strings.Builder
+	runner.reportWriter = &report
+	if err := runner.Plan(context.Background()); err != nil {
+		t.Fatalf("Plan() should not return error, got: %v", err)
+	}
+
+	splits, err := readRunnerSplits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for index, split := range splits {
+		if slices.Contains(split, "test/file4_test.rb") {
+			found = true
+			if split[0] != "test/file4_test.rb" {
+				t.Errorf("expected the new test file first in runner-%d, got %v", index, split)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("expected the new test file in a runner split, got %v", splits)
+	}
+	if !strings.Contains(report.String(), "New test files: 1") {
+		t.Errorf("expected report to count the new test file, got:\n%s", report.String())
+	}
+}
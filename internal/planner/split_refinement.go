@@ -0,0 +1,239 @@
+package planner
+
+import "slices"
+
+// refineSortedFiles distributes files, sorted by compareWeightedTestFiles,
+// with weighted list scheduling and then refines the split with a local search
+// that moves and swaps test files between runners. It returns the files of
+// each runner sorted by compareWeightedTestFiles, and leaves the builder's
+// loads at the refined split. The search assumes a file adds the same load to
+// every runner, so the greedy split is kept when serial files go to runners of
+// different capacities. When files are assigned by P90 durations, the search
+// result is only kept when it lowers the P90 wall time of the greedy split.
+// The search makes at most one step per test file, so the same files always
+// give the same split.
+func (b *testSplitBuilder) refineSortedFiles(files []weightedTestFile) [][]weightedTestFile {
+	runners := make([][]weightedTestFile, b.parallelRunners)
+	for _, file := range files {
+		runnerIndex := b.addFile(file)
+		runners[runnerIndex] = append(runners[runnerIndex], file)
+	}
+	if b.capacities != nil && slices.ContainsFunc(files, func(file weightedTestFile) bool { return file.serial }) {
+		return runners
+	}
+
+	capacities := make([]int, b.parallelRunners)
+	for i := range capacities {
+		capacities[i] = b.capacity(i)
+	}
+	var greedy [][]weightedTestFile
+	if b.variances != nil {
+		greedy = make([][]weightedTestFile, len(runners))
+		for i := range runners {
+			greedy[i] = slices.Clone(runners[i])
+		}
+	}
+	search := newSplitLocalSearch(runners, b.runnerLoads(), capacities)
+	search.run(len(files))
+
+	if greedy != nil {
+		_, greedyP90WallTime := b.p90WallTimes(greedy)
+		if _, refinedP90WallTime := b.p90WallTimes(search.runners); greedyP90WallTime <= refinedP90WallTime {
+			return greedy
+		}
+		b.setRunnerFiles(search.runners)
+		return search.runners
+	}
+	b.setRunnerLoads(search.loads)
+	return search.runners
+}
+
+// splitLocalSearch improves a runner split one step at a time. A runner
+// finishes after its load divided by its capacity. Every step makes the runner
+// that finishes last finish earlier, either by moving one of its test files to
+// another runner or by swapping one of its test files for a lighter test file
+// of another runner, without making the other runner finish as late as the
+// first one did. Each step therefore lowers the finish times sorted from last
+// to first, in lexicographic order, so the search cannot revisit a split.
+type splitLocalSearch struct {
+	// runners holds the test files of each runner sorted by
+	// compareWeightedTestFiles.
+	runners    [][]weightedTestFile
+	loads      []int
+	capacities []int
+	// byLoad holds the runner indexes sorted by when they finish, then by
+	// index.
+	byLoad []int
+}
+
+func newSplitLocalSearch(runners [][]weightedTestFile, loads []int, capacities []int) splitLocalSearch {
+	byLoad := make([]int, len(runners))
+	for i := range byLoad {
+		byLoad[i] = i
+	}
+	s := splitLocalSearch{
+		runners:    runners,
+		loads:      loads,
+		capacities: capacities,
+		byLoad:     byLoad,
+	}
+	slices.SortFunc(s.byLoad, s.compareLoads)
+	return s
+}
+
+func (s *splitLocalSearch) compareLoads(a, b int) int {
+	finishA, finishB := s.loads[a]*s.capacities[b], s.loads[b]*s.capacities[a]
+	if finishA != finishB {
+		return finishA - finishB
+	}
+	return a - b
+}
+
+// run makes up to maxSteps search steps, stopping early at a split it cannot
+// improve.
+func (s *splitLocalSearch) run(maxSteps int) {
+	for range maxSteps {
+		if !s.improve() {
+			return
+		}
+	}
+}
+
+// improve makes one search step and reports whether it found one.
+func (s *splitLocalSearch) improve() bool {
+	heaviest := s.byLoad[len(s.byLoad)-1]
+	heaviestCapacity := s.capacities[heaviest]
+	for _, target := range s.byLoad[:len(s.byLoad)-1] {
+		// room is how much load target can take, times the capacity of the
+		// heaviest runner, before it finishes as late as the heaviest runner.
+		// Runners finish later along byLoad, so once it is gone, it is gone
+		// for every remaining runner.
+		targetCapacity := s.capacities[target]
+		room := s.loads[heaviest]*targetCapacity - s.loads[target]*heaviestCapacity
+		if room <= 0 {
+			return false
+		}
+		maxWeight := (room - 1) / heaviestCapacity
+		if maxWeight < 1 {
+			continue
+		}
+		// Both runners finish together when the heaviest runner hands over
+		// this much load.
+		balancedWeight := min(max(room/(heaviestCapacity+targetCapacity), 1), maxWeight)
+
+		if fileIndex, ok := closestWeight(s.runners[heaviest], 1, maxWeight, balancedWeight); ok {
+			s.move(heaviest, fileIndex, target)
+			s.reorder(heaviest, target)
+			return true
+		}
+		if s.swap(heaviest, target, maxWeight, balancedWeight) {
+			s.reorder(heaviest, target)
+			return true
+		}
+	}
+	return false
+}
+
+// reorder restores the order of byLoad after the loads of runners a and b
+// changed.
+func (s *splitLocalSearch) reorder(a int, b int) {
+	s.byLoad = slices.DeleteFunc(s.byLoad, func(runner int) bool {
+		return runner == a || runner == b
+	})
+	for _, runner := range []int{a, b} {
+		index, _ := slices.BinarySearchFunc(s.byLoad, runner, s.compareLoads)
+		s.byLoad = slices.Insert(s.byLoad, index, runner)
+	}
+}
+
+// swap exchanges a test file of the heaviest runner for a lighter test file
+// of target whose weight differs by at most maxWeight, picking the pair whose
+// weight difference is closest to balancedWeight. It reports whether it found
+// such a pair.
+func (s *splitLocalSearch) swap(heaviest int, target int, maxWeight int, balancedWeight int) bool {
+	targetFiles := s.runners[target]
+	bestFrom, bestTo, bestDistance := -1, -1, 0
+	// The wanted weight only decreases along the heaviest runner's files, so
+	// the first target file that weighs at most it only moves forward.
+	lighter := 0
+	for fromIndex, file := range s.runners[heaviest] {
+		wantWeight := file.weight - balancedWeight
+		for lighter < len(targetFiles) && targetFiles[lighter].weight > wantWeight {
+			lighter++
+		}
+		toIndex, ok := closestWeightAround(targetFiles, lighter, file.weight-maxWeight, file.weight-1, wantWeight)
+		if !ok {
+			continue
+		}
+		distance := abs(targetFiles[toIndex].weight - wantWeight)
+		if bestFrom < 0 || distance < bestDistance {
+			bestFrom, bestTo, bestDistance = fromIndex, toIndex, distance
+			if distance == 0 {
+				break
+			}
+		}
+	}
+	if bestFrom < 0 {
+		return false
+	}
+
+	from := s.runners[heaviest][bestFrom]
+	to := s.runners[target][bestTo]
+	s.runners[heaviest] = slices.Delete(s.runners[heaviest], bestFrom, bestFrom+1)
+	s.runners[target] = slices.Delete(s.runners[target], bestTo, bestTo+1)
+	s.loads[heaviest] -= from.weight
+	s.loads[target] -= to.weight
+	s.insert(heaviest, to)
+	s.insert(target, from)
+	return true
+}
+
+func (s *splitLocalSearch) move(from int, fileIndex int, to int) {
+	file := s.runners[from][fileIndex]
+	s.runners[from] = slices.Delete(s.runners[from], fileIndex, fileIndex+1)
+	s.loads[from] -= file.weight
+	s.insert(to, file)
+}
+
+func (s *splitLocalSearch) insert(runner int, file weightedTestFile) {
+	index, _ := slices.BinarySearchFunc(s.runners[runner], file, compareWeightedTestFiles)
+	s.runners[runner] = slices.Insert(s.runners[runner], index, file)
+	s.loads[runner] += file.weight
+}
+
+// closestWeight returns the index of the file in files, sorted by
+// compareWeightedTestFiles, whose weight is within [minWeight, maxWeight] and
+// closest to wantWeight, which must be within the same range.
+func closestWeight(files []weightedTestFile, minWeight int, maxWeight int, wantWeight int) (int, bool) {
+	lighter, _ := slices.BinarySearchFunc(files, wantWeight, func(file weightedTestFile, weight int) int {
+		if file.weight > weight {
+			return -1
+		}
+		return 1
+	})
+	return closestWeightAround(files, lighter, minWeight, maxWeight, wantWeight)
+}
+
+// closestWeightAround is closestWeight for a known lighter, the index of the
+// first file that weighs at most wantWeight. The file before it is the
+// lightest one that weighs more, so one of the two is the closest.
+func closestWeightAround(files []weightedTestFile, lighter int, minWeight int, maxWeight int, wantWeight int) (int, bool) {
+	best, bestDistance := -1, 0
+	for _, index := range [2]int{lighter - 1, lighter} {
+		if index < 0 || index >= len(files) || files[index].weight < minWeight || files[index].weight > maxWeight {
+			continue
+		}
+		distance := abs(files[index].weight - wantWeight)
+		if best < 0 || distance < bestDistance {
+			best, bestDistance = index, distance
+		}
+	}
+	return best, best >= 0
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
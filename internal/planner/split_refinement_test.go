@@ -0,0 +1,140 @@
+package planner
+
+import (
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/settings"
+)
+
+// greedyImbalancedFiles are split 17/13 by weighted list scheduling on two
+// runners, while {a, b} and {c, d, e} split them 15/15.
+var greedyImbalancedFiles = []weightedTestFile{
+	{path: "a_spec.rb", weight: 8},
+	{path: "b_spec.rb", weight: 7},
+	{path: "c_spec.rb", weight: 6},
+	{path: "d_spec.rb", weight: 5},
+	{path: "e_spec.rb", weight: 4},
+}
+
+func TestScoreSortedWeightedRunnerSplit_LocalSearchImprovesGreedySplit(t *testing.T) {
+	greedy := scoreSortedWeightedRunnerSplit(greedyImbalancedFiles, 2, splitOptions{})
+	if greedy.wallTime != 17 || greedy.imbalance != 4 {
+		t.Fatalf("greedy score = %+v, expected wall time 17 and imbalance 4", greedy)
+	}
+
+	result := scoreSortedWeightedRunnerSplit(greedyImbalancedFiles, 2, splitOptions{optimizer: settings.SplitOptimizerLocalSearch})
+	expected := splitScore{
+		parallelRunners: 2,
+		wallTime:        15,
+		imbalance:       0,
+		totalRuntime:    30,
+	}
+	if result != expected {
+		t.Fatalf("scoreSortedWeightedRunnerSplit() = %+v, expected %+v", result, expected)
+	}
+}
+
+func TestTestSplitBuilderDistributeSortedFiles_LocalSearch(t *testing.T) {
+	builder := newTestSplitBuilder(2).withOptimizer(settings.SplitOptimizerLocalSearch)
+	result := builder.distributeSortedFiles(greedyImbalancedFiles)
+	expected := [][]string{
+		{"c_spec.rb", "d_spec.rb", "e_spec.rb"},
+		{"a_spec.rb", "b_spec.rb"},
+	}
+
+	assertDistribution(t, result, expected)
+}
+
+func TestTestSplitBuilderDistributeSortedFiles_LocalSearchKeepsBalancedSplit(t *testing.T) {
+	files := []weightedTestFile{
+		{path: "slow.rb", weight: 3},
+		{path: "medium.rb", weight: 2},
+		{path: "fast.rb", weight: 1},
+	}
+
+	builder := newTestSplitBuilder(2).withOptimizer(settings.SplitOptimizerLocalSearch)
+	result := builder.distributeSortedFiles(files)
+	expected := [][]string{
+		{"slow.rb"},
+		{"medium.rb", "fast.rb"},
+	}
+
+	assertDistribution(t, result, expected)
+}
+
+func TestRefineSortedFiles_NeverWorseThanGreedy(t *testing.T) {
+	random := rand.New(rand.NewPCG(1, 2))
+	for iteration := range 50 {
+		testFiles := make(map[string]int)
+		for i := range 20 + random.IntN(200) {
+			testFiles[fmt.Sprintf("spec/%03d_spec.rb", i)] = 1 + random.IntN(5000)
+		}
+		files := sortedWeightedTestFiles(testFiles)
+		parallelRunners := 2 + random.IntN(12)
+
+		greedy := scoreSortedWeightedRunnerSplit(files, parallelRunners, splitOptions{})
+		builder := newTestSplitBuilder(parallelRunners).withOptimizer(settings.SplitOptimizerLocalSearch)
+		runners := builder.refineSortedFiles(files)
+		refined := builder.score()
+
+		if refined.wallTime > greedy.wallTime || refined.totalRuntime != greedy.totalRuntime {
+			t.Fatalf("iteration %d: refined score %+v is worse than greedy score %+v", iteration, refined, greedy)
+		}
+
+		var distributed []string
+		for index, runnerFiles := range runners {
+			var load int
+			for _, file := range runnerFiles {
+				load += file.weight
+				distributed = append(distributed, file.path)
+			}
+			if !slices.IsSortedFunc(runnerFiles, compareWeightedTestFiles) {
+				t.Fatalf("iteration %d: runner %d files are not sorted: %v", iteration, index, runnerFiles)
+			}
+			if load > refined.wallTime {
+				t.Fatalf("iteration %d: runner %d load %d exceeds wall time %d", iteration, index, load, refined.wallTime)
+			}
+		}
+		slices.Sort(distributed)
+		if !slices.Equal(distributed, slices.Sorted(maps.Keys(testFiles))) {
+			t.Fatalf("iteration %d: refined split does not contain every test file exactly once", iteration)
+		}
+	}
+}
+
+func TestClosestWeight(t *testing.T) {
+	files := []weightedTestFile{
+		{path: "a.rb", weight: 9},
+		{path: "b.rb", weight: 6},
+		{path: "c.rb", weight: 6},
+		{path: "d.rb", weight: 2},
+	}
+
+	tests := []struct {
+		name       string
+		minWeight  int
+		maxWeight  int
+		wantWeight int
+		expected   int
+		expectedOK bool
+	}{
+		{name: "exact weight picks first path", minWeight: 1, maxWeight: 9, wantWeight: 6, expected: 1, expectedOK: true},
+		{name: "closer heavier file", minWeight: 1, maxWeight: 9, wantWeight: 8, expected: 0, expectedOK: true},
+		{name: "closer lighter file", minWeight: 1, maxWeight: 9, wantWeight: 3, expected: 3, expectedOK: true},
+		{name: "heavier file outside range", minWeight: 1, maxWeight: 8, wantWeight: 8, expected: 1, expectedOK: true},
+		{name: "no file in range", minWeight: 3, maxWeight: 5, wantWeight: 4, expected: -1, expectedOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := closestWeight(files, tt.minWeight, tt.maxWeight, tt.wantWeight)
+			if result != tt.expected || ok != tt.expectedOK {
+				t.Fatalf("closestWeight() = %d, %t, expected %d, %t", result, ok, tt.expected, tt.expectedOK)
+			}
+		})
+	}
+}
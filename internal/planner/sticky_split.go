@@ -0,0 +1,226 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/settings"
+)
+
+// stickySplitReport compares a split that keeps test files on the runner of
+// the previous plan with the split from scratch.
+type stickySplitReport struct {
+	Available    bool
+	PreviousPlan string
+	// Tolerance is how much longer, in percent, the wall time may get to
+	// keep test files on their previous runner.
+	Tolerance float64
+	// FromScratch is set when keeping test files on their previous runner
+	// made the wall time longer than the tolerance allows, so the split from
+	// scratch was used instead.
+	FromScratch         bool
+	WallTime            time.Duration
+	FromScratchWallTime time.Duration
+	KeptTestFiles       int
+	MovedTestFiles      int
+	NewTestFiles        int
+}
+
+// loadPreviousTestSplit loads the runner split of the previous plan, when one
+// is configured. Without it, test files are distributed from scratch.
+func (tp *TestPlanner) loadPreviousTestSplit() {
+	previousPlan := settings.GetPreviousPlan()
+	if previousPlan == "" {
+		return
+	}
+
+	previousTestSplit, err := readPreviousTestSplit(previousPlan)
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.Info("Previous plan not found; distributing test files from scratch", "previousPlan", previousPlan)
+		return
+	}
+	if err != nil {
+		slog.Warn("Failed to read previous plan; distributing test files from scratch", "previousPlan", previousPlan, "error", err)
+		return
+	}
+	slog.Debug("Loaded previous plan runner split", "previousPlan", previousPlan, "testFilesCount", len(previousTestSplit))
+	tp.previousTestSplit = previousTestSplit
+}
+
+// readPreviousTestSplit returns the runner that the plan in planDir assigned
+// each test file to.
+func readPreviousTestSplit(planDir string) (map[string]int, error) {
+	data, err := os.ReadFile(planDirPath(planDir, constants.ParallelRunnersOutputPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parallel runners count of plan %s: %w", planDir, err)
+	}
+	parallelRunners, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse parallel runners count of plan %s: %w", planDir, err)
+	}
+
+	splits, err := readRunnerSplitFiles(planDirPath(planDir, constants.TestsSplitDir))
+	if err != nil {
+		return nil, err
+	}
+	if splits == nil {
+		return nil, fmt.Errorf("plan %s has no test splits: %w", planDir, fs.ErrNotExist)
+	}
+
+	runners := make(map[string]int)
+	for index, testFiles := range splits {
+		// Splits past the runner count are left over from an earlier plan
+		// with more runners.
+		if index >= parallelRunners {
+			continue
+		}
+		for _, testFile := range testFiles {
+			runners[testFile] = index
+		}
+	}
+	return runners, nil
+}
+
+// distributeTestFiles distributes test files across parallelRunners runners.
+// With a previous split, it keeps test files on their previous runner unless
+// that makes the wall time longer than the sticky split tolerance allows, and
+// reports how many test files moved.
+func (o splitOptions) distributeTestFiles(testFiles map[string]int, parallelRunners int) ([][]string, stickySplitReport) {
+	files, members := o.sortedWeightedUnits(testFiles)
+	builder := o.newTestSplitBuilder(parallelRunners)
+	distribution := builder.distributeSortedFiles(files)
+	if o.previousRunners == nil {
+		return expandUnits(distribution, members), stickySplitReport{}
+	}
+
+	fromScratchWallTime := builder.score().wallTime
+	maxWallTime := fromScratchWallTime + int(math.Floor(float64(fromScratchWallTime)*o.stickySplitTolerance/100))
+	report := stickySplitReport{
+		Available:           true,
+		Tolerance:           o.stickySplitTolerance,
+		FromScratch:         true,
+		WallTime:            time.Duration(fromScratchWallTime) * time.Millisecond,
+		FromScratchWallTime: time.Duration(fromScratchWallTime) * time.Millisecond,
+	}
+
+	sticky := o.newTestSplitBuilder(parallelRunners)
+	if runners, ok := sticky.keepPreviousRunners(files, members, o.previousRunners, maxWallTime); ok {
+		for i, runnerFiles := range runners {
+			distribution[i] = make([]string, 0, len(runnerFiles))
+			for _, file := range runnerFiles {
+				distribution[i] = append(distribution[i], file.path)
+			}
+		}
+		report.FromScratch = false
+		report.WallTime = sticky.score().wallTimeDuration()
+	}
+
+	distribution = expandUnits(distribution, members)
+	report.countMoves(distribution, o.previousRunners)
+	return distribution, report
+}
+
+// keepPreviousRunners assigns each file to its previous runner, and files
+// without one to the runner that would finish them first. When the runner
+// that finishes last finishes after maxWallTime, it moves files off it the
+// way the local search does, one step at a time, until it finishes by
+// maxWallTime. It returns the files of each runner, and false when no split
+// within maxWallTime was found.
+func (b *testSplitBuilder) keepPreviousRunners(files []weightedTestFile, members map[string][]string, previous map[string]int, maxWallTime int) ([][]weightedTestFile, bool) {
+	runners := make([][]weightedTestFile, b.parallelRunners)
+	var newFiles []weightedTestFile
+	for _, file := range files {
+		runnerIndex, ok := previousRunner(file, members, previous, b.parallelRunners)
+		if !ok {
+			newFiles = append(newFiles, file)
+			continue
+		}
+		b.addFileTo(file, runnerIndex)
+		runners[runnerIndex] = append(runners[runnerIndex], file)
+	}
+	for _, file := range newFiles {
+		runnerIndex := b.addFile(file)
+		runners[runnerIndex] = append(runners[runnerIndex], file)
+	}
+	if b.score().wallTime <= maxWallTime {
+		return runners, true
+	}
+	if b.capacities != nil && slices.ContainsFunc(files, func(file weightedTestFile) bool { return file.serial }) {
+		return nil, false
+	}
+
+	capacities := make([]int, b.parallelRunners)
+	for i := range runners {
+		slices.SortFunc(runners[i], compareWeightedTestFiles)
+		capacities[i] = b.capacity(i)
+	}
+	search := newSplitLocalSearch(runners, b.runnerLoads(), capacities)
+	search.runUntil(len(files), maxWallTime)
+
+	b.setRunnerLoads(search.loads)
+	return search.runners, b.score().wallTime <= maxWallTime
+}
+
+// runUntil is run, stopping as soon as every runner finishes by maxWallTime.
+// Like run, it makes at most maxSteps steps, so whether the sticky split is
+// kept does not depend on how fast the planner runs.
+func (s *splitLocalSearch) runUntil(maxSteps int, maxWallTime int) {
+	for range maxSteps {
+		heaviest := s.byLoad[len(s.byLoad)-1]
+		capacity := s.capacities[heaviest]
+		if (s.loads[heaviest]+capacity-1)/capacity <= maxWallTime || !s.improve() {
+			return
+		}
+	}
+}
+
+// previousRunner returns the runner that the previous split assigned most test
+// files of file, a test file or a group unit, to, when this split has that
+// runner.
+func previousRunner(file weightedTestFile, members map[string][]string, previous map[string]int, parallelRunners int) (int, bool) {
+	testFiles, ok := members[file.path]
+	if !ok {
+		testFiles = []string{file.path}
+	}
+
+	counts := make(map[int]int, 1)
+	best, bestCount := -1, 0
+	for _, testFile := range testFiles {
+		runnerIndex, ok := previous[testFile]
+		if !ok || runnerIndex >= parallelRunners {
+			continue
+		}
+		counts[runnerIndex]++
+		if count := counts[runnerIndex]; count > bestCount || (count == bestCount && runnerIndex < best) {
+			best, bestCount = runnerIndex, count
+		}
+	}
+	return best, best >= 0
+}
+
+// countMoves counts the test files of distribution that stay on their
+// previous runner, that move to another runner, and that are new.
+func (r *stickySplitReport) countMoves(distribution [][]string, previous map[string]int) {
+	for runnerIndex, testFiles := range distribution {
+		for _, testFile := range testFiles {
+			previousRunnerIndex, ok := previous[testFile]
+			switch {
+			case !ok:
+				r.NewTestFiles++
+			case previousRunnerIndex == runnerIndex:
+				r.KeptTestFiles++
+			default:
+				r.MovedTestFiles++
+			}
+		}
+	}
+}
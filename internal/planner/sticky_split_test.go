@@ -0,0 +1,201 @@
+package planner
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/testoptimization"
+)
+
+func writePreviousPlan(t *testing.T, planDir string, parallelRunners string, splits ...string) {
+	t.Helper()
+	if err := writePlanFile(planDirPath(planDir, constants.ParallelRunnersOutputPath), []byte(parallelRunners)); err != nil {
+		t.Fatal(err)
+	}
+	for i, split := range splits {
+		if err := writeRunnerSplit(planDirPath(planDir, constants.TestsSplitDir), i, []byte(split)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadPreviousTestSplit(t *testing.T) {
+	planDir := filepath.Join(t.TempDir(), "previous")
+	// runner-2 is left over from an earlier plan with three runners.
+	writePreviousPlan(t, planDir, "2\n", "spec/a_spec.rb\nspec/b_spec.rb\n", "spec/c_spec.rb\n", "spec/stale_spec.rb\n")
+
+	runners, err := readPreviousTestSplit(planDir)
+	if err != nil {
+		t.Fatalf("readPreviousTestSplit() returned error: %v", err)
+	}
+	expected := map[string]int{"spec/a_spec.rb": 0, "spec/b_spec.rb": 0, "spec/c_spec.rb": 1}
+	if len(runners) != len(expected) {
+		t.Fatalf("readPreviousTestSplit() = %v, want %v", runners, expected)
+	}
+	for testFile, runner := range expected {
+		if runners[testFile] != runner {
+			t.Errorf("runner of %s = %d, want %d", testFile, runners[testFile], runner)
+		}
+	}
+
+	if _, err := readPreviousTestSplit(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected a missing previous plan to be an error")
+	}
+}
+
+func TestSplitOptions_DistributeTestFiles_KeepsPreviousRunners(t *testing.T) {
+	weights := map[string]int{"spec/a_spec.rb": 5000, "spec/b_spec.rb": 4000, "spec/c_spec.rb": 3000, "spec/d_spec.rb": 2000}
+	previous := map[string]int{"spec/a_spec.rb": 1, "spec/b_spec.rb": 0, "spec/c_spec.rb": 1, "spec/d_spec.rb": 0}
+
+	// From scratch, both runners take 7s. Keeping every test file takes 8s,
+	// within a 20% tolerance.
+	distribution, report := splitOptions{previousRunners: previous, stickySplitTolerance: 20}.distributeTestFiles(weights, 2)
+	assertDistribution(t, distribution, [][]string{{"spec/b_spec.rb", "spec/d_spec.rb"}, {"spec/a_spec.rb", "spec/c_spec.rb"}})
+	if report.FromScratch || report.KeptTestFiles != 4 || report.MovedTestFiles != 0 || report.WallTime != 8*time.Second || report.FromScratchWallTime != 7*time.Second {
+		t.Errorf("unexpected sticky split report %+v", report)
+	}
+
+	// Without tolerance, the slowest runner swaps test files until the split
+	// is as fast as from scratch.
+	distribution, report = splitOptions{previousRunners: previous}.distributeTestFiles(weights, 2)
+	assertDistribution(t, distribution, [][]string{{"spec/a_spec.rb", "spec/d_spec.rb"}, {"spec/b_spec.rb", "spec/c_spec.rb"}})
+	if report.FromScratch || report.KeptTestFiles != 2 || report.MovedTestFiles != 2 || report.WallTime != 7*time.Second {
+		t.Errorf("unexpected sticky split report %+v", report)
+	}
+
+	// Without a previous split, test files are distributed from scratch.
+	distribution, report = splitOptions{}.distributeTestFiles(weights, 2)
+	assertDistribution(t, distribution, [][]string{{"spec/a_spec.rb", "spec/d_spec.rb"}, {"spec/b_spec.rb", "spec/c_spec.rb"}})
+	if report.Available {
+		t.Errorf("expected no sticky split report without a previous split, got %+v", report)
+	}
+}
+
+func TestSplitOptions_DistributeTestFiles_NewTestFilesAndRemovedRunners(t *testing.T) {
+	weights := map[string]int{"spec/a_spec.rb": 3000, "spec/b_spec.rb": 3000, "spec/c_spec.rb": 2000, "spec/new_spec.rb": 2000}
+	// spec/c_spec.rb ran on a runner this split does not have.
+	previous := map[string]int{"spec/a_spec.rb": 1, "spec/b_spec.rb": 0, "spec/c_spec.rb": 2}
+
+	distribution, report := splitOptions{previousRunners: previous, stickySplitTolerance: 5}.distributeTestFiles(weights, 2)
+	assertDistribution(t, distribution, [][]string{{"spec/b_spec.rb", "spec/c_spec.rb"}, {"spec/a_spec.rb", "spec/new_spec.rb"}})
+	if report.KeptTestFiles != 2 || report.MovedTestFiles != 1 || report.NewTestFiles != 1 {
+		t.Errorf("unexpected sticky split report %+v", report)
+	}
+}
+
+func TestSplitOptions_DistributeTestFiles_KeepsGroupsOnTheirMostCommonRunner(t *testing.T) {
+	constraints := NewTestFileConstraints(map[string][]string{"db": {"spec/db/a_spec.rb", "spec/db/b_spec.rb", "spec/db/c_spec.rb"}}, nil)
+	weights := map[string]int{"spec/db/a_spec.rb": 1000, "spec/db/b_spec.rb": 1000, "spec/db/c_spec.rb": 1000, "spec/one_spec.rb": 3000}
+	previous := map[string]int{"spec/db/a_spec.rb": 0, "spec/db/b_spec.rb": 1, "spec/db/c_spec.rb": 1, "spec/one_spec.rb": 0}
+
+	distribution, report := splitOptions{constraints: constraints, previousRunners: previous}.distributeTestFiles(weights, 2)
+	assertDistribution(t, distribution, [][]string{{"spec/one_spec.rb"}, {"spec/db/a_spec.rb", "spec/db/b_spec.rb", "spec/db/c_spec.rb"}})
+	if report.KeptTestFiles != 3 || report.MovedTestFiles != 1 {
+		t.Errorf("unexpected sticky split report %+v", report)
+	}
+}
+
+func TestSplitOptions_DistributeTestFiles_FromScratchBeyondTolerance(t *testing.T) {
+	constraints := NewTestFileConstraints(nil, []string{"spec/es_spec.rb"})
+	weights := map[string]int{"spec/es_spec.rb": 10, "spec/one_spec.rb": 10, "spec/two_spec.rb": 10}
+	previous := map[string]int{"spec/es_spec.rb": 0, "spec/one_spec.rb": 0, "spec/two_spec.rb": 0}
+	options := splitOptions{ciNodeCapacities: map[int]int{0: 2, 1: 2}, ciNodeWorkers: 2, constraints: constraints, previousRunners: previous}
+
+	// Moving test files between CI nodes that run an exclusive test file is
+	// not searched, so the split from scratch is used.
+	distribution, report := options.distributeTestFiles(weights, 2)
+	assertDistribution(t, distribution, [][]string{{"spec/es_spec.rb"}, {"spec/one_spec.rb", "spec/two_spec.rb"}})
+	if !report.FromScratch || report.KeptTestFiles != 1 || report.MovedTestFiles != 2 || report.WallTime != report.FromScratchWallTime {
+		t.Errorf("unexpected sticky split report %+v", report)
+	}
+}
+
+func TestPrintStickySplitPlanningReport(t *testing.T) {
+	var output strings.Builder
+	printStickySplitPlanningReport(&output, stickySplitReport{
+		Available:           true,
+		PreviousPlan:        ".testoptimization",
+		Tolerance:           5,
+		WallTime:            62 * time.Second,
+		FromScratchWallTime: time.Minute,
+		KeptTestFiles:       40,
+		MovedTestFiles:      2,
+		NewTestFiles:        1,
+	})
+
+	for _, want := range []string{
+		"Sticky split",
+		"Previous plan: .testoptimization",
+		"Test files kept on their runner: 40",
+		"Test files moved: 2",
+		"New test files: 1",
+		"Wall time: 1m2s (from scratch 1m0s, tolerance 5%)",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, output.String())
+		}
+	}
+
+	output.Reset()
+	printStickySplitPlanningReport(&output, stickySplitReport{Available: true, Tolerance: 2.5, FromScratch: true, FromScratchWallTime: time.Minute, WallTime: time.Minute})
+	if !strings.Contains(output.String(), "Split: from scratch, keeping test files on their runner exceeded the 2.5% tolerance") {
+		t.Errorf("expected report of a split from scratch, got:\n%s", output.String())
+	}
+
+	output.Reset()
+	printStickySplitPlanningReport(&output, stickySplitReport{})
+	if output.Len() != 0 {
+		t.Errorf("expected no report without a previous plan, got:\n%s", output.String())
+	}
+}
+
+func TestTestPlanner_Plan_KeepsTestFilesOnPreviousRunners(t *testing.T) {
+	t.Chdir(t.TempDir())
+	// The previous plan is the plan directory that this plan overwrites.
+	writePreviousPlan(t, constants.PlanDirectory, "2", "test/file2_test.rb\n", "test/file1_test.rb\n")
+	setPlannerEnv(t, map[string]string{
+		"DD_TEST_OPTIMIZATION_RUNNER_PREVIOUS_PLAN":          constants.PlanDirectory,
+		"DD_TEST_OPTIMIZATION_RUNNER_STICKY_SPLIT_TOLERANCE": "5",
+		"DD_TEST_OPTIMIZATION_RUNNER_MIN_PARALLELISM":        "2",
+		"DD_TEST_OPTIMIZATION_RUNNER_MAX_PARALLELISM":        "2",
+	})
+
+	mockPlatform := &MockPlatform{
+		PlatformName: "ruby",
+		Tags:         map[string]string{"platform": "ruby"},
+		Framework: &MockFramework{
+			FrameworkName: "rspec",
+			Tests: []testoptimization.Test{
+				{Suite: "TestSuite1", Name: "test1", SuiteSourceFile: "test/file1_test.rb"},
+				{Suite: "TestSuite2", Name: "test2", SuiteSourceFile: "test/file2_test.rb"},
+				{Suite: "TestSuite3", Name: "test3", SuiteSourceFile: "test/file3_test.rb"},
+			},
+		},
+	}
+	runner := NewWithDependencies(
+		&MockPlatformDetector{Platform: mockPlatform},
+		&MockTestOptimizationClient{Settings: testOptimizationSettings(true, true, false)},
+		newDefaultMockCIProviderDetector(),
+	)
+	var report strings.Builder
+	runner.reportWriter = &report
+	if err := runner.Plan(context.Background()); err != nil {
+		t.Fatalf("Plan() should not return error, got: %v", err)
+	}
+
+	assertFileContent(t, filepath.Join(constants.TestsSplitDir, "runner-0"), "test/file2_test.rb\ntest/file3_test.rb\n")
+	assertFileContent(t, filepath.Join(constants.TestsSplitDir, "runner-1"), "test/file1_test.rb\n")
+	for _, want := range []string{
+		"Test files kept on their runner: 2",
+		"Test files moved: 0",
+		"New test files: 1",
+	} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, report.String())
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package planner
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/framework"
+	"github.com/DataDog/ddtest/internal/settings"
+)
+
+// chunkSlowTestFiles splits each runnable test file that would take longer
+// than testChunkThreshold into test chunks of about equal estimated duration,
+// assuming its tests take about as long each. It returns the estimated weight
+// of each test chunk, or nil when no test file was split.
+func (tp *TestPlanner) chunkSlowTestFiles(testFramework framework.Framework) map[string]int {
+	selector, ok := testFramework.(framework.TestChunkSelector)
+	if !ok {
+		slog.Info("Framework cannot run chunks of a test file; slow test files are not chunked", "framework", testFramework.Name())
+		return nil
+	}
+
+	threshold := testChunkThreshold(tp.testFileWeights, settings.GetMaxParallelism(), settings.GetTargetTime())
+	if threshold <= 0 {
+		return nil
+	}
+
+	chunkWeights := make(map[string]int)
+	chunkedTestFiles := 0
+	for _, testFile := range slices.Sorted(maps.Keys(tp.testFileWeights)) {
+		weight := tp.testFileWeights[testFile]
+		if weight <= threshold {
+			continue
+		}
+		if tp.testFileConstraints.constrains(testFile) {
+			slog.Debug("Slow test file is grouped or exclusive and is not chunked", "testFile", testFile, "weight", weight)
+			continue
+		}
+
+		selectors, ok := tp.testSelectors(selector, testFile)
+		if !ok || len(selectors) < 2 {
+			slog.Debug("Slow test file cannot be chunked", "testFile", testFile, "weight", weight)
+			continue
+		}
+
+		chunks := min((weight+threshold-1)/threshold, len(selectors))
+		for chunk := range chunks {
+			start, end := chunk*len(selectors)/chunks, (chunk+1)*len(selectors)/chunks
+			chunkWeight := weight*end/len(selectors) - weight*start/len(selectors)
+			chunkWeights[framework.FormatTestChunk(testFile, selectors[start:end])] = max(chunkWeight, 1)
+		}
+		chunkedTestFiles++
+	}
+
+	if chunkedTestFiles == 0 {
+		return nil
+	}
+	slog.Info("Split slow test files into test chunks",
+		"testFilesCount", chunkedTestFiles, "testChunksCount", len(chunkWeights), "threshold", time.Duration(threshold)*time.Millisecond)
+	return chunkWeights
+}
+
+// testSelectors returns the selectors of the discovered tests of testFile in
+// discovery order, or false when one of them cannot be selected.
+func (tp *TestPlanner) testSelectors(selector framework.TestChunkSelector, testFile string) ([]string, bool) {
+	tests := tp.testsBySourceFile[testFile]
+	selectors := make([]string, 0, len(tests))
+	for _, test := range tests {
+		testSelector, ok := selector.TestSelector(test)
+		if !ok {
+			return nil, false
+		}
+		if !slices.Contains(selectors, testSelector) {
+			selectors = append(selectors, testSelector)
+		}
+	}
+	return selectors, true
+}
+
+// testChunkThreshold returns the estimated duration, in milliseconds, above
+// which a test file is split into test chunks: the target time when set,
+// otherwise the share of the work of one runner at max parallelism. It returns
+// 0 when a single runner is planned.
+func testChunkThreshold(testFileWeights map[string]int, maxParallelism int, targetTime time.Duration) int {
+	if maxParallelism <= 1 {
+		return 0
+	}
+	if targetTime > 0 {
+		return max(int(targetTime.Milliseconds()), 1)
+	}
+
+	totalWeight := 0
+	for _, weight := range testFileWeights {
+		totalWeight += weight
+	}
+	return max(totalWeight/maxParallelism, 1)
+}
+
+// splitWeights returns the estimated weight of each runner split entry: the
+// runnable test files, with the test files split into test chunks replaced by
+// their chunks.
+func (tp *TestPlanner) splitWeights() map[string]int {
+	if len(tp.testChunkWeights) == 0 {
+		return tp.testFileWeights
+	}
+
+	weights := maps.Clone(tp.testChunkWeights)
+	chunkedTestFiles := make(map[string]bool)
+	for chunk := range tp.testChunkWeights {
+		chunkedTestFiles[framework.TestChunkFile(chunk)] = true
+	}
+	for testFile, weight := range tp.testFileWeights {
+		if !chunkedTestFiles[testFile] {
+			weights[testFile] = weight
+		}
+	}
+	return weights
+}
@@ -0,0 +1,162 @@
+package planner
+
+import (
+	"context"
+	"maps"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/testoptimization"
+	"github.com/DataDog/ddtest/internal/testoptimization/api"
+)
+
+// chunkingMockFramework selects each test by its name.
+type chunkingMockFramework struct {
+	*MockFramework
+}
+
+func (m chunkingMockFramework) TestSelector(test testoptimization.Test) (string, bool) {
+	return test.Name, test.Name != ""
+}
+
+func slowTestFilePlanner() *TestPlanner {
+	tp := newTestPlannerWithDefaults()
+	tp.testFileWeights = map[string]int{
+		"spec/slow_spec.rb": 9000,
+		"spec/fast_spec.rb": 1000,
+	}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		tp.testsBySourceFile["spec/slow_spec.rb"] = append(tp.testsBySourceFile["spec/slow_spec.rb"], testoptimization.Test{Name: name})
+	}
+	tp.testsBySourceFile["spec/fast_spec.rb"] = []testoptimization.Test{{Name: "f"}}
+	return tp
+}
+
+func TestTestChunkThreshold(t *testing.T) {
+	weights := map[string]int{"a": 9000, "b": 1000}
+
+	if threshold := testChunkThreshold(weights, 4, 0); threshold != 2500 {
+		t.Errorf("testChunkThreshold() without target time = %d, want 2500", threshold)
+	}
+	if threshold := testChunkThreshold(weights, 4, 3*time.Second); threshold != 3000 {
+		t.Errorf("testChunkThreshold() with target time = %d, want 3000", threshold)
+	}
+	if threshold := testChunkThreshold(weights, 1, 3*time.Second); threshold != 0 {
+		t.Errorf("testChunkThreshold() for a single runner = %d, want 0", threshold)
+	}
+}
+
+func TestChunkSlowTestFiles(t *testing.T) {
+	setPlannerEnv(t, map[string]string{
+		"DD_TEST_OPTIMIZATION_RUNNER_CHUNK_SLOW_TEST_FILES": "true",
+		"DD_TEST_OPTIMIZATION_RUNNER_MIN_PARALLELISM":       "1",
+		"DD_TEST_OPTIMIZATION_RUNNER_MAX_PARALLELISM":       "4",
+	})
+	tp := slowTestFilePlanner()
+
+	chunks := tp.chunkSlowTestFiles(chunkingMockFramework{&MockFramework{FrameworkName: "rspec"}})
+	expected := map[string]int{
+		"spec/slow_spec.rb[a]":   1800,
+		"spec/slow_spec.rb[b]":   1800,
+		"spec/slow_spec.rb[c]":   1800,
+		"spec/slow_spec.rb[d,e]": 3600,
+	}
+	if !maps.Equal(chunks, expected) {
+		t.Fatalf("chunkSlowTestFiles() = %v, want %v", chunks, expected)
+	}
+
+	tp.testChunkWeights = chunks
+	expectedSplitWeights := map[string]int{
+		"spec/slow_spec.rb[a]":   1800,
+		"spec/slow_spec.rb[b]":   1800,
+		"spec/slow_spec.rb[c]":   1800,
+		"spec/slow_spec.rb[d,e]": 3600,
+		"spec/fast_spec.rb":      1000,
+	}
+	if weights := tp.splitWeights(); !maps.Equal(weights, expectedSplitWeights) {
+		t.Fatalf("splitWeights() = %v, want %v", weights, expectedSplitWeights)
+	}
+
+	tp.planLoaded = true
+	weights := tp.TestFileWeights([]string{"spec/slow_spec.rb", "spec/slow_spec.rb[d,e]", "spec/fast_spec.rb"})
+	expectedWeights := map[string]int{
+		"spec/slow_spec.rb":      9000,
+		"spec/slow_spec.rb[d,e]": 3600,
+		"spec/fast_spec.rb":      1000,
+	}
+	if !maps.Equal(weights, expectedWeights) {
+		t.Fatalf("TestFileWeights() = %v, want %v", weights, expectedWeights)
+	}
+}
+
+func TestChunkSlowTestFiles_SkipsTestFilesThatCannotBeChunked(t *testing.T) {
+	setPlannerEnv(t, map[string]string{
+		"DD_TEST_OPTIMIZATION_RUNNER_CHUNK_SLOW_TEST_FILES": "true",
+		"DD_TEST_OPTIMIZATION_RUNNER_MIN_PARALLELISM":       "1",
+		"DD_TEST_OPTIMIZATION_RUNNER_MAX_PARALLELISM":       "4",
+	})
+
+	tp := slowTestFilePlanner()
+	if chunks := tp.chunkSlowTestFiles(&MockFramework{FrameworkName: "jest"}); chunks != nil {
+		t.Errorf("chunkSlowTestFiles() without test selectors = %v, want nil", chunks)
+	}
+
+	tp.testsBySourceFile["spec/slow_spec.rb"] = append(tp.testsBySourceFile["spec/slow_spec.rb"], testoptimization.Test{})
+	if chunks := tp.chunkSlowTestFiles(chunkingMockFramework{&MockFramework{FrameworkName: "rspec"}}); chunks != nil {
+		t.Errorf("chunkSlowTestFiles() with a test that cannot be selected = %v, want nil", chunks)
+	}
+}
+
+func TestTestPlanner_Plan_ChunksSlowTestFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	setPlannerForceFullTestDiscovery(t, true)
+	setPlannerEnv(t, map[string]string{
+		"DD_TEST_OPTIMIZATION_RUNNER_CHUNK_SLOW_TEST_FILES": "true",
+		"DD_TEST_OPTIMIZATION_RUNNER_MIN_PARALLELISM":       "2",
+		"DD_TEST_OPTIMIZATION_RUNNER_MAX_PARALLELISM":       "2",
+	})
+
+	mockFramework := &MockFramework{
+		FrameworkName:    "rspec",
+		TestPatternValue: "spec/**/*_spec.rb",
+		TestFiles:        []string{"spec/slow_spec.rb", "spec/fast_spec.rb"},
+		Tests: []testoptimization.Test{
+			{Module: "rspec", Suite: "Slow", Name: "a", SuiteSourceFile: "spec/slow_spec.rb"},
+			{Module: "rspec", Suite: "Slow", Name: "b", SuiteSourceFile: "spec/slow_spec.rb"},
+			{Module: "rspec", Suite: "Slow", Name: "c", SuiteSourceFile: "spec/slow_spec.rb"},
+			{Module: "rspec", Suite: "Slow", Name: "d", SuiteSourceFile: "spec/slow_spec.rb"},
+			{Module: "rspec", Suite: "Fast", Name: "e", SuiteSourceFile: "spec/fast_spec.rb"},
+		},
+	}
+	mockOptimizationClient := &MockTestOptimizationClient{
+		Settings: testOptimizationSettings(true, true, false),
+		Durations: map[string]map[string]api.TestSuiteDurationInfo{
+			"rspec": {
+				"Slow": {SourceFile: "spec/slow_spec.rb", Duration: api.DurationPercentiles{P50: "8000000000"}},
+				"Fast": {SourceFile: "spec/fast_spec.rb", Duration: api.DurationPercentiles{P50: "2000000000"}},
+			},
+		},
+	}
+	mockPlatform := &MockPlatform{
+		PlatformName: "ruby",
+		Tags:         map[string]string{"platform": "ruby"},
+		Framework:    chunkingMockFramework{mockFramework},
+	}
+
+	runner := NewWithDependencies(&MockPlatformDetector{Platform: mockPlatform}, mockOptimizationClient, newDefaultMockCIProviderDetector())
+	if err := runner.Plan(context.Background()); err != nil {
+		t.Fatalf("Plan() should not return error, got: %v", err)
+	}
+
+	assertFileContent(t, constants.TestFilesOutputPath, "spec/fast_spec.rb\nspec/slow_spec.rb\n")
+	assertFileContent(t, constants.ParallelRunnersOutputPath, "2")
+	assertFileContent(t, filepath.Join(constants.TestsSplitDir, "runner-0"), "spec/slow_spec.rb[a,b]\nspec/fast_spec.rb\n")
+	assertFileContent(t, filepath.Join(constants.TestsSplitDir, "runner-1"), "spec/slow_spec.rb[c,d]\n")
+
+	restored := newTestPlannerWithDefaults()
+	if weight := restored.TestFileWeights([]string{"spec/slow_spec.rb[c,d]"})["spec/slow_spec.rb[c,d]"]; weight != 4000 {
+		t.Errorf("expected restored test chunk weight 4000, got %d", weight)
+	}
+}
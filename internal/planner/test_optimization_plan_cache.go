@@ -14,8 +14,12 @@ type testOptimizationPlanCache struct {
 	SuitesBySourceFile      map[string][]testSuiteKey                       `json:"suitesBySourceFile"`
 	TestFileWeights         map[string]int                                  `json:"testFileWeights"`
 	TestFileDurationSources map[string]testFileDurationSource               `json:"testFileDurationSources"`
+	TestChunkWeights        map[string]int                                  `json:"testChunkWeights,omitempty"`
+	TestFileGroups          map[string][]string                             `json:"testFileGroups,omitempty"`
+	ExclusiveTestFiles      []string                                        `json:"exclusiveTestFiles,omitempty"`
 	RunInfo                 runmetadata.RunInfo                             `json:"runInfo"`
 	PlanMetadata            PlanMetadata                                    `json:"planMetadata"`
+	DiscoveryMode           discoveryMode                                   `json:"discoveryMode,omitempty"`
 }
 
 func (tp *TestPlanner) storeTestOptimizationPlanCache() error {
@@ -25,8 +29,12 @@ func (tp *TestPlanner) storeTestOptimizationPlanCache() error {
 		SuitesBySourceFile:      tp.suitesBySourceFile,
 		TestFileWeights:         tp.testFileWeights,
 		TestFileDurationSources: tp.testFileDurationSources,
+		TestChunkWeights:        tp.testChunkWeights,
+		TestFileGroups:          tp.testFileConstraints.Groups,
+		ExclusiveTestFiles:      tp.testFileConstraints.Exclusive,
 		RunInfo:                 tp.runInfo,
 		PlanMetadata:            tp.planMetadata,
+		DiscoveryMode:           tp.reportStats.discoveryMode,
 	}
 
 	return testoptimization.NewCacheManager().StoreTestOptimizationPlanCache(cache)
@@ -57,6 +65,8 @@ func (tp *TestPlanner) restoreTestOptimizationPlanCache() error {
 	tp.suitesBySourceFile = cache.SuitesBySourceFile
 	tp.testFileWeights = cache.TestFileWeights
 	tp.testFileDurationSources = cache.TestFileDurationSources
+	tp.testChunkWeights = cache.TestChunkWeights
+	tp.testFileConstraints = NewTestFileConstraints(cache.TestFileGroups, cache.ExclusiveTestFiles)
 	tp.runInfo = cache.RunInfo
 	tp.planMetadata = cache.PlanMetadata
 	tp.planLoaded = true
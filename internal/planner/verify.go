@@ -0,0 +1,226 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/errcode"
+	"github.com/DataDog/ddtest/internal/framework"
+)
+
+// maxReportedTestFiles caps how many offending test files a verification
+// error lists.
+const maxReportedTestFiles = 5
+
+// VerifyPlan checks that the plan directory is complete and consistent: the
+// manifest version matches this ddtest, the plan cache parses, there is one
+// runner split per parallel runner, the splits cover test-files.txt exactly
+// once, and every planned test file exists. It returns an error for the first
+// failed check.
+func VerifyPlan(w io.Writer) error {
+	if err := verifyManifest(); err != nil {
+		return err
+	}
+
+	var cache testOptimizationPlanCache
+	if err := readAndNormalizeTestOptimizationPlanCache(&cache); err != nil {
+		return errcode.WithCode(errcode.VerifyPlanCacheInvalid, fmt.Errorf("failed to read test optimization plan cache: %w", err))
+	}
+
+	testFiles, err := readPlanFileLines(constants.TestFilesOutputPath)
+	if err != nil {
+		return errcode.WithCode(errcode.VerifyPlanTestFilesReadFailed, fmt.Errorf("failed to read test files from %s: %w", constants.TestFilesOutputPath, err))
+	}
+	if duplicates := duplicateTestFiles(testFiles); len(duplicates) > 0 {
+		return errcode.New(errcode.VerifyPlanDuplicateTestFile, fmt.Sprintf("%s lists test files more than once: %s", constants.TestFilesOutputPath, formatTestFileSample(duplicates)))
+	}
+
+	parallelRunners, err := verifyRunnerSplitCount()
+	if err != nil {
+		return err
+	}
+	splits, err := readRunnerSplits()
+	if err != nil {
+		return errcode.WithCode(errcode.VerifyPlanTestSplitsReadFailed, err)
+	}
+	if err := verifyRunnerSplitsCoverTestFiles(splits, testFiles); err != nil {
+		return err
+	}
+	if err := verifyPlanJSON(parallelRunners, splits); err != nil {
+		return err
+	}
+
+	missing := make([]string, 0)
+	for _, testFile := range testFiles {
+		if _, err := os.Stat(testFile); err != nil {
+			missing = append(missing, testFile)
+		}
+	}
+	if len(missing) > 0 {
+		return errcode.New(errcode.VerifyPlanTestFileMissing, fmt.Sprintf("%s planned test files do not exist: %s", formatCount(len(missing)), formatTestFileSample(missing)))
+	}
+
+	reportFprintf(w, "+++ DDTest: plan verified, %s across %s\n",
+		formatCountWithUnit(len(testFiles), "test file", "test files"),
+		formatCountWithUnit(parallelRunners, "runner", "runners"))
+	return nil
+}
+
+func verifyManifest() error {
+	data, err := os.ReadFile(constants.ManifestPath)
+	if err != nil {
+		return errcode.WithCode(errcode.VerifyPlanManifestMissing, fmt.Errorf("failed to read test optimization manifest %s: %w", constants.ManifestPath, err))
+	}
+	if version := strings.TrimSpace(string(data)); version != constants.ManifestVersion {
+		return errcode.New(errcode.VerifyPlanManifestVersionMismatch, fmt.Sprintf("test optimization manifest %s has version %q, this ddtest expects %q; plan again with this ddtest version", constants.ManifestPath, version, constants.ManifestVersion))
+	}
+	return nil
+}
+
+// verifyPlanJSON checks that plan.json, when the plan has one, has this
+// ddtest's schema version and the same runner splits as the runner-N files.
+func verifyPlanJSON(parallelRunners int, splits [][]string) error {
+	plan, err := ReadPlanJSON(constants.PlanJSONPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errcode.WithCode(errcode.VerifyPlanJSONInvalid, err)
+	}
+	if plan.ParallelRunners != parallelRunners || len(plan.Runners) != len(splits) {
+		return errcode.New(errcode.VerifyPlanJSONInvalid, fmt.Sprintf("%s plans %s, but %s has %s",
+			constants.PlanJSONPath,
+			formatCountWithUnit(plan.ParallelRunners, "runner", "runners"),
+			constants.TestsSplitDir,
+			formatCountWithUnit(len(splits), "runner split", "runner splits")))
+	}
+	for index, runner := range plan.Runners {
+		if runner.Index != index || !slices.Equal(runner.TestFiles, splits[index]) {
+			return errcode.New(errcode.VerifyPlanJSONInvalid, fmt.Sprintf("%s does not match runner-%d in %s; copy the whole plan directory", constants.PlanJSONPath, index, constants.TestsSplitDir))
+		}
+	}
+	return nil
+}
+
+// verifyRunnerSplitCount checks that runner-0 through runner-(N-1) exist for
+// the N runners in parallel-runners.txt, and that no other runner split does.
+func verifyRunnerSplitCount() (int, error) {
+	data, err := os.ReadFile(constants.ParallelRunnersOutputPath)
+	if err != nil {
+		return 0, errcode.WithCode(errcode.VerifyPlanParallelRunnersInvalid, fmt.Errorf("failed to read parallel runners count from %s: %w", constants.ParallelRunnersOutputPath, err))
+	}
+	parallelRunners, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || parallelRunners < 1 {
+		return 0, errcode.New(errcode.VerifyPlanParallelRunnersInvalid, fmt.Sprintf("%s does not contain a positive runner count: %q", constants.ParallelRunnersOutputPath, strings.TrimSpace(string(data))))
+	}
+
+	entries, err := os.ReadDir(constants.TestsSplitDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, errcode.WithCode(errcode.VerifyPlanTestSplitsReadFailed, fmt.Errorf("failed to read tests split directory %s: %w", constants.TestsSplitDir, err))
+	}
+	found := make(map[int]bool, len(entries))
+	for _, entry := range entries {
+		if index, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), "runner-")); err == nil && !entry.IsDir() && index >= 0 {
+			found[index] = true
+		}
+	}
+
+	missing := make([]string, 0)
+	for index := range parallelRunners {
+		if !found[index] {
+			missing = append(missing, fmt.Sprintf("runner-%d", index))
+		}
+	}
+	if len(missing) > 0 || len(found) != parallelRunners {
+		return 0, errcode.New(errcode.VerifyPlanRunnerCountMismatch, fmt.Sprintf("%s expects %s, but %s has %s (missing: %s)",
+			constants.ParallelRunnersOutputPath,
+			formatCountWithUnit(parallelRunners, "runner split", "runner splits"),
+			constants.TestsSplitDir,
+			formatCountWithUnit(len(found), "runner split", "runner splits"),
+			valueOrNotAvailable(strings.Join(missing, ", "))))
+	}
+	return parallelRunners, nil
+}
+
+// verifyRunnerSplitsCoverTestFiles checks that every planned test file is in
+// exactly one runner split, or split into test chunks, and that the splits
+// contain nothing else.
+func verifyRunnerSplitsCoverTestFiles(splits [][]string, testFiles []string) error {
+	entries := slices.Concat(splits...)
+	if duplicates := duplicateTestFiles(entries); len(duplicates) > 0 {
+		return errcode.New(errcode.VerifyPlanDuplicateTestFile, fmt.Sprintf("runner splits assign test files more than once: %s", formatTestFileSample(duplicates)))
+	}
+
+	splitTestFiles := make([]string, 0, len(entries))
+	chunked := make(map[string]bool)
+	for _, entry := range entries {
+		if testFile, _, ok := framework.ParseTestChunk(entry); ok {
+			if !chunked[testFile] {
+				splitTestFiles = append(splitTestFiles, testFile)
+			}
+			chunked[testFile] = true
+		}
+	}
+	for _, entry := range entries {
+		if _, _, ok := framework.ParseTestChunk(entry); !ok {
+			splitTestFiles = append(splitTestFiles, entry)
+		}
+	}
+	if duplicates := duplicateTestFiles(splitTestFiles); len(duplicates) > 0 {
+		return errcode.New(errcode.VerifyPlanDuplicateTestFile, fmt.Sprintf("runner splits assign test files both whole and as test chunks: %s", formatTestFileSample(duplicates)))
+	}
+
+	planned := make(map[string]bool, len(testFiles))
+	for _, testFile := range testFiles {
+		planned[testFile] = true
+	}
+	split := make(map[string]bool, len(splitTestFiles))
+	unplanned := make([]string, 0)
+	for _, testFile := range splitTestFiles {
+		split[testFile] = true
+		if !planned[testFile] {
+			unplanned = append(unplanned, testFile)
+		}
+	}
+	unassigned := make([]string, 0)
+	for _, testFile := range testFiles {
+		if !split[testFile] {
+			unassigned = append(unassigned, testFile)
+		}
+	}
+
+	if len(unassigned) > 0 {
+		return errcode.New(errcode.VerifyPlanTestSplitsMismatch, fmt.Sprintf("%s test files from %s are not in any runner split: %s", formatCount(len(unassigned)), constants.TestFilesOutputPath, formatTestFileSample(unassigned)))
+	}
+	if len(unplanned) > 0 {
+		return errcode.New(errcode.VerifyPlanTestSplitsMismatch, fmt.Sprintf("%s test files in runner splits are not in %s: %s", formatCount(len(unplanned)), constants.TestFilesOutputPath, formatTestFileSample(unplanned)))
+	}
+	return nil
+}
+
+func duplicateTestFiles(testFiles []string) []string {
+	seen := make(map[string]bool, len(testFiles))
+	duplicates := make([]string, 0)
+	for _, testFile := range testFiles {
+		if seen[testFile] && !slices.Contains(duplicates, testFile) {
+			duplicates = append(duplicates, testFile)
+		}
+		seen[testFile] = true
+	}
+	return duplicates
+}
+
+func formatTestFileSample(testFiles []string) string {
+	sorted := slices.Sorted(slices.Values(testFiles))
+	if len(sorted) <= maxReportedTestFiles {
+		return strings.Join(sorted, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(sorted[:maxReportedTestFiles], ", "), len(sorted)-maxReportedTestFiles)
+}
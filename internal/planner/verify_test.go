@@ -0,0 +1,233 @@
+package planner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/errcode"
+	"github.com/DataDog/ddtest/internal/testoptimization"
+)
+
+func writeVerifyTestPlan(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+
+	cache := testOptimizationPlanCache{
+		TestFileWeights: map[string]int{
+			"spec/a_spec.rb": 1000,
+			"spec/b_spec.rb": 1000,
+			"spec/c_spec.rb": 1000,
+		},
+	}
+	if err := testoptimization.NewCacheManager().StoreTestOptimizationPlanCache(cache); err != nil {
+		t.Fatal(err)
+	}
+
+	writePlanTestFile(t, constants.ManifestPath, constants.ManifestVersion+"\n")
+	writePlanTestFile(t, constants.TestFilesOutputPath, "spec/a_spec.rb\nspec/b_spec.rb\nspec/c_spec.rb\n")
+	writePlanTestFile(t, constants.ParallelRunnersOutputPath, "2")
+	writePlanTestFile(t, filepath.Join(constants.TestsSplitDir, "runner-0"), "spec/a_spec.rb\nspec/c_spec.rb\n")
+	writePlanTestFile(t, filepath.Join(constants.TestsSplitDir, "runner-1"), "spec/b_spec.rb\n")
+	for _, testFile := range []string{"spec/a_spec.rb", "spec/b_spec.rb", "spec/c_spec.rb"} {
+		writePlanTestFile(t, testFile, "")
+	}
+}
+
+func TestVerifyPlan_ValidPlan(t *testing.T) {
+	writeVerifyTestPlan(t)
+
+	var output strings.Builder
+	if err := VerifyPlan(&output); err != nil {
+		t.Fatalf("VerifyPlan() error = %v", err)
+	}
+	if output.String() != "+++ DDTest: plan verified, 3 test files across 2 runners\n" {
+		t.Fatalf("unexpected output %q", output.String())
+	}
+}
+
+func TestVerifyPlan_ValidPlanWithTestChunks(t *testing.T) {
+	writeVerifyTestPlan(t)
+	writePlanTestFile(t, filepath.Join(constants.TestsSplitDir, "runner-0"), "spec/a_spec.rb[1:1]\nspec/c_spec.rb\n")
+	writePlanTestFile(t, filepath.Join(constants.TestsSplitDir, "runner-1"), "spec/b_spec.rb\nspec/a_spec.rb[1:2,1:3]\n")
+
+	var output strings.Builder
+	if err := VerifyPlan(&output); err != nil {
+		t.Fatalf("VerifyPlan() error = %v", err)
+	}
+	if output.String() != "+++ DDTest: plan verified, 3 test files across 2 runners\n" {
+		t.Fatalf("unexpected output %q", output.String())
+	}
+}
+
+func writeVerifyTestPlanJSON(t *testing.T, splits [][]string) {
+	t.Helper()
+	plan := PlanJSON{SchemaVersion: constants.PlanJSONSchemaVersion, ParallelRunners: len(splits)}
+	for index, testFiles := range splits {
+		plan.Runners = append(plan.Runners, PlanJSONRunner{Index: index, TestFiles: testFiles})
+	}
+	data, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePlanTestFile(t, constants.PlanJSONPath, string(data))
+}
+
+func TestVerifyPlan_ValidPlanWithPlanJSON(t *testing.T) {
+	writeVerifyTestPlan(t)
+	writeVerifyTestPlanJSON(t, [][]string{{"spec/a_spec.rb", "spec/c_spec.rb"}, {"spec/b_spec.rb"}})
+
+	if err := VerifyPlan(&strings.Builder{}); err != nil {
+		t.Fatalf("VerifyPlan() error = %v", err)
+	}
+}
+
+func TestVerifyPlan_Failures(t *testing.T) {
+	tests := []struct {
+		name     string
+		corrupt  func(t *testing.T)
+		wantCode errcode.Code
+		wantText string
+	}{
+		{
+			name:     "missing manifest",
+			corrupt:  func(t *testing.T) { removePlanTestFile(t, constants.ManifestPath) },
+			wantCode: errcode.VerifyPlanManifestMissing,
+		},
+		{
+			name:     "manifest version mismatch",
+			corrupt:  func(t *testing.T) { writePlanTestFile(t, constants.ManifestPath, "0\n") },
+			wantCode: errcode.VerifyPlanManifestVersionMismatch,
+			wantText: `version "0"`,
+		},
+		{
+			name: "unparseable plan cache",
+			corrupt: func(t *testing.T) {
+				writePlanTestFile(t, filepath.Join(constants.RunnerCacheDir, constants.TestOptimizationPlanCacheFile), "{")
+			},
+			wantCode: errcode.VerifyPlanCacheInvalid,
+		},
+		{
+			name:     "missing test files list",
+			corrupt:  func(t *testing.T) { removePlanTestFile(t, constants.TestFilesOutputPath) },
+			wantCode: errcode.VerifyPlanTestFilesReadFailed,
+		},
+		{
+			name: "duplicate in test files list",
+			corrupt: func(t *testing.T) {
+				writePlanTestFile(t, constants.TestFilesOutputPath, "spec/a_spec.rb\nspec/b_spec.rb\nspec/c_spec.rb\nspec/a_spec.rb\n")
+			},
+			wantCode: errcode.VerifyPlanDuplicateTestFile,
+			wantText: "spec/a_spec.rb",
+		},
+		{
+			name:     "invalid parallel runners",
+			corrupt:  func(t *testing.T) { writePlanTestFile(t, constants.ParallelRunnersOutputPath, "two") },
+			wantCode: errcode.VerifyPlanParallelRunnersInvalid,
+		},
+		{
+			name:     "missing runner split",
+			corrupt:  func(t *testing.T) { removePlanTestFile(t, filepath.Join(constants.TestsSplitDir, "runner-1")) },
+			wantCode: errcode.VerifyPlanRunnerCountMismatch,
+			wantText: "missing: runner-1",
+		},
+		{
+			name: "extra runner split",
+			corrupt: func(t *testing.T) {
+				writePlanTestFile(t, filepath.Join(constants.TestsSplitDir, "runner-2"), "")
+			},
+			wantCode: errcode.VerifyPlanRunnerCountMismatch,
+		},
+		{
+			name: "test file in two splits",
+			corrupt: func(t *testing.T) {
+				writePlanTestFile(t, filepath.Join(constants.TestsSplitDir, "runner-1"), "spec/b_spec.rb\nspec/a_spec.rb\n")
+			},
+			wantCode: errcode.VerifyPlanDuplicateTestFile,
+			wantText: "spec/a_spec.rb",
+		},
+		{
+			name: "test file both whole and in test chunks",
+			corrupt: func(t *testing.T) {
+				writePlanTestFile(t, filepath.Join(constants.TestsSplitDir, "runner-1"), "spec/b_spec.rb\nspec/a_spec.rb[1:2]\n")
+			},
+			wantCode: errcode.VerifyPlanDuplicateTestFile,
+			wantText: "both whole and as test chunks: spec/a_spec.rb",
+		},
+		{
+			name: "test file not in any split",
+			corrupt: func(t *testing.T) {
+				writePlanTestFile(t, filepath.Join(constants.TestsSplitDir, "runner-1"), "")
+			},
+			wantCode: errcode.VerifyPlanTestSplitsMismatch,
+			wantText: "not in any runner split: spec/b_spec.rb",
+		},
+		{
+			name: "split file not in test files list",
+			corrupt: func(t *testing.T) {
+				writePlanTestFile(t, filepath.Join(constants.TestsSplitDir, "runner-1"), "spec/b_spec.rb\nspec/d_spec.rb\n")
+			},
+			wantCode: errcode.VerifyPlanTestSplitsMismatch,
+			wantText: "spec/d_spec.rb",
+		},
+		{
+			name:     "unparseable plan manifest",
+			corrupt:  func(t *testing.T) { writePlanTestFile(t, constants.PlanJSONPath, "{") },
+			wantCode: errcode.VerifyPlanJSONInvalid,
+		},
+		{
+			name: "plan manifest with another runner count",
+			corrupt: func(t *testing.T) {
+				writeVerifyTestPlanJSON(t, [][]string{{"spec/a_spec.rb", "spec/b_spec.rb", "spec/c_spec.rb"}})
+			},
+			wantCode: errcode.VerifyPlanJSONInvalid,
+			wantText: "plans 1 runner",
+		},
+		{
+			name: "plan manifest with other runner splits",
+			corrupt: func(t *testing.T) {
+				writeVerifyTestPlanJSON(t, [][]string{{"spec/a_spec.rb"}, {"spec/b_spec.rb", "spec/c_spec.rb"}})
+			},
+			wantCode: errcode.VerifyPlanJSONInvalid,
+			wantText: "does not match runner-0",
+		},
+		{
+			name:     "test file missing on disk",
+			corrupt:  func(t *testing.T) { removePlanTestFile(t, "spec/c_spec.rb") },
+			wantCode: errcode.VerifyPlanTestFileMissing,
+			wantText: "spec/c_spec.rb",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeVerifyTestPlan(t)
+			tt.corrupt(t)
+
+			err := VerifyPlan(&strings.Builder{})
+			if errcode.CodeOf(err) != tt.wantCode {
+				t.Fatalf("VerifyPlan() error = %v, want %s", err, tt.wantCode)
+			}
+			if !strings.Contains(err.Error(), tt.wantText) {
+				t.Fatalf("VerifyPlan() error = %v, want it to contain %q", err, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestFormatTestFileSample_TruncatesLongLists(t *testing.T) {
+	got := formatTestFileSample([]string{"g", "f", "e", "d", "c", "b", "a"})
+	if got != "a, b, c, d, e and 2 more" {
+		t.Fatalf("formatTestFileSample() = %q", got)
+	}
+}
+
+func removePlanTestFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,233 @@
+package planner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/environment"
+	"github.com/DataDog/ddtest/internal/errcode"
+	"github.com/DataDog/ddtest/internal/ext"
+	"github.com/DataDog/ddtest/internal/settings"
+)
+
+// workspaceConfig is the content of the workspace file: the packages of a
+// monorepo, each planned on its own in its directory.
+type workspaceConfig struct {
+	Packages []workspacePackage `json:"packages"`
+}
+
+// workspacePackage is a package of a workspace. Its settings override those
+// of the workspace plan, and the settings it does not set are inherited.
+type workspacePackage struct {
+	// Name identifies the package in the CI matrix; it defaults to Dir.
+	Name string `json:"name"`
+	// Dir is the package directory, relative to the workspace plan's working
+	// directory. The package plan is written to its .testoptimization/.
+	Dir string `json:"dir"`
+	// Service is the Datadog service name of the package, used for the
+	// package's Test Optimization requests.
+	Service       string            `json:"service"`
+	Platform      string            `json:"platform"`
+	Framework     string            `json:"framework"`
+	TestsLocation string            `json:"testsLocation"`
+	Env           map[string]string `json:"env"`
+}
+
+// workspacePlanner plans the packages of a workspace one after the other,
+// each with its own ddtest plan process started in the package directory.
+type workspacePlanner struct {
+	executor           ext.CommandExecutor
+	executable         string
+	args               []string
+	ciProviderDetector environment.CIProviderDetector
+	reportWriter       io.Writer
+}
+
+// PlanWorkspace plans every package of the workspace file at path, passing
+// args, the ddtest plan flags of the workspace plan, to each package plan,
+// and writes the combined CI matrix of the packages.
+func PlanWorkspace(ctx context.Context, path string, args []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return errcode.WithCode(errcode.PlanWorkspacePackageFailed, fmt.Errorf("failed to find the ddtest executable: %w", err))
+	}
+	wp := workspacePlanner{
+		executor:           &ext.DefaultCommandExecutor{},
+		executable:         executable,
+		args:               args,
+		ciProviderDetector: environment.NewCIProviderDetector(settings.GetPipelineTemplate()),
+		reportWriter:       os.Stderr,
+	}
+	return wp.plan(ctx, path)
+}
+
+func loadWorkspaceConfig(path string) (workspaceConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return workspaceConfig{}, fmt.Errorf("failed to read workspace file %s: %w", path, err)
+	}
+
+	var config workspaceConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return workspaceConfig{}, fmt.Errorf("failed to parse workspace file %s: %w", path, err)
+	}
+	if len(config.Packages) == 0 {
+		return workspaceConfig{}, fmt.Errorf("workspace file %s lists no packages", path)
+	}
+
+	names := make(map[string]bool)
+	dirs := make(map[string]bool)
+	for i := range config.Packages {
+		pkg := &config.Packages[i]
+		if strings.TrimSpace(pkg.Dir) == "" {
+			return workspaceConfig{}, fmt.Errorf("workspace file %s: package %d has no dir", path, i+1)
+		}
+		pkg.Dir = filepath.ToSlash(filepath.Clean(pkg.Dir))
+		if pkg.Name == "" {
+			pkg.Name = pkg.Dir
+		}
+		if names[pkg.Name] {
+			return workspaceConfig{}, fmt.Errorf("workspace file %s: duplicate package name %q", path, pkg.Name)
+		}
+		if dirs[pkg.Dir] {
+			return workspaceConfig{}, fmt.Errorf("workspace file %s: duplicate package dir %q", path, pkg.Dir)
+		}
+		names[pkg.Name] = true
+		dirs[pkg.Dir] = true
+	}
+	return config, nil
+}
+
+func (wp workspacePlanner) plan(ctx context.Context, path string) error {
+	config, err := loadWorkspaceConfig(path)
+	if err != nil {
+		return errcode.WithCode(errcode.PlanWorkspaceInvalid, err)
+	}
+
+	var jobs []environment.WorkspaceMatrixEntry
+	for _, pkg := range config.Packages {
+		slog.Info("Planning workspace package", "package", pkg.Name, "dir", pkg.Dir, "service", pkg.Service)
+		if err := wp.planPackage(ctx, pkg); err != nil {
+			return errcode.WithCode(errcode.PlanWorkspacePackageFailed, fmt.Errorf("failed to plan workspace package %s: %w", pkg.Name, err))
+		}
+		packageJobs, err := workspacePackageJobs(pkg)
+		if err != nil {
+			return errcode.WithCode(errcode.PlanWorkspacePackageFailed, err)
+		}
+		jobs = append(jobs, packageJobs...)
+	}
+
+	if err := writeWorkspaceMatrix(jobs); err != nil {
+		return errcode.WithCode(errcode.PlanWorkspaceMatrixWriteFailed, err)
+	}
+	if ciProvider, err := wp.ciProviderDetector.DetectCIProvider(); err == nil {
+		if workspaceProvider, ok := ciProvider.(environment.WorkspaceCIProvider); ok {
+			if err := workspaceProvider.ConfigureWorkspace(jobs); err != nil {
+				slog.Warn("Failed to configure CI provider", "provider", ciProvider.Name(), "error", err)
+			}
+		}
+	}
+
+	if settings.GetReportEnabled() {
+		printWorkspacePlanReport(wp.reportWriter, config, jobs)
+	}
+	return nil
+}
+
+// planPackage runs ddtest plan in the package directory. The package settings
+// are passed as flags after the workspace plan flags, so that they win.
+func (wp workspacePlanner) planPackage(ctx context.Context, pkg workspacePackage) error {
+	args := append([]string{"plan"}, wp.args...)
+	if pkg.Platform != "" {
+		args = append(args, "--platform", pkg.Platform)
+	}
+	if pkg.Framework != "" {
+		args = append(args, "--framework", pkg.Framework)
+	}
+	if pkg.TestsLocation != "" {
+		args = append(args, "--tests-location", pkg.TestsLocation)
+	}
+
+	env := make(map[string]string, len(pkg.Env)+2)
+	for key, value := range pkg.Env {
+		env[key] = value
+	}
+	if pkg.Service != "" {
+		env["DD_SERVICE"] = pkg.Service
+	}
+	env[constants.WorkspacePackageEnvVar] = pkg.Name
+
+	return wp.executor.Run(ext.WithDir(ctx, filepath.FromSlash(pkg.Dir)), wp.executable, args, env)
+}
+
+// workspacePackageJobs returns the CI jobs of the plan of a package, one per
+// CI node.
+func workspacePackageJobs(pkg workspacePackage) ([]environment.WorkspaceMatrixEntry, error) {
+	planDir := filepath.Join(filepath.FromSlash(pkg.Dir), constants.PlanDirectory)
+	data, err := os.ReadFile(planDirPath(planDir, constants.ParallelRunnersOutputPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parallel runners count of workspace package %s: %w", pkg.Name, err)
+	}
+	parallelRunners, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || parallelRunners < 1 {
+		return nil, fmt.Errorf("invalid parallel runners count %q of workspace package %s", strings.TrimSpace(string(data)), pkg.Name)
+	}
+
+	ciNodeWorkers, err := readPlanFileLines(planDirPath(planDir, constants.CINodeWorkersOutputPath))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read CI node worker counts of workspace package %s: %w", pkg.Name, err)
+	}
+
+	jobs := make([]environment.WorkspaceMatrixEntry, parallelRunners)
+	for i := range jobs {
+		jobs[i] = environment.WorkspaceMatrixEntry{
+			Package:     pkg.Name,
+			Dir:         pkg.Dir,
+			CINodeIndex: i,
+			CINodeTotal: parallelRunners,
+		}
+		if i < len(ciNodeWorkers) {
+			jobs[i].CINodeWorkers, _ = strconv.Atoi(ciNodeWorkers[i])
+		}
+	}
+	return jobs, nil
+}
+
+func writeWorkspaceMatrix(jobs []environment.WorkspaceMatrixEntry) error {
+	data, err := json.MarshalIndent(environment.WorkspaceMatrix{Include: jobs}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal workspace matrix: %w", err)
+	}
+	return writePlanFile(constants.WorkspaceMatrixPath, append(data, '\n'))
+}
+
+func printWorkspacePlanReport(w io.Writer, config workspaceConfig, jobs []environment.WorkspaceMatrixEntry) {
+	ciJobs := make(map[string]int)
+	for _, job := range jobs {
+		ciJobs[job.Package]++
+	}
+
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintf(w, "Workspace plan: %d packages, %d CI jobs\n", len(config.Packages), len(jobs))
+	for _, pkg := range config.Packages {
+		service := ""
+		if pkg.Service != "" {
+			service = ", service " + pkg.Service
+		}
+		_, _ = fmt.Fprintf(w, "  %s (%s%s): %d CI jobs\n", pkg.Name, pkg.Dir, service, ciJobs[pkg.Name])
+	}
+	_, _ = fmt.Fprintf(w, "CI matrix: %s\n", constants.WorkspaceMatrixPath)
+}
@@ -0,0 +1,240 @@
+package planner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/environment"
+	"github.com/DataDog/ddtest/internal/errcode"
+	"github.com/DataDog/ddtest/internal/testoptimization"
+)
+
+// workspaceCommandExecutor records the ddtest plan processes of a workspace
+// plan instead of starting them.
+type workspaceCommandExecutor struct {
+	runs []workspaceCommandRun
+	err  error
+}
+
+type workspaceCommandRun struct {
+	name string
+	args []string
+	env  map[string]string
+}
+
+func (e *workspaceCommandExecutor) CombinedOutput(ctx context.Context, name string, args []string, envMap map[string]string) ([]byte, error) {
+	return nil, errors.New("unexpected CombinedOutput call")
+}
+
+func (e *workspaceCommandExecutor) Run(ctx context.Context, name string, args []string, envMap map[string]string) error {
+	e.runs = append(e.runs, workspaceCommandRun{name: name, args: args, env: envMap})
+	return e.err
+}
+
+// workspaceCIProvider is a CI provider that runs workspace matrices.
+type workspaceCIProvider struct {
+	MockCIProvider
+	jobs []environment.WorkspaceMatrixEntry
+}
+
+func (p *workspaceCIProvider) ConfigureWorkspace(jobs []environment.WorkspaceMatrixEntry) error {
+	p.jobs = jobs
+	return nil
+}
+
+func writeWorkspaceFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ddtest-workspace.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write workspace file: %v", err)
+	}
+	return path
+}
+
+func writeWorkspacePackagePlan(t *testing.T, dir string, parallelRunners string, ciNodeWorkers string) {
+	t.Helper()
+	planDir := filepath.Join(dir, constants.PlanDirectory)
+	if err := writePlanFile(planDirPath(planDir, constants.ParallelRunnersOutputPath), []byte(parallelRunners)); err != nil {
+		t.Fatal(err)
+	}
+	if ciNodeWorkers != "" {
+		if err := writePlanFile(planDirPath(planDir, constants.CINodeWorkersOutputPath), []byte(ciNodeWorkers)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadWorkspaceConfig(t *testing.T) {
+	config, err := loadWorkspaceConfig(writeWorkspaceFile(t, `{"packages": [
+		{"name": "api", "dir": "./services/api/", "service": "api", "framework": "rspec"},
+		{"dir": "apps/web"}
+	]}`))
+	if err != nil {
+		t.Fatalf("loadWorkspaceConfig() returned error: %v", err)
+	}
+	if len(config.Packages) != 2 || config.Packages[0].Dir != "services/api" || config.Packages[1].Name != "apps/web" {
+		t.Fatalf("unexpected workspace packages %+v", config.Packages)
+	}
+
+	for name, content := range map[string]string{
+		"invalid JSON":      `{"packages": [`,
+		"unknown field":     `{"packages": [{"dir": "api", "command": "bin/rspec"}]}`,
+		"no packages":       `{"packages": []}`,
+		"no dir":            `{"packages": [{"name": "api"}]}`,
+		"duplicate name":    `{"packages": [{"name": "api", "dir": "a"}, {"name": "api", "dir": "b"}]}`,
+		"duplicate dir":     `{"packages": [{"name": "a", "dir": "api"}, {"name": "b", "dir": "./api"}]}`,
+		"missing workspace": "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "missing.json")
+			if content != "" {
+				path = writeWorkspaceFile(t, content)
+			}
+			if _, err := loadWorkspaceConfig(path); err == nil {
+				t.Fatal("expected an invalid workspace to be rejected")
+			}
+		})
+	}
+}
+
+func TestWorkspacePlanner_Plan(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeWorkspacePackagePlan(t, filepath.Join("services", "api"), "2", "")
+	writeWorkspacePackagePlan(t, filepath.Join("apps", "web"), "1\n", "4\n")
+	workspace := writeWorkspaceFile(t, `{"packages": [
+		{"name": "api", "dir": "services/api", "service": "api", "platform": "ruby", "framework": "rspec,minitest"},
+		{"name": "web", "dir": "apps/web", "service": "web", "framework": "jest", "testsLocation": "src/**/*.test.ts", "env": {"NODE_ENV": "test"}}
+	]}`)
+
+	executor := &workspaceCommandExecutor{}
+	ciProvider := &workspaceCIProvider{MockCIProvider: MockCIProvider{ProviderName: "github"}}
+	var report strings.Builder
+	wp := workspacePlanner{
+		executor:           executor,
+		executable:         "ddtest",
+		args:               []string{"--max-parallelism=4"},
+		ciProviderDetector: &MockCIProviderDetector{CIProvider: ciProvider},
+		reportWriter:       &report,
+	}
+	if err := wp.plan(context.Background(), workspace); err != nil {
+		t.Fatalf("plan() returned error: %v", err)
+	}
+
+	if len(executor.runs) != 2 {
+		t.Fatalf("expected one ddtest plan process per package, got %+v", executor.runs)
+	}
+	api, web := executor.runs[0], executor.runs[1]
+	if !slices.Equal(api.args, []string{"plan", "--max-parallelism=4", "--platform", "ruby", "--framework", "rspec,minitest"}) {
+		t.Errorf("unexpected api plan args %v", api.args)
+	}
+	if api.env["DD_SERVICE"] != "api" || api.env[constants.WorkspacePackageEnvVar] != "api" {
+		t.Errorf("unexpected api plan env %v", api.env)
+	}
+	if !slices.Equal(web.args, []string{"plan", "--max-parallelism=4", "--framework", "jest", "--tests-location", "src/**/*.test.ts"}) {
+		t.Errorf("unexpected web plan args %v", web.args)
+	}
+	if web.env["DD_SERVICE"] != "web" || web.env["NODE_ENV"] != "test" {
+		t.Errorf("unexpected web plan env %v", web.env)
+	}
+
+	expectedJobs := []environment.WorkspaceMatrixEntry{
+		{Package: "api", Dir: "services/api", CINodeIndex: 0, CINodeTotal: 2},
+		{Package: "api", Dir: "services/api", CINodeIndex: 1, CINodeTotal: 2},
+		{Package: "web", Dir: "apps/web", CINodeIndex: 0, CINodeTotal: 1, CINodeWorkers: 4},
+	}
+	if !slices.Equal(ciProvider.jobs, expectedJobs) {
+		t.Errorf("unexpected workspace CI jobs %+v", ciProvider.jobs)
+	}
+	if ciProvider.ConfigureCalled {
+		t.Error("expected the workspace plan to configure the CI provider with the workspace matrix only")
+	}
+	assertFileContent(t, constants.WorkspaceMatrixPath, `{
+  "include": [
+    {
+      "package": "api",
+      "dir": "services/api",
+      "ci_node_index": 0,
+      "ci_node_total": 2
+    },
+    {
+      "package": "api",
+      "dir": "services/api",
+      "ci_node_index": 1,
+      "ci_node_total": 2
+    },
+    {
+      "package": "web",
+      "dir": "apps/web",
+      "ci_node_index": 0,
+      "ci_node_total": 1,
+      "ci_node_workers": 4
+    }
+  ]
+}
+`)
+
+	for _, want := range []string{
+		"Workspace plan: 2 packages, 3 CI jobs",
+		"  api (services/api, service api): 2 CI jobs",
+		"  web (apps/web, service web): 1 CI jobs",
+	} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, report.String())
+		}
+	}
+}
+
+func TestWorkspacePlanner_PlanFailures(t *testing.T) {
+	t.Chdir(t.TempDir())
+	workspace := writeWorkspaceFile(t, `{"packages": [{"name": "api", "dir": "services/api"}]}`)
+	newPlanner := func(executor *workspaceCommandExecutor) workspacePlanner {
+		return workspacePlanner{executor: executor, executable: "ddtest", ciProviderDetector: newDefaultMockCIProviderDetector(), reportWriter: &strings.Builder{}}
+	}
+
+	err := newPlanner(&workspaceCommandExecutor{}).plan(context.Background(), filepath.Join(t.TempDir(), "missing.json"))
+	if code := errcode.CodeOf(err); code != errcode.PlanWorkspaceInvalid {
+		t.Errorf("expected %s for a missing workspace file, got %v", errcode.PlanWorkspaceInvalid, err)
+	}
+
+	err = newPlanner(&workspaceCommandExecutor{err: errors.New("exit status 1")}).plan(context.Background(), workspace)
+	if code := errcode.CodeOf(err); code != errcode.PlanWorkspacePackageFailed || !strings.Contains(err.Error(), "workspace package api") {
+		t.Errorf("expected %s for a failed package plan, got %v", errcode.PlanWorkspacePackageFailed, err)
+	}
+
+	err = newPlanner(&workspaceCommandExecutor{}).plan(context.Background(), workspace)
+	if code := errcode.CodeOf(err); code != errcode.PlanWorkspacePackageFailed {
+		t.Errorf("expected %s for a package without a plan, got %v", errcode.PlanWorkspacePackageFailed, err)
+	}
+}
+
+func TestTestPlanner_Plan_WorkspacePackageLeavesCIProviderToWorkspace(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(constants.WorkspacePackageEnvVar, "api")
+
+	mockPlatform := &MockPlatform{
+		PlatformName: "ruby",
+		Tags:         map[string]string{"platform": "ruby"},
+		Framework: &MockFramework{
+			FrameworkName: "rspec",
+			Tests:         []testoptimization.Test{{Suite: "TestSuite1", Name: "test1", SuiteSourceFile: "test/file1_test.rb"}},
+		},
+	}
+	mockCIProvider := &MockCIProvider{ProviderName: "github"}
+	runner := NewWithDependencies(
+		&MockPlatformDetector{Platform: mockPlatform},
+		&MockTestOptimizationClient{Settings: testOptimizationSettings(true, true, false)},
+		&MockCIProviderDetector{CIProvider: mockCIProvider},
+	)
+	if err := runner.Plan(context.Background()); err != nil {
+		t.Fatalf("Plan() should not return error, got: %v", err)
+	}
+	if mockCIProvider.ConfigureCalled {
+		t.Error("expected a workspace package plan not to configure the CI provider")
+	}
+}
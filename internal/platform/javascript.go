@@ -122,7 +122,10 @@ func (j *JavaScript) CreateTagsMap() (map[string]string, error) {
 }
 
 func (j *JavaScript) DetectFramework() (framework.Framework, error) {
-	frameworkName := settings.GetFramework()
+	return detectFrameworks(j.Name(), j.newFramework)
+}
+
+func (j *JavaScript) newFramework(frameworkName string) (framework.Framework, error) {
 	platformEnv := j.GetPlatformEnv()
 
 	var fw framework.Framework
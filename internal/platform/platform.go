@@ -1,6 +1,7 @@
 package platform
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/DataDog/ddtest/internal/framework"
@@ -51,3 +52,52 @@ func DetectPlatform() (Platform, error) {
 func NewPlatformDetector() PlatformDetector {
 	return &DatadogPlatformDetector{}
 }
+
+// ErrFrameworksOfSeveralPlatforms is returned when the framework setting lists
+// frameworks of another platform than the platform setting. Frameworks planned
+// together must belong to one platform, whose environment they all run with.
+var ErrFrameworksOfSeveralPlatforms = errors.New("frameworks planned together must belong to one platform")
+
+// frameworkPlatforms maps each supported framework to its platform.
+var frameworkPlatforms = map[string]string{
+	"rspec":      "ruby",
+	"minitest":   "ruby",
+	"pytest":     "python",
+	"cucumber":   "javascript",
+	"cypress":    "javascript",
+	"jest":       "javascript",
+	"mocha":      "javascript",
+	"playwright": "javascript",
+	"vitest":     "javascript",
+}
+
+// detectFrameworks creates the frameworks listed in the framework setting with
+// newFramework. Several frameworks are planned and run together as one
+// framework.Multi, and must all belong to platformName.
+func detectFrameworks(platformName string, newFramework func(frameworkName string) (framework.Framework, error)) (framework.Framework, error) {
+	entries := settings.GetFrameworks()
+	if len(entries) < 2 {
+		return newFramework(settings.GetFramework())
+	}
+
+	for _, entry := range entries {
+		if entryPlatform, ok := frameworkPlatforms[entry.Name]; ok && entryPlatform != platformName {
+			return nil, fmt.Errorf("%w: framework '%s' belongs to platform '%s', not '%s'; plan each platform in its own workspace package",
+				ErrFrameworksOfSeveralPlatforms, entry.Name, entryPlatform, platformName)
+		}
+	}
+
+	members := make([]framework.MultiMember, 0, len(entries))
+	for _, entry := range entries {
+		fw, err := newFramework(entry.Name)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, framework.MultiMember{Framework: fw, TestPattern: entry.TestsLocation})
+	}
+	multi, err := framework.NewMulti(members)
+	if err != nil {
+		return nil, err
+	}
+	return multi, nil
+}
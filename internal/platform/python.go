@@ -110,7 +110,10 @@ func (p *Python) CreateTagsMap() (map[string]string, error) {
 }
 
 func (p *Python) DetectFramework() (framework.Framework, error) {
-	frameworkName := settings.GetFramework()
+	return detectFrameworks(p.Name(), p.newFramework)
+}
+
+func (p *Python) newFramework(frameworkName string) (framework.Framework, error) {
 	platformEnv := p.GetPlatformEnv()
 
 	var fw framework.Framework
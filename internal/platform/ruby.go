@@ -101,7 +101,10 @@ func (r *Ruby) CreateTagsMap() (map[string]string, error) {
 }
 
 func (r *Ruby) DetectFramework() (framework.Framework, error) {
-	frameworkName := settings.GetFramework()
+	return detectFrameworks(r.Name(), r.newFramework)
+}
+
+func (r *Ruby) newFramework(frameworkName string) (framework.Framework, error) {
 	platformEnv := r.GetPlatformEnv()
 
 	var fw framework.Framework
@@ -3,12 +3,14 @@ package platform
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"os/exec"
 	"strings"
 	"testing"
 
 	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/framework"
 	"github.com/DataDog/ddtest/internal/settings"
 	"github.com/spf13/viper"
 )
@@ -213,6 +215,67 @@ func TestRuby_DetectFramework_Minitest(t *testing.T) {
 	}
 }
 
+func TestRuby_DetectFramework_Multiple(t *testing.T) {
+	viper.Reset()
+	viper.Set("framework", "rspec,minitest=test/**/*_test.rb")
+	settings.Init()
+	defer func() {
+		viper.Reset()
+		settings.Init()
+	}()
+
+	fw, err := newTestRuby().DetectFramework()
+	if err != nil {
+		t.Fatalf("DetectFramework failed: %v", err)
+	}
+
+	multi, ok := fw.(*framework.Multi)
+	if !ok {
+		t.Fatalf("expected several frameworks to be combined, got %T", fw)
+	}
+	if fw.Name() != "rspec,minitest" || len(multi.Frameworks()) != 2 {
+		t.Errorf("expected rspec and minitest, got %q", fw.Name())
+	}
+	if fw.TestPattern() != "{spec/**/*_spec.rb,test/**/*_test.rb}" {
+		t.Errorf("unexpected test pattern %q", fw.TestPattern())
+	}
+	for _, member := range multi.Frameworks() {
+		if member.GetPlatformEnv() == nil {
+			t.Errorf("expected %s to have the platform env", member.Name())
+		}
+	}
+}
+
+func TestRuby_DetectFramework_MultipleUnsupported(t *testing.T) {
+	viper.Reset()
+	viper.Set("framework", "rspec,jest")
+	settings.Init()
+	defer func() {
+		viper.Reset()
+		settings.Init()
+	}()
+
+	_, err := newTestRuby().DetectFramework()
+	if !errors.Is(err, ErrFrameworksOfSeveralPlatforms) || !strings.Contains(err.Error(), "framework 'jest' belongs to platform 'javascript', not 'ruby'") {
+		t.Fatalf("expected jest to be rejected as a javascript framework, got %v", err)
+	}
+}
+
+func TestRuby_DetectFramework_MultipleUnknown(t *testing.T) {
+	viper.Reset()
+	viper.Set("framework", "rspec,unknown")
+	settings.Init()
+	defer func() {
+		viper.Reset()
+		settings.Init()
+	}()
+
+	_, err := newTestRuby().DetectFramework()
+	if err == nil || errors.Is(err, ErrFrameworksOfSeveralPlatforms) || err.Error() != "framework 'unknown' is not supported by platform 'ruby'" {
+		t.Fatalf("expected unknown to be unsupported by ruby, got %v", err)
+	}
+}
+
 func TestRuby_DetectFramework_Unsupported(t *testing.T) {
 	viper.Reset()
 	viper.Set("framework", "cucumber")
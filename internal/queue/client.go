@@ -0,0 +1,135 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	clientTimeout     = 30 * time.Second
+	clientAttempts    = 3
+	clientRetryPeriod = time.Second
+)
+
+// Client talks to a queue served by `ddtest serve`.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	retryDelay time.Duration
+}
+
+// NewClient creates a client for the queue at rawURL, such as
+// "http://10.0.0.5:7878".
+func NewClient(rawURL string) (*Client, error) {
+	baseURL, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid queue URL %q: %w", rawURL, err)
+	}
+	if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
+		return nil, fmt.Errorf("invalid queue URL %q: scheme must be http or https", rawURL)
+	}
+	if baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid queue URL %q: host is required", rawURL)
+	}
+
+	return &Client{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: clientTimeout},
+		retryDelay: clientRetryPeriod,
+	}, nil
+}
+
+// Lease requests the next batch for a worker on node.
+func (c *Client) Lease(ctx context.Context, node int, worker int) (Lease, error) {
+	var lease Lease
+	if err := c.post(ctx, leasePath, leaseRequest{Node: node, Worker: worker}, &lease); err != nil {
+		return Lease{}, err
+	}
+	return lease, nil
+}
+
+// Heartbeat extends a lease. It returns ErrLeaseNotFound if the lease already
+// expired.
+func (c *Client) Heartbeat(ctx context.Context, leaseID string) error {
+	return c.post(ctx, heartbeatPath, heartbeatRequest{LeaseID: leaseID}, nil)
+}
+
+// Ack completes a lease. It returns ErrLeaseNotFound if the lease already
+// expired.
+func (c *Client) Ack(ctx context.Context, leaseID string, failed bool) error {
+	return c.post(ctx, ackPath, ackRequest{LeaseID: leaseID, Failed: failed}, nil)
+}
+
+// URL returns the queue base URL.
+func (c *Client) URL() string {
+	return c.baseURL.String()
+}
+
+func (c *Client) post(ctx context.Context, path string, request any, response any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode queue request: %w", err)
+	}
+	endpoint := c.baseURL.JoinPath(path).String()
+
+	var lastErr error
+	for attempt := range clientAttempts {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.retryDelay):
+			}
+		}
+
+		retry, err := c.postOnce(ctx, endpoint, body, response)
+		if err == nil || !retry {
+			return err
+		}
+		lastErr = err
+	}
+	return lastErr
+}
+
+func (c *Client) postOnce(ctx context.Context, endpoint string, body []byte, response any) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create queue request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("queue request to %s failed: %w", endpoint, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusGone:
+		return false, ErrLeaseNotFound
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return true, fmt.Errorf("queue request to %s failed: %s: %s", endpoint, resp.Status, readErrorBody(resp.Body))
+	case resp.StatusCode >= http.StatusBadRequest:
+		return false, fmt.Errorf("queue request to %s failed: %s: %s", endpoint, resp.Status, readErrorBody(resp.Body))
+	}
+
+	if response == nil {
+		return false, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return false, fmt.Errorf("failed to decode queue response from %s: %w", endpoint, err)
+	}
+	return false, nil
+}
+
+func readErrorBody(body io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(body, 1024))
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,304 @@
+// Package queue coordinates test file batches between CI nodes. A single
+// `ddtest serve` process owns the queue and CI nodes lease batches from it
+// over HTTP, keep them alive with heartbeats, and acknowledge them when done.
+package queue
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrLeaseNotFound is returned for heartbeats and acknowledgements of leases
+// that the queue no longer tracks, usually because they expired and their
+// test files were handed to another node.
+var ErrLeaseNotFound = errors.New("lease not found")
+
+// Lease is the server's answer to a lease request. A lease with an ID carries
+// test files to run. A lease without an ID asks the node to retry after
+// RetryAfter, unless Done reports that every batch has been acknowledged.
+type Lease struct {
+	ID                string        `json:"id,omitempty"`
+	TestFiles         []string      `json:"test_files,omitempty"`
+	HeartbeatInterval time.Duration `json:"heartbeat_interval,omitempty"`
+	RetryAfter        time.Duration `json:"retry_after,omitempty"`
+	Done              bool          `json:"done"`
+}
+
+// Status summarizes queue progress.
+type Status struct {
+	TotalBatches     int `json:"total_batches"`
+	PendingBatches   int `json:"pending_batches"`
+	LeasedBatches    int `json:"leased_batches"`
+	CompletedBatches int `json:"completed_batches"`
+	FailedBatches    int `json:"failed_batches"`
+	ExpiredLeases    int `json:"expired_leases"`
+}
+
+type activeLease struct {
+	testFiles []string
+	node      int
+	worker    int
+	expiresAt time.Time
+}
+
+// Queue hands out batches of test files to CI nodes. Leases that are not
+// renewed within the lease timeout are returned to the front of the queue.
+type Queue struct {
+	mu           sync.Mutex
+	pending      [][]string
+	leases       map[string]*activeLease
+	leaseTimeout time.Duration
+	now          func() time.Time
+	nextLeaseID  int
+	status       Status
+	done         chan struct{}
+}
+
+func New(batches [][]string, leaseTimeout time.Duration) *Queue {
+	return newQueueWithClock(batches, leaseTimeout, time.Now)
+}
+
+func newQueueWithClock(batches [][]string, leaseTimeout time.Duration, now func() time.Time) *Queue {
+	pending := make([][]string, 0, len(batches))
+	for _, batch := range batches {
+		if len(batch) > 0 {
+			pending = append(pending, slices.Clone(batch))
+		}
+	}
+
+	q := &Queue{
+		pending:      pending,
+		leases:       make(map[string]*activeLease),
+		leaseTimeout: leaseTimeout,
+		now:          now,
+		status:       Status{TotalBatches: len(pending)},
+		done:         make(chan struct{}),
+	}
+	if len(pending) == 0 {
+		close(q.done)
+	}
+	return q
+}
+
+// Batches groups test files into batches of batchSize, longest estimated
+// first, so the slowest files start as early as possible.
+func Batches(testFileWeights map[string]int, batchSize int) [][]string {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	testFiles := OrderByWeight(testFileWeights)
+	batches := make([][]string, 0, (len(testFiles)+batchSize-1)/batchSize)
+	for batch := range slices.Chunk(testFiles, batchSize) {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// GroupedBatches is Batches for units of test files that must share a batch,
+// such as the test files of a group. Each unit counts as one test file that
+// weighs as much as its test files together.
+func GroupedBatches(units [][]string, testFileWeights map[string]int, batchSize int) [][]string {
+	unitWeights := make(map[string]int, len(units))
+	unitsByFirstFile := make(map[string][]string, len(units))
+	for _, unit := range units {
+		if len(unit) == 0 {
+			continue
+		}
+		for _, testFile := range unit {
+			unitWeights[unit[0]] += testFileWeights[testFile]
+		}
+		unitsByFirstFile[unit[0]] = unit
+	}
+
+	unitBatches := Batches(unitWeights, batchSize)
+	batches := make([][]string, 0, len(unitBatches))
+	for _, unitBatch := range unitBatches {
+		var batch []string
+		for _, firstFile := range unitBatch {
+			batch = append(batch, unitsByFirstFile[firstFile]...)
+		}
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// OrderedBatches is GroupedBatches for units that are already in the order
+// they should run in, such as the entries of a runner split ordered by split
+// order. The batches keep that order instead of putting the longest estimated
+// units first.
+func OrderedBatches(units [][]string, batchSize int) [][]string {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	units = slices.DeleteFunc(slices.Clone(units), func(unit []string) bool { return len(unit) == 0 })
+	batches := make([][]string, 0, (len(units)+batchSize-1)/batchSize)
+	for unitBatch := range slices.Chunk(units, batchSize) {
+		batches = append(batches, slices.Concat(unitBatch...))
+	}
+	return batches
+}
+
+// OrderByWeight returns test files sorted by descending weight, then by path.
+func OrderByWeight(testFileWeights map[string]int) []string {
+	testFiles := make([]string, 0, len(testFileWeights))
+	for testFile := range testFileWeights {
+		testFiles = append(testFiles, testFile)
+	}
+	slices.SortFunc(testFiles, func(a, b string) int {
+		if testFileWeights[a] != testFileWeights[b] {
+			if testFileWeights[a] > testFileWeights[b] {
+				return -1
+			}
+			return 1
+		}
+		if a < b {
+			return -1
+		}
+		if a > b {
+			return 1
+		}
+		return 0
+	})
+	return testFiles
+}
+
+// Lease hands the next pending batch to a worker on node.
+func (q *Queue) Lease(node int, worker int) Lease {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.requeueExpiredLocked(now)
+
+	if len(q.pending) == 0 {
+		if len(q.leases) == 0 {
+			return Lease{Done: true}
+		}
+		// Batches are still leased by other nodes. Keep this node around in
+		// case one of those leases expires and its files come back.
+		return Lease{RetryAfter: q.retryAfter()}
+	}
+
+	testFiles := q.pending[0]
+	q.pending = q.pending[1:]
+	q.nextLeaseID++
+	id := strconv.Itoa(q.nextLeaseID)
+	q.leases[id] = &activeLease{
+		testFiles: testFiles,
+		node:      node,
+		worker:    worker,
+		expiresAt: now.Add(q.leaseTimeout),
+	}
+
+	slog.Debug("Leased test files", "leaseID", id, "node", node, "worker", worker, "testFiles", testFiles)
+	return Lease{
+		ID:                id,
+		TestFiles:         slices.Clone(testFiles),
+		HeartbeatInterval: q.heartbeatInterval(),
+	}
+}
+
+// Heartbeat extends a lease by the lease timeout.
+func (q *Queue) Heartbeat(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.requeueExpiredLocked(now)
+
+	lease, ok := q.leases[id]
+	if !ok {
+		return ErrLeaseNotFound
+	}
+	lease.expiresAt = now.Add(q.leaseTimeout)
+	return nil
+}
+
+// Ack completes a lease. Failed batches are not retried; the node that ran
+// them reports the failure.
+func (q *Queue) Ack(id string, failed bool) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.requeueExpiredLocked(q.now())
+
+	lease, ok := q.leases[id]
+	if !ok {
+		return ErrLeaseNotFound
+	}
+	delete(q.leases, id)
+	q.status.CompletedBatches++
+	if failed {
+		q.status.FailedBatches++
+	}
+	slog.Debug("Acknowledged test files", "leaseID", id, "node", lease.node, "worker", lease.worker, "failed", failed)
+
+	if len(q.pending) == 0 && len(q.leases) == 0 {
+		close(q.done)
+	}
+	return nil
+}
+
+// Status returns a snapshot of queue progress.
+func (q *Queue) Status() Status {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.requeueExpiredLocked(q.now())
+
+	status := q.status
+	status.PendingBatches = len(q.pending)
+	status.LeasedBatches = len(q.leases)
+	return status
+}
+
+// Done is closed once every batch has been acknowledged.
+func (q *Queue) Done() <-chan struct{} {
+	return q.done
+}
+
+// ExpireLeases returns expired leases to the queue. The queue also does this
+// on every request, so callers only need it to make progress visible while
+// nodes are idle.
+func (q *Queue) ExpireLeases() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.requeueExpiredLocked(q.now())
+}
+
+func (q *Queue) requeueExpiredLocked(now time.Time) {
+	ids := make([]string, 0)
+	for id, lease := range q.leases {
+		if !now.Before(lease.expiresAt) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	requeued := make([][]string, 0, len(ids))
+	for _, id := range ids {
+		lease := q.leases[id]
+		delete(q.leases, id)
+		q.status.ExpiredLeases++
+		requeued = append(requeued, lease.testFiles)
+		slog.Warn("Lease expired, returning test files to the queue",
+			"leaseID", id, "node", lease.node, "worker", lease.worker, "testFilesCount", len(lease.testFiles))
+	}
+	if len(requeued) > 0 {
+		q.pending = append(requeued, q.pending...)
+	}
+}
+
+func (q *Queue) heartbeatInterval() time.Duration {
+	return max(q.leaseTimeout/3, time.Millisecond)
+}
+
+func (q *Queue) retryAfter() time.Duration {
+	return min(q.heartbeatInterval(), time.Second)
+}
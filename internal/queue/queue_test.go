@@ -0,0 +1,173 @@
+package queue
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestQueue(batches [][]string, leaseTimeout time.Duration) (*Queue, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	return newQueueWithClock(batches, leaseTimeout, clock.Now), clock
+}
+
+func TestBatches(t *testing.T) {
+	batches := Batches(map[string]int{
+		"spec/a_spec.rb": 100,
+		"spec/b_spec.rb": 500,
+		"spec/c_spec.rb": 100,
+		"spec/d_spec.rb": 10,
+		"spec/e_spec.rb": 1,
+	}, 2)
+
+	want := [][]string{
+		{"spec/b_spec.rb", "spec/a_spec.rb"},
+		{"spec/c_spec.rb", "spec/d_spec.rb"},
+		{"spec/e_spec.rb"},
+	}
+	if !slices.EqualFunc(batches, want, slices.Equal) {
+		t.Fatalf("Batches() = %v, want %v", batches, want)
+	}
+}
+
+func TestGroupedBatches(t *testing.T) {
+	weights := map[string]int{
+		"spec/a_spec.rb": 100,
+		"spec/b_spec.rb": 500,
+		"spec/c_spec.rb": 300,
+		"spec/d_spec.rb": 300,
+		"spec/e_spec.rb": 1,
+	}
+	units := [][]string{
+		{"spec/a_spec.rb"},
+		{"spec/b_spec.rb"},
+		{"spec/c_spec.rb", "spec/d_spec.rb"},
+		{"spec/e_spec.rb"},
+	}
+
+	batches := GroupedBatches(units, weights, 2)
+	want := [][]string{
+		{"spec/c_spec.rb", "spec/d_spec.rb", "spec/b_spec.rb"},
+		{"spec/a_spec.rb", "spec/e_spec.rb"},
+	}
+	if !slices.EqualFunc(batches, want, slices.Equal) {
+		t.Fatalf("GroupedBatches() = %v, want %v", batches, want)
+	}
+}
+
+func TestOrderedBatches(t *testing.T) {
+	units := [][]string{
+		{"spec/e_spec.rb"},
+		{"spec/c_spec.rb", "spec/d_spec.rb"},
+		{},
+		{"spec/a_spec.rb"},
+		{"spec/b_spec.rb"},
+	}
+
+	batches := OrderedBatches(units, 2)
+	want := [][]string{
+		{"spec/e_spec.rb", "spec/c_spec.rb", "spec/d_spec.rb"},
+		{"spec/a_spec.rb", "spec/b_spec.rb"},
+	}
+	if !slices.EqualFunc(batches, want, slices.Equal) {
+		t.Fatalf("OrderedBatches() = %v, want %v", batches, want)
+	}
+}
+
+func TestQueue_LeaseAndAck(t *testing.T) {
+	q, _ := newTestQueue([][]string{{"spec/a_spec.rb"}, {"spec/b_spec.rb"}}, time.Minute)
+
+	first := q.Lease(0, 0)
+	second := q.Lease(1, 0)
+	if first.ID == "" || !slices.Equal(first.TestFiles, []string{"spec/a_spec.rb"}) {
+		t.Fatalf("unexpected first lease: %+v", first)
+	}
+	if second.ID == "" || second.ID == first.ID || !slices.Equal(second.TestFiles, []string{"spec/b_spec.rb"}) {
+		t.Fatalf("unexpected second lease: %+v", second)
+	}
+	if first.HeartbeatInterval != 20*time.Second {
+		t.Fatalf("expected heartbeat interval to be a third of the lease timeout, got %s", first.HeartbeatInterval)
+	}
+
+	waiting := q.Lease(2, 0)
+	if waiting.ID != "" || waiting.Done || waiting.RetryAfter <= 0 {
+		t.Fatalf("expected node to wait while batches are leased, got %+v", waiting)
+	}
+
+	if err := q.Ack(first.ID, false); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	if err := q.Ack(second.ID, true); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+
+	select {
+	case <-q.Done():
+	default:
+		t.Fatal("expected queue to be done after every batch was acknowledged")
+	}
+	if lease := q.Lease(2, 0); !lease.Done {
+		t.Fatalf("expected done lease, got %+v", lease)
+	}
+
+	status := q.Status()
+	if status.TotalBatches != 2 || status.CompletedBatches != 2 || status.FailedBatches != 1 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+}
+
+func TestQueue_ExpiredLeaseIsHandedOutAgain(t *testing.T) {
+	q, clock := newTestQueue([][]string{{"spec/a_spec.rb"}, {"spec/b_spec.rb"}}, time.Minute)
+
+	dead := q.Lease(0, 0)
+	clock.Advance(30 * time.Second)
+	alive := q.Lease(1, 0)
+
+	clock.Advance(30 * time.Second)
+	if err := q.Heartbeat(alive.ID); err != nil {
+		t.Fatalf("Heartbeat() error = %v", err)
+	}
+
+	retried := q.Lease(1, 1)
+	if retried.ID == "" || !slices.Equal(retried.TestFiles, dead.TestFiles) {
+		t.Fatalf("expected expired lease files to be handed out again, got %+v", retried)
+	}
+	if err := q.Heartbeat(dead.ID); !errors.Is(err, ErrLeaseNotFound) {
+		t.Fatalf("Heartbeat() on expired lease error = %v, want ErrLeaseNotFound", err)
+	}
+	if err := q.Ack(dead.ID, false); !errors.Is(err, ErrLeaseNotFound) {
+		t.Fatalf("Ack() on expired lease error = %v, want ErrLeaseNotFound", err)
+	}
+
+	clock.Advance(59 * time.Second)
+	status := q.Status()
+	if status.LeasedBatches != 2 || status.ExpiredLeases != 1 {
+		t.Fatalf("expected heartbeat to keep lease alive, got %+v", status)
+	}
+}
+
+func TestQueue_EmptyQueueIsDone(t *testing.T) {
+	q, _ := newTestQueue(nil, time.Minute)
+
+	select {
+	case <-q.Done():
+	default:
+		t.Fatal("expected empty queue to be done")
+	}
+	if lease := q.Lease(0, 0); !lease.Done {
+		t.Fatalf("expected done lease, got %+v", lease)
+	}
+}
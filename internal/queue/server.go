@@ -0,0 +1,143 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	leasePath     = "/v1/lease"
+	heartbeatPath = "/v1/heartbeat"
+	ackPath       = "/v1/ack"
+	statusPath    = "/v1/status"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+type leaseRequest struct {
+	Node   int `json:"node"`
+	Worker int `json:"worker"`
+}
+
+type heartbeatRequest struct {
+	LeaseID string `json:"lease_id"`
+}
+
+type ackRequest struct {
+	LeaseID string `json:"lease_id"`
+	Failed  bool   `json:"failed"`
+}
+
+// NewHandler returns the HTTP API for q.
+func NewHandler(q *Queue) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+leasePath, func(w http.ResponseWriter, r *http.Request) {
+		var request leaseRequest
+		if !decodeRequest(w, r, &request) {
+			return
+		}
+		writeJSON(w, http.StatusOK, q.Lease(request.Node, request.Worker))
+	})
+	mux.HandleFunc("POST "+heartbeatPath, func(w http.ResponseWriter, r *http.Request) {
+		var request heartbeatRequest
+		if !decodeRequest(w, r, &request) {
+			return
+		}
+		writeLeaseResult(w, q.Heartbeat(request.LeaseID))
+	})
+	mux.HandleFunc("POST "+ackPath, func(w http.ResponseWriter, r *http.Request) {
+		var request ackRequest
+		if !decodeRequest(w, r, &request) {
+			return
+		}
+		writeLeaseResult(w, q.Ack(request.LeaseID, request.Failed))
+	})
+	mux.HandleFunc("GET "+statusPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, q.Status())
+	})
+	return mux
+}
+
+// Serve serves q on listener until every batch is acknowledged or ctx is
+// cancelled. After the queue drains, it keeps answering for drainPeriod so
+// nodes waiting on other leases learn that the queue is done.
+func Serve(ctx context.Context, listener net.Listener, q *Queue, drainPeriod time.Duration) error {
+	server := &http.Server{
+		Handler:           NewHandler(q),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	slog.Info("Serving test queue", "address", listener.Addr().String(), "batches", q.Status().TotalBatches)
+
+	expireTicker := time.NewTicker(q.heartbeatInterval())
+	defer expireTicker.Stop()
+
+	var drainTimer <-chan time.Time
+	done := q.Done()
+	for {
+		select {
+		case err := <-serveErr:
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return fmt.Errorf("test queue server failed: %w", err)
+		case <-ctx.Done():
+			slog.Info("Stopping test queue", "reason", context.Cause(ctx))
+			return shutdown(server, ctx.Err())
+		case <-expireTicker.C:
+			q.ExpireLeases()
+		case <-done:
+			done = nil
+			slog.Info("All test queue batches acknowledged", "drainPeriod", drainPeriod)
+			drainTimer = time.After(drainPeriod)
+		case <-drainTimer:
+			return shutdown(server, nil)
+		}
+	}
+}
+
+func shutdown(server *http.Server, cause error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to stop test queue server: %w", err)
+	}
+	return cause
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, request any) bool {
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeLeaseResult(w http.ResponseWriter, err error) {
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, ErrLeaseNotFound):
+		http.Error(w, err.Error(), http.StatusGone)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		slog.Debug("Failed to write test queue response", "error", err)
+	}
+}
@@ -0,0 +1,171 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientAndHandler(t *testing.T) {
+	q := New([][]string{{"spec/a_spec.rb", "spec/b_spec.rb"}}, time.Minute)
+	server := httptest.NewServer(NewHandler(q))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	ctx := context.Background()
+
+	lease, err := client.Lease(ctx, 3, 1)
+	if err != nil {
+		t.Fatalf("Lease() error = %v", err)
+	}
+	if lease.ID == "" || !slices.Equal(lease.TestFiles, []string{"spec/a_spec.rb", "spec/b_spec.rb"}) {
+		t.Fatalf("unexpected lease: %+v", lease)
+	}
+	if err := client.Heartbeat(ctx, lease.ID); err != nil {
+		t.Fatalf("Heartbeat() error = %v", err)
+	}
+	if err := client.Ack(ctx, lease.ID, false); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	if err := client.Ack(ctx, lease.ID, false); !errors.Is(err, ErrLeaseNotFound) {
+		t.Fatalf("second Ack() error = %v, want ErrLeaseNotFound", err)
+	}
+
+	done, err := client.Lease(ctx, 3, 1)
+	if err != nil {
+		t.Fatalf("Lease() error = %v", err)
+	}
+	if !done.Done {
+		t.Fatalf("expected done lease, got %+v", done)
+	}
+}
+
+func TestHandlerRejectsInvalidRequests(t *testing.T) {
+	server := httptest.NewServer(NewHandler(New(nil, time.Minute)))
+	t.Cleanup(server.Close)
+
+	resp, err := http.Post(server.URL+leasePath, "application/json", strings.NewReader("{"))
+	if err != nil {
+		t.Fatalf("POST lease error = %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	resp, err = http.Get(server.URL + leasePath)
+	if err != nil {
+		t.Fatalf("GET lease error = %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestNewClientValidatesURL(t *testing.T) {
+	for _, rawURL := range []string{"", "localhost:7878", "ftp://localhost:7878", "http://"} {
+		if _, err := NewClient(rawURL); err == nil {
+			t.Errorf("NewClient(%q) should fail", rawURL)
+		}
+	}
+}
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < clientAttempts {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client.retryDelay = time.Millisecond
+
+	if err := client.Heartbeat(context.Background(), "1"); err != nil {
+		t.Fatalf("Heartbeat() error = %v", err)
+	}
+	if attempts != clientAttempts {
+		t.Fatalf("attempts = %d, want %d", attempts, clientAttempts)
+	}
+}
+
+func TestServeStopsAfterQueueDrains(t *testing.T) {
+	q := New([][]string{{"spec/a_spec.rb"}}, time.Minute)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- Serve(context.Background(), listener, q, 50*time.Millisecond)
+	}()
+
+	client, err := NewClient("http://" + listener.Addr().String())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	lease, err := client.Lease(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatalf("Lease() error = %v", err)
+	}
+	if err := client.Ack(context.Background(), lease.ID, false); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+
+	// Nodes still polling during the drain period learn the queue is done.
+	done, err := client.Lease(context.Background(), 1, 0)
+	if err != nil || !done.Done {
+		t.Fatalf("expected done lease during drain period, got %+v, error %v", done, err)
+	}
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			t.Fatalf("Serve() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() did not stop after the queue drained")
+	}
+}
+
+func TestServeStopsWhenContextIsCancelled(t *testing.T) {
+	q := New([][]string{{"spec/a_spec.rb"}}, time.Minute)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- Serve(ctx, listener, q, time.Minute)
+	}()
+	cancel()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Serve() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() did not stop after cancellation")
+	}
+}
@@ -1,18 +1,24 @@
 package runner
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 
 	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/environment"
 	"github.com/DataDog/ddtest/internal/errcode"
-	"golang.org/x/sync/errgroup"
+	"github.com/DataDog/ddtest/internal/settings"
 )
 
 // runCINode executes tests for a specific CI node (one split, not the whole tests set).
-// It further splits the node's tests among local workers based on ci_node_workers setting.
+// It further splits the node's tests among ciNodeWorkers local workers.
 func (e testExecutor) runCINode(ciNode int, ciNodeWorkers int) runExecutionResult {
 	report := newCINodeExecutionReport(ciNode, ciNodeWorkers)
 	testFiles, err := loadCINodeTestFiles(ciNode)
@@ -20,6 +26,7 @@ func (e testExecutor) runCINode(ciNode int, ciNodeWorkers int) runExecutionResul
 		return report.failure(err)
 	}
 	report.TestFilesRun = len(testFiles)
+	report.WorkQueue = e.workQueueEnabled() && report.LocalWorkers > 1
 
 	if report.LocalWorkers <= 1 {
 		err = e.runCINodeSingleWorker(ciNode, testFiles)
@@ -44,6 +51,72 @@ func newCINodeExecutionReport(ciNode int, ciNodeWorkers int) runExecutionReport
 	}
 }
 
+// detectCINodePosition is replaced in tests.
+var detectCINodePosition = environment.DetectCINodePosition
+
+// resolveCINode returns the CI node this run is restricted to: the ci_node
+// setting when it was set explicitly, otherwise the position of this job
+// among the parallel jobs of its CI provider. A CI job that is not parallel
+// runs the whole plan, as without ci_node. It fails when the CI provider runs
+// another number of parallel jobs than the plan has CI nodes.
+func resolveCINode(parallelRunners int) (int, error) {
+	ciNode := settings.GetCiNode()
+	if ciNode >= 0 || settings.CiNodeConfigured() {
+		return ciNode, nil
+	}
+
+	position, ok := detectCINodePosition()
+	if !ok || position.Total <= 1 {
+		return ciNode, nil
+	}
+	if position.Total != parallelRunners {
+		return ciNode, errcode.New(errcode.RunCINodeTotalMismatch, fmt.Sprintf(
+			"%s runs %d parallel jobs (%s), but the plan has %d CI nodes in %s; set the parallelism of the CI job to %d, or pass --ci-node",
+			position.Provider, position.Total, position.TotalEnv, parallelRunners, constants.ParallelRunnersOutputPath, parallelRunners))
+	}
+	slog.Info("Detected CI node from the CI environment",
+		"provider", position.Provider, "ciNode", position.Index, "ciNodeTotal", position.Total, "indexEnv", position.IndexEnv)
+	return position.Index, nil
+}
+
+// resolveCINodeWorkers returns the local worker count of ciNode: the
+// ci_node_workers setting when it was set explicitly, otherwise the count the
+// plan records for ciNode, otherwise the ci_node_workers default.
+func resolveCINodeWorkers(ciNode int) int {
+	ciNodeWorkers := settings.GetCiNodeWorkers()
+	if ciNode < 0 || settings.CiNodeWorkersConfigured() {
+		return ciNodeWorkers
+	}
+
+	plannedWorkers, err := loadPlannedCINodeWorkers(ciNode)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("Failed to read the planned worker count of CI node; using ci_node_workers",
+				"ciNode", ciNode, "path", constants.CINodeWorkersOutputPath, "error", err)
+		}
+		return ciNodeWorkers
+	}
+	slog.Info("Using the worker count the plan records for CI node", "ciNode", ciNode, "ciNodeWorkers", plannedWorkers)
+	return plannedWorkers
+}
+
+// loadPlannedCINodeWorkers reads the worker count of ciNode from the plan's
+// ci-node-workers.txt, which lists one count per CI node.
+func loadPlannedCINodeWorkers(ciNode int) (int, error) {
+	lines, err := loadTestBatch(constants.CINodeWorkersOutputPath)
+	if err != nil {
+		return 0, err
+	}
+	if ciNode >= len(lines) {
+		return 0, fmt.Errorf("plan records worker counts for %d CI nodes, not for ci-node %d", len(lines), ciNode)
+	}
+	workers, err := strconv.Atoi(lines[ciNode])
+	if err != nil || workers < 1 {
+		return 0, fmt.Errorf("invalid worker count %q for ci-node %d", lines[ciNode], ciNode)
+	}
+	return workers, nil
+}
+
 func loadCINodeTestFiles(ciNode int) ([]string, error) {
 	runnerFilePath := filepath.Join(constants.TestsSplitDir, fmt.Sprintf("runner-%d", ciNode))
 	testFiles, err := loadTestBatch(runnerFilePath)
@@ -62,7 +135,12 @@ func (e testExecutor) runCINodeSingleWorker(ciNode int, testFiles []string) erro
 		slog.Info("No tests to run", "nodeIndex", ciNode, "workerIndex", 0)
 		return nil
 	}
-	if err := e.runBatch(testFiles, ciNode, 0); err != nil {
+	testFiles, exclusive := e.withoutExclusiveTestFiles(testFiles)
+	var err error
+	if len(testFiles) > 0 {
+		err = e.runBatch(testFiles, ciNode, 0)
+	}
+	if err := e.runExclusiveTestFiles(exclusive, ciNode, err); err != nil {
 		return errcode.WithCode(errcode.RunCINodeTestsFailed, fmt.Errorf("failed to run tests for ci-node %d: %w", ciNode, err))
 	}
 	return nil
@@ -77,12 +155,22 @@ func (e testExecutor) runCINodeWorkers(ciNode int, ciNodeWorkers int, testFiles
 	slog.Info("Running tests for CI node in parallel mode",
 		"ciNode", ciNode, "ciNodeWorkers", ciNodeWorkers, "testFilesCount", len(testFiles))
 
+	if e.workQueueEnabled() {
+		if err := e.runLocalQueueWorkers(testFiles, ciNode, ciNodeWorkers); err != nil {
+			return errcode.WithCode(errcode.RunCINodeTestsFailed, fmt.Errorf("failed to run tests for ci-node %d: %w", ciNode, err))
+		}
+		return nil
+	}
+
+	testFiles, exclusive := e.withoutExclusiveTestFiles(testFiles)
 	groups := e.subsplitTestsBetweenWorkers(testFiles, ciNodeWorkers)
-	return e.runCINodeWorkerGroups(ciNode, groups)
+	return e.runCINodeWorkerGroups(ciNode, groups, exclusive)
 }
 
-func (e testExecutor) runCINodeWorkerGroups(ciNode int, groups [][]string) error {
-	var g errgroup.Group
+// runCINodeWorkerGroups runs each group of test files in its own worker, then
+// the exclusive test files alone.
+func (e testExecutor) runCINodeWorkerGroups(ciNode int, groups [][]string, exclusive []string) error {
+	g, workers := e.workerGroup()
 	for workerIndex, groupFiles := range groups {
 		if len(groupFiles) == 0 {
 			continue
@@ -94,16 +182,34 @@ func (e testExecutor) runCINodeWorkerGroups(ciNode int, groups [][]string) error
 			"testFiles", groupFiles)
 
 		g.Go(func() error {
-			return e.runBatch(groupFiles, ciNode, workerIndex)
+			return workers.runBatch(groupFiles, ciNode, workerIndex)
 		})
 	}
 
-	if err := g.Wait(); err != nil {
+	if err := e.runExclusiveTestFiles(exclusive, ciNode, g.Wait()); err != nil {
 		return errcode.WithCode(errcode.RunCINodeTestsFailed, fmt.Errorf("failed to run tests for ci-node %d: %w", ciNode, err))
 	}
 	return nil
 }
 
+// subsplitTestsBetweenWorkers distributes testFiles between n workers. With a
+// split order, each worker runs its test files in the order of testFiles.
 func (e testExecutor) subsplitTestsBetweenWorkers(testFiles []string, n int) [][]string {
-	return e.planner.DistributeTestFiles(testFiles, n)
+	groups := e.planner.DistributeTestFiles(testFiles, n)
+	if !e.keepSplitOrder {
+		return groups
+	}
+
+	positions := make(map[string]int, len(testFiles))
+	for i, testFile := range testFiles {
+		if _, ok := positions[testFile]; !ok {
+			positions[testFile] = i
+		}
+	}
+	for _, group := range groups {
+		slices.SortStableFunc(group, func(a, b string) int {
+			return cmp.Compare(positions[a], positions[b])
+		})
+	}
+	return groups
 }
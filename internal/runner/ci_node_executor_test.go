@@ -9,6 +9,10 @@ import (
 	"testing"
 
 	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/environment"
+	"github.com/DataDog/ddtest/internal/errcode"
+	"github.com/DataDog/ddtest/internal/settings"
+	"github.com/spf13/viper"
 )
 
 func TestRunCINode_SingleWorker(t *testing.T) {
@@ -261,6 +265,66 @@ func TestRunCINode_EmptyFile(t *testing.T) {
 	}
 }
 
+func TestRunCINode_KeepsSplitOrder(t *testing.T) {
+	chdirTemp(t)
+
+	// The planner ordered the runner split new test files first.
+	_ = os.MkdirAll(constants.TestsSplitDir, 0755)
+	_ = os.WriteFile(filepath.Join(constants.TestsSplitDir, "runner-1"),
+		[]byte("test/new_test.rb\ntest/changed_test.rb\ntest/slow_test.rb\ntest/fast_test.rb\n"), 0644)
+
+	mockFramework := &MockFramework{FrameworkName: "rspec"}
+	// Sub-splits come back in assignment order, longest first.
+	testPlanner := &fakePlanner{distributeFunc: func([]string, int) [][]string {
+		return [][]string{
+			{"test/slow_test.rb", "test/new_test.rb"},
+			{"test/fast_test.rb", "test/changed_test.rb"},
+		}
+	}}
+
+	for _, tc := range []struct {
+		name     string
+		executor testExecutor
+		want     [][]string
+	}{
+		{
+			name:     "sub-splits",
+			executor: newTestExecutor(context.Background(), mockFramework, map[string]string{}, testPlanner).withSplitOrder(),
+			want: [][]string{
+				{"test/new_test.rb", "test/slow_test.rb"},
+				{"test/changed_test.rb", "test/fast_test.rb"},
+			},
+		},
+		{
+			name:     "work queue",
+			executor: newTestExecutor(context.Background(), mockFramework, map[string]string{}, testPlanner).withWorkQueue(2).withSplitOrder(),
+			want: [][]string{
+				{"test/new_test.rb", "test/changed_test.rb"},
+				{"test/slow_test.rb", "test/fast_test.rb"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockFramework.RunTestsCalls = nil
+			testPlanner.testFileWeights = map[string]int{"test/slow_test.rb": 1000, "test/fast_test.rb": 10}
+
+			if result := tc.executor.runCINode(1, 2); result.err != nil {
+				t.Fatalf("runCINode() should not return error, got: %v", result.err)
+			}
+
+			var batches [][]string
+			for _, call := range mockFramework.GetRunTestsCalls() {
+				batches = append(batches, call.TestFiles)
+			}
+			slices.SortFunc(batches, func(a, b []string) int { return slices.Compare(a, b) })
+			slices.SortFunc(tc.want, func(a, b []string) int { return slices.Compare(a, b) })
+			if !slices.EqualFunc(batches, tc.want, slices.Equal) {
+				t.Fatalf("expected worker batches %v in split order, got %v", tc.want, batches)
+			}
+		})
+	}
+}
+
 func TestSubsplitTestsBetweenWorkers(t *testing.T) {
 	executor := testExecutor{planner: roundRobinTestPlanner{}}
 
@@ -368,3 +432,94 @@ func TestSubsplitTestsBetweenWorkers(t *testing.T) {
 	})
 
 }
+
+func TestResolveCINodeWorkers(t *testing.T) {
+	tests := []struct {
+		name          string
+		ciNodeWorkers string
+		plan          string
+		ciNode        int
+		expected      int
+	}{
+		{name: "planned worker count", plan: "8\n4\n", ciNode: 1, expected: 4},
+		{name: "explicit ci_node_workers wins", ciNodeWorkers: "2", plan: "8\n4\n", ciNode: 0, expected: 2},
+		{name: "no planned worker counts", ciNode: 0, expected: 1},
+		{name: "CI node missing from plan", plan: "8\n", ciNode: 3, expected: 1},
+		{name: "invalid planned worker count", plan: "many\n", ciNode: 0, expected: 1},
+		{name: "not in CI-node mode", plan: "8\n", ciNode: -1, expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.ciNodeWorkers != "" {
+				t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_CI_NODE_WORKERS", tt.ciNodeWorkers)
+			}
+			viper.Reset()
+			settings.Init()
+			t.Cleanup(func() {
+				viper.Reset()
+				settings.Init()
+			})
+			chdirTemp(t)
+			if tt.plan != "" {
+				writeRunnerTestFile(t, constants.CINodeWorkersOutputPath, tt.plan)
+			}
+
+			if got := resolveCINodeWorkers(tt.ciNode); got != tt.expected {
+				t.Fatalf("resolveCINodeWorkers(%d) = %d, want %d", tt.ciNode, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResolveCINode(t *testing.T) {
+	circleCINode := func(index, total int) func() (environment.CINodePosition, bool) {
+		return func() (environment.CINodePosition, bool) {
+			return environment.CINodePosition{Provider: "circleci", Index: index, Total: total, IndexEnv: "CIRCLE_NODE_INDEX", TotalEnv: "CIRCLE_NODE_TOTAL"}, true
+		}
+	}
+	noCINode := func() (environment.CINodePosition, bool) { return environment.CINodePosition{}, false }
+
+	tests := []struct {
+		name            string
+		ciNode          string
+		detect          func() (environment.CINodePosition, bool)
+		parallelRunners int
+		expected        int
+		wantCode        errcode.Code
+	}{
+		{name: "detected CI node", detect: circleCINode(2, 4), parallelRunners: 4, expected: 2},
+		{name: "explicit ci_node wins", ciNode: "1", detect: circleCINode(2, 4), parallelRunners: 4, expected: 1},
+		{name: "explicit ci_node -1 disables detection", ciNode: "-1", detect: circleCINode(2, 4), parallelRunners: 4, expected: -1},
+		{name: "CI job that is not parallel", detect: circleCINode(0, 1), parallelRunners: 4, expected: -1},
+		{name: "no CI node position", detect: noCINode, parallelRunners: 4, expected: -1},
+		{name: "parallel job count mismatch", detect: circleCINode(2, 4), parallelRunners: 3, expected: -1, wantCode: errcode.RunCINodeTotalMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.ciNode != "" {
+				t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_CI_NODE", tt.ciNode)
+			}
+			viper.Reset()
+			settings.Init()
+			t.Cleanup(func() {
+				viper.Reset()
+				settings.Init()
+			})
+			originalDetect := detectCINodePosition
+			detectCINodePosition = tt.detect
+			t.Cleanup(func() { detectCINodePosition = originalDetect })
+
+			got, err := resolveCINode(tt.parallelRunners)
+			if tt.wantCode != "" {
+				assertRunnerErrorCode(t, err, tt.wantCode)
+			} else if err != nil {
+				t.Fatalf("resolveCINode(%d) returned error: %v", tt.parallelRunners, err)
+			}
+			if got != tt.expected {
+				t.Fatalf("resolveCINode(%d) = %d, want %d", tt.parallelRunners, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"log/slog"
+	"slices"
+)
+
+// withoutExclusiveTestFiles returns testFiles without the test files that the
+// plan marks as exclusive, and those exclusive test files.
+func (e testExecutor) withoutExclusiveTestFiles(testFiles []string) ([]string, []string) {
+	constraints := e.planner.TestFileConstraints()
+	if len(constraints.Exclusive) == 0 {
+		return testFiles, nil
+	}
+
+	regular := make([]string, 0, len(testFiles))
+	var exclusive []string
+	for _, testFile := range testFiles {
+		if constraints.IsExclusive(testFile) {
+			exclusive = append(exclusive, testFile)
+		} else {
+			regular = append(regular, testFile)
+		}
+	}
+	return regular, exclusive
+}
+
+// runExclusiveTestFiles runs the exclusive test files of a node in one test
+// process, once every other test process of the node is done. err is the
+// error of those test processes and is returned first; with fail-fast, the
+// exclusive test files do not run after a failure.
+func (e testExecutor) runExclusiveTestFiles(exclusive []string, nodeIndex int, err error) error {
+	if len(exclusive) == 0 {
+		return err
+	}
+	if err != nil && e.failFast != nil {
+		e.failFast.recordNotRun(exclusive)
+		return err
+	}
+
+	slog.Info("Running exclusive test files alone", "nodeIndex", nodeIndex, "testFilesCount", len(exclusive))
+	if exclusiveErr := e.runBatch(exclusive, nodeIndex, 0); err == nil {
+		err = exclusiveErr
+	}
+	return err
+}
+
+// isExclusiveBatch reports whether a batch leased from a queue holds an
+// exclusive test file.
+func (e testExecutor) isExclusiveBatch(testFiles []string) bool {
+	constraints := e.planner.TestFileConstraints()
+	return slices.ContainsFunc(testFiles, constraints.IsExclusive)
+}
@@ -0,0 +1,139 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/errcode"
+	"github.com/DataDog/ddtest/internal/planner"
+)
+
+func constrainedTestPlanner() *fakePlanner {
+	return &fakePlanner{
+		testFileWeights: map[string]int{"spec/search/a_spec.rb": 10, "spec/search/b_spec.rb": 10, "spec/one_spec.rb": 30},
+		constraints: planner.NewTestFileConstraints(
+			map[string][]string{"search": {"spec/search/a_spec.rb", "spec/search/b_spec.rb"}},
+			[]string{"spec/es_spec.rb"},
+		),
+	}
+}
+
+func TestRunSequential_RunsExclusiveTestFilesLast(t *testing.T) {
+	chdirTemp(t)
+	writeRunnerTestFile(t, constants.TestFilesOutputPath, "spec/es_spec.rb\nspec/one_spec.rb\nspec/two_spec.rb\n")
+
+	mockFramework := &MockFramework{FrameworkName: "rspec"}
+	result := newTestExecutor(context.Background(), mockFramework, map[string]string{}, constrainedTestPlanner()).runSequential()
+	if result.err != nil {
+		t.Fatalf("runSequential() should not return error, got: %v", result.err)
+	}
+
+	calls := mockFramework.GetRunTestsCalls()
+	if len(calls) != 2 ||
+		!slices.Equal(calls[0].TestFiles, []string{"spec/one_spec.rb", "spec/two_spec.rb"}) ||
+		!slices.Equal(calls[1].TestFiles, []string{"spec/es_spec.rb"}) {
+		t.Fatalf("expected the exclusive test file to run last on its own, got %+v", calls)
+	}
+}
+
+func TestRunCINode_MultipleWorkersRunExclusiveTestFilesLast(t *testing.T) {
+	chdirTemp(t)
+	_ = os.MkdirAll(constants.TestsSplitDir, 0755)
+	_ = os.WriteFile(filepath.Join(constants.TestsSplitDir, "runner-0"), []byte("spec/one_spec.rb\nspec/es_spec.rb\nspec/two_spec.rb\n"), 0644)
+
+	mockFramework := &MockFramework{FrameworkName: "rspec"}
+	testPlanner := constrainedTestPlanner()
+	result := newTestExecutor(context.Background(), mockFramework, map[string]string{}, testPlanner).runCINode(0, 2)
+	if result.err != nil {
+		t.Fatalf("runCINode() should not return error, got: %v", result.err)
+	}
+
+	if len(testPlanner.distributedTestFiles) != 1 || !slices.Equal(testPlanner.distributedTestFiles[0], []string{"spec/one_spec.rb", "spec/two_spec.rb"}) {
+		t.Fatalf("expected only the regular test files to be split between workers, got %v", testPlanner.distributedTestFiles)
+	}
+	calls := mockFramework.GetRunTestsCalls()
+	if len(calls) != 3 || !slices.Equal(calls[2].TestFiles, []string{"spec/es_spec.rb"}) {
+		t.Fatalf("expected the exclusive test file to run last on its own, got %+v", calls)
+	}
+}
+
+func TestRunParallel_FailFastDoesNotRunExclusiveTestFilesAfterFailure(t *testing.T) {
+	chdirTemp(t)
+	_ = os.MkdirAll(constants.TestsSplitDir, 0755)
+	_ = os.WriteFile(filepath.Join(constants.TestsSplitDir, "runner-0"), []byte("spec/one_spec.rb\nspec/es_spec.rb\n"), 0644)
+
+	mockFramework := &MockFramework{FrameworkName: "rspec", Err: errors.New("tests failed")}
+	executor := newTestExecutor(context.Background(), mockFramework, map[string]string{}, constrainedTestPlanner()).withFailFast()
+
+	result := executor.runParallel()
+	assertRunnerErrorCode(t, result.err, errcode.RunParallelTestsFailed)
+	if calls := mockFramework.GetRunTestsCalls(); len(calls) != 1 || !slices.Equal(calls[0].TestFiles, []string{"spec/one_spec.rb"}) {
+		t.Fatalf("expected only the regular test file to run, got %+v", calls)
+	}
+	if report := executor.failFastReport(); !slices.Equal(report.NotRunTestFiles, []string{"spec/es_spec.rb"}) {
+		t.Fatalf("expected the exclusive test file to be recorded as not run, got %+v", report)
+	}
+}
+
+func TestWorkQueue_KeepsGroupsInOneBatchAndRunsExclusiveTestFilesLast(t *testing.T) {
+	mockFramework := &MockFramework{FrameworkName: "rspec"}
+	executor := newTestExecutor(context.Background(), mockFramework, map[string]string{}, constrainedTestPlanner()).withWorkQueue(1)
+
+	err := executor.runLocalQueueWorkers([]string{"spec/es_spec.rb", "spec/search/a_spec.rb", "spec/one_spec.rb", "spec/search/b_spec.rb"}, 0, 1)
+	if err != nil {
+		t.Fatalf("runLocalQueueWorkers() returned error: %v", err)
+	}
+
+	calls := mockFramework.GetRunTestsCalls()
+	expected := [][]string{
+		{"spec/one_spec.rb"},
+		{"spec/search/a_spec.rb", "spec/search/b_spec.rb"},
+		{"spec/es_spec.rb"},
+	}
+	if len(calls) != len(expected) {
+		t.Fatalf("expected %d RunTests calls, got %+v", len(expected), calls)
+	}
+	for i, call := range calls {
+		if !slices.Equal(call.TestFiles, expected[i]) {
+			t.Errorf("call %d ran %v, want %v", i, call.TestFiles, expected[i])
+		}
+	}
+}
+
+func TestLockBatch(t *testing.T) {
+	var lock sync.RWMutex
+
+	unlock := lockBatch(&lock, false)
+	if !lock.TryRLock() {
+		t.Fatal("expected regular batches to run together")
+	}
+	lock.RUnlock()
+	if lock.TryLock() {
+		t.Fatal("expected an exclusive batch to wait for regular batches")
+	}
+	unlock()
+
+	unlock = lockBatch(&lock, true)
+	if lock.TryRLock() {
+		t.Fatal("expected regular batches to wait for an exclusive batch")
+	}
+	unlock()
+}
+
+func TestQueueBatches(t *testing.T) {
+	batches := queueBatches(constrainedTestPlanner(), []string{"spec/search/a_spec.rb", "spec/es_spec.rb", "spec/one_spec.rb", "spec/search/b_spec.rb"}, 1)
+	expected := [][]string{
+		{"spec/es_spec.rb"},
+		{"spec/one_spec.rb"},
+		{"spec/search/a_spec.rb", "spec/search/b_spec.rb"},
+	}
+	if !slices.EqualFunc(batches, expected, slices.Equal) {
+		t.Fatalf("queueBatches() = %v, want %v", batches, expected)
+	}
+}
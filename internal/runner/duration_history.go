@@ -0,0 +1,44 @@
+package runner
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/planner"
+)
+
+const durationHistorySourceJUnit = "junit"
+
+// recordDurationHistory appends the test file durations that JUnit reports
+// observed in this run to the duration history at path. Worker durations are
+// left out because they include the boot time of the test command, which
+// would inflate the durations of short test files. It returns the number of
+// entries appended. Failures only log a warning because the test run itself is
+// already complete.
+func recordDurationHistory(path string, junitDurations map[string]time.Duration, recordedAt time.Time) int {
+	entries := make([]planner.DurationHistoryEntry, 0, len(junitDurations))
+	for testFile, duration := range junitDurations {
+		entries = append(entries, planner.DurationHistoryEntry{
+			TestFile:   testFile,
+			DurationMs: max(duration.Milliseconds(), 1),
+			RecordedAt: recordedAt,
+			Source:     durationHistorySourceJUnit,
+		})
+	}
+	if len(entries) == 0 {
+		slog.Info("No test file durations to add to the duration history", "path", path)
+		return 0
+	}
+	slices.SortFunc(entries, func(a, b planner.DurationHistoryEntry) int {
+		return strings.Compare(a.TestFile, b.TestFile)
+	})
+
+	if err := planner.AppendDurationHistory(path, entries); err != nil {
+		slog.Warn("Failed to append to the duration history", "path", path, "error", err)
+		return 0
+	}
+	slog.Info("Appended test file durations to the duration history", "path", path, "entriesCount", len(entries))
+	return len(entries)
+}
@@ -0,0 +1,38 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordDurationHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "durations.jsonl")
+	recordedAt := time.Date(2026, 1, 15, 10, 4, 12, 0, time.UTC)
+
+	recorded := recordDurationHistory(path, map[string]time.Duration{
+		"spec/b_spec.rb": 3 * time.Second,
+		"spec/a_spec.rb": 1500 * time.Millisecond,
+	}, recordedAt)
+	if recorded != 2 {
+		t.Errorf("recordDurationHistory() = %d, want 2", recorded)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected duration history to be written: %v", err)
+	}
+	expected := strings.Join([]string{
+		`{"testFile":"spec/a_spec.rb","durationMs":1500,"recordedAt":"2026-01-15T10:04:12Z","source":"junit"}`,
+		`{"testFile":"spec/b_spec.rb","durationMs":3000,"recordedAt":"2026-01-15T10:04:12Z","source":"junit"}`,
+	}, "\n") + "\n"
+	if string(data) != expected {
+		t.Errorf("unexpected duration history:\n%s\nwant:\n%s", data, expected)
+	}
+
+	if recorded := recordDurationHistory(path, nil, recordedAt); recorded != 0 {
+		t.Errorf("recordDurationHistory() without durations = %d, want 0", recorded)
+	}
+}
@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/DataDog/ddtest/internal/ext"
+	"golang.org/x/sync/errgroup"
+)
+
+// failFastRun records which workers fail-fast cancelled after another worker
+// failed, and the test files that did not run to completion because of it.
+type failFastRun struct {
+	mu               sync.Mutex
+	cancelledWorkers []string
+	notRunTestFiles  []string
+}
+
+type failFastReport struct {
+	Enabled          bool     `json:"enabled"`
+	CancelledWorkers []string `json:"cancelledWorkers"`
+	NotRunTestFiles  []string `json:"notRunTestFiles"`
+}
+
+func (f *failFastRun) recordCancelledWorker(nodeIndex int, workerIndex int, testFiles []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	worker := workerName(nodeIndex, workerIndex)
+	if !slices.Contains(f.cancelledWorkers, worker) {
+		f.cancelledWorkers = append(f.cancelledWorkers, worker)
+	}
+	f.notRunTestFiles = append(f.notRunTestFiles, testFiles...)
+}
+
+func (f *failFastRun) recordNotRun(testFiles []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.notRunTestFiles = append(f.notRunTestFiles, testFiles...)
+}
+
+func (f *failFastRun) report() failFastReport {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	report := failFastReport{
+		Enabled:          true,
+		CancelledWorkers: slices.Clone(f.cancelledWorkers),
+		NotRunTestFiles:  slices.Clone(f.notRunTestFiles),
+	}
+	slices.Sort(report.CancelledWorkers)
+	slices.Sort(report.NotRunTestFiles)
+	return report
+}
+
+// withFailFast makes the first failed worker cancel its sibling workers
+// instead of letting them run to completion.
+func (e testExecutor) withFailFast() testExecutor {
+	e.failFast = &failFastRun{}
+	return e
+}
+
+func (e testExecutor) failFastReport() failFastReport {
+	if e.failFast == nil {
+		return failFastReport{}
+	}
+	return e.failFast.report()
+}
+
+// errFailFast is the cause of the cancellation of sibling workers by
+// fail-fast, which tells it apart from other cancellations such as signals.
+var errFailFast = errors.New("another worker failed")
+
+// workerGroup runs sibling workers. With fail-fast, the first worker that
+// returns an error cancels the context of the others with errFailFast.
+type workerGroup struct {
+	errgroup.Group
+	cancel context.CancelCauseFunc
+}
+
+func (g *workerGroup) Go(f func() error) {
+	g.Group.Go(func() error {
+		err := f()
+		if err != nil && g.cancel != nil {
+			g.cancel(errFailFast)
+		}
+		return err
+	})
+}
+
+func (g *workerGroup) Wait() error {
+	err := g.Group.Wait()
+	if g.cancel != nil {
+		g.cancel(nil)
+	}
+	return err
+}
+
+// workerGroup returns the group that runs sibling workers and the executor
+// they should use. With fail-fast, the executor's context is cancelled as soon
+// as one worker returns an error, and cancellation signals the process tree of
+// every running test command.
+func (e testExecutor) workerGroup() (*workerGroup, testExecutor) {
+	if e.failFast == nil {
+		return &workerGroup{}, e
+	}
+	ctx, cancel := context.WithCancelCause(e.ctx)
+	e.ctx = ext.WithGracefulCancel(ctx)
+	return &workerGroup{cancel: cancel}, e
+}
+
+// cancelledByFailFast reports whether the worker context was cancelled because
+// a sibling worker failed.
+func (e testExecutor) cancelledByFailFast() bool {
+	return e.failFast != nil && errors.Is(context.Cause(e.ctx), errFailFast)
+}
+
+// recordCancelledBatch records a batch whose worker was cancelled by
+// fail-fast.
+func (e testExecutor) recordCancelledBatch(testFiles []string, nodeIndex int, workerIndex int) {
+	slog.Info("Worker cancelled after another worker failed", "nodeIndex", nodeIndex, "workerIndex", workerIndex, "testFilesCount", len(testFiles))
+	e.failFast.recordCancelledWorker(nodeIndex, workerIndex, testFiles)
+}
@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/errcode"
+)
+
+// cancellableFramework fails batches that contain failingFile and runs every
+// other batch until its context is cancelled. The failing batch waits until
+// another batch is running, so the failure always interrupts it.
+type cancellableFramework struct {
+	*MockFramework
+	failingFile string
+	running     chan struct{}
+	runningOnce *sync.Once
+}
+
+func newCancellableFramework(failingFile string) cancellableFramework {
+	return cancellableFramework{
+		MockFramework: &MockFramework{FrameworkName: "rspec"},
+		failingFile:   failingFile,
+		running:       make(chan struct{}),
+		runningOnce:   &sync.Once{},
+	}
+}
+
+func (f cancellableFramework) RunTests(ctx context.Context, testFiles []string, envMap map[string]string) error {
+	_ = f.MockFramework.RunTests(ctx, testFiles, envMap)
+	if slices.Contains(testFiles, f.failingFile) {
+		<-f.running
+		return errors.New("tests failed")
+	}
+	f.runningOnce.Do(func() { close(f.running) })
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRunParallel_FailFastCancelsOtherWorkers(t *testing.T) {
+	chdirTemp(t)
+
+	_ = os.MkdirAll(constants.TestsSplitDir, 0755)
+	_ = os.WriteFile(filepath.Join(constants.TestsSplitDir, "runner-0"), []byte("spec/fail_spec.rb\n"), 0644)
+	_ = os.WriteFile(filepath.Join(constants.TestsSplitDir, "runner-1"), []byte("spec/slow_spec.rb\nspec/other_spec.rb\n"), 0644)
+
+	framework := newCancellableFramework("spec/fail_spec.rb")
+	executor := newTestExecutor(context.Background(), framework, map[string]string{}, roundRobinTestPlanner{}).withFailFast()
+
+	result := executor.runParallel()
+	assertRunnerErrorCode(t, result.err, errcode.RunParallelTestsFailed)
+	if !strings.Contains(result.err.Error(), "tests failed") {
+		t.Fatalf("expected the failing worker's error, got %v", result.err)
+	}
+
+	report := executor.failFastReport()
+	if !slices.Equal(report.CancelledWorkers, []string{"node 0 / worker 1"}) {
+		t.Fatalf("CancelledWorkers = %v, want [node 0 / worker 1]", report.CancelledWorkers)
+	}
+	if !slices.Equal(report.NotRunTestFiles, []string{"spec/other_spec.rb", "spec/slow_spec.rb"}) {
+		t.Fatalf("NotRunTestFiles = %v", report.NotRunTestFiles)
+	}
+}
+
+func TestRunQueueWorkers_FailFastStopsQueue(t *testing.T) {
+	framework := newCancellableFramework("spec/a_spec.rb")
+	executor := newTestExecutor(context.Background(), framework, map[string]string{}, roundRobinTestPlanner{}).withWorkQueue(1).withFailFast()
+
+	err := executor.runLocalQueueWorkers([]string{"spec/a_spec.rb", "spec/b_spec.rb", "spec/c_spec.rb", "spec/d_spec.rb"}, 0, 2)
+	if err == nil || err.Error() != "tests failed" {
+		t.Fatalf("runLocalQueueWorkers() error = %v, want tests failed", err)
+	}
+
+	report := executor.failFastReport()
+	if len(report.CancelledWorkers) != 1 {
+		t.Fatalf("expected one cancelled worker, got %v", report.CancelledWorkers)
+	}
+	if !slices.Equal(report.NotRunTestFiles, []string{"spec/b_spec.rb", "spec/c_spec.rb", "spec/d_spec.rb"}) {
+		t.Fatalf("NotRunTestFiles = %v", report.NotRunTestFiles)
+	}
+	if calls := framework.GetRunTestsCallsCount(); calls != 2 {
+		t.Fatalf("expected no batch to start after the failure, got %d RunTests calls", calls)
+	}
+}
+
+func TestRunBatch_WithoutFailFastIgnoresCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mockFramework := &MockFramework{FrameworkName: "rspec"}
+	executor := newTestExecutor(ctx, mockFramework, map[string]string{}, roundRobinTestPlanner{})
+
+	if err := executor.runBatch([]string{"spec/a_spec.rb"}, 0, 0); err != nil {
+		t.Fatalf("runBatch() error = %v", err)
+	}
+	if mockFramework.GetRunTestsCallsCount() != 1 {
+		t.Fatal("expected the batch to run")
+	}
+	if report := executor.failFastReport(); report.Enabled {
+		t.Fatalf("expected fail-fast to be disabled, got %+v", report)
+	}
+}
+
+func TestRunParallel_FailFastIgnoresOtherCancellations(t *testing.T) {
+	chdirTemp(t)
+
+	_ = os.MkdirAll(constants.TestsSplitDir, 0755)
+	_ = os.WriteFile(filepath.Join(constants.TestsSplitDir, "runner-0"), []byte("spec/a_spec.rb\n"), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mockFramework := &MockFramework{FrameworkName: "rspec", RunTestsFunc: func([]string) error {
+		return context.Canceled
+	}}
+	executor := newTestExecutor(ctx, mockFramework, map[string]string{}, roundRobinTestPlanner{}).withFailFast()
+
+	if result := executor.runParallel(); result.err == nil {
+		t.Fatal("expected runParallel() to fail")
+	}
+	if mockFramework.GetRunTestsCallsCount() != 1 {
+		t.Fatal("expected the batch to start")
+	}
+	report := executor.failFastReport()
+	if len(report.CancelledWorkers) != 0 || len(report.NotRunTestFiles) != 0 {
+		t.Fatalf("expected a cancellation that is not fail-fast to be left out of the report, got %+v", report)
+	}
+}
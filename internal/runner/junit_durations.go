@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/framework"
+	"github.com/DataDog/ddtest/internal/junit"
+	"github.com/DataDog/ddtest/internal/planner"
+)
+
+// recordJUnitDurations stores the per-file durations found in the JUnit
+// reports matching pattern so later plans can use them, and returns them.
+// Only reports written since the run started are read. Test files that runs
+// ran as test chunks are left out: their reports only cover some of their
+// tests, so they do not measure the whole file. Failures only log a warning
+// because the test run itself is already complete.
+func recordJUnitDurations(pattern string, since time.Time, runs []workerRun) map[string]time.Duration {
+	durations, reports, err := junit.ReadTestFileDurations(pattern, since)
+	if err != nil {
+		slog.Warn("Failed to read JUnit reports", "pattern", pattern, "error", err)
+		return nil
+	}
+	if len(reports) == 0 {
+		slog.Warn("No JUnit reports of this run matched", "pattern", pattern)
+		return nil
+	}
+	for testFile := range chunkedTestFiles(runs) {
+		delete(durations, testFile)
+	}
+	if len(durations) == 0 {
+		slog.Warn("JUnit reports have no test file durations", "pattern", pattern, "reportsCount", len(reports))
+		return nil
+	}
+
+	if err := planner.StoreLocalTestFileDurations(durations, time.Now()); err != nil {
+		slog.Warn("Failed to store test file durations from JUnit reports", "error", err)
+		return nil
+	}
+	slog.Info("Recorded test file durations from JUnit reports", "reportsCount", len(reports), "testFilesCount", len(durations))
+	return durations
+}
+
+// chunkedTestFiles returns the test files that runs ran as test chunks.
+func chunkedTestFiles(runs []workerRun) map[string]bool {
+	testFiles := make(map[string]bool)
+	for _, run := range runs {
+		for _, entry := range run.TestFiles {
+			if testFile, _, ok := framework.ParseTestChunk(entry); ok {
+				testFiles[testFile] = true
+			}
+		}
+	}
+	return testFiles
+}
@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/constants"
+)
+
+func TestTestRunner_Run_RecordsJUnitDurations(t *testing.T) {
+	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_JUNIT_REPORTS", "tmp/junit/*.xml")
+	withRunnerTestSettings(t)
+	chdirTemp(t)
+	writeRunnerTestFile(t, constants.ParallelRunnersOutputPath, "1")
+	writeRunnerTestFile(t, constants.TestFilesOutputPath, "spec/a_spec.rb\nspec/b_spec.rb\n")
+	writeRunnerTestFile(t, "tmp/junit/stale.xml", `<testsuite><testcase file="./spec/a_spec.rb" time="60"/></testsuite>`)
+	staleTime := time.Now().Add(-time.Hour)
+	if err := os.Chtimes("tmp/junit/stale.xml", staleTime, staleTime); err != nil {
+		t.Fatal(err)
+	}
+
+	framework := &MockFramework{FrameworkName: "rspec", RunTestsFunc: func([]string) error {
+		writeRunnerTestFile(t, "tmp/junit/rspec.xml", `<testsuite>
+  <testcase file="./spec/a_spec.rb" time="1.5"/>
+  <testcase file="./spec/a_spec.rb" time="0.5"/>
+  <testcase file="./spec/b_spec.rb" time="3"/>
+</testsuite>`)
+		return nil
+	}}
+	platform := &MockPlatform{PlatformName: "ruby", Framework: framework}
+	runner := NewWithDependencies(&MockPlatformDetector{Platform: platform}, &fakePlanner{})
+
+	if err := runner.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(constants.RunnerCacheDir, constants.TestFileDurationsCacheFile))
+	if err != nil {
+		t.Fatalf("expected durations cache to be written: %v", err)
+	}
+	var cache struct {
+		TestFiles map[string]struct {
+			DurationMs int64 `json:"durationMs"`
+		} `json:"testFiles"`
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		t.Fatalf("failed to parse durations cache: %v", err)
+	}
+	if cache.TestFiles["spec/a_spec.rb"].DurationMs != 2000 || cache.TestFiles["spec/b_spec.rb"].DurationMs != 3000 {
+		t.Fatalf("unexpected recorded durations: %s", data)
+	}
+}
+
+func TestRecordJUnitDurations_SkipsTestFilesRunAsTestChunks(t *testing.T) {
+	chdirTemp(t)
+	writeRunnerTestFile(t, "tmp/junit/rspec.xml", `<testsuite>
+  <testcase file="./spec/a_spec.rb" time="1"/>
+  <testcase file="./spec/b_spec.rb" time="2"/>
+</testsuite>`)
+	runs := []workerRun{
+		{TestFiles: []string{"spec/a_spec.rb", "spec/b_spec.rb[1:1]"}},
+		{TestFiles: []string{"spec/b_spec.rb[1:2]"}},
+	}
+
+	recorded := recordJUnitDurations("tmp/junit/*.xml", time.Now().Add(-time.Minute), runs)
+
+	want := map[string]time.Duration{"spec/a_spec.rb": time.Second}
+	if !maps.Equal(recorded, want) {
+		t.Fatalf("recordJUnitDurations() = %v, want %v", recorded, want)
+	}
+}
+
+func TestRecordJUnitDurations_NoMatchingReports(t *testing.T) {
+	chdirTemp(t)
+
+	if recorded := recordJUnitDurations("tmp/junit/*.xml", time.Now(), nil); len(recorded) != 0 {
+		t.Fatalf("recordJUnitDurations() = %v, want none", recorded)
+	}
+	if _, err := os.Stat(filepath.Join(constants.RunnerCacheDir, constants.TestFileDurationsCacheFile)); !os.IsNotExist(err) {
+		t.Fatalf("expected no durations cache without reports, got %v", err)
+	}
+}
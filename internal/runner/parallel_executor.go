@@ -8,7 +8,6 @@ import (
 
 	"github.com/DataDog/ddtest/internal/constants"
 	"github.com/DataDog/ddtest/internal/errcode"
-	"golang.org/x/sync/errgroup"
 )
 
 const runModeParallel = "parallel"
@@ -25,7 +24,9 @@ func (e testExecutor) runParallel() runExecutionResult {
 		return report.failure(errcode.WithCode(errcode.RunParallelSplitsReadFailed, fmt.Errorf("failed to read tests split directory %s: %w", constants.TestsSplitDir, err)))
 	}
 
-	var g errgroup.Group
+	g, workers := e.workerGroup()
+	var queuedTestFiles []string
+	var exclusiveTestFiles []string
 
 	for workerIndex, entry := range entries {
 		if entry.IsDir() {
@@ -39,16 +40,29 @@ func (e testExecutor) runParallel() runExecutionResult {
 			return report.failure(errcode.WithCode(errcode.RunParallelTestFilesReadFailed, fmt.Errorf("failed to read test files from %s: %w", splitFilePath, err)))
 		}
 		report.TestFilesRun += len(testFiles)
+		if e.workQueueEnabled() {
+			queuedTestFiles = append(queuedTestFiles, testFiles...)
+			continue
+		}
+		testFiles, exclusive := e.withoutExclusiveTestFiles(testFiles)
+		exclusiveTestFiles = append(exclusiveTestFiles, exclusive...)
 		if len(testFiles) == 0 {
 			continue
 		}
 
 		g.Go(func() error {
-			return e.runBatch(testFiles, 0, workerIndex)
+			return workers.runBatch(testFiles, 0, workerIndex)
+		})
+	}
+
+	if e.workQueueEnabled() {
+		report.WorkQueue = true
+		g.Go(func() error {
+			return e.runLocalQueueWorkers(queuedTestFiles, 0, report.LocalWorkers)
 		})
 	}
 
-	if err := g.Wait(); err != nil {
+	if err := e.runExclusiveTestFiles(exclusiveTestFiles, 0, g.Wait()); err != nil {
 		return report.failure(errcode.WithCode(errcode.RunParallelTestsFailed, fmt.Errorf("failed to run parallel tests: %w", err)))
 	}
 	return report.success()
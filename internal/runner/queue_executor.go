@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/errcode"
+	"github.com/DataDog/ddtest/internal/queue"
+)
+
+const runModeQueue = "queue"
+
+// queueClient is the subset of queue.Client used by queue workers.
+type queueClient interface {
+	Lease(ctx context.Context, node int, worker int) (queue.Lease, error)
+	Heartbeat(ctx context.Context, leaseID string) error
+	Ack(ctx context.Context, leaseID string, failed bool) error
+	URL() string
+}
+
+// runQueue executes tests leased from a `ddtest serve` queue instead of a
+// static split. Each local worker leases batches until the queue is done.
+func (e testExecutor) runQueue(client queueClient, nodeIndex int, workers int) runExecutionResult {
+	report := runExecutionReport{
+		Mode:         runModeQueue,
+		CINode:       nodeIndex,
+		LocalWorkers: max(workers, 1),
+		QueueURL:     client.URL(),
+	}
+	slog.Info("Running tests from test queue", "queueURL", client.URL(), "nodeIndex", nodeIndex, "workers", report.LocalWorkers)
+
+	source := newRemoteBatchSource(client, nodeIndex)
+	err := e.runQueueWorkers(source, nodeIndex, report.LocalWorkers)
+	report.TestFilesRun = int(source.testFilesLeased.Load())
+	if err != nil {
+		if errors.Is(err, errQueueUnavailable) {
+			return report.failure(errcode.WithCode(errcode.RunQueueUnavailable, err))
+		}
+		return report.failure(errcode.WithCode(errcode.RunQueueTestsFailed, fmt.Errorf("failed to run tests from queue: %w", err)))
+	}
+	return report.success()
+}
+
+var errQueueUnavailable = errors.New("test queue unavailable")
+
+// remoteBatchSource leases batches from a `ddtest serve` queue and keeps
+// them alive with heartbeats while they run.
+type remoteBatchSource struct {
+	client          queueClient
+	node            int
+	testFilesLeased atomic.Int64
+
+	mu         sync.Mutex
+	heartbeats map[string]context.CancelFunc
+}
+
+func newRemoteBatchSource(client queueClient, node int) *remoteBatchSource {
+	return &remoteBatchSource{
+		client:     client,
+		node:       node,
+		heartbeats: make(map[string]context.CancelFunc),
+	}
+}
+
+func (s *remoteBatchSource) nextBatch(ctx context.Context, workerIndex int) (testBatch, bool, error) {
+	for {
+		lease, err := s.client.Lease(ctx, s.node, workerIndex)
+		if err != nil {
+			return testBatch{}, false, fmt.Errorf("%w: failed to lease test files from %s: %w", errQueueUnavailable, s.client.URL(), err)
+		}
+		if lease.Done {
+			return testBatch{}, false, nil
+		}
+		if lease.ID == "" {
+			select {
+			case <-ctx.Done():
+				return testBatch{}, false, ctx.Err()
+			case <-time.After(lease.RetryAfter):
+			}
+			continue
+		}
+
+		s.testFilesLeased.Add(int64(len(lease.TestFiles)))
+		s.startHeartbeat(ctx, lease)
+		return testBatch{leaseID: lease.ID, testFiles: lease.TestFiles}, true, nil
+	}
+}
+
+func (s *remoteBatchSource) completeBatch(ctx context.Context, batch testBatch, runErr error) error {
+	s.stopHeartbeat(batch.leaseID)
+
+	err := s.client.Ack(ctx, batch.leaseID, runErr != nil)
+	if errors.Is(err, queue.ErrLeaseNotFound) {
+		slog.Warn("Test queue lease expired before it was acknowledged; its test files may run again on another node",
+			"leaseID", batch.leaseID, "testFiles", batch.testFiles)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: failed to acknowledge lease %s: %w", errQueueUnavailable, batch.leaseID, err)
+	}
+	return nil
+}
+
+func (s *remoteBatchSource) startHeartbeat(ctx context.Context, lease queue.Lease) {
+	heartbeatCtx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	s.heartbeats[lease.ID] = cancel
+	s.mu.Unlock()
+
+	go func() {
+		interval := lease.HeartbeatInterval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-heartbeatCtx.Done():
+				return
+			case <-ticker.C:
+				err := s.client.Heartbeat(heartbeatCtx, lease.ID)
+				if errors.Is(err, queue.ErrLeaseNotFound) {
+					slog.Warn("Test queue lease expired while its tests were running", "leaseID", lease.ID)
+					return
+				}
+				if err != nil && heartbeatCtx.Err() == nil {
+					slog.Debug("Failed to send test queue heartbeat", "leaseID", lease.ID, "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *remoteBatchSource) stopHeartbeat(leaseID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.heartbeats[leaseID]; ok {
+		cancel()
+		delete(s.heartbeats, leaseID)
+	}
+}
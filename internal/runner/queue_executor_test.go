@@ -0,0 +1,150 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/errcode"
+	"github.com/DataDog/ddtest/internal/queue"
+)
+
+func newTestQueueClient(t *testing.T, q *queue.Queue) *queue.Client {
+	t.Helper()
+	server := httptest.NewServer(queue.NewHandler(q))
+	t.Cleanup(server.Close)
+
+	client, err := queue.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client
+}
+
+func TestRunQueue_NodesShareQueue(t *testing.T) {
+	q := queue.New(queue.Batches(map[string]int{
+		"spec/a_spec.rb": 4,
+		"spec/b_spec.rb": 3,
+		"spec/c_spec.rb": 2,
+		"spec/d_spec.rb": 1,
+	}, 1), time.Minute)
+	client := newTestQueueClient(t, q)
+
+	frameworks := []*MockFramework{{FrameworkName: "rspec"}, {FrameworkName: "rspec"}}
+	results := make([]runExecutionResult, len(frameworks))
+	var wg sync.WaitGroup
+	for node, framework := range frameworks {
+		wg.Go(func() {
+			executor := newTestExecutor(context.Background(), framework, map[string]string{}, roundRobinTestPlanner{})
+			results[node] = executor.runQueue(client, node, 2)
+		})
+	}
+	wg.Wait()
+
+	var ranFiles []string
+	testFilesRun := 0
+	for node, result := range results {
+		if result.err != nil {
+			t.Fatalf("node %d runQueue() error = %v", node, result.err)
+		}
+		if result.report.Mode != runModeQueue || result.report.CINode != node || result.report.QueueURL != client.URL() {
+			t.Fatalf("unexpected node %d report: %+v", node, result.report)
+		}
+		testFilesRun += result.report.TestFilesRun
+		for _, call := range frameworks[node].GetRunTestsCalls() {
+			ranFiles = append(ranFiles, call.TestFiles...)
+		}
+	}
+	slices.Sort(ranFiles)
+	if !slices.Equal(ranFiles, []string{"spec/a_spec.rb", "spec/b_spec.rb", "spec/c_spec.rb", "spec/d_spec.rb"}) {
+		t.Fatalf("expected every queued file to run exactly once, got %v", ranFiles)
+	}
+	if testFilesRun != 4 {
+		t.Fatalf("expected reports to count 4 test files, got %d", testFilesRun)
+	}
+	if status := q.Status(); status.CompletedBatches != 4 || status.FailedBatches != 0 {
+		t.Fatalf("unexpected queue status: %+v", status)
+	}
+}
+
+func TestRunQueue_ReportsFailedBatches(t *testing.T) {
+	q := queue.New([][]string{{"spec/a_spec.rb"}, {"spec/b_spec.rb"}}, time.Minute)
+	client := newTestQueueClient(t, q)
+
+	framework := &MockFramework{FrameworkName: "rspec", Err: errors.New("tests failed")}
+	executor := newTestExecutor(context.Background(), framework, map[string]string{}, roundRobinTestPlanner{})
+	result := executor.runQueue(client, 0, 1)
+
+	assertRunnerErrorCode(t, result.err, errcode.RunQueueTestsFailed)
+	if framework.GetRunTestsCallsCount() != 2 {
+		t.Fatalf("expected failed batches not to stop the worker, got %d RunTests calls", framework.GetRunTestsCallsCount())
+	}
+	if status := q.Status(); status.CompletedBatches != 2 || status.FailedBatches != 2 {
+		t.Fatalf("expected failed batches to be acknowledged as failed, got %+v", status)
+	}
+}
+
+func TestRunQueue_QueueUnavailable(t *testing.T) {
+	server := httptest.NewServer(nil)
+	url := server.URL
+	server.Close()
+
+	client, err := queue.NewClient(url)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	framework := &MockFramework{FrameworkName: "rspec"}
+	executor := newTestExecutor(context.Background(), framework, map[string]string{}, roundRobinTestPlanner{})
+	result := executor.runQueue(client, 0, 1)
+
+	assertRunnerErrorCode(t, result.err, errcode.RunQueueUnavailable)
+	if framework.GetRunTestsCallsCount() != 0 {
+		t.Fatalf("expected no tests to run without a queue, got %d calls", framework.GetRunTestsCallsCount())
+	}
+}
+
+type expiringQueueClient struct {
+	*queue.Client
+}
+
+func (c expiringQueueClient) Ack(context.Context, string, bool) error {
+	return queue.ErrLeaseNotFound
+}
+
+func TestRunQueue_IgnoresExpiredLeaseOnAck(t *testing.T) {
+	q := queue.New([][]string{{"spec/a_spec.rb"}}, time.Minute)
+	client := newTestQueueClient(t, q)
+
+	framework := &MockFramework{FrameworkName: "rspec"}
+	executor := newTestExecutor(context.Background(), framework, map[string]string{}, roundRobinTestPlanner{})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	executor.ctx = ctx
+
+	result := executor.runQueue(expiringQueueClient{client}, 0, 1)
+
+	// The queue still holds the lease, so the worker waits for it until the
+	// context ends; the expired acknowledgement itself is not an error.
+	if !errors.Is(result.err, context.DeadlineExceeded) {
+		t.Fatalf("runQueue() error = %v, want context deadline", result.err)
+	}
+	if framework.GetRunTestsCallsCount() != 1 {
+		t.Fatalf("expected leased batch to run once, got %d calls", framework.GetRunTestsCallsCount())
+	}
+}
+
+func TestRunFromQueue_InvalidURL(t *testing.T) {
+	framework := &MockFramework{FrameworkName: "rspec"}
+	executor := newTestExecutor(context.Background(), framework, map[string]string{}, roundRobinTestPlanner{})
+
+	result := runFromQueue(executor, "localhost:7878", 2, 1)
+
+	assertRunnerErrorCode(t, result.err, errcode.RunQueueURLInvalid)
+	if result.report.Mode != runModeQueue || result.report.CINode != 2 {
+		t.Fatalf("unexpected report: %+v", result.report)
+	}
+}
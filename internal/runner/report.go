@@ -13,10 +13,34 @@ import (
 )
 
 type runExecutionReport struct {
-	Mode         string
-	CINode       int
-	LocalWorkers int
-	TestFilesRun int
+	Mode         string         `json:"mode"`
+	CINode       int            `json:"ciNode"`
+	LocalWorkers int            `json:"localWorkers"`
+	TestFilesRun int            `json:"testFilesRun"`
+	WorkQueue    bool           `json:"workQueue"`
+	QueueURL     string         `json:"queueUrl,omitempty"`
+	Retries      retryReport    `json:"retries"`
+	FailFast     failFastReport `json:"failFast"`
+	// WorkerTimeouts lists the test commands stopped by their hang timeout.
+	WorkerTimeouts []timedOutBatch `json:"workerTimeouts"`
+	// JUnitDurationsRecorded is the number of test files whose durations were
+	// recorded from JUnit reports.
+	JUnitDurationsRecorded int `json:"junitDurationsRecorded"`
+	// DurationHistoryRecorded is the number of test file durations appended
+	// to the duration history.
+	DurationHistoryRecorded int `json:"durationHistoryRecorded"`
+	// FailedWorkerLogs lists the log files of failed workers when worker
+	// output is written to files.
+	FailedWorkerLogs []string `json:"failedWorkerLogs"`
+	// Workers lists every batch of test files run by a worker. Only the JSON
+	// run report includes it.
+	Workers []workerRun `json:"workers"`
+}
+
+type retryReport struct {
+	MaxRetries       int      `json:"maxRetries"`
+	RetriedTestFiles []string `json:"retriedTestFiles"`
+	PassedOnRetry    []string `json:"passedOnRetry"`
 }
 
 type runReport struct {
@@ -49,11 +73,26 @@ func printRunInfoReport(w io.Writer, runInfo runmetadata.RunInfo, planMetadata p
 func printExecutionReport(w io.Writer, report runReport) {
 	reportFprintln(w, "Execution")
 	reportFprintf(w, "  Mode: %s\n", valueOrNotAvailable(report.Execution.Mode))
-	if report.Execution.Mode == constants.RunModeCINode {
+	if report.Execution.Mode == constants.RunModeCINode || report.Execution.Mode == runModeQueue {
 		reportFprintf(w, "  CI node: %d\n", report.Execution.CINode)
 	}
+	if report.Execution.QueueURL != "" {
+		reportFprintf(w, "  Queue: %s\n", report.Execution.QueueURL)
+	}
 	reportFprintf(w, "  Local workers: %s\n", formatCount(report.Execution.LocalWorkers))
+	if report.Execution.WorkQueue {
+		reportFprintln(w, "  Scheduling: work queue")
+	}
 	reportFprintf(w, "  Test files run: %s\n", formatCount(report.Execution.TestFilesRun))
+	printRetryReport(w, report.Execution.Retries)
+	printFailFastReport(w, report.Execution.FailFast)
+	printWorkerTimeoutReport(w, report.Execution.WorkerTimeouts)
+	if report.Execution.JUnitDurationsRecorded > 0 {
+		reportFprintf(w, "  Test file durations recorded: %s\n", formatCount(report.Execution.JUnitDurationsRecorded))
+	}
+	if report.Execution.DurationHistoryRecorded > 0 {
+		reportFprintf(w, "  Duration history entries added: %s\n", formatCount(report.Execution.DurationHistoryRecorded))
+	}
 	reportFprintf(w, "  Duration: %s\n", formatDuration(report.Duration))
 	if report.Err == nil {
 		reportFprintln(w, "  Result: passed")
@@ -61,6 +100,55 @@ func printExecutionReport(w io.Writer, report runReport) {
 	}
 	reportFprintln(w, "  Result: failed")
 	reportFprintf(w, "  Error: %s\n", report.Err)
+	if len(report.Execution.FailedWorkerLogs) > 0 {
+		reportFprintln(w, "  Failed worker logs:")
+		for _, path := range report.Execution.FailedWorkerLogs {
+			reportFprintf(w, "    %s\n", path)
+		}
+	}
+}
+
+func printRetryReport(w io.Writer, retries retryReport) {
+	if retries.MaxRetries == 0 {
+		return
+	}
+	reportFprintf(w, "  Retries per failed file: %d\n", retries.MaxRetries)
+	reportFprintf(w, "  Test files retried: %s\n", formatCount(len(retries.RetriedTestFiles)))
+	reportFprintf(w, "  Passed on retry: %s\n", formatCount(len(retries.PassedOnRetry)))
+	for _, testFile := range retries.PassedOnRetry {
+		reportFprintf(w, "    %s\n", testFile)
+	}
+}
+
+func printFailFastReport(w io.Writer, failFast failFastReport) {
+	if !failFast.Enabled {
+		return
+	}
+	reportFprintln(w, "  Fail fast: enabled")
+	if len(failFast.CancelledWorkers) == 0 {
+		return
+	}
+	reportFprintf(w, "  Cancelled workers: %s\n", formatCount(len(failFast.CancelledWorkers)))
+	for _, worker := range failFast.CancelledWorkers {
+		reportFprintf(w, "    %s\n", worker)
+	}
+	reportFprintf(w, "  Test files not run: %s\n", formatCount(len(failFast.NotRunTestFiles)))
+	for _, testFile := range failFast.NotRunTestFiles {
+		reportFprintf(w, "    %s\n", testFile)
+	}
+}
+
+func printWorkerTimeoutReport(w io.Writer, timedOut []timedOutBatch) {
+	if len(timedOut) == 0 {
+		return
+	}
+	reportFprintf(w, "  Timed out workers: %s\n", formatCount(len(timedOut)))
+	for _, batch := range timedOut {
+		reportFprintf(w, "    %s after %s, in flight:\n", batch.Worker, formatDuration(batch.Timeout))
+		for _, testFile := range batch.TestFiles {
+			reportFprintf(w, "      %s\n", testFile)
+		}
+	}
 }
 
 func reportFprintln(w io.Writer, args ...any) {
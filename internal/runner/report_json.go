@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/errcode"
+	"github.com/DataDog/ddtest/internal/planner"
+	"github.com/DataDog/ddtest/internal/runmetadata"
+)
+
+// runJSONReport is the machine-readable form of the run report.
+type runJSONReport struct {
+	RunInfo      runmetadata.RunInfo  `json:"runInfo"`
+	PlanMetadata planner.PlanMetadata `json:"planMetadata"`
+	Execution    runExecutionReport   `json:"execution"`
+	StartTime    time.Time            `json:"startTime"`
+	EndTime      time.Time            `json:"endTime"`
+	DurationMs   int64                `json:"durationMs"`
+	// Result is "passed" or "failed".
+	Result    string       `json:"result"`
+	ErrorCode errcode.Code `json:"errorCode"`
+	Error     string       `json:"error,omitempty"`
+}
+
+func defaultRunReportPath(ciNode int) string {
+	return filepath.Join(constants.PlanDirectory, fmt.Sprintf("run-report-node-%d.json", max(ciNode, 0)))
+}
+
+func newRunJSONReport(report runReport, startTime time.Time) runJSONReport {
+	jsonReport := runJSONReport{
+		RunInfo:      report.RunInfo,
+		PlanMetadata: report.PlanMetadata,
+		Execution:    report.Execution,
+		StartTime:    startTime,
+		EndTime:      startTime.Add(report.Duration),
+		DurationMs:   report.Duration.Milliseconds(),
+		Result:       "passed",
+		ErrorCode:    errcode.CodeOf(report.Err),
+	}
+	if report.Err != nil {
+		jsonReport.Result = "failed"
+		jsonReport.Error = report.Err.Error()
+	}
+	return jsonReport
+}
+
+// writeRunJSONReport writes the JSON run report to path, creating its
+// directory when needed.
+func writeRunJSONReport(path string, report runJSONReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run report: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create run report directory %s: %w", dir, err)
+		}
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write run report %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/errcode"
+	"github.com/DataDog/ddtest/internal/ext"
+	"github.com/DataDog/ddtest/internal/planner"
+	"github.com/DataDog/ddtest/internal/settings"
+	"github.com/spf13/viper"
+)
+
+func TestWriteRunJSONReport(t *testing.T) {
+	chdirTemp(t)
+
+	startTime := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	report := newRunJSONReport(runReport{
+		Execution: runExecutionReport{
+			Mode:         runModeParallel,
+			LocalWorkers: 2,
+			TestFilesRun: 2,
+			WorkerTimeouts: []timedOutBatch{
+				{Worker: "node 0 / worker 1", Timeout: 90 * time.Second, TestFiles: []string{"spec/b_spec.rb"}},
+			},
+			Workers: []workerRun{
+				{Worker: "node 0 / worker 0", TestFiles: []string{"spec/a_spec.rb"}, StartTime: startTime, EndTime: startTime.Add(time.Second), DurationMs: 1000},
+				{Worker: "node 0 / worker 1", TestFiles: []string{"spec/b_spec.rb"}, StartTime: startTime, EndTime: startTime.Add(time.Second), DurationMs: 1000,
+					ExitCode: 1, Error: "exit status 1", ErrorCode: string(errcode.Unknown)},
+			},
+		},
+		Duration: 2 * time.Second,
+		Err:      errcode.New(errcode.RunParallelTestsFailed, "tests failed"),
+	}, startTime)
+
+	path := defaultRunReportPath(-1)
+	if path != filepath.Join(constants.PlanDirectory, "run-report-node-0.json") {
+		t.Fatalf("defaultRunReportPath(-1) = %q", path)
+	}
+	if err := writeRunJSONReport(path, report); err != nil {
+		t.Fatalf("writeRunJSONReport() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("run report is not valid JSON: %v\n%s", err, data)
+	}
+	if decoded["result"] != "failed" || decoded["errorCode"] != string(errcode.RunParallelTestsFailed) {
+		t.Fatalf("unexpected result fields in %s", data)
+	}
+	if decoded["endTime"] != "2026-03-04T10:00:02Z" || decoded["durationMs"] != float64(2000) {
+		t.Fatalf("unexpected timing fields in %s", data)
+	}
+	execution := decoded["execution"].(map[string]any)
+	timeouts := execution["workerTimeouts"].([]any)
+	if timeouts[0].(map[string]any)["timeoutMs"] != float64(90000) {
+		t.Fatalf("expected worker timeout in milliseconds, got %s", data)
+	}
+	workers := execution["workers"].([]any)
+	if len(workers) != 2 || workers[0].(map[string]any)["worker"] != "node 0 / worker 0" {
+		t.Fatalf("unexpected workers in %s", data)
+	}
+	if _, ok := workers[0].(map[string]any)["errorCode"]; ok {
+		t.Fatalf("expected no error code for the passed worker run in %s", data)
+	}
+	if workers[1].(map[string]any)["errorCode"] != string(errcode.Unknown) {
+		t.Fatalf("expected the error code of the failed worker run in %s", data)
+	}
+}
+
+func TestWorkerRuns_RecordsErrorCode(t *testing.T) {
+	runs := &workerRuns{}
+	runs.record(0, 0, []string{"spec/a_spec.rb"}, time.Now(), nil)
+	runs.record(0, 1, []string{"spec/b_spec.rb"}, time.Now(), errors.New("exit status 1"))
+	runs.record(0, 2, []string{"spec/c_spec.rb"}, time.Now(), errcode.New(errcode.RunWorkerLogsDirCreateFailed, "failed to open worker log"))
+
+	var codes []string
+	for _, run := range runs.report() {
+		codes = append(codes, run.ErrorCode)
+	}
+	want := []string{"", string(errcode.Unknown), string(errcode.RunWorkerLogsDirCreateFailed)}
+	if !slices.Equal(codes, want) {
+		t.Fatalf("worker run error codes = %v, want %v", codes, want)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if got := exitCode(nil); got != 0 {
+		t.Fatalf("exitCode(nil) = %d, want 0", got)
+	}
+	if got := exitCode(errors.New("failed to start")); got != -1 {
+		t.Fatalf("exitCode(start error) = %d, want -1", got)
+	}
+
+	err := exec.Command("sh", "-c", "exit 3").Run()
+	if got := exitCode(&ext.TimeoutError{Err: err}); got != 3 {
+		t.Fatalf("exitCode(wrapped exit error) = %d, want 3", got)
+	}
+}
+
+func TestTestRunner_Run_WritesJSONRunReport(t *testing.T) {
+	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_REPORT_ENABLED", "false")
+	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_CI_NODE", "-1")
+	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_RUN_REPORT_PATH", filepath.Join("reports", "run.json"))
+	viper.Reset()
+	settings.Init()
+	t.Cleanup(func() {
+		viper.Reset()
+		settings.Init()
+	})
+	chdirTemp(t)
+	writeRunnerTestFile(t, constants.ParallelRunnersOutputPath, "1")
+	writeRunnerTestFile(t, constants.TestFilesOutputPath, "spec/a_spec.rb\nspec/b_spec.rb\n")
+
+	framework := &MockFramework{FrameworkName: "rspec"}
+	platform := &MockPlatform{PlatformName: "ruby", Framework: framework}
+	testPlanner := &fakePlanner{plan: planner.PlanMetadata{Platform: "ruby", Framework: "rspec"}}
+	runner := NewWithDependencies(&MockPlatformDetector{Platform: platform}, testPlanner)
+
+	if err := runner.Run(context.Background()); err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join("reports", "run.json"))
+	if err != nil {
+		t.Fatalf("expected JSON run report: %v", err)
+	}
+	var report runJSONReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("run report is not valid JSON: %v", err)
+	}
+	if report.Result != "passed" || report.ErrorCode != errcode.None {
+		t.Fatalf("unexpected result %q with error code %q", report.Result, report.ErrorCode)
+	}
+	if report.Execution.Mode != runModeSequential || len(report.Execution.Workers) != 1 {
+		t.Fatalf("unexpected execution report %+v", report.Execution)
+	}
+	worker := report.Execution.Workers[0]
+	if worker.Worker != "node 0 / worker 0" || worker.ExitCode != 0 || !slices.Equal(worker.TestFiles, []string{"spec/a_spec.rb", "spec/b_spec.rb"}) {
+		t.Fatalf("unexpected worker run %+v", worker)
+	}
+	if worker.EndTime.Before(worker.StartTime) {
+		t.Fatalf("worker run ends before it starts: %+v", worker)
+	}
+}
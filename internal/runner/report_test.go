@@ -86,6 +86,23 @@ func TestPrintRunReport_Failed(t *testing.T) {
 	}
 }
 
+func TestPrintRunReport_WorkQueue(t *testing.T) {
+	var output strings.Builder
+
+	printRunReport(&output, runReport{
+		Execution: runExecutionReport{
+			Mode:         runModeParallel,
+			LocalWorkers: 4,
+			TestFilesRun: 12,
+			WorkQueue:    true,
+		},
+	})
+
+	if !strings.Contains(output.String(), "  Local workers: 4\n  Scheduling: work queue\n") {
+		t.Errorf("expected work queue scheduling in run report, got:\n%s", output.String())
+	}
+}
+
 func TestFormatPlatform(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -128,3 +145,101 @@ func TestFormatCount(t *testing.T) {
 		})
 	}
 }
+
+func TestPrintRunReport_Retries(t *testing.T) {
+	var output strings.Builder
+
+	printRunReport(&output, runReport{
+		Execution: runExecutionReport{
+			Mode:         runModeSequential,
+			LocalWorkers: 1,
+			TestFilesRun: 3,
+			Retries: retryReport{
+				MaxRetries:       2,
+				RetriedTestFiles: []string{"spec/a_spec.rb", "spec/b_spec.rb"},
+				PassedOnRetry:    []string{"spec/b_spec.rb"},
+			},
+		},
+	})
+
+	expected := "  Test files run: 3\n  Retries per failed file: 2\n  Test files retried: 2\n  Passed on retry: 1\n    spec/b_spec.rb\n"
+	if !strings.Contains(output.String(), expected) {
+		t.Errorf("expected retried files in run report, got:\n%s", output.String())
+	}
+}
+
+func TestPrintRunReport_JUnitDurationsRecorded(t *testing.T) {
+	var output strings.Builder
+
+	printRunReport(&output, runReport{
+		Execution: runExecutionReport{
+			Mode:                   runModeSequential,
+			LocalWorkers:           1,
+			TestFilesRun:           3,
+			JUnitDurationsRecorded: 3,
+		},
+	})
+
+	expected := "  Test files run: 3\n  Test file durations recorded: 3\n"
+	if !strings.Contains(output.String(), expected) {
+		t.Errorf("expected recorded durations in run report, got:\n%s", output.String())
+	}
+}
+
+func TestPrintRunReport_DurationHistoryRecorded(t *testing.T) {
+	var output strings.Builder
+
+	printRunReport(&output, runReport{
+		Execution: runExecutionReport{
+			Mode:                    runModeSequential,
+			LocalWorkers:            1,
+			TestFilesRun:            3,
+			DurationHistoryRecorded: 3,
+		},
+	})
+
+	if !strings.Contains(output.String(), "  Test files run: 3\n  Duration history entries added: 3\n") {
+		t.Errorf("expected duration history entries in run report, got:\n%s", output.String())
+	}
+}
+
+func TestPrintRunReport_FailedWorkerLogs(t *testing.T) {
+	var output strings.Builder
+
+	printRunReport(&output, runReport{
+		Execution: runExecutionReport{
+			Mode:             runModeParallel,
+			LocalWorkers:     2,
+			FailedWorkerLogs: []string{".testoptimization/logs/node-0-worker-1.log"},
+		},
+		Err: errors.New("tests failed"),
+	})
+
+	expected := "  Error: tests failed\n  Failed worker logs:\n    .testoptimization/logs/node-0-worker-1.log\n"
+	if !strings.HasSuffix(output.String(), expected) {
+		t.Errorf("expected failed worker logs in run report, got:\n%s", output.String())
+	}
+}
+
+func TestPrintRunReport_FailFast(t *testing.T) {
+	var output strings.Builder
+
+	printRunReport(&output, runReport{
+		Execution: runExecutionReport{
+			Mode:         runModeParallel,
+			LocalWorkers: 2,
+			TestFilesRun: 3,
+			FailFast: failFastReport{
+				Enabled:          true,
+				CancelledWorkers: []string{"node 0 / worker 1"},
+				NotRunTestFiles:  []string{"spec/b_spec.rb", "spec/c_spec.rb"},
+			},
+		},
+		Err: errors.New("tests failed"),
+	})
+
+	expected := "  Test files run: 3\n  Fail fast: enabled\n  Cancelled workers: 1\n    node 0 / worker 1\n  Test files not run: 2\n    spec/b_spec.rb\n    spec/c_spec.rb\n"
+	if !strings.Contains(output.String(), expected) {
+		t.Errorf("expected cancelled workers in run report, got:\n%s", output.String())
+	}
+}
@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/ddtest/internal/framework"
+)
+
+// testFileRetries records the outcome of file retries across every worker of
+// a run, so the run report can list the files that only passed on retry.
+type testFileRetries struct {
+	mu            sync.Mutex
+	retried       []string
+	passedOnRetry []string
+}
+
+func (r *testFileRetries) record(testFile string, passed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.retried = append(r.retried, testFile)
+	if passed {
+		r.passedOnRetry = append(r.passedOnRetry, testFile)
+	}
+}
+
+func (r *testFileRetries) report(maxRetries int) retryReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := retryReport{
+		MaxRetries:       maxRetries,
+		RetriedTestFiles: slices.Clone(r.retried),
+		PassedOnRetry:    slices.Clone(r.passedOnRetry),
+	}
+	slices.Sort(report.RetriedTestFiles)
+	slices.Sort(report.PassedOnRetry)
+	return report
+}
+
+// withRetryFailedFiles makes workers re-run the test files of a failed batch
+// one at a time, up to maxRetries times each, before reporting the failure.
+func (e testExecutor) withRetryFailedFiles(maxRetries int) testExecutor {
+	e.maxFileRetries = max(maxRetries, 0)
+	e.retries = &testFileRetries{}
+	return e
+}
+
+func (e testExecutor) retryReport() retryReport {
+	if e.retries == nil {
+		return retryReport{}
+	}
+	return e.retries.report(e.maxFileRetries)
+}
+
+// retryFailedTestFiles re-runs each failed file of a batch in isolation. The
+// batch only fails if at least one file keeps failing after every retry.
+func (e testExecutor) retryFailedTestFiles(testFiles []string, batchErr error, workerEnv map[string]string, nodeIndex int, workerIndex int) error {
+	failedFiles := failedTestFiles(testFiles, batchErr)
+	slog.Warn("Test batch failed, retrying test files individually",
+		"nodeIndex", nodeIndex, "workerIndex", workerIndex, "testFilesCount", len(failedFiles), "maxRetries", e.maxFileRetries, "error", batchErr)
+
+	var stillFailing []string
+	for _, testFile := range failedFiles {
+		passed := false
+		for attempt := 1; attempt <= e.maxFileRetries && !passed; attempt++ {
+			if e.ctx.Err() != nil {
+				return batchErr
+			}
+			slog.Info("Retrying test file", "nodeIndex", nodeIndex, "workerIndex", workerIndex, "testFile", testFile, "attempt", attempt)
+			passed = e.runTests([]string{testFile}, workerEnv, nodeIndex, workerIndex) == nil
+		}
+		e.retries.record(testFile, passed)
+		if !passed {
+			stillFailing = append(stillFailing, testFile)
+		}
+	}
+
+	if len(stillFailing) > 0 {
+		return fmt.Errorf("%d test files failed after %d retries (%s): %w", len(stillFailing), e.maxFileRetries, strings.Join(stillFailing, ", "), batchErr)
+	}
+	slog.Info("Failed test files passed on retry", "nodeIndex", nodeIndex, "workerIndex", workerIndex, "testFilesCount", len(failedFiles))
+	return nil
+}
+
+// failedTestFiles returns the files of the batch that the framework reported
+// as failed, or the whole batch when it could not tell which files failed.
+func failedTestFiles(testFiles []string, batchErr error) []string {
+	var filesErr *framework.TestFilesFailedError
+	if !errors.As(batchErr, &filesErr) {
+		return testFiles
+	}
+
+	failed := make([]string, 0, len(filesErr.TestFiles))
+	for _, testFile := range testFiles {
+		if slices.Contains(filesErr.TestFiles, testFile) {
+			failed = append(failed, testFile)
+		}
+	}
+	if len(failed) == 0 {
+		return testFiles
+	}
+	return failed
+}
@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/framework"
+)
+
+// failingFilesFramework fails any batch that contains a file with remaining
+// failures and consumes one failure per run of that file.
+func failingFilesFramework(failures map[string]int, batchErr func(failed []string) error) *MockFramework {
+	return &MockFramework{
+		FrameworkName: "rspec",
+		RunTestsFunc: func(testFiles []string) error {
+			var failed []string
+			for _, testFile := range testFiles {
+				if failures[testFile] > 0 {
+					failures[testFile]--
+					failed = append(failed, testFile)
+				}
+			}
+			if len(failed) == 0 {
+				return nil
+			}
+			return batchErr(failed)
+		},
+	}
+}
+
+func runTestsCallFiles(framework *MockFramework) [][]string {
+	var calls [][]string
+	for _, call := range framework.GetRunTestsCalls() {
+		calls = append(calls, call.TestFiles)
+	}
+	return calls
+}
+
+func TestRunBatch_RetriesEveryFileOfFailedBatch(t *testing.T) {
+	mockFramework := failingFilesFramework(map[string]int{"spec/b_spec.rb": 2}, func([]string) error {
+		return errors.New("tests failed")
+	})
+	executor := newTestExecutor(context.Background(), mockFramework, map[string]string{}, roundRobinTestPlanner{}).withRetryFailedFiles(2)
+
+	if err := executor.runBatch([]string{"spec/a_spec.rb", "spec/b_spec.rb", "spec/c_spec.rb"}, 0, 0); err != nil {
+		t.Fatalf("runBatch() error = %v", err)
+	}
+
+	want := [][]string{
+		{"spec/a_spec.rb", "spec/b_spec.rb", "spec/c_spec.rb"},
+		{"spec/a_spec.rb"},
+		{"spec/b_spec.rb"},
+		{"spec/b_spec.rb"},
+		{"spec/c_spec.rb"},
+	}
+	if calls := runTestsCallFiles(mockFramework); !slices.EqualFunc(calls, want, slices.Equal) {
+		t.Fatalf("RunTests() calls = %v, want %v", calls, want)
+	}
+
+	report := executor.retryReport()
+	if report.MaxRetries != 2 || len(report.RetriedTestFiles) != 3 {
+		t.Fatalf("unexpected retry report: %+v", report)
+	}
+	if !slices.Equal(report.PassedOnRetry, []string{"spec/a_spec.rb", "spec/b_spec.rb", "spec/c_spec.rb"}) {
+		t.Fatalf("PassedOnRetry = %v", report.PassedOnRetry)
+	}
+}
+
+func TestRunBatch_RetriesOnlyReportedFailedFiles(t *testing.T) {
+	mockFramework := failingFilesFramework(map[string]int{"spec/b_spec.rb": 1}, func(failed []string) error {
+		return &framework.TestFilesFailedError{TestFiles: failed, Err: errors.New("tests failed")}
+	})
+	executor := newTestExecutor(context.Background(), mockFramework, map[string]string{}, roundRobinTestPlanner{}).withRetryFailedFiles(1)
+
+	if err := executor.runBatch([]string{"spec/a_spec.rb", "spec/b_spec.rb"}, 0, 0); err != nil {
+		t.Fatalf("runBatch() error = %v", err)
+	}
+
+	want := [][]string{{"spec/a_spec.rb", "spec/b_spec.rb"}, {"spec/b_spec.rb"}}
+	if calls := runTestsCallFiles(mockFramework); !slices.EqualFunc(calls, want, slices.Equal) {
+		t.Fatalf("RunTests() calls = %v, want %v", calls, want)
+	}
+	if report := executor.retryReport(); !slices.Equal(report.PassedOnRetry, []string{"spec/b_spec.rb"}) {
+		t.Fatalf("PassedOnRetry = %v, want only the reported file", report.PassedOnRetry)
+	}
+}
+
+func TestRunBatch_FailsWhenRetriesKeepFailing(t *testing.T) {
+	batchErr := errors.New("tests failed")
+	mockFramework := failingFilesFramework(map[string]int{"spec/a_spec.rb": 1, "spec/b_spec.rb": 3}, func([]string) error {
+		return batchErr
+	})
+	executor := newTestExecutor(context.Background(), mockFramework, map[string]string{}, roundRobinTestPlanner{}).withRetryFailedFiles(2)
+
+	err := executor.runBatch([]string{"spec/a_spec.rb", "spec/b_spec.rb"}, 0, 0)
+	if !errors.Is(err, batchErr) {
+		t.Fatalf("runBatch() error = %v, want wrapped batch error", err)
+	}
+	if !strings.Contains(err.Error(), "1 test files failed after 2 retries (spec/b_spec.rb)") {
+		t.Fatalf("runBatch() error = %v, want still failing file", err)
+	}
+	if count := mockFramework.GetRunTestsCallsCount(); count != 4 {
+		t.Fatalf("expected batch run, one retry of a and two of b, got %d calls", count)
+	}
+
+	report := executor.retryReport()
+	if !slices.Equal(report.RetriedTestFiles, []string{"spec/a_spec.rb", "spec/b_spec.rb"}) || !slices.Equal(report.PassedOnRetry, []string{"spec/a_spec.rb"}) {
+		t.Fatalf("unexpected retry report: %+v", report)
+	}
+}
+
+func TestRunBatch_DoesNotRetryByDefault(t *testing.T) {
+	mockFramework := &MockFramework{FrameworkName: "rspec", Err: errors.New("tests failed")}
+	executor := newTestExecutor(context.Background(), mockFramework, map[string]string{}, roundRobinTestPlanner{})
+
+	if err := executor.runBatch([]string{"spec/a_spec.rb", "spec/b_spec.rb"}, 0, 0); err == nil {
+		t.Fatal("expected runBatch() to fail")
+	}
+	if count := mockFramework.GetRunTestsCallsCount(); count != 1 {
+		t.Fatalf("expected no retries, got %d calls", count)
+	}
+	if report := executor.retryReport(); report.MaxRetries != 0 || len(report.RetriedTestFiles) != 0 {
+		t.Fatalf("expected empty retry report, got %+v", report)
+	}
+}
+
+func TestTestRunner_Run_RetriesFailedFiles(t *testing.T) {
+	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_RETRY_FAILED_FILES", "1")
+	withRunnerTestSettings(t)
+	chdirTemp(t)
+	writeRunnerTestFile(t, constants.ParallelRunnersOutputPath, "1")
+	writeRunnerTestFile(t, constants.TestFilesOutputPath, "spec/a_spec.rb\nspec/b_spec.rb\n")
+
+	mockFramework := failingFilesFramework(map[string]int{"spec/b_spec.rb": 1}, func([]string) error {
+		return errors.New("tests failed")
+	})
+	platform := &MockPlatform{PlatformName: "ruby", Framework: mockFramework}
+	runner := NewWithDependencies(&MockPlatformDetector{Platform: platform}, &fakePlanner{})
+
+	if err := runner.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := [][]string{{"spec/a_spec.rb", "spec/b_spec.rb"}, {"spec/a_spec.rb"}, {"spec/b_spec.rb"}}
+	if calls := runTestsCallFiles(mockFramework); !slices.EqualFunc(calls, want, slices.Equal) {
+		t.Fatalf("RunTests() calls = %v, want %v", calls, want)
+	}
+}
+
+func TestTestRunner_Run_FailFastSkipsRetries(t *testing.T) {
+	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_RETRY_FAILED_FILES", "1")
+	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_FAIL_FAST", "true")
+	withRunnerTestSettings(t)
+	chdirTemp(t)
+	writeRunnerTestFile(t, constants.ParallelRunnersOutputPath, "1")
+	writeRunnerTestFile(t, constants.TestFilesOutputPath, "spec/a_spec.rb\nspec/b_spec.rb\n")
+
+	mockFramework := failingFilesFramework(map[string]int{"spec/b_spec.rb": 1}, func([]string) error {
+		return errors.New("tests failed")
+	})
+	platform := &MockPlatform{PlatformName: "ruby", Framework: mockFramework}
+	runner := NewWithDependencies(&MockPlatformDetector{Platform: platform}, &fakePlanner{})
+
+	if err := runner.Run(context.Background()); err == nil {
+		t.Fatal("expected Run() to fail without retries")
+	}
+	want := [][]string{{"spec/a_spec.rb", "spec/b_spec.rb"}}
+	if calls := runTestsCallFiles(mockFramework); !slices.EqualFunc(calls, want, slices.Equal) {
+		t.Fatalf("RunTests() calls = %v, want %v", calls, want)
+	}
+}
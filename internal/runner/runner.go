@@ -2,6 +2,7 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -12,8 +13,10 @@ import (
 	"github.com/DataDog/ddtest/internal/constants"
 	ciUtils "github.com/DataDog/ddtest/internal/environment"
 	"github.com/DataDog/ddtest/internal/errcode"
+	"github.com/DataDog/ddtest/internal/ext"
 	"github.com/DataDog/ddtest/internal/planner"
 	"github.com/DataDog/ddtest/internal/platform"
+	"github.com/DataDog/ddtest/internal/queue"
 	"github.com/DataDog/ddtest/internal/runmetadata"
 	"github.com/DataDog/ddtest/internal/settings"
 	"github.com/DataDog/ddtest/internal/telemetry"
@@ -27,6 +30,8 @@ type Planner interface {
 	Plan(ctx context.Context) error
 	LoadPlan() (planner.PlanMetadata, error)
 	DistributeTestFiles(testFiles []string, parallelRunners int) [][]string
+	TestFileWeights(testFiles []string) map[string]int
+	TestFileConstraints() planner.TestFileConstraints
 }
 
 type TestRunner struct {
@@ -106,6 +111,9 @@ func (tr *TestRunner) Run(ctx context.Context) error {
 	slog.Info("Platform detected", "platform", detectedPlatform.Name())
 
 	framework, err := detectedPlatform.DetectFramework()
+	if errors.Is(err, platform.ErrFrameworksOfSeveralPlatforms) {
+		return errcode.WithCode(errcode.RunFrameworkPlatformMismatch, fmt.Errorf("failed to detect framework: %w", err))
+	}
 	if err != nil {
 		return errcode.WithCode(errcode.RunFrameworkDetectionFailed, fmt.Errorf("failed to detect framework: %w", err))
 	}
@@ -120,30 +128,102 @@ func (tr *TestRunner) Run(ctx context.Context) error {
 		planMetadata = planner.NewPlanMetadata(nil, detectedPlatform.Name(), framework.Name(), detectedPlatform.TestSkippingLevel())
 	}
 
+	// In queue mode the CI node only identifies this node to the queue, so it
+	// is not detected from the CI environment.
 	ciNode := settings.GetCiNode()
+	if settings.GetQueueURL() == "" {
+		if ciNode, err = resolveCINode(parallelRunners); err != nil {
+			return err
+		}
+	}
 	startTime := time.Now()
 	executor := newTestExecutor(ctx, framework, workerEnvMap, tr.planner)
+	if settings.GetWorkQueue() {
+		executor = executor.withWorkQueue(settings.GetWorkQueueBatchSize())
+	}
+	if len(settings.GetSplitOrder()) > 0 {
+		executor = executor.withSplitOrder()
+	}
+	// With fail-fast, a failed batch stops its siblings right away, so it is
+	// not retried first.
+	if retries := settings.GetRetryFailedFiles(); retries > 0 && settings.GetFailFast() {
+		slog.Warn("Ignoring --retry-failed-files because --fail-fast is set", "retryFailedFiles", retries)
+	} else if retries > 0 {
+		executor = executor.withRetryFailedFiles(retries)
+	}
+	if settings.GetFailFast() {
+		executor = executor.withFailFast()
+	}
+	if timeout, multiplier := settings.GetWorkerTimeout(), settings.GetWorkerTimeoutMultiplier(); timeout > 0 || multiplier > 0 {
+		diagnosticSignal, _ := ext.SignalByName(settings.GetWorkerTimeoutSignal())
+		executor = executor.withWorkerTimeout(timeout, multiplier, diagnosticSignal)
+	}
+	if outputMode := settings.GetWorkerOutput(); outputMode != settings.WorkerOutputStream {
+		if outputMode == settings.WorkerOutputFile {
+			if err := os.MkdirAll(constants.WorkerLogsDir, 0o755); err != nil {
+				return errcode.WithCode(errcode.RunWorkerLogsDirCreateFailed, fmt.Errorf("failed to create worker logs directory %s: %w", constants.WorkerLogsDir, err))
+			}
+		}
+		executor = executor.withWorkerOutput(newWorkerOutput(outputMode, os.Stdout, os.Stderr))
+	}
 	var executionResult runExecutionResult
-	if ciNode >= 0 {
-		executionResult = executor.runCINode(ciNode, settings.GetCiNodeWorkers())
+	if queueURL := settings.GetQueueURL(); queueURL != "" {
+		executionResult = runFromQueue(executor, queueURL, ciNode, resolveCINodeWorkers(ciNode))
+	} else if ciNode >= 0 {
+		executionResult = executor.runCINode(ciNode, resolveCINodeWorkers(ciNode))
 	} else if parallelRunners > 1 {
 		executionResult = executor.runParallel()
 	} else {
 		executionResult = executor.runSequential()
 	}
+	executionResult.report.Retries = executor.retryReport()
+	executionResult.report.FailFast = executor.failFastReport()
+	executionResult.report.WorkerTimeouts = executor.workerTimeoutReport()
+	executionResult.report.FailedWorkerLogs = executor.output.failedWorkerLogs()
+	executionResult.report.Workers = executor.runs.report()
+	var junitDurations map[string]time.Duration
+	if pattern := settings.GetJUnitReports(); pattern != "" {
+		junitDurations = recordJUnitDurations(pattern, startTime, executionResult.report.Workers)
+		executionResult.report.JUnitDurationsRecorded = len(junitDurations)
+	}
+	if path := settings.GetDurationHistory(); path != "" {
+		executionResult.report.DurationHistoryRecorded = recordDurationHistory(path, junitDurations, time.Now())
+	}
 
+	report := runReport{
+		RunInfo:      runInfo,
+		PlanMetadata: planMetadata,
+		Execution:    executionResult.report,
+		Duration:     time.Since(startTime),
+		Err:          executionResult.err,
+	}
 	if settings.GetReportEnabled() {
-		printRunReport(tr.reportWriter, runReport{
-			RunInfo:      runInfo,
-			PlanMetadata: planMetadata,
-			Execution:    executionResult.report,
-			Duration:     time.Since(startTime),
-			Err:          executionResult.err,
-		})
+		printRunReport(tr.reportWriter, report)
+	}
+	reportPath := settings.GetRunReportPath()
+	if reportPath == "" {
+		reportPath = defaultRunReportPath(ciNode)
+	}
+	if err := writeRunJSONReport(reportPath, newRunJSONReport(report, startTime)); err != nil {
+		slog.Warn("Failed to write JSON run report", "error", err)
+	} else {
+		slog.Info("Wrote JSON run report", "path", reportPath)
 	}
 	return executionResult.err
 }
 
+// runFromQueue runs test files leased from the queue at queueURL. The CI node
+// index only identifies this node to the queue; it does not select a split.
+func runFromQueue(executor testExecutor, queueURL string, ciNode int, workers int) runExecutionResult {
+	nodeIndex := max(ciNode, 0)
+	client, err := queue.NewClient(queueURL)
+	if err != nil {
+		report := runExecutionReport{Mode: runModeQueue, CINode: nodeIndex, LocalWorkers: max(workers, 1), QueueURL: queueURL}
+		return report.failure(errcode.WithCode(errcode.RunQueueURLInvalid, err))
+	}
+	return executor.runQueue(client, nodeIndex, workers)
+}
+
 func readParallelRunnersCount() (int, error) {
 	runnersData, err := os.ReadFile(constants.ParallelRunnersOutputPath)
 	if err != nil {
@@ -3,6 +3,7 @@ package runner
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"slices"
@@ -12,6 +13,7 @@ import (
 	"github.com/DataDog/ddtest/internal/constants"
 	"github.com/DataDog/ddtest/internal/errcode"
 	"github.com/DataDog/ddtest/internal/planner"
+	"github.com/DataDog/ddtest/internal/platform"
 	"github.com/DataDog/ddtest/internal/settings"
 	"github.com/DataDog/ddtest/internal/telemetry"
 	"github.com/spf13/viper"
@@ -27,6 +29,8 @@ type fakePlanner struct {
 	loadErr               error
 	distributedTestFiles  [][]string
 	distributedWorkerNums []int
+	testFileWeights       map[string]int
+	constraints           planner.TestFileConstraints
 }
 
 func (f *fakePlanner) Plan(ctx context.Context) error {
@@ -52,6 +56,22 @@ func (f *fakePlanner) DistributeTestFiles(testFiles []string, parallelRunners in
 	return distributeRoundRobin(testFiles, parallelRunners)
 }
 
+func (f *fakePlanner) TestFileConstraints() planner.TestFileConstraints {
+	return f.constraints
+}
+
+func (f *fakePlanner) TestFileWeights(testFiles []string) map[string]int {
+	weights := make(map[string]int, len(testFiles))
+	for _, testFile := range testFiles {
+		weight, ok := f.testFileWeights[testFile]
+		if !ok {
+			weight = 1
+		}
+		weights[testFile] = weight
+	}
+	return weights
+}
+
 func TestNew(t *testing.T) {
 	if runner := New(); runner == nil {
 		t.Fatal("New() returned nil")
@@ -278,6 +298,19 @@ func TestTestRunner_Run_ReturnsFrameworkDetectionError(t *testing.T) {
 	}
 }
 
+func TestTestRunner_Run_RejectsFrameworksOfSeveralPlatforms(t *testing.T) {
+	withRunnerTestSettings(t)
+	chdirTemp(t)
+	writeRunnerTestFile(t, constants.ParallelRunnersOutputPath, "1")
+
+	frameworkErr := fmt.Errorf("%w: framework 'jest' belongs to platform 'javascript', not 'ruby'", platform.ErrFrameworksOfSeveralPlatforms)
+	mockPlatform := &MockPlatform{PlatformName: "ruby", FrameworkErr: frameworkErr}
+	runner := NewWithDependencies(&MockPlatformDetector{Platform: mockPlatform}, &fakePlanner{})
+
+	err := runner.Run(context.Background())
+	assertRunnerErrorCode(t, err, errcode.RunFrameworkPlatformMismatch)
+}
+
 func TestTestRunner_Run_WritesReportWhenEnabled(t *testing.T) {
 	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_REPORT_ENABLED", "true")
 	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_CI_NODE", "-1")
@@ -456,7 +489,7 @@ func TestRunCINodeWorkerGroupsSkipsEmptyGroups(t *testing.T) {
 	framework := &MockFramework{}
 	executor := newTestExecutor(context.Background(), framework, nil, roundRobinTestPlanner{})
 
-	err := executor.runCINodeWorkerGroups(0, [][]string{{}, {"spec/one_spec.rb"}})
+	err := executor.runCINodeWorkerGroups(0, [][]string{{}, {"spec/one_spec.rb"}}, nil)
 	if err != nil {
 		t.Fatalf("runCINodeWorkerGroups() returned error: %v", err)
 	}
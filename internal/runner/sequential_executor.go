@@ -29,7 +29,11 @@ func (e testExecutor) runSequential() runExecutionResult {
 		return report.success()
 	}
 
-	if err := e.runBatch(testFiles, 0, 0); err != nil {
+	testFiles, exclusive := e.withoutExclusiveTestFiles(testFiles)
+	if len(testFiles) > 0 {
+		err = e.runBatch(testFiles, 0, 0)
+	}
+	if err := e.runExclusiveTestFiles(exclusive, 0, err); err != nil {
 		return report.failure(errcode.WithCode(errcode.RunSequentialTestsFailed, fmt.Errorf("failed to run tests: %w", err)))
 	}
 	return report.success()
@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/errcode"
+	"github.com/DataDog/ddtest/internal/queue"
+	"github.com/DataDog/ddtest/internal/settings"
+)
+
+// queueDrainPeriod is how long `ddtest serve` keeps answering after every
+// batch is acknowledged, so nodes waiting for other leases see that the queue
+// is done instead of a refused connection.
+var queueDrainPeriod = 10 * time.Second
+
+// Serve loads the plan and serves its test files as a queue that `ddtest run
+// --queue-url` nodes lease batches from. It returns once every batch has been
+// acknowledged or ctx is cancelled.
+func (tr *TestRunner) Serve(ctx context.Context) error {
+	if _, err := os.Stat(constants.ParallelRunnersOutputPath); os.IsNotExist(err) {
+		slog.Info("Test optimization planning data not found, running planning phase...")
+		if err := tr.planner.Plan(ctx); err != nil {
+			return errcode.WithCode(errcode.ServePlanningFailed, fmt.Errorf("failed to run planning phase: %w", err))
+		}
+	} else if err != nil {
+		return errcode.WithCode(errcode.ServePlanStatusCheckFailed, fmt.Errorf("failed to check parallel runners count at %s: %w", constants.ParallelRunnersOutputPath, err))
+	}
+
+	if _, err := tr.planner.LoadPlan(); err != nil {
+		return errcode.WithCode(errcode.ServePlanLoadFailed, fmt.Errorf("test optimization plan is not available: %w", err))
+	}
+
+	testFiles, err := loadTestBatch(constants.TestFilesOutputPath)
+	if err != nil {
+		return errcode.WithCode(errcode.ServeTestFilesReadFailed, fmt.Errorf("failed to read test files from %s: %w", constants.TestFilesOutputPath, err))
+	}
+	batches := queueBatches(tr.planner, testFiles, settings.GetWorkQueueBatchSize())
+	q := queue.New(batches, settings.GetQueueLeaseTimeout())
+
+	listenAddress := settings.GetQueueListen()
+	listener, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		return errcode.WithCode(errcode.ServeListenFailed, fmt.Errorf("failed to listen on %s: %w", listenAddress, err))
+	}
+
+	slog.Info("Loaded test queue", "testFilesCount", len(testFiles), "batches", len(batches), "leaseTimeout", settings.GetQueueLeaseTimeout())
+	err = queue.Serve(ctx, listener, q, queueDrainPeriod)
+	status := q.Status()
+	unacknowledged := status.PendingBatches + status.LeasedBatches
+	slog.Info("Test queue finished",
+		"completedBatches", status.CompletedBatches,
+		"failedBatches", status.FailedBatches,
+		"expiredLeases", status.ExpiredLeases,
+		"unacknowledgedBatches", unacknowledged)
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		if unacknowledged == 0 {
+			return nil
+		}
+		return errcode.WithCode(errcode.ServeQueueInterrupted, fmt.Errorf("test queue stopped with %d of %d batches unacknowledged: %w",
+			unacknowledged, status.TotalBatches, err))
+	}
+	if err != nil {
+		return errcode.WithCode(errcode.ServeQueueFailed, err)
+	}
+	return nil
+}
+
+// queueBatches returns the batches of testFiles to serve, heaviest first, with
+// the test files of each group of the plan in one batch. The exclusive test
+// files share a batch of their own, leased first, that the node leasing it
+// runs alone.
+func queueBatches(testPlanner testFilePlanner, testFiles []string, batchSize int) [][]string {
+	units, exclusive := testPlanner.TestFileConstraints().Partition(testFiles)
+	batches := queue.GroupedBatches(units, testPlanner.TestFileWeights(testFiles), batchSize)
+	if len(exclusive) > 0 {
+		batches = append([][]string{exclusive}, batches...)
+	}
+	return batches
+}
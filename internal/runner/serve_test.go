@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/errcode"
+	"github.com/DataDog/ddtest/internal/queue"
+)
+
+func withServeTestSettings(t *testing.T, listenAddress string) {
+	t.Helper()
+	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_QUEUE_LISTEN", listenAddress)
+	withRunnerTestSettings(t)
+
+	originalDrainPeriod := queueDrainPeriod
+	queueDrainPeriod = 0
+	t.Cleanup(func() { queueDrainPeriod = originalDrainPeriod })
+}
+
+func TestTestRunner_Serve_FinishesWhenQueueIsEmpty(t *testing.T) {
+	withServeTestSettings(t, "127.0.0.1:0")
+	chdirTemp(t)
+
+	testPlanner := &fakePlanner{
+		planFunc: func(ctx context.Context) error {
+			writeRunnerTestFile(t, constants.ParallelRunnersOutputPath, "1")
+			writeRunnerTestFile(t, constants.TestFilesOutputPath, "")
+			return nil
+		},
+	}
+	runner := NewWithDependencies(&MockPlatformDetector{}, testPlanner)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := runner.Serve(ctx); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	if testPlanner.planCalls != 1 || testPlanner.loadCalls != 1 {
+		t.Fatalf("expected Serve() to plan and load once, got plan=%d load=%d", testPlanner.planCalls, testPlanner.loadCalls)
+	}
+}
+
+func TestTestRunner_Serve_InterruptedBeforeQueueDrains(t *testing.T) {
+	withServeTestSettings(t, "127.0.0.1:0")
+	chdirTemp(t)
+	writeRunnerTestFile(t, constants.ParallelRunnersOutputPath, "2")
+	writeRunnerTestFile(t, constants.TestFilesOutputPath, "spec/a_spec.rb\nspec/b_spec.rb\n")
+
+	testPlanner := &fakePlanner{}
+	runner := NewWithDependencies(&MockPlatformDetector{}, testPlanner)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := runner.Serve(ctx)
+
+	assertRunnerErrorCode(t, err, errcode.ServeQueueInterrupted)
+	if testPlanner.planCalls != 0 {
+		t.Fatalf("expected existing plan to be reused, got %d Plan() calls", testPlanner.planCalls)
+	}
+}
+
+func TestTestRunner_Serve_Errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		listenAddress string
+		setup         func(t *testing.T) *fakePlanner
+		want          errcode.Code
+	}{
+		{
+			name:          "planning fails",
+			listenAddress: "127.0.0.1:0",
+			setup: func(t *testing.T) *fakePlanner {
+				return &fakePlanner{planFunc: func(context.Context) error { return errors.New("backend down") }}
+			},
+			want: errcode.ServePlanningFailed,
+		},
+		{
+			name:          "plan load fails",
+			listenAddress: "127.0.0.1:0",
+			setup: func(t *testing.T) *fakePlanner {
+				writeRunnerTestFile(t, constants.ParallelRunnersOutputPath, "1")
+				return &fakePlanner{loadErr: errors.New("corrupt cache")}
+			},
+			want: errcode.ServePlanLoadFailed,
+		},
+		{
+			name:          "test files missing",
+			listenAddress: "127.0.0.1:0",
+			setup: func(t *testing.T) *fakePlanner {
+				writeRunnerTestFile(t, constants.ParallelRunnersOutputPath, "1")
+				return &fakePlanner{}
+			},
+			want: errcode.ServeTestFilesReadFailed,
+		},
+		{
+			name:          "listen fails",
+			listenAddress: "127.0.0.1:-1",
+			setup: func(t *testing.T) *fakePlanner {
+				writeRunnerTestFile(t, constants.ParallelRunnersOutputPath, "1")
+				writeRunnerTestFile(t, constants.TestFilesOutputPath, "spec/a_spec.rb\n")
+				return &fakePlanner{}
+			},
+			want: errcode.ServeListenFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withServeTestSettings(t, test.listenAddress)
+			chdirTemp(t)
+
+			runner := NewWithDependencies(&MockPlatformDetector{}, test.setup(t))
+			assertRunnerErrorCode(t, runner.Serve(context.Background()), test.want)
+		})
+	}
+}
+
+func TestTestRunner_Run_LeasesFromQueue(t *testing.T) {
+	q := queue.New([][]string{{"spec/a_spec.rb"}, {"spec/b_spec.rb"}}, time.Minute)
+	client := newTestQueueClient(t, q)
+	t.Setenv("DD_TEST_OPTIMIZATION_RUNNER_QUEUE_URL", client.URL())
+	withRunnerTestSettings(t)
+	chdirTemp(t)
+	writeRunnerTestFile(t, constants.ParallelRunnersOutputPath, "2")
+
+	framework := &MockFramework{FrameworkName: "rspec"}
+	platform := &MockPlatform{PlatformName: "ruby", Framework: framework}
+	runner := NewWithDependencies(&MockPlatformDetector{Platform: platform}, &fakePlanner{})
+
+	if err := runner.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var ranFiles []string
+	for _, call := range framework.GetRunTestsCalls() {
+		ranFiles = append(ranFiles, call.TestFiles...)
+	}
+	slices.Sort(ranFiles)
+	if !slices.Equal(ranFiles, []string{"spec/a_spec.rb", "spec/b_spec.rb"}) {
+		t.Fatalf("expected runner to run leased files, got %v", ranFiles)
+	}
+}
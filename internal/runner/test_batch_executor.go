@@ -5,12 +5,17 @@ import (
 	"log/slog"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/DataDog/ddtest/internal/framework"
+	"github.com/DataDog/ddtest/internal/planner"
+	"github.com/DataDog/ddtest/internal/settings"
 )
 
 type testFilePlanner interface {
 	DistributeTestFiles(testFiles []string, parallelRunners int) [][]string
+	TestFileWeights(testFiles []string) map[string]int
+	TestFileConstraints() planner.TestFileConstraints
 }
 
 type testExecutor struct {
@@ -18,6 +23,22 @@ type testExecutor struct {
 	framework    framework.Framework
 	workerEnvMap map[string]string
 	planner      testFilePlanner
+	// workQueueBatchSize enables the shared work queue for local workers when
+	// it is positive.
+	workQueueBatchSize int
+	// keepSplitOrder makes workers run the test files of their runner split in
+	// the order of the split, which the planner ordered by split order.
+	keepSplitOrder bool
+	// maxFileRetries is how many times each file of a failed batch is re-run
+	// on its own; retries records the outcomes shared by every worker.
+	maxFileRetries int
+	retries        *testFileRetries
+	output         *workerOutput
+	// failFast is set when the first failed worker cancels its siblings.
+	failFast *failFastRun
+	// timeouts is set when test commands have a hang timeout.
+	timeouts *workerTimeouts
+	runs     *workerRuns
 }
 
 func newTestExecutor(ctx context.Context, framework framework.Framework, workerEnvMap map[string]string, planner testFilePlanner) testExecutor {
@@ -26,9 +47,50 @@ func newTestExecutor(ctx context.Context, framework framework.Framework, workerE
 		framework:    framework,
 		workerEnvMap: workerEnvMap,
 		planner:      planner,
+		output:       newWorkerOutput(settings.WorkerOutputStream, os.Stdout, os.Stderr),
+		runs:         &workerRuns{},
 	}
 }
 
+// withWorkerOutput routes the output of worker test processes through output.
+func (e testExecutor) withWorkerOutput(output *workerOutput) testExecutor {
+	e.output = output
+	return e
+}
+
+// withWorkQueue makes local workers pull batches of batchSize test files from
+// a shared queue instead of running static splits.
+func (e testExecutor) withWorkQueue(batchSize int) testExecutor {
+	e.workQueueBatchSize = max(batchSize, 1)
+	return e
+}
+
+// withSplitOrder makes workers keep the order of the test files of their
+// runner split, both in static sub-splits and in work queue batches.
+func (e testExecutor) withSplitOrder() testExecutor {
+	e.keepSplitOrder = true
+	return e
+}
+
+func (e testExecutor) workQueueEnabled() bool {
+	return e.workQueueBatchSize > 0
+}
+
+// newWorkQueue returns the work queue of testFiles, which keeps the test files
+// of each group of the plan in one batch. Batches hold the longest estimated
+// test files first, or follow the order of testFiles with a split order.
+func (e testExecutor) newWorkQueue(testFiles []string) *testFileQueue {
+	units, _ := e.planner.TestFileConstraints().Partition(testFiles)
+	var q *testFileQueue
+	if e.keepSplitOrder {
+		q = newOrderedTestFileQueue(units, e.workQueueBatchSize)
+	} else {
+		q = newGroupedTestFileQueue(units, e.planner.TestFileWeights(testFiles), e.workQueueBatchSize)
+	}
+	slog.Info("Created work queue", "testFilesCount", q.len(), "batchSize", q.batchSize)
+	return q
+}
+
 type runExecutionResult struct {
 	report runExecutionReport
 	err    error
@@ -43,11 +105,32 @@ func (r runExecutionReport) failure(err error) runExecutionResult {
 }
 
 // runBatch executes an already selected batch of test files in one worker.
-func (e testExecutor) runBatch(testFiles []string, nodeIndex int, workerIndex int) error {
+func (e testExecutor) runBatch(testFiles []string, nodeIndex int, workerIndex int) (err error) {
+	if e.cancelledByFailFast() {
+		e.failFast.recordNotRun(testFiles)
+		return e.ctx.Err()
+	}
+	startTime := time.Now()
+	defer func() { e.runs.record(nodeIndex, workerIndex, testFiles, startTime, err) }()
 	workerEnv := createWorkerEnv(e.workerEnvMap, nodeIndex, workerIndex)
 
+	capture, err := e.output.open(nodeIndex, workerIndex)
+	if err != nil {
+		return err
+	}
+	defer func() { capture.close(err) }()
+	e.ctx = capture.context(e.ctx)
+
 	slog.Info("Running tests in worker", "nodeIndex", nodeIndex, "workerIndex", workerIndex, "testFilesCount", len(testFiles), "workerEnvKeys", workerEnvKeys(workerEnv))
-	return e.framework.RunTests(e.ctx, testFiles, workerEnv)
+	err = e.runTests(testFiles, workerEnv, nodeIndex, workerIndex)
+	if err != nil && e.cancelledByFailFast() {
+		e.recordCancelledBatch(testFiles, nodeIndex, workerIndex)
+		return err
+	}
+	if err == nil || e.maxFileRetries == 0 || e.ctx.Err() != nil {
+		return err
+	}
+	return e.retryFailedTestFiles(testFiles, err, workerEnv, nodeIndex, workerIndex)
 }
 
 // loadTestBatch reads a file containing test file paths (one per line)
@@ -11,6 +11,7 @@ import (
 
 	"github.com/DataDog/ddtest/internal/discovery"
 	"github.com/DataDog/ddtest/internal/framework"
+	"github.com/DataDog/ddtest/internal/planner"
 	"github.com/DataDog/ddtest/internal/platform"
 	"github.com/DataDog/ddtest/internal/settings"
 	"github.com/DataDog/ddtest/internal/testoptimization"
@@ -77,6 +78,7 @@ type MockFramework struct {
 	Err                      error
 	DiscoverTestsErr         error
 	RunTestsCalls            []RunTestsCall
+	RunTestsFunc             func(testFiles []string) error
 	FullDiscoveryUnsupported bool
 	SuiteSourceFiles         map[string]string
 	UnskippableFiles         map[string]bool
@@ -120,6 +122,9 @@ func (m *MockFramework) RunTests(ctx context.Context, testFiles []string, envMap
 		TestFiles: slices.Clone(testFiles),
 		EnvMap:    maps.Clone(envMap),
 	})
+	if m.RunTestsFunc != nil {
+		return m.RunTestsFunc(testFiles)
+	}
 	return m.Err
 }
 
@@ -177,3 +182,15 @@ func (roundRobinTestPlanner) DistributeTestFiles(testFiles []string, parallelRun
 	}
 	return groups
 }
+
+func (roundRobinTestPlanner) TestFileConstraints() planner.TestFileConstraints {
+	return planner.TestFileConstraints{}
+}
+
+func (roundRobinTestPlanner) TestFileWeights(testFiles []string) map[string]int {
+	weights := make(map[string]int, len(testFiles))
+	for _, testFile := range testFiles {
+		weights[testFile] = 1
+	}
+	return weights
+}
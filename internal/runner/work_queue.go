@@ -0,0 +1,168 @@
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/DataDog/ddtest/internal/queue"
+)
+
+// testBatch is a batch of test files handed to one queue worker. leaseID is
+// set when the batch was leased from a `ddtest serve` queue.
+type testBatch struct {
+	leaseID   string
+	testFiles []string
+}
+
+// testBatchSource hands batches of test files to queue workers until it runs
+// out of work.
+type testBatchSource interface {
+	nextBatch(ctx context.Context, workerIndex int) (testBatch, bool, error)
+	completeBatch(ctx context.Context, batch testBatch, runErr error) error
+}
+
+// testFileQueue is an in-process work queue shared by local workers. Workers
+// pull the longest estimated test files first, so a mis-estimated file delays
+// only the worker that runs it instead of the whole static split.
+type testFileQueue struct {
+	mu        sync.Mutex
+	batches   [][]string
+	batchSize int
+}
+
+func newTestFileQueue(testFileWeights map[string]int, batchSize int) *testFileQueue {
+	return &testFileQueue{
+		batches:   queue.Batches(testFileWeights, batchSize),
+		batchSize: max(batchSize, 1),
+	}
+}
+
+// newGroupedTestFileQueue returns a queue whose batches keep the test files
+// of each unit together, as queue.GroupedBatches does.
+func newGroupedTestFileQueue(units [][]string, testFileWeights map[string]int, batchSize int) *testFileQueue {
+	return &testFileQueue{
+		batches:   queue.GroupedBatches(units, testFileWeights, batchSize),
+		batchSize: max(batchSize, 1),
+	}
+}
+
+// newOrderedTestFileQueue returns a queue whose batches keep the test files
+// of each unit together and the units in order, as queue.OrderedBatches does.
+func newOrderedTestFileQueue(units [][]string, batchSize int) *testFileQueue {
+	return &testFileQueue{
+		batches:   queue.OrderedBatches(units, batchSize),
+		batchSize: max(batchSize, 1),
+	}
+}
+
+// next removes and returns the next batch of test files. It returns false once
+// the queue is empty.
+func (q *testFileQueue) next() ([]string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.batches) == 0 {
+		return nil, false
+	}
+
+	batch := q.batches[0]
+	q.batches = q.batches[1:]
+	return batch, true
+}
+
+// remaining returns the test files that were not handed out yet.
+func (q *testFileQueue) remaining() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return slices.Concat(q.batches...)
+}
+
+func (q *testFileQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	count := 0
+	for _, batch := range q.batches {
+		count += len(batch)
+	}
+	return count
+}
+
+func (q *testFileQueue) nextBatch(context.Context, int) (testBatch, bool, error) {
+	testFiles, ok := q.next()
+	return testBatch{testFiles: testFiles}, ok, nil
+}
+
+func (q *testFileQueue) completeBatch(context.Context, testBatch, error) error {
+	return nil
+}
+
+// runLocalQueueWorkers runs testFiles from an in-process work queue, then the
+// exclusive test files alone. With fail-fast, the files still queued when a
+// worker fails are recorded as not run.
+func (e testExecutor) runLocalQueueWorkers(testFiles []string, nodeIndex int, workers int) error {
+	testFiles, exclusive := e.withoutExclusiveTestFiles(testFiles)
+	q := e.newWorkQueue(testFiles)
+	err := e.runQueueWorkers(q, nodeIndex, workers)
+	if e.failFast != nil {
+		e.failFast.recordNotRun(q.remaining())
+	}
+	return e.runExclusiveTestFiles(exclusive, nodeIndex, err)
+}
+
+// runQueueWorkers starts workers that pull batches from source until it is
+// empty. A failed batch does not stop its worker: every queued file still
+// runs, and the first failure is returned once all workers are done. With
+// fail-fast, the first failed batch stops every worker instead. Errors from
+// source itself stop the worker that saw them. A batch with an exclusive test
+// file waits for the other workers to finish their batches and runs alone.
+func (e testExecutor) runQueueWorkers(source testBatchSource, nodeIndex int, workers int) error {
+	slog.Info("Running tests from work queue", "nodeIndex", nodeIndex, "workers", workers)
+
+	var exclusive sync.RWMutex
+	g, queueWorkers := e.workerGroup()
+	for workerIndex := range workers {
+		g.Go(func() error {
+			var firstErr error
+			for {
+				if queueWorkers.cancelledByFailFast() {
+					return nil
+				}
+				batch, ok, err := source.nextBatch(queueWorkers.ctx, workerIndex)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return firstErr
+				}
+				unlock := lockBatch(&exclusive, e.isExclusiveBatch(batch.testFiles))
+				runErr := queueWorkers.runBatch(batch.testFiles, nodeIndex, workerIndex)
+				unlock()
+				if runErr != nil && firstErr == nil {
+					firstErr = runErr
+				}
+				// Batches are acknowledged with the parent context so that
+				// fail-fast cancellation does not prevent it.
+				if err := source.completeBatch(e.ctx, batch, runErr); err != nil {
+					return err
+				}
+				if runErr != nil && e.failFast != nil {
+					return runErr
+				}
+			}
+		})
+	}
+	return g.Wait()
+}
+
+// lockBatch takes lock for a batch, exclusively when the batch must run
+// alone, and returns the function that releases it.
+func lockBatch(lock *sync.RWMutex, exclusive bool) func() {
+	if exclusive {
+		lock.Lock()
+		return lock.Unlock
+	}
+	lock.RLock()
+	return lock.RUnlock
+}
@@ -0,0 +1,153 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/errcode"
+)
+
+func TestTestFileQueue_NextReturnsLongestFilesFirst(t *testing.T) {
+	queue := newTestFileQueue(map[string]int{
+		"spec/fast_spec.rb":   10,
+		"spec/slow_spec.rb":   500,
+		"spec/medium_spec.rb": 100,
+		"spec/a_spec.rb":      100,
+	}, 2)
+
+	first, ok := queue.next()
+	if !ok || !slices.Equal(first, []string{"spec/slow_spec.rb", "spec/a_spec.rb"}) {
+		t.Fatalf("expected first batch to contain longest files, got %v (ok=%t)", first, ok)
+	}
+	second, ok := queue.next()
+	if !ok || !slices.Equal(second, []string{"spec/medium_spec.rb", "spec/fast_spec.rb"}) {
+		t.Fatalf("expected second batch to contain remaining files, got %v (ok=%t)", second, ok)
+	}
+	if batch, ok := queue.next(); ok {
+		t.Fatalf("expected empty queue, got %v", batch)
+	}
+}
+
+func TestTestFileQueue_NormalizesBatchSize(t *testing.T) {
+	queue := newTestFileQueue(map[string]int{"spec/a_spec.rb": 1, "spec/b_spec.rb": 1}, 0)
+
+	batch, ok := queue.next()
+	if !ok || len(batch) != 1 {
+		t.Fatalf("expected batch size to default to 1, got %v", batch)
+	}
+	if queue.len() != 1 {
+		t.Fatalf("expected one queued file to remain, got %d", queue.len())
+	}
+}
+
+func TestRunQueueWorkers_RunsEveryFileAfterFailure(t *testing.T) {
+	mockFramework := &MockFramework{FrameworkName: "rspec", Err: errors.New("tests failed")}
+	executor := newTestExecutor(context.Background(), mockFramework, map[string]string{}, roundRobinTestPlanner{}).withWorkQueue(1)
+
+	queue := executor.newWorkQueue([]string{"spec/a_spec.rb", "spec/b_spec.rb", "spec/c_spec.rb"})
+	err := executor.runQueueWorkers(queue, 0, 2)
+	if err == nil {
+		t.Fatal("expected runQueueWorkers() to return the batch failure")
+	}
+
+	calls := mockFramework.GetRunTestsCalls()
+	var ranFiles []string
+	for _, call := range calls {
+		ranFiles = append(ranFiles, call.TestFiles...)
+	}
+	slices.Sort(ranFiles)
+	if !slices.Equal(ranFiles, []string{"spec/a_spec.rb", "spec/b_spec.rb", "spec/c_spec.rb"}) {
+		t.Fatalf("expected every queued file to run despite failures, got %v", ranFiles)
+	}
+}
+
+func TestRunParallel_WorkQueue(t *testing.T) {
+	chdirTemp(t)
+
+	_ = os.MkdirAll(constants.TestsSplitDir, 0755)
+	_ = os.WriteFile(filepath.Join(constants.TestsSplitDir, "runner-0"), []byte("test/file1_test.rb\ntest/file2_test.rb\n"), 0644)
+	_ = os.WriteFile(filepath.Join(constants.TestsSplitDir, "runner-1"), []byte("test/file3_test.rb\n"), 0644)
+
+	mockFramework := &MockFramework{FrameworkName: "rspec"}
+	workerEnvMap := map[string]string{"NODE_INDEX": "{{nodeIndex}}"}
+	executor := newTestExecutor(context.Background(), mockFramework, workerEnvMap, roundRobinTestPlanner{}).withWorkQueue(1)
+
+	result := executor.runParallel()
+	if result.err != nil {
+		t.Fatalf("runParallel() should not return error, got: %v", result.err)
+	}
+	if !result.report.WorkQueue || result.report.LocalWorkers != 2 || result.report.TestFilesRun != 3 {
+		t.Fatalf("unexpected work queue report: %+v", result.report)
+	}
+
+	calls := mockFramework.GetRunTestsCalls()
+	if len(calls) != 3 {
+		t.Fatalf("expected one RunTests call per queued file, got %d", len(calls))
+	}
+	for _, call := range calls {
+		if call.EnvMap["NODE_INDEX"] != "0" {
+			t.Errorf("expected queued batches to run with node index 0, got %q", call.EnvMap["NODE_INDEX"])
+		}
+	}
+}
+
+func TestRunParallel_WorkQueueFailure(t *testing.T) {
+	chdirTemp(t)
+
+	_ = os.MkdirAll(constants.TestsSplitDir, 0755)
+	_ = os.WriteFile(filepath.Join(constants.TestsSplitDir, "runner-0"), []byte("test/file1_test.rb\n"), 0644)
+
+	mockFramework := &MockFramework{FrameworkName: "rspec", Err: errors.New("tests failed")}
+	executor := newTestExecutor(context.Background(), mockFramework, map[string]string{}, roundRobinTestPlanner{}).withWorkQueue(1)
+
+	result := executor.runParallel()
+	assertRunnerErrorCode(t, result.err, errcode.RunParallelTestsFailed)
+}
+
+func TestRunCINode_WorkQueue(t *testing.T) {
+	chdirTemp(t)
+
+	_ = os.MkdirAll(constants.TestsSplitDir, 0755)
+	_ = os.WriteFile(filepath.Join(constants.TestsSplitDir, "runner-1"),
+		[]byte("test/file1_test.rb\ntest/file2_test.rb\ntest/file3_test.rb\n"), 0644)
+
+	mockFramework := &MockFramework{FrameworkName: "rspec"}
+	testPlanner := &fakePlanner{testFileWeights: map[string]int{"test/file2_test.rb": 1000}}
+	workerEnvMap := map[string]string{"NODE_INDEX": "{{nodeIndex}}"}
+	executor := newTestExecutor(context.Background(), mockFramework, workerEnvMap, testPlanner).withWorkQueue(2)
+
+	result := executor.runCINode(1, 2)
+	if result.err != nil {
+		t.Fatalf("runCINode() should not return error, got: %v", result.err)
+	}
+	if !result.report.WorkQueue {
+		t.Fatalf("expected report to record work queue scheduling, got %+v", result.report)
+	}
+	if testPlanner.distributeCalls != 0 {
+		t.Fatalf("expected work queue mode not to subsplit files, got %d DistributeTestFiles() calls", testPlanner.distributeCalls)
+	}
+
+	calls := mockFramework.GetRunTestsCalls()
+	if len(calls) != 2 {
+		t.Fatalf("expected two queued batches, got %+v", calls)
+	}
+	var firstBatch []string
+	for _, call := range calls {
+		if slices.Contains(call.TestFiles, "test/file2_test.rb") {
+			firstBatch = call.TestFiles
+		}
+	}
+	if !slices.Equal(firstBatch, []string{"test/file2_test.rb", "test/file1_test.rb"}) {
+		t.Fatalf("expected heaviest file to be queued first, got %+v", calls)
+	}
+	for _, call := range calls {
+		if call.EnvMap["NODE_INDEX"] != "1" {
+			t.Errorf("expected queued batches to use ci-node index, got %+v", call.EnvMap)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/ext"
+	"github.com/DataDog/ddtest/internal/settings"
+)
+
+// maxPendingPrefixedLine bounds how much of an unterminated line, such as
+// RSpec progress dots, is held back before it is written with a prefix.
+const maxPendingPrefixedLine = 64 * 1024
+
+// workerOutput routes the output of worker test processes according to the
+// worker output mode. It is shared by every worker of a run.
+type workerOutput struct {
+	mode   settings.WorkerOutputMode
+	stdout io.Writer
+	stderr io.Writer
+
+	// mu serializes writes to stdout and stderr and guards the log file state.
+	mu             sync.Mutex
+	openedLogFiles map[string]bool
+	failedLogFiles []string
+}
+
+func newWorkerOutput(mode settings.WorkerOutputMode, stdout, stderr io.Writer) *workerOutput {
+	return &workerOutput{
+		mode:           mode,
+		stdout:         stdout,
+		stderr:         stderr,
+		openedLogFiles: make(map[string]bool),
+	}
+}
+
+func workerOutputLabel(nodeIndex int, workerIndex int) string {
+	return "[" + workerName(nodeIndex, workerIndex) + "]"
+}
+
+// workerName identifies a worker in output and run reports.
+func workerName(nodeIndex int, workerIndex int) string {
+	return fmt.Sprintf("node %d / worker %d", nodeIndex, workerIndex)
+}
+
+func workerLogFilePath(nodeIndex int, workerIndex int) string {
+	return filepath.Join(constants.WorkerLogsDir, fmt.Sprintf("node-%d-worker-%d.log", nodeIndex, workerIndex))
+}
+
+// open starts capturing the output of one worker test run. The capture must
+// be closed once the run finishes.
+func (o *workerOutput) open(nodeIndex int, workerIndex int) (*workerCapture, error) {
+	capture := &workerCapture{output: o, label: workerOutputLabel(nodeIndex, workerIndex)}
+
+	switch o.mode {
+	case settings.WorkerOutputPrefix:
+		capture.stdoutPrefix = &prefixWriter{mu: &o.mu, w: o.stdout, prefix: capture.label + " "}
+		capture.stderrPrefix = &prefixWriter{mu: &o.mu, w: o.stderr, prefix: capture.label + " "}
+	case settings.WorkerOutputBuffered:
+		capture.buffer = &bytes.Buffer{}
+	case settings.WorkerOutputFile:
+		file, err := o.openLogFile(workerLogFilePath(nodeIndex, workerIndex))
+		if err != nil {
+			return nil, err
+		}
+		capture.file = file
+	}
+	return capture, nil
+}
+
+// openLogFile truncates a worker log file the first time a run opens it and
+// appends to it afterwards, so one log covers every batch of a worker.
+func (o *workerOutput) openLogFile(path string) (*os.File, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !o.openedLogFiles[path] {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open worker log file %s: %w", path, err)
+	}
+	o.openedLogFiles[path] = true
+	return file, nil
+}
+
+func (o *workerOutput) recordFailedLogFile(path string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !slices.Contains(o.failedLogFiles, path) {
+		o.failedLogFiles = append(o.failedLogFiles, path)
+	}
+}
+
+// failedWorkerLogs returns the log files of workers whose tests failed.
+func (o *workerOutput) failedWorkerLogs() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	logs := slices.Clone(o.failedLogFiles)
+	slices.Sort(logs)
+	return logs
+}
+
+// workerCapture holds the output destination of one worker test run.
+type workerCapture struct {
+	output       *workerOutput
+	label        string
+	stdoutPrefix *prefixWriter
+	stderrPrefix *prefixWriter
+	buffer       *bytes.Buffer
+	file         *os.File
+}
+
+// context returns ctx with the worker's output writers, or ctx unchanged when
+// output streams directly to ddtest's output.
+func (c *workerCapture) context(ctx context.Context) context.Context {
+	switch {
+	case c.stdoutPrefix != nil:
+		return ext.WithOutput(ctx, c.stdoutPrefix, c.stderrPrefix)
+	case c.buffer != nil:
+		return ext.WithOutput(ctx, c.buffer, c.buffer)
+	case c.file != nil:
+		return ext.WithOutput(ctx, c.file, c.file)
+	default:
+		return ctx
+	}
+}
+
+// close flushes the captured output and records the log file of a failed run.
+func (c *workerCapture) close(runErr error) {
+	switch {
+	case c.stdoutPrefix != nil:
+		c.stdoutPrefix.flush()
+		c.stderrPrefix.flush()
+	case c.buffer != nil:
+		c.output.mu.Lock()
+		reportFprintf(c.output.stdout, "==> %s <==\n", c.label)
+		_, _ = c.buffer.WriteTo(c.output.stdout)
+		c.output.mu.Unlock()
+	case c.file != nil:
+		path := c.file.Name()
+		if err := c.file.Close(); err != nil {
+			slog.Warn("Failed to close worker log file", "path", path, "error", err)
+		}
+		if runErr != nil {
+			c.output.recordFailedLogFile(path)
+		}
+		slog.Info("Worker output written to log file", "worker", c.label, "path", path)
+	}
+}
+
+// prefixWriter writes complete lines to w, each starting with prefix. Partial
+// lines are held back until they are complete so lines from different workers
+// never mix.
+type prefixWriter struct {
+	mu      *sync.Mutex
+	w       io.Writer
+	prefix  string
+	pending []byte
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.pending = append(p.pending, data...)
+
+	end := bytes.LastIndexByte(p.pending, '\n') + 1
+	if end == 0 && len(p.pending) >= maxPendingPrefixedLine {
+		p.pending = append(p.pending, '\n')
+		end = len(p.pending)
+	}
+	if end == 0 {
+		return len(data), nil
+	}
+
+	var out bytes.Buffer
+	for line := range bytes.Lines(p.pending[:end]) {
+		out.WriteString(p.prefix)
+		out.Write(line)
+	}
+	p.pending = append(p.pending[:0], p.pending[end:]...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.w.Write(out.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (p *prefixWriter) flush() {
+	if len(p.pending) > 0 {
+		_, _ = p.Write([]byte{'\n'})
+	}
+}
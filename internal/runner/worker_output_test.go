@@ -0,0 +1,110 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/ext"
+	"github.com/DataDog/ddtest/internal/settings"
+)
+
+func runCapturedCommand(t *testing.T, output *workerOutput, nodeIndex int, workerIndex int, script string) *workerCapture {
+	t.Helper()
+	capture, err := output.open(nodeIndex, workerIndex)
+	if err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	executor := &ext.DefaultCommandExecutor{}
+	if err := executor.Run(capture.context(context.Background()), "sh", []string{"-c", script}, nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	return capture
+}
+
+func TestPrefixWriter_WritesCompleteLines(t *testing.T) {
+	var out bytes.Buffer
+	writer := &prefixWriter{mu: &sync.Mutex{}, w: &out, prefix: "[node 0 / worker 1] "}
+
+	_, _ = writer.Write([]byte("first\nsec"))
+	if out.String() != "[node 0 / worker 1] first\n" {
+		t.Fatalf("expected partial line to be held back, got %q", out.String())
+	}
+	_, _ = writer.Write([]byte("ond\nthird"))
+	writer.flush()
+
+	want := "[node 0 / worker 1] first\n[node 0 / worker 1] second\n[node 0 / worker 1] third\n"
+	if out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestWorkerOutput_PrefixesLines(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	output := newWorkerOutput(settings.WorkerOutputPrefix, &stdout, &stderr)
+
+	capture := runCapturedCommand(t, output, 1, 2, "printf 'one\\ntwo'; echo err >&2")
+	capture.close(nil)
+
+	if stdout.String() != "[node 1 / worker 2] one\n[node 1 / worker 2] two\n" {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+	if stderr.String() != "[node 1 / worker 2] err\n" {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+}
+
+func TestWorkerOutput_BufferedFlushesOnClose(t *testing.T) {
+	var stdout bytes.Buffer
+	output := newWorkerOutput(settings.WorkerOutputBuffered, &stdout, &stdout)
+
+	capture := runCapturedCommand(t, output, 0, 3, "echo out; echo err >&2")
+	if stdout.Len() != 0 {
+		t.Fatalf("expected output to be buffered until close, got %q", stdout.String())
+	}
+	capture.close(nil)
+
+	if stdout.String() != "==> [node 0 / worker 3] <==\nout\nerr\n" {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+}
+
+func TestRunBatch_WritesWorkerLogFiles(t *testing.T) {
+	chdirTemp(t)
+	if err := os.MkdirAll(constants.WorkerLogsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	logPath := workerLogFilePath(0, 1)
+	writeRunnerTestFile(t, logPath, "output from a previous run\n")
+
+	mockFramework := &MockFramework{FrameworkName: "rspec", Err: errors.New("tests failed")}
+	output := newWorkerOutput(settings.WorkerOutputFile, os.Stdout, os.Stderr)
+	executor := newTestExecutor(context.Background(), mockFramework, map[string]string{}, roundRobinTestPlanner{}).withWorkerOutput(output)
+
+	if err := executor.runBatch([]string{"spec/a_spec.rb"}, 0, 1); err == nil {
+		t.Fatal("expected runBatch() to fail")
+	}
+	mockFramework.Err = nil
+	if err := executor.runBatch([]string{"spec/b_spec.rb"}, 0, 2); err != nil {
+		t.Fatalf("runBatch() error = %v", err)
+	}
+
+	if logs := output.failedWorkerLogs(); !slices.Equal(logs, []string{logPath}) {
+		t.Fatalf("failedWorkerLogs() = %v, want %v", logs, []string{logPath})
+	}
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("failed to read worker log: %v", err)
+	}
+	if len(data) != 0 {
+		t.Fatalf("expected log from a previous run to be truncated, got %q", data)
+	}
+	if _, err := os.Stat(workerLogFilePath(0, 2)); err != nil {
+		t.Fatalf("expected log file for worker 2: %v", err)
+	}
+}
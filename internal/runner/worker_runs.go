@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"errors"
+	"os/exec"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/errcode"
+)
+
+// workerRuns records every batch of test files a worker ran, across every
+// worker of a run, for the JSON run report.
+type workerRuns struct {
+	mu   sync.Mutex
+	runs []workerRun
+}
+
+// workerRun is one test command run by a worker. Static splits run one batch
+// per worker; work queues run one per leased batch.
+type workerRun struct {
+	Worker      string    `json:"worker"`
+	NodeIndex   int       `json:"nodeIndex"`
+	WorkerIndex int       `json:"workerIndex"`
+	TestFiles   []string  `json:"testFiles"`
+	StartTime   time.Time `json:"startTime"`
+	EndTime     time.Time `json:"endTime"`
+	DurationMs  int64     `json:"durationMs"`
+	// ExitCode is the exit code of the test command, or -1 when it was
+	// stopped by a signal or did not start.
+	ExitCode int    `json:"exitCode"`
+	Error    string `json:"error,omitempty"`
+	// ErrorCode is the ddtest error code of Error, "unknown" when the test
+	// command itself failed.
+	ErrorCode string `json:"errorCode,omitempty"`
+}
+
+func (r *workerRuns) record(nodeIndex int, workerIndex int, testFiles []string, startTime time.Time, err error) {
+	endTime := time.Now()
+	run := workerRun{
+		Worker:      workerName(nodeIndex, workerIndex),
+		NodeIndex:   nodeIndex,
+		WorkerIndex: workerIndex,
+		TestFiles:   slices.Clone(testFiles),
+		StartTime:   startTime,
+		EndTime:     endTime,
+		DurationMs:  endTime.Sub(startTime).Milliseconds(),
+		ExitCode:    exitCode(err),
+	}
+	if err != nil {
+		run.Error = err.Error()
+		run.ErrorCode = string(errcode.CodeOf(err))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, run)
+}
+
+func (r *workerRuns) report() []workerRun {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	runs := slices.Clone(r.runs)
+	slices.SortStableFunc(runs, func(a, b workerRun) int {
+		if a.NodeIndex != b.NodeIndex {
+			return a.NodeIndex - b.NodeIndex
+		}
+		if a.WorkerIndex != b.WorkerIndex {
+			return a.WorkerIndex - b.WorkerIndex
+		}
+		return a.StartTime.Compare(b.StartTime)
+	})
+	return runs
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/ext"
+)
+
+// workerTimeouts holds the hang timeout budget of a run and records the
+// batches that exceeded it across every worker.
+type workerTimeouts struct {
+	// timeout is the absolute budget of one test command; zero disables it.
+	timeout time.Duration
+	// multiplier scales the estimated duration of the files in a test command
+	// into its budget; zero disables it.
+	multiplier       float64
+	diagnosticSignal os.Signal
+
+	mu       sync.Mutex
+	timedOut []timedOutBatch
+}
+
+// timedOutBatch is a test command that was stopped by its hang timeout,
+// together with the test files that were in flight.
+type timedOutBatch struct {
+	Worker    string        `json:"worker"`
+	Timeout   time.Duration `json:"-"`
+	TestFiles []string      `json:"testFiles"`
+}
+
+func (b timedOutBatch) MarshalJSON() ([]byte, error) {
+	type batch timedOutBatch
+	return json.Marshal(struct {
+		batch
+		TimeoutMs int64 `json:"timeoutMs"`
+	}{batch: batch(b), TimeoutMs: b.Timeout.Milliseconds()})
+}
+
+// withWorkerTimeout stops test commands that run longer than their budget:
+// the smaller of timeout and the estimated duration of their test files times
+// multiplier, ignoring whichever of the two is zero. The command first gets
+// diagnosticSignal, when it is not nil, and is then terminated.
+func (e testExecutor) withWorkerTimeout(timeout time.Duration, multiplier float64, diagnosticSignal os.Signal) testExecutor {
+	e.timeouts = &workerTimeouts{
+		timeout:          timeout,
+		multiplier:       multiplier,
+		diagnosticSignal: diagnosticSignal,
+	}
+	return e
+}
+
+func (e testExecutor) workerTimeoutReport() []timedOutBatch {
+	if e.timeouts == nil {
+		return nil
+	}
+	e.timeouts.mu.Lock()
+	defer e.timeouts.mu.Unlock()
+
+	report := slices.Clone(e.timeouts.timedOut)
+	slices.SortFunc(report, func(a, b timedOutBatch) int {
+		return strings.Compare(a.Worker, b.Worker)
+	})
+	return report
+}
+
+// batchTimeout returns the budget for running testFiles in one command, or
+// zero when it has no budget.
+func (e testExecutor) batchTimeout(testFiles []string) time.Duration {
+	if e.timeouts == nil {
+		return 0
+	}
+	timeout := e.timeouts.timeout
+	if e.timeouts.multiplier > 0 {
+		var estimatedMs int
+		for _, weight := range e.planner.TestFileWeights(testFiles) {
+			estimatedMs += weight
+		}
+		estimated := time.Duration(float64(estimatedMs)*e.timeouts.multiplier) * time.Millisecond
+		if estimated > 0 && (timeout == 0 || estimated < timeout) {
+			timeout = estimated
+		}
+	}
+	return timeout
+}
+
+// runTests runs testFiles in one test command under their hang timeout and
+// records the command when the timeout stopped it.
+func (e testExecutor) runTests(testFiles []string, workerEnv map[string]string, nodeIndex int, workerIndex int) error {
+	timeout := e.batchTimeout(testFiles)
+	if timeout == 0 {
+		return e.framework.RunTests(e.ctx, testFiles, workerEnv)
+	}
+
+	err := e.framework.RunTests(ext.WithHangTimeout(e.ctx, timeout, e.timeouts.diagnosticSignal), testFiles, workerEnv)
+	var timeoutErr *ext.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		slog.Warn("Worker timed out, test command was terminated",
+			"nodeIndex", nodeIndex, "workerIndex", workerIndex, "timeout", timeoutErr.Timeout, "testFilesCount", len(testFiles))
+		e.timeouts.mu.Lock()
+		e.timeouts.timedOut = append(e.timeouts.timedOut, timedOutBatch{
+			Worker:    workerName(nodeIndex, workerIndex),
+			Timeout:   timeoutErr.Timeout,
+			TestFiles: slices.Sorted(slices.Values(testFiles)),
+		})
+		e.timeouts.mu.Unlock()
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/ddtest/internal/ext"
+)
+
+// timingOutFramework fails every run as if its hang timeout had stopped the
+// test command.
+type timingOutFramework struct {
+	*MockFramework
+	timeout time.Duration
+}
+
+func (f timingOutFramework) RunTests(ctx context.Context, testFiles []string, envMap map[string]string) error {
+	_ = f.MockFramework.RunTests(ctx, testFiles, envMap)
+	return &ext.TimeoutError{Timeout: f.timeout, Err: errors.New("signal: terminated")}
+}
+
+func TestBatchTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		multiplier float64
+		want       time.Duration
+	}{
+		{name: "absolute", timeout: time.Minute, want: time.Minute},
+		{name: "multiplier", multiplier: 1.5, want: 6 * time.Millisecond},
+		{name: "multiplier below absolute", timeout: time.Minute, multiplier: 2, want: 8 * time.Millisecond},
+		{name: "absolute below multiplier", timeout: time.Millisecond, multiplier: 2, want: time.Millisecond},
+	}
+
+	testFiles := []string{"spec/a_spec.rb", "spec/b_spec.rb", "spec/c_spec.rb", "spec/d_spec.rb"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := newTestExecutor(context.Background(), &MockFramework{}, map[string]string{}, roundRobinTestPlanner{}).
+				withWorkerTimeout(tt.timeout, tt.multiplier, nil)
+			if got := executor.batchTimeout(testFiles); got != tt.want {
+				t.Fatalf("batchTimeout() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	executor := newTestExecutor(context.Background(), &MockFramework{}, map[string]string{}, roundRobinTestPlanner{})
+	if got := executor.batchTimeout(testFiles); got != 0 {
+		t.Fatalf("batchTimeout() without worker timeout = %s, want 0", got)
+	}
+}
+
+func TestRunBatch_RecordsTimedOutWorker(t *testing.T) {
+	framework := timingOutFramework{MockFramework: &MockFramework{FrameworkName: "rspec"}, timeout: time.Minute}
+	executor := newTestExecutor(context.Background(), framework, map[string]string{}, roundRobinTestPlanner{}).
+		withWorkerTimeout(time.Minute, 0, nil)
+
+	err := executor.runBatch([]string{"spec/b_spec.rb", "spec/a_spec.rb"}, 1, 2)
+	var timeoutErr *ext.TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("runBatch() error = %v, want *ext.TimeoutError", err)
+	}
+
+	report := executor.workerTimeoutReport()
+	if len(report) != 1 {
+		t.Fatalf("expected one timed out worker, got %+v", report)
+	}
+	if report[0].Worker != "node 1 / worker 2" || report[0].Timeout != time.Minute {
+		t.Fatalf("unexpected timed out worker %+v", report[0])
+	}
+	if strings.Join(report[0].TestFiles, ",") != "spec/a_spec.rb,spec/b_spec.rb" {
+		t.Fatalf("TestFiles = %v", report[0].TestFiles)
+	}
+}
+
+func TestPrintRunReport_WorkerTimeouts(t *testing.T) {
+	var output strings.Builder
+
+	printRunReport(&output, runReport{
+		Execution: runExecutionReport{
+			Mode:         runModeParallel,
+			LocalWorkers: 2,
+			TestFilesRun: 3,
+			WorkerTimeouts: []timedOutBatch{
+				{Worker: "node 0 / worker 1", Timeout: 90 * time.Second, TestFiles: []string{"spec/a_spec.rb", "spec/b_spec.rb"}},
+			},
+		},
+		Err: errors.New("command timed out after 1m30s"),
+	})
+
+	expected := "  Timed out workers: 1\n    node 0 / worker 1 after 1m30s, in flight:\n      spec/a_spec.rb\n      spec/b_spec.rb\n"
+	if !strings.Contains(output.String(), expected) {
+		t.Errorf("expected timed out workers in run report, got:\n%s", output.String())
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -15,8 +16,13 @@ import (
 
 const (
 	defaultCiNodeWorkers          = 1
+	defaultWorkQueueBatchSize     = 1
 	defaultParallelRunnerOverhead = 25 * time.Second
 	defaultTargetTime             = 0 * time.Second
+	defaultQueueListen            = "127.0.0.1:7878"
+	defaultQueueLeaseTimeout      = 2 * time.Minute
+	defaultWorkerTimeoutSignal    = "SIGQUIT"
+	defaultStickySplitTolerance   = 5.0
 	ncpuCiNodeWorkers             = "ncpu"
 	envPrefix                     = "DD_TEST_OPTIMIZATION_RUNNER"
 	platformEnv                   = "DD_TEST_OPTIMIZATION_RUNNER_PLATFORM"
@@ -25,9 +31,39 @@ const (
 	maxParallelismEnv             = "DD_TEST_OPTIMIZATION_RUNNER_MAX_PARALLELISM"
 	parallelRunnerOverheadEnv     = "DD_TEST_OPTIMIZATION_RUNNER_CI_JOB_OVERHEAD"
 	targetTimeEnv                 = "DD_TEST_OPTIMIZATION_RUNNER_TARGET_TIME"
+	splitOptimizerEnv             = "DD_TEST_OPTIMIZATION_RUNNER_SPLIT_OPTIMIZER"
+	durationEstimateEnv           = "DD_TEST_OPTIMIZATION_RUNNER_DURATION_ESTIMATE"
+	optimizeForEnv                = "DD_TEST_OPTIMIZATION_RUNNER_OPTIMIZE_FOR"
+	ciJobPricePerMinuteEnv        = "DD_TEST_OPTIMIZATION_RUNNER_CI_JOB_PRICE_PER_MINUTE"
+	ciJobBillingIncrementEnv      = "DD_TEST_OPTIMIZATION_RUNNER_CI_JOB_BILLING_INCREMENT"
+	ciJobStartupTimeEnv           = "DD_TEST_OPTIMIZATION_RUNNER_CI_JOB_STARTUP_TIME"
+	ciBudgetEnv                   = "DD_TEST_OPTIMIZATION_RUNNER_CI_BUDGET"
+	chunkSlowTestFilesEnv         = "DD_TEST_OPTIMIZATION_RUNNER_CHUNK_SLOW_TEST_FILES"
+	constraintsFileEnv            = "DD_TEST_OPTIMIZATION_RUNNER_CONSTRAINTS_FILE"
+	workspaceEnv                  = "DD_TEST_OPTIMIZATION_RUNNER_WORKSPACE"
+	previousPlanEnv               = "DD_TEST_OPTIMIZATION_RUNNER_PREVIOUS_PLAN"
+	stickySplitToleranceEnv       = "DD_TEST_OPTIMIZATION_RUNNER_STICKY_SPLIT_TOLERANCE"
+	planLayoutEnv                 = "DD_TEST_OPTIMIZATION_RUNNER_PLAN_LAYOUT"
+	splitOrderEnv                 = "DD_TEST_OPTIMIZATION_RUNNER_SPLIT_ORDER"
+	pipelineTemplateEnv           = "DD_TEST_OPTIMIZATION_RUNNER_PIPELINE_TEMPLATE"
 	workerEnv                     = "DD_TEST_OPTIMIZATION_RUNNER_WORKER_ENV"
 	ciNodeEnv                     = "DD_TEST_OPTIMIZATION_RUNNER_CI_NODE"
 	ciNodeWorkersEnv              = "DD_TEST_OPTIMIZATION_RUNNER_CI_NODE_WORKERS"
+	ciNodeCapacitiesEnv           = "DD_TEST_OPTIMIZATION_RUNNER_CI_NODE_CAPACITIES"
+	workQueueEnv                  = "DD_TEST_OPTIMIZATION_RUNNER_WORK_QUEUE"
+	workQueueBatchSizeEnv         = "DD_TEST_OPTIMIZATION_RUNNER_WORK_QUEUE_BATCH_SIZE"
+	queueURLEnv                   = "DD_TEST_OPTIMIZATION_RUNNER_QUEUE_URL"
+	queueListenEnv                = "DD_TEST_OPTIMIZATION_RUNNER_QUEUE_LISTEN"
+	queueLeaseTimeoutEnv          = "DD_TEST_OPTIMIZATION_RUNNER_QUEUE_LEASE_TIMEOUT"
+	retryFailedFilesEnv           = "DD_TEST_OPTIMIZATION_RUNNER_RETRY_FAILED_FILES"
+	workerOutputEnv               = "DD_TEST_OPTIMIZATION_RUNNER_WORKER_OUTPUT"
+	failFastEnv                   = "DD_TEST_OPTIMIZATION_RUNNER_FAIL_FAST"
+	workerTimeoutEnv              = "DD_TEST_OPTIMIZATION_RUNNER_WORKER_TIMEOUT"
+	workerTimeoutMultiplierEnv    = "DD_TEST_OPTIMIZATION_RUNNER_WORKER_TIMEOUT_MULTIPLIER"
+	workerTimeoutSignalEnv        = "DD_TEST_OPTIMIZATION_RUNNER_WORKER_TIMEOUT_SIGNAL"
+	junitReportsEnv               = "DD_TEST_OPTIMIZATION_RUNNER_JUNIT_REPORTS"
+	preferLocalDurationsEnv       = "DD_TEST_OPTIMIZATION_RUNNER_PREFER_LOCAL_DURATIONS"
+	durationHistoryEnv            = "DD_TEST_OPTIMIZATION_RUNNER_DURATION_HISTORY"
 	commandEnv                    = "DD_TEST_OPTIMIZATION_RUNNER_COMMAND"
 	testsLocationEnv              = "DD_TEST_OPTIMIZATION_RUNNER_TESTS_LOCATION"
 	testsExcludePatternEnv        = "DD_TEST_OPTIMIZATION_RUNNER_TESTS_EXCLUDE_PATTERN"
@@ -38,6 +74,7 @@ const (
 	runtimeTagsEnv                = "DD_TEST_OPTIMIZATION_RUNNER_RUNTIME_TAGS"
 	runtimeTagsAliasEnv           = "DD_TEST_OPTIMIZATION_RUNTIME_TAGS"
 	reportEnabledEnv              = "DD_TEST_OPTIMIZATION_RUNNER_REPORT_ENABLED"
+	runReportPathEnv              = "DD_TEST_OPTIMIZATION_RUNNER_RUN_REPORT_PATH"
 	knapsackTestFilePatternEnv    = "KNAPSACK_PRO_TEST_FILE_PATTERN"
 	knapsackTestFileExcludeEnv    = "KNAPSACK_PRO_TEST_FILE_EXCLUDE_PATTERN"
 )
@@ -49,6 +86,89 @@ const (
 	TestSkippingLevelSuite TestSkippingLevel = "suite"
 )
 
+type SplitOptimizer string
+
+const (
+	// SplitOptimizerGreedy assigns each test file, longest first, to the
+	// least loaded runner.
+	SplitOptimizerGreedy SplitOptimizer = "greedy"
+	// SplitOptimizerLocalSearch refines the greedy split by moving and
+	// swapping test files between runners.
+	SplitOptimizerLocalSearch SplitOptimizer = "local-search"
+)
+
+type DurationEstimate string
+
+const (
+	// DurationEstimateP50 plans with the median duration of each test file.
+	DurationEstimateP50 DurationEstimate = "p50"
+	// DurationEstimateExpected models each test file from its P50 and P90
+	// durations and minimizes the expected wall time.
+	DurationEstimateExpected DurationEstimate = "expected"
+	// DurationEstimateP90 models each test file from its P50 and P90
+	// durations and minimizes the P90 wall time.
+	DurationEstimateP90 DurationEstimate = "p90"
+)
+
+type OptimizationGoal string
+
+const (
+	// OptimizationGoalTime selects the split with the lowest wall time plus
+	// modeled CI job overhead, within the CI budget when one is set.
+	OptimizationGoalTime OptimizationGoal = "time"
+	// OptimizationGoalCost selects the split with the lowest estimated CI
+	// cost, within the target time when one is set.
+	OptimizationGoalCost OptimizationGoal = "cost"
+)
+
+// PlanLayout selects the files that ddtest plan writes.
+type PlanLayout string
+
+const (
+	// PlanLayoutV1 writes the plain text plan files.
+	PlanLayoutV1 PlanLayout = "v1"
+	// PlanLayoutV2 also writes plan.json, a single versioned manifest of the
+	// whole plan.
+	PlanLayoutV2 PlanLayout = "v2"
+)
+
+// SplitOrder is a priority that orders the test files within each runner
+// split.
+type SplitOrder string
+
+const (
+	// SplitOrderNew puts test files with tests unknown to Datadog first.
+	SplitOrderNew SplitOrder = "new"
+	// SplitOrderChanged puts test files changed by the pull request or the
+	// working tree first.
+	SplitOrderChanged SplitOrder = "changed"
+	// SplitOrderFlaky puts test files with tests that Test Management
+	// quarantines or is attempting to fix first.
+	SplitOrderFlaky SplitOrder = "flaky"
+	// SplitOrderLongest puts the longest test files first.
+	SplitOrderLongest SplitOrder = "longest"
+)
+
+type WorkerOutputMode string
+
+const (
+	// WorkerOutputStream connects worker output directly to ddtest's output.
+	WorkerOutputStream WorkerOutputMode = "stream"
+	// WorkerOutputPrefix prefixes every worker output line with its node and worker index.
+	WorkerOutputPrefix WorkerOutputMode = "prefix"
+	// WorkerOutputBuffered prints each worker's output in one block when its test process exits.
+	WorkerOutputBuffered WorkerOutputMode = "buffered"
+	// WorkerOutputFile writes each worker's output to a log file.
+	WorkerOutputFile WorkerOutputMode = "file"
+)
+
+// WorkerTimeoutSignalNone disables the diagnostic signal sent to a timed-out
+// worker before it is terminated.
+const WorkerTimeoutSignalNone = "none"
+
+// workerTimeoutSignals are the diagnostic signals a timed-out worker can get.
+var workerTimeoutSignals = []string{"SIGQUIT", "SIGABRT", "SIGINT", "SIGTERM", "SIGUSR1", "SIGUSR2"}
+
 // DefaultParallelism returns the default parallelism value.
 func DefaultParallelism() int {
 	return PhysicalCPUCount()
@@ -65,6 +185,23 @@ func DefaultTargetTime() time.Duration {
 	return defaultTargetTime
 }
 
+// DefaultQueueListen returns the default address `ddtest serve` listens on.
+func DefaultQueueListen() string {
+	return defaultQueueListen
+}
+
+// DefaultQueueLeaseTimeout returns the default time a queue lease stays valid
+// without a heartbeat.
+func DefaultQueueLeaseTimeout() time.Duration {
+	return defaultQueueLeaseTimeout
+}
+
+// DefaultStickySplitTolerance returns the default wall time increase, in
+// percent, accepted to keep test files on their previous runner.
+func DefaultStickySplitTolerance() float64 {
+	return defaultStickySplitTolerance
+}
+
 // PhysicalCPUCount returns the number of physical CPU cores available to this process.
 //
 // It starts from runtime.GOMAXPROCS(0), which is the number of logical CPUs the
@@ -114,28 +251,64 @@ func ceilDiv(numerator, denominator int) int {
 }
 
 type Config struct {
-	Platform               string            `mapstructure:"platform"`
-	Framework              string            `mapstructure:"framework"`
-	MinParallelism         int               `mapstructure:"min_parallelism"`
-	MaxParallelism         int               `mapstructure:"max_parallelism"`
-	ParallelRunnerOverhead time.Duration     `mapstructure:"parallel_runner_overhead"`
-	TargetTime             time.Duration     `mapstructure:"target_time"`
-	WorkerEnv              string            `mapstructure:"worker_env"`
-	CiNode                 int               `mapstructure:"ci_node"`
-	CiNodeWorkers          int               `mapstructure:"ci_node_workers"`
-	Command                string            `mapstructure:"command"`
-	TestsLocation          string            `mapstructure:"tests_location"`
-	TestsExcludePattern    string            `mapstructure:"tests_exclude_pattern"`
-	TestDiscoveryCache     string            `mapstructure:"test_discovery_cache"`
-	TestSkippingLevel      TestSkippingLevel `mapstructure:"test_skipping_mode"`
-	ForceFullTestDiscovery bool              `mapstructure:"force_full_test_discovery"`
-	StrictDiscovery        bool              `mapstructure:"strict_discovery"`
-	RuntimeTags            string            `mapstructure:"runtime_tags"`
-	ReportEnabled          bool              `mapstructure:"report_enabled"`
+	Platform                string            `mapstructure:"platform"`
+	Framework               string            `mapstructure:"framework"`
+	MinParallelism          int               `mapstructure:"min_parallelism"`
+	MaxParallelism          int               `mapstructure:"max_parallelism"`
+	ParallelRunnerOverhead  time.Duration     `mapstructure:"parallel_runner_overhead"`
+	TargetTime              time.Duration     `mapstructure:"target_time"`
+	SplitOptimizer          SplitOptimizer    `mapstructure:"split_optimizer"`
+	DurationEstimate        DurationEstimate  `mapstructure:"duration_estimate"`
+	OptimizeFor             OptimizationGoal  `mapstructure:"optimize_for"`
+	CiJobPricePerMinute     float64           `mapstructure:"ci_job_price_per_minute"`
+	CiJobBillingIncrement   time.Duration     `mapstructure:"ci_job_billing_increment"`
+	CiJobStartupTime        time.Duration     `mapstructure:"ci_job_startup_time"`
+	CiBudget                float64           `mapstructure:"ci_budget"`
+	ChunkSlowTestFiles      bool              `mapstructure:"chunk_slow_test_files"`
+	ConstraintsFile         string            `mapstructure:"constraints_file"`
+	Workspace               string            `mapstructure:"workspace"`
+	PreviousPlan            string            `mapstructure:"previous_plan"`
+	StickySplitTolerance    float64           `mapstructure:"sticky_split_tolerance"`
+	PlanLayout              PlanLayout        `mapstructure:"plan_layout"`
+	SplitOrder              string            `mapstructure:"split_order"`
+	PipelineTemplate        string            `mapstructure:"pipeline_template"`
+	WorkerEnv               string            `mapstructure:"worker_env"`
+	CiNode                  int               `mapstructure:"ci_node"`
+	CiNodeWorkers           int               `mapstructure:"ci_node_workers"`
+	CiNodeCapacities        string            `mapstructure:"ci_node_capacities"`
+	WorkQueue               bool              `mapstructure:"work_queue"`
+	WorkQueueBatchSize      int               `mapstructure:"work_queue_batch_size"`
+	QueueURL                string            `mapstructure:"queue_url"`
+	QueueListen             string            `mapstructure:"queue_listen"`
+	QueueLeaseTimeout       time.Duration     `mapstructure:"queue_lease_timeout"`
+	RetryFailedFiles        int               `mapstructure:"retry_failed_files"`
+	WorkerOutput            WorkerOutputMode  `mapstructure:"worker_output"`
+	FailFast                bool              `mapstructure:"fail_fast"`
+	WorkerTimeout           time.Duration     `mapstructure:"worker_timeout"`
+	WorkerTimeoutMultiplier float64           `mapstructure:"worker_timeout_multiplier"`
+	WorkerTimeoutSignal     string            `mapstructure:"worker_timeout_signal"`
+	JUnitReports            string            `mapstructure:"junit_reports"`
+	PreferLocalDurations    bool              `mapstructure:"prefer_local_durations"`
+	DurationHistory         string            `mapstructure:"duration_history"`
+	Command                 string            `mapstructure:"command"`
+	TestsLocation           string            `mapstructure:"tests_location"`
+	TestsExcludePattern     string            `mapstructure:"tests_exclude_pattern"`
+	TestDiscoveryCache      string            `mapstructure:"test_discovery_cache"`
+	TestSkippingLevel       TestSkippingLevel `mapstructure:"test_skipping_mode"`
+	ForceFullTestDiscovery  bool              `mapstructure:"force_full_test_discovery"`
+	StrictDiscovery         bool              `mapstructure:"strict_discovery"`
+	RuntimeTags             string            `mapstructure:"runtime_tags"`
+	ReportEnabled           bool              `mapstructure:"report_enabled"`
+	RunReportPath           string            `mapstructure:"run_report_path"`
 }
 
 var (
 	config *Config
+	// ciNodeWorkersConfigured records whether ci_node_workers was set by a
+	// flag or environment variable rather than left at its default.
+	ciNodeWorkersConfigured bool
+	// ciNodeConfigured records the same for ci_node.
+	ciNodeConfigured bool
 )
 
 func Init() {
@@ -179,14 +352,108 @@ func Init() {
 		os.Exit(1)
 	}
 
+	// Checked before the defaults are set, so that only flags and
+	// environment variables count.
+	ciNodeWorkersConfigured = viper.IsSet("ci_node_workers")
+	ciNodeConfigured = viper.IsSet("ci_node")
 	setDefaults()
 
+	frameworks, err := ParseFrameworks(viper.GetString("framework"))
+	if err == nil {
+		err = validateFrameworks(frameworks, viper.GetString("tests_location"), viper.GetString("command"))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
 	ciNodeWorkers, err := ParseCiNodeWorkers(viper.GetString("ci_node_workers"))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 		os.Exit(1)
 	}
 	viper.Set("ci_node_workers", ciNodeWorkers)
+	if _, err := ParseCiNodeCapacities(viper.GetString("ci_node_capacities")); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if batchSize := viper.GetInt("work_queue_batch_size"); batchSize < 1 {
+		fmt.Fprintf(os.Stderr, "Error loading config: work_queue_batch_size must be greater than 0, got %d\n", batchSize)
+		os.Exit(1)
+	}
+	if retries := viper.GetInt("retry_failed_files"); retries < 0 {
+		fmt.Fprintf(os.Stderr, "Error loading config: retry_failed_files must not be negative, got %d\n", retries)
+		os.Exit(1)
+	}
+	splitOptimizer, err := ParseSplitOptimizer(viper.GetString("split_optimizer"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	viper.Set("split_optimizer", splitOptimizer)
+	durationEstimate, err := ParseDurationEstimate(viper.GetString("duration_estimate"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	viper.Set("duration_estimate", durationEstimate)
+	optimizeFor, err := ParseOptimizationGoal(viper.GetString("optimize_for"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	viper.Set("optimize_for", optimizeFor)
+	if err := validateCostModel(optimizeFor, viper.GetFloat64("ci_job_price_per_minute"), viper.GetFloat64("ci_budget")); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	ciJobBillingIncrement, err := ParseNonNegativeDurationSetting(viper.GetString("ci_job_billing_increment"), 0, "ci-job-billing-increment")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	viper.Set("ci_job_billing_increment", ciJobBillingIncrement)
+	ciJobStartupTime, err := ParseNonNegativeDurationSetting(viper.GetString("ci_job_startup_time"), 0, "ci-job-startup-time")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	viper.Set("ci_job_startup_time", ciJobStartupTime)
+	if tolerance := viper.GetFloat64("sticky_split_tolerance"); tolerance < 0 {
+		fmt.Fprintf(os.Stderr, "Error loading config: sticky_split_tolerance must not be negative, got %g\n", tolerance)
+		os.Exit(1)
+	}
+	planLayout, err := ParsePlanLayout(viper.GetString("plan_layout"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	viper.Set("plan_layout", planLayout)
+	if _, err := ParseSplitOrder(viper.GetString("split_order")); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	workerOutput, err := ParseWorkerOutputMode(viper.GetString("worker_output"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	viper.Set("worker_output", workerOutput)
+	workerTimeout, err := ParseNonNegativeDurationSetting(viper.GetString("worker_timeout"), 0, "worker-timeout")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	viper.Set("worker_timeout", workerTimeout)
+	if multiplier := viper.GetFloat64("worker_timeout_multiplier"); multiplier < 0 {
+		fmt.Fprintf(os.Stderr, "Error loading config: worker_timeout_multiplier must not be negative, got %g\n", multiplier)
+		os.Exit(1)
+	}
+	workerTimeoutSignal, err := ParseWorkerTimeoutSignal(viper.GetString("worker_timeout_signal"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	viper.Set("worker_timeout_signal", workerTimeoutSignal)
 	parallelRunnerOverhead, err := ParseNonNegativeDurationSetting(
 		viper.GetString("parallel_runner_overhead"),
 		defaultParallelRunnerOverhead,
@@ -207,6 +474,19 @@ func Init() {
 		os.Exit(1)
 	}
 	viper.Set("target_time", targetTime)
+	queueLeaseTimeout, err := ParseNonNegativeDurationSetting(
+		viper.GetString("queue_lease_timeout"),
+		defaultQueueLeaseTimeout,
+		"queue-lease-timeout",
+	)
+	if err == nil && queueLeaseTimeout == 0 {
+		err = fmt.Errorf("queue-lease-timeout must be greater than 0, got %q", viper.GetString("queue_lease_timeout"))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	viper.Set("queue_lease_timeout", queueLeaseTimeout)
 	viper.Set("test_skipping_mode", NormalizeTestSkippingLevel(TestSkippingLevel(viper.GetString("test_skipping_mode"))))
 
 	config = &Config{}
@@ -223,9 +503,39 @@ func setDefaults() {
 	viper.SetDefault("max_parallelism", DefaultParallelism())
 	viper.SetDefault("parallel_runner_overhead", defaultParallelRunnerOverhead.String())
 	viper.SetDefault("target_time", defaultTargetTime.String())
+	viper.SetDefault("split_optimizer", SplitOptimizerGreedy)
+	viper.SetDefault("duration_estimate", DurationEstimateP50)
+	viper.SetDefault("optimize_for", OptimizationGoalTime)
+	viper.SetDefault("ci_job_price_per_minute", 0)
+	viper.SetDefault("ci_job_billing_increment", "0s")
+	viper.SetDefault("ci_job_startup_time", "0s")
+	viper.SetDefault("ci_budget", 0)
+	viper.SetDefault("chunk_slow_test_files", false)
+	viper.SetDefault("constraints_file", "")
+	viper.SetDefault("workspace", "")
+	viper.SetDefault("previous_plan", "")
+	viper.SetDefault("sticky_split_tolerance", defaultStickySplitTolerance)
+	viper.SetDefault("plan_layout", PlanLayoutV1)
+	viper.SetDefault("split_order", "")
+	viper.SetDefault("pipeline_template", "")
 	viper.SetDefault("worker_env", "")
 	viper.SetDefault("ci_node", -1)
 	viper.SetDefault("ci_node_workers", strconv.Itoa(defaultCiNodeWorkers))
+	viper.SetDefault("ci_node_capacities", "")
+	viper.SetDefault("work_queue", false)
+	viper.SetDefault("work_queue_batch_size", defaultWorkQueueBatchSize)
+	viper.SetDefault("queue_url", "")
+	viper.SetDefault("queue_listen", defaultQueueListen)
+	viper.SetDefault("queue_lease_timeout", defaultQueueLeaseTimeout.String())
+	viper.SetDefault("retry_failed_files", 0)
+	viper.SetDefault("worker_output", WorkerOutputStream)
+	viper.SetDefault("fail_fast", false)
+	viper.SetDefault("worker_timeout", "0s")
+	viper.SetDefault("worker_timeout_multiplier", 0)
+	viper.SetDefault("worker_timeout_signal", defaultWorkerTimeoutSignal)
+	viper.SetDefault("junit_reports", "")
+	viper.SetDefault("prefer_local_durations", false)
+	viper.SetDefault("duration_history", "")
 	viper.SetDefault("command", "")
 	viper.SetDefault("tests_location", "")
 	viper.SetDefault("tests_exclude_pattern", "")
@@ -235,6 +545,7 @@ func setDefaults() {
 	viper.SetDefault("strict_discovery", false)
 	viper.SetDefault("runtime_tags", "")
 	viper.SetDefault("report_enabled", true)
+	viper.SetDefault("run_report_path", "")
 }
 
 // NormalizeTestSkippingLevel accepts only the backend-supported TIA skipping modes.
@@ -291,6 +602,241 @@ func ParseCiNodeWorkers(value string) (int, error) {
 	return workers, nil
 }
 
+// ParseCiNodeCapacities parses the ci_node_capacities setting, a
+// comma-separated list of CI node indexes or inclusive index ranges with their
+// local worker counts, such as "0-3:8,4-7:4". It returns the worker count of
+// each listed CI node.
+func ParseCiNodeCapacities(value string) (map[int]int, error) {
+	capacities := make(map[int]int)
+	for entry := range strings.SplitSeq(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		nodes, workersValue, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("ci_node_capacities entries must look like %q or %q, got %q", "0-3:8", "4:2", entry)
+		}
+		workers, err := strconv.Atoi(strings.TrimSpace(workersValue))
+		if err != nil || workers < 1 {
+			return nil, fmt.Errorf("ci_node_capacities worker count must be a positive integer, got %q in %q", workersValue, entry)
+		}
+
+		firstValue, lastValue, isRange := strings.Cut(nodes, "-")
+		first, err := strconv.Atoi(strings.TrimSpace(firstValue))
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("ci_node_capacities CI node must be a non-negative integer, got %q in %q", firstValue, entry)
+		}
+		last := first
+		if isRange {
+			last, err = strconv.Atoi(strings.TrimSpace(lastValue))
+			if err != nil || last < first {
+				return nil, fmt.Errorf("ci_node_capacities CI node range must end at or after %d, got %q in %q", first, lastValue, entry)
+			}
+		}
+
+		for node := first; node <= last; node++ {
+			if _, exists := capacities[node]; exists {
+				return nil, fmt.Errorf("ci_node_capacities lists CI node %d more than once", node)
+			}
+			capacities[node] = workers
+		}
+	}
+	return capacities, nil
+}
+
+// FrameworkEntry is one framework of the framework setting. TestsLocation is
+// the glob pattern of the test files it owns when several frameworks are
+// planned together, or "" for the framework's default test files.
+type FrameworkEntry struct {
+	Name          string
+	TestsLocation string
+}
+
+// ParseFrameworks parses the framework setting: a framework name, or a
+// comma-separated list of frameworks of the same platform planned together,
+// each optionally followed by "=" and the glob pattern of its test files, such
+// as "jest=src/**/*.test.ts,playwright". Commas inside braces belong to the
+// pattern.
+func ParseFrameworks(value string) ([]FrameworkEntry, error) {
+	var entries []FrameworkEntry
+	seen := make(map[string]bool)
+	for _, entry := range splitOutsideBraces(value) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, testsLocation, _ := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		testsLocation = strings.TrimSpace(testsLocation)
+		if name == "" {
+			return nil, fmt.Errorf("framework entries must look like %q or %q, got %q", "jest", "jest=src/**/*.test.ts", entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("framework lists %q more than once", name)
+		}
+		seen[name] = true
+		entries = append(entries, FrameworkEntry{Name: name, TestsLocation: testsLocation})
+	}
+	if len(entries) == 1 && entries[0].TestsLocation != "" {
+		return nil, fmt.Errorf("framework test patterns are only used with several frameworks, use tests_location instead")
+	}
+	return entries, nil
+}
+
+func splitOutsideBraces(value string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range value {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth = max(depth-1, 0)
+		case ',':
+			if depth == 0 {
+				parts = append(parts, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// validateFrameworks rejects the settings that apply to a single framework
+// when several frameworks are planned together.
+func validateFrameworks(frameworks []FrameworkEntry, testsLocation, command string) error {
+	if len(frameworks) < 2 {
+		return nil
+	}
+	if testsLocation != "" {
+		return fmt.Errorf("tests_location cannot be used with several frameworks, set each framework's test pattern with name=pattern instead")
+	}
+	if command != "" {
+		return fmt.Errorf("command cannot be used with several frameworks")
+	}
+	return nil
+}
+
+func ParseSplitOptimizer(value string) (SplitOptimizer, error) {
+	optimizer := SplitOptimizer(strings.ToLower(strings.TrimSpace(value)))
+	switch optimizer {
+	case "":
+		return SplitOptimizerGreedy, nil
+	case SplitOptimizerGreedy, SplitOptimizerLocalSearch:
+		return optimizer, nil
+	default:
+		return "", fmt.Errorf("split_optimizer must be one of %q or %q, got %q", SplitOptimizerGreedy, SplitOptimizerLocalSearch, value)
+	}
+}
+
+func ParseDurationEstimate(value string) (DurationEstimate, error) {
+	estimate := DurationEstimate(strings.ToLower(strings.TrimSpace(value)))
+	switch estimate {
+	case "":
+		return DurationEstimateP50, nil
+	case DurationEstimateP50, DurationEstimateExpected, DurationEstimateP90:
+		return estimate, nil
+	default:
+		return "", fmt.Errorf("duration_estimate must be one of %q, %q, or %q, got %q", DurationEstimateP50, DurationEstimateExpected, DurationEstimateP90, value)
+	}
+}
+
+func ParseOptimizationGoal(value string) (OptimizationGoal, error) {
+	goal := OptimizationGoal(strings.ToLower(strings.TrimSpace(value)))
+	switch goal {
+	case "":
+		return OptimizationGoalTime, nil
+	case OptimizationGoalTime, OptimizationGoalCost:
+		return goal, nil
+	default:
+		return "", fmt.Errorf("optimize_for must be one of %q or %q, got %q", OptimizationGoalTime, OptimizationGoalCost, value)
+	}
+}
+
+func ParsePlanLayout(value string) (PlanLayout, error) {
+	layout := PlanLayout(strings.ToLower(strings.TrimSpace(value)))
+	switch layout {
+	case "":
+		return PlanLayoutV1, nil
+	case PlanLayoutV1, PlanLayoutV2:
+		return layout, nil
+	default:
+		return "", fmt.Errorf("plan_layout must be one of %q or %q, got %q", PlanLayoutV1, PlanLayoutV2, value)
+	}
+}
+
+// validateCostModel checks that the CI job price and budget are not negative,
+// and that a price is set when the planner needs to estimate CI cost.
+func validateCostModel(goal OptimizationGoal, pricePerMinute, budget float64) error {
+	if pricePerMinute < 0 {
+		return fmt.Errorf("ci_job_price_per_minute must not be negative, got %g", pricePerMinute)
+	}
+	if budget < 0 {
+		return fmt.Errorf("ci_budget must not be negative, got %g", budget)
+	}
+	if pricePerMinute == 0 && goal == OptimizationGoalCost {
+		return fmt.Errorf("optimize_for %q requires ci_job_price_per_minute to be set", OptimizationGoalCost)
+	}
+	if pricePerMinute == 0 && budget > 0 {
+		return fmt.Errorf("ci_budget requires ci_job_price_per_minute to be set")
+	}
+	return nil
+}
+
+// ParseSplitOrder parses the split_order setting: a comma-separated list of
+// priorities, highest first, such as "new,changed,longest".
+func ParseSplitOrder(value string) ([]SplitOrder, error) {
+	var order []SplitOrder
+	for _, entry := range strings.Split(value, ",") {
+		priority := SplitOrder(strings.ToLower(strings.TrimSpace(entry)))
+		switch priority {
+		case "":
+			continue
+		case SplitOrderNew, SplitOrderChanged, SplitOrderFlaky, SplitOrderLongest:
+		default:
+			return nil, fmt.Errorf("split_order entries must be %q, %q, %q, or %q, got %q", SplitOrderNew, SplitOrderChanged, SplitOrderFlaky, SplitOrderLongest, entry)
+		}
+		if slices.Contains(order, priority) {
+			return nil, fmt.Errorf("split_order lists %q more than once", priority)
+		}
+		order = append(order, priority)
+	}
+	return order, nil
+}
+
+func ParseWorkerOutputMode(value string) (WorkerOutputMode, error) {
+	mode := WorkerOutputMode(strings.ToLower(strings.TrimSpace(value)))
+	switch mode {
+	case "":
+		return WorkerOutputStream, nil
+	case WorkerOutputStream, WorkerOutputPrefix, WorkerOutputBuffered, WorkerOutputFile:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("worker_output must be one of %q, %q, %q, or %q, got %q", WorkerOutputStream, WorkerOutputPrefix, WorkerOutputBuffered, WorkerOutputFile, value)
+	}
+}
+
+// ParseWorkerTimeoutSignal normalizes the name of the diagnostic signal sent
+// to a timed-out worker, accepting names with or without the SIG prefix.
+// "none" skips the diagnostic signal.
+func ParseWorkerTimeoutSignal(value string) (string, error) {
+	name := strings.ToUpper(strings.TrimSpace(value))
+	switch {
+	case name == "":
+		return defaultWorkerTimeoutSignal, nil
+	case name == "NONE":
+		return WorkerTimeoutSignalNone, nil
+	case !strings.HasPrefix(name, "SIG"):
+		name = "SIG" + name
+	}
+	if !slices.Contains(workerTimeoutSignals, name) {
+		return "", fmt.Errorf("worker_timeout_signal must be one of %s, or %q, got %q", strings.Join(workerTimeoutSignals, ", "), WorkerTimeoutSignalNone, value)
+	}
+	return name, nil
+}
+
 func Get() *Config {
 	if config == nil {
 		Init()
@@ -306,6 +852,15 @@ func GetFramework() string {
 	return Get().Framework
 }
 
+// GetFrameworks returns the frameworks listed in the framework setting.
+func GetFrameworks() []FrameworkEntry {
+	frameworks, err := ParseFrameworks(GetFramework())
+	if err != nil {
+		return nil
+	}
+	return frameworks
+}
+
 func GetMinParallelism() int {
 	return Get().MinParallelism
 }
@@ -322,6 +877,79 @@ func GetTargetTime() time.Duration {
 	return Get().TargetTime
 }
 
+func GetSplitOptimizer() SplitOptimizer {
+	return Get().SplitOptimizer
+}
+
+func GetDurationEstimate() DurationEstimate {
+	return Get().DurationEstimate
+}
+
+func GetOptimizeFor() OptimizationGoal {
+	return Get().OptimizeFor
+}
+
+// GetCiJobPricePerMinute returns the price of one minute of a CI job. Zero
+// disables the CI cost model.
+func GetCiJobPricePerMinute() float64 {
+	return Get().CiJobPricePerMinute
+}
+
+func GetCiJobBillingIncrement() time.Duration {
+	return Get().CiJobBillingIncrement
+}
+
+func GetCiJobStartupTime() time.Duration {
+	return Get().CiJobStartupTime
+}
+
+func GetCiBudget() float64 {
+	return Get().CiBudget
+}
+
+func GetChunkSlowTestFiles() bool {
+	return Get().ChunkSlowTestFiles
+}
+
+func GetConstraintsFile() string {
+	return Get().ConstraintsFile
+}
+
+func GetWorkspace() string {
+	return Get().Workspace
+}
+
+func GetPreviousPlan() string {
+	return Get().PreviousPlan
+}
+
+// GetStickySplitTolerance returns how much longer, in percent, the wall time
+// of a split that keeps test files on their previous runner may be than the
+// wall time of a split from scratch.
+func GetStickySplitTolerance() float64 {
+	return Get().StickySplitTolerance
+}
+
+func GetPlanLayout() PlanLayout {
+	return Get().PlanLayout
+}
+
+// GetSplitOrder returns the priorities that order the test files within each
+// runner split, highest first.
+func GetSplitOrder() []SplitOrder {
+	order, err := ParseSplitOrder(Get().SplitOrder)
+	if err != nil {
+		return nil
+	}
+	return order
+}
+
+// GetPipelineTemplate returns the path of the job skeleton that ddtest plan
+// renders into a pipeline definition for Buildkite, GitLab, or CircleCI.
+func GetPipelineTemplate() string {
+	return Get().PipelineTemplate
+}
+
 func GetWorkerEnv() string {
 	return Get().WorkerEnv
 }
@@ -330,10 +958,96 @@ func GetCiNode() int {
 	return Get().CiNode
 }
 
+// CiNodeConfigured reports whether ci_node was set explicitly instead of left
+// at its default.
+func CiNodeConfigured() bool {
+	Get()
+	return ciNodeConfigured
+}
+
 func GetCiNodeWorkers() int {
 	return Get().CiNodeWorkers
 }
 
+// CiNodeWorkersConfigured reports whether ci_node_workers was set explicitly
+// instead of left at its default.
+func CiNodeWorkersConfigured() bool {
+	Get()
+	return ciNodeWorkersConfigured
+}
+
+func GetCiNodeCapacities() string {
+	return Get().CiNodeCapacities
+}
+
+// GetCiNodeCapacitiesMap returns the local worker count of each CI node listed
+// in the ci_node_capacities setting, or an empty map when it is not set.
+func GetCiNodeCapacitiesMap() map[int]int {
+	capacities, err := ParseCiNodeCapacities(GetCiNodeCapacities())
+	if err != nil {
+		return make(map[int]int)
+	}
+	return capacities
+}
+
+func GetWorkQueue() bool {
+	return Get().WorkQueue
+}
+
+func GetWorkQueueBatchSize() int {
+	return Get().WorkQueueBatchSize
+}
+
+func GetQueueURL() string {
+	return Get().QueueURL
+}
+
+func GetQueueListen() string {
+	return Get().QueueListen
+}
+
+func GetQueueLeaseTimeout() time.Duration {
+	return Get().QueueLeaseTimeout
+}
+
+func GetRetryFailedFiles() int {
+	return Get().RetryFailedFiles
+}
+
+func GetWorkerOutput() WorkerOutputMode {
+	return Get().WorkerOutput
+}
+
+func GetFailFast() bool {
+	return Get().FailFast
+}
+
+func GetWorkerTimeout() time.Duration {
+	return Get().WorkerTimeout
+}
+
+func GetWorkerTimeoutMultiplier() float64 {
+	return Get().WorkerTimeoutMultiplier
+}
+
+func GetWorkerTimeoutSignal() string {
+	return Get().WorkerTimeoutSignal
+}
+
+func GetJUnitReports() string {
+	return Get().JUnitReports
+}
+
+func GetPreferLocalDurations() bool {
+	return Get().PreferLocalDurations
+}
+
+// GetDurationHistory returns the path of the JSONL file of observed test file
+// durations that ddtest run appends to and ddtest plan learns from.
+func GetDurationHistory() string {
+	return Get().DurationHistory
+}
+
 func GetCommand() string {
 	return Get().Command
 }
@@ -370,6 +1084,12 @@ func GetReportEnabled() bool {
 	return Get().ReportEnabled
 }
 
+// GetRunReportPath returns where ddtest run writes its JSON run report. An
+// empty path means the default path for the CI node.
+func GetRunReportPath() string {
+	return Get().RunReportPath
+}
+
 // GetRuntimeTagsMap parses the runtime_tags setting as JSON and returns it as a map.
 // Returns nil if runtime_tags is empty or not set.
 // Returns an error if the JSON is invalid.
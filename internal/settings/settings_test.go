@@ -1,7 +1,9 @@
 package settings
 
 import (
+	"maps"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -142,15 +144,106 @@ func TestInit(t *testing.T) {
 	if config.TargetTime != expectedDefaultTargetTime {
 		t.Errorf("expected default target_time to be %s, got %s", expectedDefaultTargetTime, config.TargetTime)
 	}
+	if config.SplitOptimizer != SplitOptimizerGreedy {
+		t.Errorf("expected default split_optimizer to be %q, got %q", SplitOptimizerGreedy, config.SplitOptimizer)
+	}
+	if config.DurationEstimate != DurationEstimateP50 {
+		t.Errorf("expected default duration_estimate to be %q, got %q", DurationEstimateP50, config.DurationEstimate)
+	}
+	if config.OptimizeFor != OptimizationGoalTime {
+		t.Errorf("expected default optimize_for to be %q, got %q", OptimizationGoalTime, config.OptimizeFor)
+	}
+	if config.CiJobPricePerMinute != 0 || config.CiJobBillingIncrement != 0 || config.CiJobStartupTime != 0 || config.CiBudget != 0 {
+		t.Errorf("expected the CI cost model to be disabled by default, got price %g, billing increment %s, startup time %s, and budget %g",
+			config.CiJobPricePerMinute, config.CiJobBillingIncrement, config.CiJobStartupTime, config.CiBudget)
+	}
+	if config.ChunkSlowTestFiles {
+		t.Errorf("expected default chunk_slow_test_files to be false, got %t", config.ChunkSlowTestFiles)
+	}
+	if config.ConstraintsFile != "" {
+		t.Errorf("expected default constraints_file to be empty, got %q", config.ConstraintsFile)
+	}
+	if config.Workspace != "" {
+		t.Errorf("expected default workspace to be empty, got %q", config.Workspace)
+	}
+	if config.PreviousPlan != "" {
+		t.Errorf("expected default previous_plan to be empty, got %q", config.PreviousPlan)
+	}
+	if config.StickySplitTolerance != 5 {
+		t.Errorf("expected default sticky_split_tolerance to be 5, got %g", config.StickySplitTolerance)
+	}
+	if config.PlanLayout != PlanLayoutV1 {
+		t.Errorf("expected default plan_layout to be %q, got %q", PlanLayoutV1, config.PlanLayout)
+	}
+	if config.SplitOrder != "" {
+		t.Errorf("expected default split_order to be empty, got %q", config.SplitOrder)
+	}
+	if config.PipelineTemplate != "" {
+		t.Errorf("expected default pipeline_template to be empty, got %q", config.PipelineTemplate)
+	}
 	if config.WorkerEnv != "" {
 		t.Errorf("expected default worker_env to be empty, got %q", config.WorkerEnv)
 	}
 	if config.CiNode != -1 {
 		t.Errorf("expected default ci_node to be -1, got %d", config.CiNode)
 	}
+	if CiNodeConfigured() {
+		t.Error("expected default ci_node not to be configured")
+	}
 	if config.CiNodeWorkers != 1 {
 		t.Errorf("expected default ci_node_workers to be 1, got %d", config.CiNodeWorkers)
 	}
+	if CiNodeWorkersConfigured() {
+		t.Error("expected default ci_node_workers not to be configured")
+	}
+	if config.CiNodeCapacities != "" {
+		t.Errorf("expected default ci_node_capacities to be empty, got %q", config.CiNodeCapacities)
+	}
+	if config.WorkQueue {
+		t.Error("expected default work_queue to be false")
+	}
+	if config.WorkQueueBatchSize != 1 {
+		t.Errorf("expected default work_queue_batch_size to be 1, got %d", config.WorkQueueBatchSize)
+	}
+	if config.QueueURL != "" {
+		t.Errorf("expected default queue_url to be empty, got %q", config.QueueURL)
+	}
+	if config.QueueListen != DefaultQueueListen() {
+		t.Errorf("expected default queue_listen to be %q, got %q", DefaultQueueListen(), config.QueueListen)
+	}
+	if config.QueueLeaseTimeout != DefaultQueueLeaseTimeout() {
+		t.Errorf("expected default queue_lease_timeout to be %s, got %s", DefaultQueueLeaseTimeout(), config.QueueLeaseTimeout)
+	}
+	if config.RetryFailedFiles != 0 {
+		t.Errorf("expected default retry_failed_files to be 0, got %d", config.RetryFailedFiles)
+	}
+	if config.WorkerOutput != WorkerOutputStream {
+		t.Errorf("expected default worker_output to be %q, got %q", WorkerOutputStream, config.WorkerOutput)
+	}
+	if config.FailFast {
+		t.Errorf("expected default fail_fast to be false, got %t", config.FailFast)
+	}
+	if config.WorkerTimeout != 0 {
+		t.Errorf("expected default worker_timeout to be 0, got %s", config.WorkerTimeout)
+	}
+	if config.WorkerTimeoutMultiplier != 0 {
+		t.Errorf("expected default worker_timeout_multiplier to be 0, got %g", config.WorkerTimeoutMultiplier)
+	}
+	if config.WorkerTimeoutSignal != "SIGQUIT" {
+		t.Errorf("expected default worker_timeout_signal to be 'SIGQUIT', got %q", config.WorkerTimeoutSignal)
+	}
+	if config.JUnitReports != "" {
+		t.Errorf("expected default junit_reports to be empty, got %q", config.JUnitReports)
+	}
+	if config.RunReportPath != "" {
+		t.Errorf("expected default run_report_path to be empty, got %q", config.RunReportPath)
+	}
+	if config.PreferLocalDurations {
+		t.Errorf("expected default prefer_local_durations to be false, got %t", config.PreferLocalDurations)
+	}
+	if config.DurationHistory != "" {
+		t.Errorf("expected default duration_history to be empty, got %q", config.DurationHistory)
+	}
 	if config.Command != "" {
 		t.Errorf("expected default command to be empty, got %q", config.Command)
 	}
@@ -204,6 +297,45 @@ func TestSetDefaults(t *testing.T) {
 	if viper.GetString("target_time") != expectedDefaultTargetTime.String() {
 		t.Errorf("expected default target_time to be %s, got %q", expectedDefaultTargetTime, viper.GetString("target_time"))
 	}
+	if viper.GetString("split_optimizer") != "greedy" {
+		t.Errorf("expected default split_optimizer to be 'greedy', got %q", viper.GetString("split_optimizer"))
+	}
+	if viper.GetString("duration_estimate") != "p50" {
+		t.Errorf("expected default duration_estimate to be 'p50', got %q", viper.GetString("duration_estimate"))
+	}
+	if viper.GetString("optimize_for") != "time" {
+		t.Errorf("expected default optimize_for to be 'time', got %q", viper.GetString("optimize_for"))
+	}
+	if viper.GetFloat64("ci_job_price_per_minute") != 0 {
+		t.Errorf("expected default ci_job_price_per_minute to be 0, got %g", viper.GetFloat64("ci_job_price_per_minute"))
+	}
+	if viper.GetFloat64("ci_budget") != 0 {
+		t.Errorf("expected default ci_budget to be 0, got %g", viper.GetFloat64("ci_budget"))
+	}
+	if viper.GetBool("chunk_slow_test_files") {
+		t.Error("expected default chunk_slow_test_files to be false")
+	}
+	if viper.GetString("constraints_file") != "" {
+		t.Errorf("expected default constraints_file to be empty, got %q", viper.GetString("constraints_file"))
+	}
+	if viper.GetString("workspace") != "" {
+		t.Errorf("expected default workspace to be empty, got %q", viper.GetString("workspace"))
+	}
+	if viper.GetString("previous_plan") != "" {
+		t.Errorf("expected default previous_plan to be empty, got %q", viper.GetString("previous_plan"))
+	}
+	if viper.GetFloat64("sticky_split_tolerance") != 5 {
+		t.Errorf("expected default sticky_split_tolerance to be 5, got %g", viper.GetFloat64("sticky_split_tolerance"))
+	}
+	if viper.GetString("plan_layout") != "v1" {
+		t.Errorf("expected default plan_layout to be 'v1', got %q", viper.GetString("plan_layout"))
+	}
+	if viper.GetString("split_order") != "" {
+		t.Errorf("expected default split_order to be empty, got %q", viper.GetString("split_order"))
+	}
+	if viper.GetString("pipeline_template") != "" {
+		t.Errorf("expected default pipeline_template to be empty, got %q", viper.GetString("pipeline_template"))
+	}
 	if viper.GetString("worker_env") != "" {
 		t.Errorf("expected default worker_env to be empty, got %q", viper.GetString("worker_env"))
 	}
@@ -213,6 +345,51 @@ func TestSetDefaults(t *testing.T) {
 	if viper.GetInt("ci_node_workers") != 1 {
 		t.Errorf("expected default ci_node_workers to be 1, got %d", viper.GetInt("ci_node_workers"))
 	}
+	if viper.GetString("ci_node_capacities") != "" {
+		t.Errorf("expected default ci_node_capacities to be empty, got %q", viper.GetString("ci_node_capacities"))
+	}
+	if viper.GetBool("work_queue") {
+		t.Error("expected default work_queue to be false")
+	}
+	if viper.GetInt("work_queue_batch_size") != 1 {
+		t.Errorf("expected default work_queue_batch_size to be 1, got %d", viper.GetInt("work_queue_batch_size"))
+	}
+	if viper.GetString("queue_listen") != "127.0.0.1:7878" {
+		t.Errorf("expected default queue_listen to be '127.0.0.1:7878', got %q", viper.GetString("queue_listen"))
+	}
+	if viper.GetString("queue_lease_timeout") != "2m0s" {
+		t.Errorf("expected default queue_lease_timeout to be '2m0s', got %q", viper.GetString("queue_lease_timeout"))
+	}
+	if viper.GetInt("retry_failed_files") != 0 {
+		t.Errorf("expected default retry_failed_files to be 0, got %d", viper.GetInt("retry_failed_files"))
+	}
+	if viper.GetString("worker_output") != "stream" {
+		t.Errorf("expected default worker_output to be 'stream', got %q", viper.GetString("worker_output"))
+	}
+	if viper.GetBool("fail_fast") {
+		t.Error("expected default fail_fast to be false")
+	}
+	if viper.GetString("worker_timeout") != "0s" {
+		t.Errorf("expected default worker_timeout to be '0s', got %q", viper.GetString("worker_timeout"))
+	}
+	if viper.GetFloat64("worker_timeout_multiplier") != 0 {
+		t.Errorf("expected default worker_timeout_multiplier to be 0, got %g", viper.GetFloat64("worker_timeout_multiplier"))
+	}
+	if viper.GetString("worker_timeout_signal") != "SIGQUIT" {
+		t.Errorf("expected default worker_timeout_signal to be 'SIGQUIT', got %q", viper.GetString("worker_timeout_signal"))
+	}
+	if viper.GetString("junit_reports") != "" {
+		t.Errorf("expected default junit_reports to be empty, got %q", viper.GetString("junit_reports"))
+	}
+	if viper.GetString("run_report_path") != "" {
+		t.Errorf("expected default run_report_path to be empty, got %q", viper.GetString("run_report_path"))
+	}
+	if viper.GetBool("prefer_local_durations") {
+		t.Errorf("expected default prefer_local_durations to be false, got %t", viper.GetBool("prefer_local_durations"))
+	}
+	if viper.GetString("duration_history") != "" {
+		t.Errorf("expected default duration_history to be empty, got %q", viper.GetString("duration_history"))
+	}
 	if viper.GetString("command") != "" {
 		t.Errorf("expected default command to be empty, got %q", viper.GetString("command"))
 	}
@@ -367,9 +544,15 @@ func TestEnvironmentVariables(t *testing.T) {
 	if config.CiNode != 5 {
 		t.Errorf("expected ci_node from env var to be 5, got %d", config.CiNode)
 	}
+	if !CiNodeConfigured() {
+		t.Error("expected ci_node from env var to be configured")
+	}
 	if config.CiNodeWorkers != 4 {
 		t.Errorf("expected ci_node_workers from env var to be 4, got %d", config.CiNodeWorkers)
 	}
+	if !CiNodeWorkersConfigured() {
+		t.Error("expected ci_node_workers from env var to be configured")
+	}
 	if config.Command != "bundle exec rspec" {
 		t.Errorf("expected command from env var to be 'bundle exec rspec', got %q", config.Command)
 	}
@@ -1043,6 +1226,688 @@ func TestEnvironmentVariableCiNodeWorkersNCPU(t *testing.T) {
 	}
 }
 
+func TestEnvironmentVariablesWorkQueue(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(workQueueEnv, "true")
+	_ = os.Setenv(workQueueBatchSizeEnv, "3")
+	defer func() {
+		_ = os.Unsetenv(workQueueEnv)
+		_ = os.Unsetenv(workQueueBatchSizeEnv)
+	}()
+
+	Init()
+
+	if !GetWorkQueue() {
+		t.Error("expected work_queue from env var to be true")
+	}
+	if GetWorkQueueBatchSize() != 3 {
+		t.Errorf("expected work_queue_batch_size from env var to be 3, got %d", GetWorkQueueBatchSize())
+	}
+}
+
+func TestEnvironmentVariablesQueue(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(queueURLEnv, "http://10.0.0.5:7878")
+	_ = os.Setenv(queueListenEnv, "0.0.0.0:7878")
+	_ = os.Setenv(queueLeaseTimeoutEnv, "30s")
+	defer func() {
+		_ = os.Unsetenv(queueURLEnv)
+		_ = os.Unsetenv(queueListenEnv)
+		_ = os.Unsetenv(queueLeaseTimeoutEnv)
+	}()
+
+	Init()
+
+	if GetQueueURL() != "http://10.0.0.5:7878" {
+		t.Errorf("expected queue_url from env var to be 'http://10.0.0.5:7878', got %q", GetQueueURL())
+	}
+	if GetQueueListen() != "0.0.0.0:7878" {
+		t.Errorf("expected queue_listen from env var to be '0.0.0.0:7878', got %q", GetQueueListen())
+	}
+	if GetQueueLeaseTimeout() != 30*time.Second {
+		t.Errorf("expected queue_lease_timeout from env var to be 30s, got %s", GetQueueLeaseTimeout())
+	}
+}
+
+func TestEnvironmentVariablesRetryFailedFiles(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(retryFailedFilesEnv, "2")
+	defer func() {
+		_ = os.Unsetenv(retryFailedFilesEnv)
+	}()
+
+	Init()
+
+	if GetRetryFailedFiles() != 2 {
+		t.Errorf("expected retry_failed_files from env var to be 2, got %d", GetRetryFailedFiles())
+	}
+}
+
+func TestEnvironmentVariablesWorkerOutput(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(workerOutputEnv, " File ")
+	defer func() {
+		_ = os.Unsetenv(workerOutputEnv)
+	}()
+
+	Init()
+
+	if GetWorkerOutput() != WorkerOutputFile {
+		t.Errorf("expected worker_output from env var to be %q, got %q", WorkerOutputFile, GetWorkerOutput())
+	}
+}
+
+func TestEnvironmentVariablesFailFast(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(failFastEnv, "true")
+	defer func() {
+		_ = os.Unsetenv(failFastEnv)
+	}()
+
+	Init()
+
+	if !GetFailFast() {
+		t.Error("expected fail_fast from env var to be true")
+	}
+}
+
+func TestEnvironmentVariablesWorkerTimeout(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(workerTimeoutEnv, "15m")
+	_ = os.Setenv(workerTimeoutMultiplierEnv, "2.5")
+	_ = os.Setenv(workerTimeoutSignalEnv, "usr1")
+	defer func() {
+		_ = os.Unsetenv(workerTimeoutEnv)
+		_ = os.Unsetenv(workerTimeoutMultiplierEnv)
+		_ = os.Unsetenv(workerTimeoutSignalEnv)
+	}()
+
+	Init()
+
+	if GetWorkerTimeout() != 15*time.Minute {
+		t.Errorf("expected worker_timeout from env var to be 15m, got %s", GetWorkerTimeout())
+	}
+	if GetWorkerTimeoutMultiplier() != 2.5 {
+		t.Errorf("expected worker_timeout_multiplier from env var to be 2.5, got %g", GetWorkerTimeoutMultiplier())
+	}
+	if GetWorkerTimeoutSignal() != "SIGUSR1" {
+		t.Errorf("expected worker_timeout_signal from env var to be 'SIGUSR1', got %q", GetWorkerTimeoutSignal())
+	}
+}
+
+func TestParseWorkerTimeoutSignal(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: "SIGQUIT"},
+		{value: "SIGQUIT", want: "SIGQUIT"},
+		{value: " abrt ", want: "SIGABRT"},
+		{value: "None", want: WorkerTimeoutSignalNone},
+		{value: "SIGKILL", wantErr: true},
+		{value: "hup", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseWorkerTimeoutSignal(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseWorkerTimeoutSignal(%q) expected error", tt.value)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseWorkerTimeoutSignal(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvironmentVariablesLocalDurations(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(junitReportsEnv, "tmp/junit/**/*.xml")
+	_ = os.Setenv(preferLocalDurationsEnv, "true")
+	_ = os.Setenv(durationHistoryEnv, ".ddtest-cache/durations.jsonl")
+	defer func() {
+		_ = os.Unsetenv(junitReportsEnv)
+		_ = os.Unsetenv(preferLocalDurationsEnv)
+		_ = os.Unsetenv(durationHistoryEnv)
+	}()
+
+	Init()
+
+	if GetJUnitReports() != "tmp/junit/**/*.xml" {
+		t.Errorf("expected junit_reports from env var to be 'tmp/junit/**/*.xml', got %q", GetJUnitReports())
+	}
+	if !GetPreferLocalDurations() {
+		t.Error("expected prefer_local_durations from env var to be true")
+	}
+	if GetDurationHistory() != ".ddtest-cache/durations.jsonl" {
+		t.Errorf("expected duration_history from env var to be '.ddtest-cache/durations.jsonl', got %q", GetDurationHistory())
+	}
+}
+
+func TestEnvironmentVariablesRunReportPath(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(runReportPathEnv, "tmp/run-report.json")
+	defer func() {
+		_ = os.Unsetenv(runReportPathEnv)
+	}()
+
+	Init()
+
+	if GetRunReportPath() != "tmp/run-report.json" {
+		t.Errorf("expected run_report_path from env var to be 'tmp/run-report.json', got %q", GetRunReportPath())
+	}
+}
+
+func TestEnvironmentVariablesCiNodeCapacities(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(ciNodeCapacitiesEnv, "0-1:8, 2:4")
+	defer func() {
+		_ = os.Unsetenv(ciNodeCapacitiesEnv)
+	}()
+
+	Init()
+
+	if GetCiNodeCapacities() != "0-1:8, 2:4" {
+		t.Errorf("expected ci_node_capacities from env var to be '0-1:8, 2:4', got %q", GetCiNodeCapacities())
+	}
+	expected := map[int]int{0: 8, 1: 8, 2: 4}
+	if !maps.Equal(GetCiNodeCapacitiesMap(), expected) {
+		t.Errorf("expected ci_node_capacities map to be %v, got %v", expected, GetCiNodeCapacitiesMap())
+	}
+}
+
+func TestParseCiNodeCapacities(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    map[int]int
+		wantErr bool
+	}{
+		{value: "", want: map[int]int{}},
+		{value: "0-3:8,4-7:4", want: map[int]int{0: 8, 1: 8, 2: 8, 3: 8, 4: 4, 5: 4, 6: 4, 7: 4}},
+		{value: " 2 : 3 ,", want: map[int]int{2: 3}},
+		{value: "0-3", wantErr: true},
+		{value: "0:0", wantErr: true},
+		{value: "0:ncpu", wantErr: true},
+		{value: "-1:2", wantErr: true},
+		{value: "3-1:2", wantErr: true},
+		{value: "0-2:4,2:8", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseCiNodeCapacities(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseCiNodeCapacities(%q) expected error", tt.value)
+				}
+				return
+			}
+			if err != nil || !maps.Equal(got, tt.want) {
+				t.Fatalf("ParseCiNodeCapacities(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvironmentVariablesSplitOptimizer(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(splitOptimizerEnv, "Local-Search")
+	defer func() {
+		_ = os.Unsetenv(splitOptimizerEnv)
+	}()
+
+	Init()
+
+	if GetSplitOptimizer() != SplitOptimizerLocalSearch {
+		t.Errorf("expected split_optimizer from env var to be %q, got %q", SplitOptimizerLocalSearch, GetSplitOptimizer())
+	}
+}
+
+func TestEnvironmentVariablesDurationEstimate(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(durationEstimateEnv, "P90")
+	defer func() {
+		_ = os.Unsetenv(durationEstimateEnv)
+	}()
+
+	Init()
+
+	if GetDurationEstimate() != DurationEstimateP90 {
+		t.Errorf("expected duration_estimate from env var to be %q, got %q", DurationEstimateP90, GetDurationEstimate())
+	}
+}
+
+func TestEnvironmentVariablesCostModel(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(optimizeForEnv, "Cost")
+	_ = os.Setenv(ciJobPricePerMinuteEnv, "0.008")
+	_ = os.Setenv(ciJobBillingIncrementEnv, "1m")
+	_ = os.Setenv(ciJobStartupTimeEnv, "45s")
+	_ = os.Setenv(ciBudgetEnv, "1.5")
+	defer func() {
+		_ = os.Unsetenv(optimizeForEnv)
+		_ = os.Unsetenv(ciJobPricePerMinuteEnv)
+		_ = os.Unsetenv(ciJobBillingIncrementEnv)
+		_ = os.Unsetenv(ciJobStartupTimeEnv)
+		_ = os.Unsetenv(ciBudgetEnv)
+	}()
+
+	Init()
+
+	if GetOptimizeFor() != OptimizationGoalCost {
+		t.Errorf("expected optimize_for from env var to be %q, got %q", OptimizationGoalCost, GetOptimizeFor())
+	}
+	if GetCiJobPricePerMinute() != 0.008 {
+		t.Errorf("expected ci_job_price_per_minute from env var to be 0.008, got %g", GetCiJobPricePerMinute())
+	}
+	if GetCiJobBillingIncrement() != time.Minute {
+		t.Errorf("expected ci_job_billing_increment from env var to be 1m, got %s", GetCiJobBillingIncrement())
+	}
+	if GetCiJobStartupTime() != 45*time.Second {
+		t.Errorf("expected ci_job_startup_time from env var to be 45s, got %s", GetCiJobStartupTime())
+	}
+	if GetCiBudget() != 1.5 {
+		t.Errorf("expected ci_budget from env var to be 1.5, got %g", GetCiBudget())
+	}
+}
+
+func TestEnvironmentVariablesChunkSlowTestFiles(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(chunkSlowTestFilesEnv, "true")
+	defer func() {
+		_ = os.Unsetenv(chunkSlowTestFilesEnv)
+	}()
+
+	Init()
+
+	if !GetChunkSlowTestFiles() {
+		t.Error("expected chunk_slow_test_files from env var to be true")
+	}
+}
+
+func TestEnvironmentVariablesConstraintsFile(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(constraintsFileEnv, "ci/ddtest-constraints.json")
+	defer func() {
+		_ = os.Unsetenv(constraintsFileEnv)
+	}()
+
+	Init()
+
+	if GetConstraintsFile() != "ci/ddtest-constraints.json" {
+		t.Errorf("expected constraints_file from env var to be %q, got %q", "ci/ddtest-constraints.json", GetConstraintsFile())
+	}
+}
+
+func TestEnvironmentVariablesWorkspace(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(workspaceEnv, "ci/ddtest-workspace.json")
+	defer func() {
+		_ = os.Unsetenv(workspaceEnv)
+	}()
+
+	Init()
+
+	if GetWorkspace() != "ci/ddtest-workspace.json" {
+		t.Errorf("expected workspace from env var to be %q, got %q", "ci/ddtest-workspace.json", GetWorkspace())
+	}
+}
+
+func TestEnvironmentVariablesStickySplit(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(previousPlanEnv, "previous/.testoptimization")
+	_ = os.Setenv(stickySplitToleranceEnv, "12.5")
+	defer func() {
+		_ = os.Unsetenv(previousPlanEnv)
+		_ = os.Unsetenv(stickySplitToleranceEnv)
+	}()
+
+	Init()
+
+	if GetPreviousPlan() != "previous/.testoptimization" {
+		t.Errorf("expected previous_plan from env var to be %q, got %q", "previous/.testoptimization", GetPreviousPlan())
+	}
+	if GetStickySplitTolerance() != 12.5 {
+		t.Errorf("expected sticky_split_tolerance from env var to be 12.5, got %g", GetStickySplitTolerance())
+	}
+}
+
+func TestParseSplitOptimizer(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    SplitOptimizer
+		wantErr bool
+	}{
+		{value: "", want: SplitOptimizerGreedy},
+		{value: "greedy", want: SplitOptimizerGreedy},
+		{value: " LOCAL-SEARCH ", want: SplitOptimizerLocalSearch},
+		{value: "karmarkar-karp", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseSplitOptimizer(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseSplitOptimizer(%q) expected error", tt.value)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseSplitOptimizer(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvironmentVariablesPlanLayout(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(planLayoutEnv, "V2")
+	defer func() {
+		_ = os.Unsetenv(planLayoutEnv)
+	}()
+
+	Init()
+
+	if GetPlanLayout() != PlanLayoutV2 {
+		t.Errorf("expected plan_layout from env var to be %q, got %q", PlanLayoutV2, GetPlanLayout())
+	}
+}
+
+func TestParsePlanLayout(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    PlanLayout
+		wantErr bool
+	}{
+		{value: "", want: PlanLayoutV1},
+		{value: "v1", want: PlanLayoutV1},
+		{value: " V2 ", want: PlanLayoutV2},
+		{value: "v3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParsePlanLayout(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParsePlanLayout(%q) expected error", tt.value)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParsePlanLayout(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvironmentVariablesSplitOrder(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(splitOrderEnv, "new, changed,longest")
+	defer func() {
+		_ = os.Unsetenv(splitOrderEnv)
+	}()
+
+	Init()
+
+	want := []SplitOrder{SplitOrderNew, SplitOrderChanged, SplitOrderLongest}
+	if got := GetSplitOrder(); !slices.Equal(got, want) {
+		t.Errorf("expected split_order from env var to be %v, got %v", want, got)
+	}
+}
+
+func TestEnvironmentVariablesPipelineTemplate(t *testing.T) {
+	config = nil
+	viper.Reset()
+
+	_ = os.Setenv(pipelineTemplateEnv, ".buildkite/ddtest-step.yml")
+	defer func() {
+		_ = os.Unsetenv(pipelineTemplateEnv)
+	}()
+
+	Init()
+
+	if GetPipelineTemplate() != ".buildkite/ddtest-step.yml" {
+		t.Errorf("expected pipeline_template from env var to be '.buildkite/ddtest-step.yml', got %q", GetPipelineTemplate())
+	}
+}
+
+func TestParseSplitOrder(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []SplitOrder
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: "longest", want: []SplitOrder{SplitOrderLongest}},
+		{value: " New,FLAKY, changed ,", want: []SplitOrder{SplitOrderNew, SplitOrderFlaky, SplitOrderChanged}},
+		{value: "new,oldest", wantErr: true},
+		{value: "new,new", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseSplitOrder(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseSplitOrder(%q) expected error", tt.value)
+				}
+				return
+			}
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Fatalf("ParseSplitOrder(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDurationEstimate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    DurationEstimate
+		wantErr bool
+	}{
+		{value: "", want: DurationEstimateP50},
+		{value: "p50", want: DurationEstimateP50},
+		{value: " Expected ", want: DurationEstimateExpected},
+		{value: "P90", want: DurationEstimateP90},
+		{value: "p99", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseDurationEstimate(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseDurationEstimate(%q) expected error", tt.value)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseDurationEstimate(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOptimizationGoal(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    OptimizationGoal
+		wantErr bool
+	}{
+		{value: "", want: OptimizationGoalTime},
+		{value: "time", want: OptimizationGoalTime},
+		{value: " COST ", want: OptimizationGoalCost},
+		{value: "money", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseOptimizationGoal(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseOptimizationGoal(%q) expected error", tt.value)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseOptimizationGoal(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCostModel(t *testing.T) {
+	tests := []struct {
+		name           string
+		goal           OptimizationGoal
+		pricePerMinute float64
+		budget         float64
+		wantErr        bool
+	}{
+		{name: "disabled", goal: OptimizationGoalTime},
+		{name: "priced", goal: OptimizationGoalCost, pricePerMinute: 0.008, budget: 2},
+		{name: "negative price", goal: OptimizationGoalTime, pricePerMinute: -1, wantErr: true},
+		{name: "negative budget", goal: OptimizationGoalTime, pricePerMinute: 0.008, budget: -1, wantErr: true},
+		{name: "cost without price", goal: OptimizationGoalCost, wantErr: true},
+		{name: "budget without price", goal: OptimizationGoalTime, budget: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCostModel(tt.goal, tt.pricePerMinute, tt.budget)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateCostModel(%q, %g, %g) = %v, wantErr %t", tt.goal, tt.pricePerMinute, tt.budget, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseFrameworks(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []FrameworkEntry
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: "rspec", want: []FrameworkEntry{{Name: "rspec"}}},
+		{value: "rspec, minitest", want: []FrameworkEntry{{Name: "rspec"}, {Name: "minitest"}}},
+		{
+			value: "playwright=e2e/**/*.spec.{js,ts},jest",
+			want:  []FrameworkEntry{{Name: "playwright", TestsLocation: "e2e/**/*.spec.{js,ts}"}, {Name: "jest"}},
+		},
+		{value: "rspec=spec/**/*_spec.rb", wantErr: true},
+		{value: "rspec,=test/**/*_test.rb", wantErr: true},
+		{value: "rspec,rspec", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseFrameworks(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseFrameworks(%q) expected error", tt.value)
+				}
+				return
+			}
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Fatalf("ParseFrameworks(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFrameworks(t *testing.T) {
+	single := []FrameworkEntry{{Name: "rspec"}}
+	several := []FrameworkEntry{{Name: "rspec"}, {Name: "minitest"}}
+
+	if err := validateFrameworks(single, "spec/models/**/*_spec.rb", "bin/rspec"); err != nil {
+		t.Errorf("expected a single framework to accept tests_location and command, got %v", err)
+	}
+	if err := validateFrameworks(several, "", ""); err != nil {
+		t.Errorf("expected several frameworks without overrides to be valid, got %v", err)
+	}
+	if err := validateFrameworks(several, "spec/**/*_spec.rb", ""); err == nil {
+		t.Error("expected tests_location to be rejected with several frameworks")
+	}
+	if err := validateFrameworks(several, "", "bin/rspec"); err == nil {
+		t.Error("expected command to be rejected with several frameworks")
+	}
+}
+
+func TestParseWorkerOutputMode(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    WorkerOutputMode
+		wantErr bool
+	}{
+		{value: "", want: WorkerOutputStream},
+		{value: "stream", want: WorkerOutputStream},
+		{value: "prefix", want: WorkerOutputPrefix},
+		{value: "BUFFERED", want: WorkerOutputBuffered},
+		{value: "file", want: WorkerOutputFile},
+		{value: "quiet", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseWorkerOutputMode(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseWorkerOutputMode(%q) expected error", tt.value)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseWorkerOutputMode(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
 func TestGetWorkerEnvMap(t *testing.T) {
 	t.Run("empty worker env", func(t *testing.T) {
 		config = &Config{WorkerEnv: ""}
@@ -28,8 +28,12 @@ const (
 type CLICommandType string
 
 const (
-	CLICommandPlan CLICommandType = "plan"
-	CLICommandRun  CLICommandType = "run"
+	CLICommandPlan       CLICommandType = "plan"
+	CLICommandRun        CLICommandType = "run"
+	CLICommandServe      CLICommandType = "serve"
+	CLICommandExplain    CLICommandType = "explain"
+	CLICommandVerifyPlan CLICommandType = "verify-plan"
+	CLICommandPlanDiff   CLICommandType = "plan-diff"
 )
 
 // TestDiscoveryMode identifies the discovery strategy selected by the planner.
@@ -82,6 +86,8 @@ type PlanningMetrics struct {
 	RunnableTestFiles         int
 	FullySkippedTestFiles     int
 	BackendDurationTestFiles  int
+	LocalDurationTestFiles    int
+	HistoryDurationTestFiles  int
 	DefaultDurationTestFiles  int
 	EstimatedTimeSavedPercent float64
 	ParallelRunners           int
@@ -285,6 +291,8 @@ func Planning(client Client, metrics PlanningMetrics) {
 	distribution(client, "ddtest.planning.test_files", appendPlanningTags(commonTags, "state:fully_skipped"), float64(metrics.FullySkippedTestFiles))
 	distribution(client, "ddtest.planning.estimated_time_saved_pct", commonTags, metrics.EstimatedTimeSavedPercent)
 	distribution(client, "ddtest.planning.test_file_durations", appendPlanningTags(commonTags, "source:backend"), float64(metrics.BackendDurationTestFiles))
+	distribution(client, "ddtest.planning.test_file_durations", appendPlanningTags(commonTags, "source:local"), float64(metrics.LocalDurationTestFiles))
+	distribution(client, "ddtest.planning.test_file_durations", appendPlanningTags(commonTags, "source:history"), float64(metrics.HistoryDurationTestFiles))
 	distribution(client, "ddtest.planning.test_file_durations", appendPlanningTags(commonTags, "source:default"), float64(metrics.DefaultDurationTestFiles))
 	distribution(client, "ddtest.planning.parallel_runners", commonTags, float64(metrics.ParallelRunners))
 	distribution(client, "ddtest.planning.expected_full_runtime_ms", commonTags, milliseconds(metrics.ExpectedFullRuntime))
@@ -287,6 +287,8 @@ func TestPlanningMetrics(t *testing.T) {
 		RunnableTestFiles:         7,
 		FullySkippedTestFiles:     3,
 		BackendDurationTestFiles:  5,
+		LocalDurationTestFiles:    1,
+		HistoryDurationTestFiles:  4,
 		DefaultDurationTestFiles:  2,
 		EstimatedTimeSavedPercent: 30,
 		ParallelRunners:           3,
@@ -320,6 +322,8 @@ func TestPlanningMetrics(t *testing.T) {
 		{kind: "distribution", name: "ddtest.planning.test_files", tags: withTag("state:fully_skipped"), value: 3},
 		{kind: "distribution", name: "ddtest.planning.estimated_time_saved_pct", tags: commonTags, value: 30},
 		{kind: "distribution", name: "ddtest.planning.test_file_durations", tags: withTag("source:backend"), value: 5},
+		{kind: "distribution", name: "ddtest.planning.test_file_durations", tags: withTag("source:local"), value: 1},
+		{kind: "distribution", name: "ddtest.planning.test_file_durations", tags: withTag("source:history"), value: 4},
 		{kind: "distribution", name: "ddtest.planning.test_file_durations", tags: withTag("source:default"), value: 2},
 		{kind: "distribution", name: "ddtest.planning.parallel_runners", tags: commonTags, value: 3},
 		{kind: "distribution", name: "ddtest.planning.expected_full_runtime_ms", tags: commonTags, value: 10000},
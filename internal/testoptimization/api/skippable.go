@@ -6,6 +6,7 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"time"
@@ -197,6 +198,29 @@ func (c *transport) GetSkippableTests() (correlationID string, skippables Skippa
 	return responseObject.Meta.CorrelationID, skippables, nil
 }
 
+// DecodeSkippablesResponse reads the skippable tests and suites of a raw
+// skippable tests response, such as the one ddtest plan caches. Unlike
+// GetSkippableTests, it keeps entries for every test configuration.
+func DecodeSkippablesResponse(body []byte) (Skippables, error) {
+	var responseObject skippableResponse
+	if err := json.Unmarshal(body, &responseObject); err != nil {
+		return NewSkippables(), fmt.Errorf("unmarshalling skippable tests response: %w", err)
+	}
+
+	skippables := NewSkippables()
+	for _, data := range responseObject.Data {
+		switch data.Type {
+		case string(settings.TestSkippingLevelTest):
+			skippables.Tests[skippableTestKey(data.Attributes)] = true
+		case string(settings.TestSkippingLevelSuite):
+			if data.Attributes.Suite != "" {
+				skippables.Suites[skippableSuiteKey(data.Attributes)] = true
+			}
+		}
+	}
+	return skippables, nil
+}
+
 func skippableTestKey(test SkippableResponseDataAttributes) string {
 	return test.Configurations.TestBundle + "." + test.Suite + "." + test.Name + "." + test.Parameters
 }
@@ -210,6 +210,25 @@ func TestTransportGetSkippableTestsParsesMixedResponse(t *testing.T) {
 	}
 }
 
+func TestDecodeSkippablesResponse(t *testing.T) {
+	response := `{"meta":{"correlation_id":"cid"},"data":[
+		{"type":"test","attributes":{"suite":"suite-a","name":"test-a","parameters":"","configurations":{"test.bundle":"rspec","os.platform":"darwin"}}},
+		{"type":"suite","attributes":{"suite":"suite-b","configurations":{"test.bundle":"rspec"}}}
+	]}`
+
+	skippables, err := DecodeSkippablesResponse([]byte(response))
+	if err != nil {
+		t.Fatalf("DecodeSkippablesResponse() returned error: %v", err)
+	}
+	if !skippables.Tests["rspec.suite-a.test-a."] || !skippables.Suites[SkippableSuite{Module: "rspec", Suite: "suite-b"}] {
+		t.Fatalf("expected every skippable entry, got %#v", skippables)
+	}
+
+	if _, err := DecodeSkippablesResponse([]byte("not json")); err == nil {
+		t.Fatal("expected invalid response to fail")
+	}
+}
+
 func TestSkippableTestKey(t *testing.T) {
 	test := SkippableResponseDataAttributes{
 		Suite:      "suite-a",
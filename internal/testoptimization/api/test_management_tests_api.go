@@ -6,6 +6,7 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -142,3 +143,13 @@ func testManagementResponseTestCount(response *TestManagementTestsResponseDataMo
 	}
 	return testCount
 }
+
+// DecodeTestManagementTestsResponse reads the test properties of a raw test
+// management tests response, such as the one ddtest plan caches.
+func DecodeTestManagementTestsResponse(body []byte) (*TestManagementTestsResponseDataModules, error) {
+	var responseObject testManagementTestsResponse
+	if err := json.Unmarshal(body, &responseObject); err != nil {
+		return nil, fmt.Errorf("unmarshalling test management tests response: %w", err)
+	}
+	return &responseObject.Data.Attributes, nil
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 
 	"github.com/DataDog/ddtest/internal/constants"
+	"github.com/DataDog/ddtest/internal/testoptimization/api"
 )
 
 const (
@@ -107,6 +108,30 @@ func (cm *CacheManager) StoreTestManagementTestsCache(data json.RawMessage) erro
 	return cm.storeHTTPResponse(data, httpTestManagementCacheFile)
 }
 
+// ReadSkippableTestsCache reads the skippable tests and suites from the cached
+// backend response.
+func (cm *CacheManager) ReadSkippableTestsCache() (api.Skippables, error) {
+	data, err := os.ReadFile(filepath.Join(constants.HTTPCacheDir, httpSkippableTestsCacheFile))
+	if err != nil {
+		return api.NewSkippables(), fmt.Errorf("failed to read file: %w", err)
+	}
+	return api.DecodeSkippablesResponse(data)
+}
+
+// ReadDisabledTestsCache reads the disabled tests, keyed by FQN, from the
+// cached test management response.
+func (cm *CacheManager) ReadDisabledTestsCache() (map[string]bool, error) {
+	data, err := os.ReadFile(filepath.Join(constants.HTTPCacheDir, httpTestManagementCacheFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	testManagementTests, err := api.DecodeTestManagementTestsResponse(data)
+	if err != nil {
+		return nil, err
+	}
+	return disabledTestsFromTestManagementData(testManagementTests), nil
+}
+
 // StoreTestOptimizationPlanCache stores ddtest-private plan data in the runner cache.
 func (cm *CacheManager) StoreTestOptimizationPlanCache(cache any) error {
 	if err := cm.createRunnerCacheDirectory(); err != nil {
@@ -133,3 +158,31 @@ func (cm *CacheManager) ReadTestOptimizationPlanCache(cache any) error {
 	slog.Debug("Test optimization plan read from file", "path", runnerPath)
 	return nil
 }
+
+// StoreTestFileDurationsCache stores locally measured test file durations in
+// the runner cache.
+func (cm *CacheManager) StoreTestFileDurationsCache(cache any) error {
+	if err := cm.createRunnerCacheDirectory(); err != nil {
+		return fmt.Errorf("failed to create runner cache directory: %w", err)
+	}
+
+	runnerPath := filepath.Join(constants.RunnerCacheDir, constants.TestFileDurationsCacheFile)
+	if err := cm.writeJSONToFile(cache, runnerPath); err != nil {
+		return err
+	}
+
+	slog.Debug("Test file durations written to file", "path", runnerPath)
+	return nil
+}
+
+// ReadTestFileDurationsCache reads locally measured test file durations from
+// the runner cache.
+func (cm *CacheManager) ReadTestFileDurationsCache(cache any) error {
+	runnerPath := filepath.Join(constants.RunnerCacheDir, constants.TestFileDurationsCacheFile)
+	if err := cm.readJSONFromFile(runnerPath, cache); err != nil {
+		return err
+	}
+
+	slog.Debug("Test file durations read from file", "path", runnerPath)
+	return nil
+}
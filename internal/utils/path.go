@@ -83,6 +83,20 @@ func StripCwdSubdirPrefix(path string) string {
 	return stripSubdirPrefix(path, CwdSubdirPrefix())
 }
 
+// NormalizeReportedPath makes a test file path that a test framework reported,
+// such as in a JUnit or failure report, match the test file paths ddtest
+// plans and runs, which are relative to the working directory.
+func NormalizeReportedPath(path string) string {
+	if filepath.IsAbs(path) {
+		if cwd, err := os.Getwd(); err == nil {
+			if relative, err := filepath.Rel(cwd, path); err == nil {
+				return NormalizePath(relative)
+			}
+		}
+	}
+	return NormalizePath(StripCwdSubdirPrefix(path))
+}
+
 func stripSubdirPrefix(path string, subdirPrefix string) string {
 	if path == "" || subdirPrefix == "" {
 		return path
@@ -263,6 +263,31 @@ func TestStripCwdSubdirPrefix_EmptyPath_NoChange(t *testing.T) {
 	}
 }
 
+func TestNormalizeReportedPath(t *testing.T) {
+	repoRoot := t.TempDir()
+	initGitRepoInDir(t, repoRoot)
+
+	coreDir := filepath.Join(repoRoot, "core")
+	_ = os.MkdirAll(coreDir, 0755)
+
+	oldWd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(oldWd) }()
+	_ = os.Chdir(coreDir)
+	resetCwdSubdirPrefixCache(t)
+	cwd, _ := os.Getwd()
+
+	tests := map[string]string{
+		filepath.Join(cwd, "spec", "models", "order_spec.rb"): "spec/models/order_spec.rb",
+		"./core/spec/models/order_spec.rb":                    "spec/models/order_spec.rb",
+		"./spec/models/order_spec.rb":                         "spec/models/order_spec.rb",
+	}
+	for path, expected := range tests {
+		if result := NormalizeReportedPath(path); result != expected {
+			t.Errorf("NormalizeReportedPath(%q) = %q, want %q", path, result, expected)
+		}
+	}
+}
+
 func resetCwdSubdirPrefixCache(t *testing.T) {
 	t.Helper()
 	ResetCwdSubdirPrefixForTesting()